@@ -0,0 +1,533 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+)
+
+// CreateAccountRequest represents the request payload for opening an account
+// swagger:model CreateAccountRequest
+type CreateAccountRequest struct {
+	// Display name of the account
+	// required: true
+	// max length: 255
+	// example: Alice's USD wallet
+	Name string `json:"name" validate:"required,max=255"`
+
+	// Chart of accounts code: four digits optionally followed by dash-separated segments
+	// max length: 64
+	// example: 2000-USER-WALLETS-NGN
+	Code string `json:"code,omitempty" validate:"omitempty,max=64"`
+
+	// Type of account, which determines its normal balance direction
+	// required: true
+	// enum: Asset,Liability,Equity,Revenue,Expense
+	// example: Liability
+	Type string `json:"type" validate:"required,oneof=Asset Liability Equity Revenue Expense"`
+
+	// The parent account in the chart of accounts; it must have the same type and no postings
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ParentAccountID string `json:"parent_account_id,omitempty" validate:"omitempty,uuid"`
+
+	// The user owning the account, for wallet accounts
+	// max length: 255
+	// example: user-123
+	UserID string `json:"user_id,omitempty" validate:"omitempty,max=255"`
+
+	// The currency code (ISO 4217)
+	// required: true
+	// example: USD
+	Currency string `json:"currency" validate:"required,iso4217"`
+
+	// The tier that selects the fee schedules charged to the account, defaults to standard
+	// max length: 32
+	// example: premium
+	Tier string `json:"tier,omitempty" validate:"omitempty,max=32"`
+}
+
+// ToServiceRequest converts a CreateAccountRequest to a service.CreateAccountRequest
+func (r CreateAccountRequest) ToServiceRequest() service.CreateAccountRequest {
+	return service.CreateAccountRequest{
+		Name:            r.Name,
+		Code:            r.Code,
+		Type:            models.AccountType(r.Type),
+		ParentAccountID: r.ParentAccountID,
+		UserID:          r.UserID,
+		Currency:        r.Currency,
+		Tier:            r.Tier,
+	}
+}
+
+// MoveAccountRequest represents the request payload for moving an account in the chart of accounts
+// swagger:model MoveAccountRequest
+type MoveAccountRequest struct {
+	// The new parent account; empty moves the account to the top level
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ParentAccountID string `json:"parent_account_id" validate:"omitempty,uuid"`
+}
+
+// RenameAccountRequest represents the request payload for renaming an account
+// swagger:model RenameAccountRequest
+type RenameAccountRequest struct {
+	// New display name of the account
+	// required: true
+	// max length: 255
+	// example: Alice's savings
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// ChangeAccountStatusRequest represents the request payload for changing an account's status
+// swagger:model ChangeAccountStatusRequest
+type ChangeAccountStatusRequest struct {
+	// The new status of the account
+	// required: true
+	// enum: active,frozen,dormant,closed
+	// example: frozen
+	Status string `json:"status" validate:"required,oneof=active frozen dormant closed"`
+
+	// Why the status is being changed
+	// required: true
+	// max length: 255
+	// example: Suspicious activity under review
+	Reason string `json:"reason" validate:"required,max=255"`
+
+	// Who is making the change
+	// required: true
+	// max length: 255
+	// example: compliance@example.com
+	Actor string `json:"actor" validate:"required,max=255"`
+}
+
+// CloseAccountRequest represents the request payload for closing an account
+// swagger:model CloseAccountRequest
+type CloseAccountRequest struct {
+	// Why the account is being closed
+	// required: true
+	// max length: 255
+	// example: Customer request
+	Reason string `json:"reason" validate:"required,max=255"`
+
+	// Who is closing the account
+	// required: true
+	// max length: 255
+	// example: support@example.com
+	Actor string `json:"actor" validate:"required,max=255"`
+}
+
+// AccountResponse represents an account in the API response
+// swagger:model AccountResponse
+type AccountResponse struct {
+	// The unique identifier of the account
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ID string `json:"id"`
+
+	// Display name of the account
+	// example: Alice's USD wallet
+	Name string `json:"name"`
+
+	// Chart of accounts code
+	// example: 2000-USER-WALLETS-NGN
+	Code string `json:"code,omitempty"`
+
+	// Type of account
+	// example: Liability
+	Type string `json:"type"`
+
+	// The parent account in the chart of accounts
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ParentAccountID string `json:"parent_account_id,omitempty"`
+
+	// The user owning the account, for wallet accounts
+	// example: user-123
+	UserID string `json:"user_id,omitempty"`
+
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// The lifecycle status of the account
+	// example: active
+	Status string `json:"status"`
+
+	// The tier that selects the fee schedules charged to the account
+	// example: standard
+	Tier string `json:"tier"`
+
+	// The current ledger balance, positive in the account's normal direction
+	// example: 750.00
+	Balance money.Money `json:"balance"`
+
+	// The date and time when the account was created
+	// example: 2023-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+
+	// The date and time when the account was last updated
+	// example: 2023-01-01T00:00:00Z
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AccountsListResponse represents a list of accounts
+// swagger:model AccountsListResponse
+type AccountsListResponse struct {
+	// The list of accounts
+	Data []AccountResponse `json:"data"`
+}
+
+// AccountStatusChangeResponse represents an entry in an account's status history
+// swagger:model AccountStatusChangeResponse
+type AccountStatusChangeResponse struct {
+	// The status the account moved from
+	// example: active
+	FromStatus string `json:"from_status"`
+
+	// The status the account moved to
+	// example: frozen
+	ToStatus string `json:"to_status"`
+
+	// Why the status was changed
+	// example: Suspicious activity under review
+	Reason string `json:"reason"`
+
+	// Who made the change
+	// example: compliance@example.com
+	Actor string `json:"actor"`
+
+	// When the change was made
+	// example: 2023-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountStatusHistoryResponse represents the status history of an account
+// swagger:model AccountStatusHistoryResponse
+type AccountStatusHistoryResponse struct {
+	// The account ID
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// The status changes, oldest first
+	Data []AccountStatusChangeResponse `json:"data"`
+}
+
+// ToStatusHistoryResponse converts an account's status changes to an AccountStatusHistoryResponse
+func ToStatusHistoryResponse(accountID string, history []*models.AccountStatusChange) *AccountStatusHistoryResponse {
+	resp := &AccountStatusHistoryResponse{
+		AccountID: accountID,
+		Data:      make([]AccountStatusChangeResponse, 0, len(history)),
+	}
+	for _, change := range history {
+		resp.Data = append(resp.Data, AccountStatusChangeResponse{
+			FromStatus: string(change.FromStatus),
+			ToStatus:   string(change.ToStatus),
+			Reason:     change.Reason,
+			Actor:      change.Actor,
+			CreatedAt:  change.CreatedAt,
+		})
+	}
+	return resp
+}
+
+// ToAccountResponse converts a models.Account and its current balance to an AccountResponse
+func ToAccountResponse(account *models.Account, balance money.Money) *AccountResponse {
+	if account == nil {
+		return nil
+	}
+
+	return &AccountResponse{
+		ID:              account.ID,
+		Name:            account.Name,
+		Code:            account.Code,
+		Type:            string(account.Type),
+		ParentAccountID: account.ParentAccountID,
+		UserID:          account.UserID,
+		Currency:        account.Currency,
+		Status:          string(account.Status),
+		Tier:            account.Tier,
+		Balance:         balance,
+		CreatedAt:       account.CreatedAt,
+		UpdatedAt:       account.UpdatedAt,
+	}
+}
+
+// AccountBalanceResponse represents the balance of an account in the API response
+// swagger:model AccountBalanceResponse
+type AccountBalanceResponse struct {
+	// The account ID
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// The account type, which determines the sign of the balance
+	// example: Liability
+	AccountType string `json:"account_type"`
+
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// Sum of all debits posted to the account up to as_of
+	// example: 250.00
+	TotalDebit money.Money `json:"total_debit"`
+
+	// Sum of all credits posted to the account up to as_of
+	// example: 1000.00
+	TotalCredit money.Money `json:"total_credit"`
+
+	// The ledger balance, positive in the account's normal direction
+	// example: 750.00
+	Balance money.Money `json:"balance"`
+
+	// The point in time the balance was computed for
+	// example: 2023-01-31T23:59:59Z
+	AsOf time.Time `json:"as_of"`
+}
+
+// ToBalanceResponse converts a service.AccountBalance to an AccountBalanceResponse
+func ToBalanceResponse(balance *service.AccountBalance) *AccountBalanceResponse {
+	if balance == nil {
+		return nil
+	}
+
+	return &AccountBalanceResponse{
+		AccountID:   balance.AccountID,
+		AccountType: string(balance.AccountType),
+		Currency:    balance.Currency,
+		TotalDebit:  balance.TotalDebit,
+		TotalCredit: balance.TotalCredit,
+		Balance:     balance.Balance,
+		AsOf:        balance.AsOf,
+	}
+}
+
+// CurrencyBalanceResponse represents the balance of a group of accounts in one currency
+// swagger:model CurrencyBalanceResponse
+type CurrencyBalanceResponse struct {
+	// The currency code (ISO 4217)
+	// example: NGN
+	Currency string `json:"currency"`
+
+	// Sum of all debits posted up to as_of
+	// example: 250.00
+	TotalDebit money.Money `json:"total_debit"`
+
+	// Sum of all credits posted up to as_of
+	// example: 1000.00
+	TotalCredit money.Money `json:"total_credit"`
+
+	// The balance, positive in the normal direction of the requested account
+	// example: 750.00
+	Balance money.Money `json:"balance"`
+}
+
+// RollupBalanceResponse represents the combined balance of an account and its descendants
+// swagger:model RollupBalanceResponse
+type RollupBalanceResponse struct {
+	// The account ID of the node the balance was rolled up to
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// Chart of accounts code of the node
+	// example: 2000-USER-WALLETS-NGN
+	Code string `json:"code,omitempty"`
+
+	// The account type, which determines the sign of the balances
+	// example: Liability
+	AccountType string `json:"account_type"`
+
+	// Number of accounts included, the node itself among them
+	// example: 42
+	AccountCount int `json:"account_count"`
+
+	// The balances, one per currency
+	Balances []CurrencyBalanceResponse `json:"balances"`
+
+	// The point in time the balance was computed for
+	// example: 2023-01-31T23:59:59Z
+	AsOf time.Time `json:"as_of"`
+}
+
+// ToRollupBalanceResponse converts a service.RollupBalance to a RollupBalanceResponse
+func ToRollupBalanceResponse(rollup *service.RollupBalance) *RollupBalanceResponse {
+	if rollup == nil {
+		return nil
+	}
+
+	resp := &RollupBalanceResponse{
+		AccountID:    rollup.AccountID,
+		Code:         rollup.Code,
+		AccountType:  string(rollup.AccountType),
+		AccountCount: rollup.AccountCount,
+		Balances:     make([]CurrencyBalanceResponse, 0, len(rollup.Balances)),
+		AsOf:         rollup.AsOf,
+	}
+	for _, b := range rollup.Balances {
+		resp.Balances = append(resp.Balances, CurrencyBalanceResponse{
+			Currency:    b.Currency,
+			TotalDebit:  b.TotalDebit,
+			TotalCredit: b.TotalCredit,
+			Balance:     b.Balance,
+		})
+	}
+	return resp
+}
+
+// StatementCounterpartResponse represents an account on the other side of a statement line
+// swagger:model StatementCounterpartResponse
+type StatementCounterpartResponse struct {
+	// The counterpart account ID
+	// example: 550e8400-e29b-41d4-a716-446655440001
+	AccountID string `json:"account_id"`
+
+	// Chart of accounts code of the counterpart
+	// example: 1000-BANK-CLEARING
+	Code string `json:"code,omitempty"`
+
+	// Display name of the counterpart
+	// example: Bob's USD wallet
+	Name string `json:"name"`
+
+	// Amount debited to the counterpart
+	// example: 0.00
+	Debit money.Money `json:"debit"`
+
+	// Amount credited to the counterpart
+	// example: 25.00
+	Credit money.Money `json:"credit"`
+}
+
+// StatementLineResponse represents one posting on an account statement
+// swagger:model StatementLineResponse
+type StatementLineResponse struct {
+	// The entry line ID
+	// example: 550e8400-e29b-41d4-a716-446655440002
+	LineID string `json:"line_id"`
+
+	// The entry the line belongs to
+	// example: 550e8400-e29b-41d4-a716-446655440003
+	EntryID string `json:"entry_id"`
+
+	// The date of the entry
+	// example: 2023-01-15T10:30:00Z
+	Date time.Time `json:"date"`
+
+	// Description of the entry
+	// example: Transfer to Bob
+	Description string `json:"description"`
+
+	// The reference ID of the entry
+	// example: REF-12345
+	ReferenceID string `json:"reference_id,omitempty"`
+
+	// The type of the entry
+	// example: transfer
+	TransactionType string `json:"transaction_type"`
+
+	// Amount debited to the account
+	// example: 25.00
+	Debit money.Money `json:"debit"`
+
+	// Amount credited to the account
+	// example: 0.00
+	Credit money.Money `json:"credit"`
+
+	// The account balance after this line, positive in the account's normal direction
+	// example: 725.00
+	Balance money.Money `json:"balance"`
+
+	// The other accounts in the entry
+	Counterparts []StatementCounterpartResponse `json:"counterparts"`
+}
+
+// StatementResponse represents a page of an account statement
+// swagger:model StatementResponse
+type StatementResponse struct {
+	// The account ID
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// Chart of accounts code
+	// example: 2000-USER-WALLETS-USD
+	Code string `json:"code,omitempty"`
+
+	// Display name of the account
+	// example: Alice's USD wallet
+	Name string `json:"name"`
+
+	// Type of account
+	// example: Liability
+	AccountType string `json:"account_type"`
+
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// Start of the period
+	// example: 2023-01-01T00:00:00Z
+	From time.Time `json:"from"`
+
+	// End of the period
+	// example: 2023-01-31T23:59:59Z
+	To time.Time `json:"to"`
+
+	// The balance before the first line of the period
+	// example: 750.00
+	OpeningBalance money.Money `json:"opening_balance"`
+
+	// The balance after the last line of the period
+	// example: 725.00
+	ClosingBalance money.Money `json:"closing_balance"`
+
+	// One page of the period's lines, oldest first
+	Lines []StatementLineResponse `json:"lines"`
+
+	// Pass as cursor to fetch the next page; absent on the last page
+	// example: MjAyMy0wMS0xNVQxMDozMDowMFp8NTUwZTg0MDA
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ToStatementResponse converts a service.Statement to a StatementResponse
+func ToStatementResponse(statement *service.Statement) *StatementResponse {
+	if statement == nil {
+		return nil
+	}
+
+	resp := &StatementResponse{
+		AccountID:      statement.AccountID,
+		Code:           statement.Code,
+		Name:           statement.Name,
+		AccountType:    string(statement.AccountType),
+		Currency:       statement.Currency,
+		From:           statement.From,
+		To:             statement.To,
+		OpeningBalance: statement.OpeningBalance,
+		ClosingBalance: statement.ClosingBalance,
+		Lines:          make([]StatementLineResponse, 0, len(statement.Lines)),
+		NextCursor:     statement.NextCursor,
+	}
+	for _, line := range statement.Lines {
+		lineResp := StatementLineResponse{
+			LineID:          line.LineID,
+			EntryID:         line.EntryID,
+			Date:            line.Date,
+			Description:     line.Description,
+			ReferenceID:     line.ReferenceID,
+			TransactionType: line.TransactionType,
+			Debit:           line.Debit,
+			Credit:          line.Credit,
+			Balance:         line.Balance,
+			Counterparts:    make([]StatementCounterpartResponse, 0, len(line.Counterparts)),
+		}
+		for _, c := range line.Counterparts {
+			lineResp.Counterparts = append(lineResp.Counterparts, StatementCounterpartResponse{
+				AccountID: c.AccountID,
+				Code:      c.Code,
+				Name:      c.Name,
+				Debit:     c.Debit,
+				Credit:    c.Credit,
+			})
+		}
+		resp.Lines = append(resp.Lines, lineResp)
+	}
+	return resp
+}
@@ -0,0 +1,270 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+)
+
+// FeeTierRequest represents one band of a tiered fee
+type FeeTierRequest struct {
+	// Largest amount in the band; omit on the last band
+	// example: 1000.00
+	UpTo money.Money `json:"up_to"`
+
+	// Fixed part of the fee in the band
+	// example: 0.50
+	Flat money.Money `json:"flat"`
+
+	// Fraction of the amount charged in the band
+	// example: 0.01
+	Rate money.Rate `json:"rate" validate:"gte=0,lte=1"`
+}
+
+// CreateFeeScheduleRequest represents the request payload for a new fee schedule version
+// swagger:model CreateFeeScheduleRequest
+type CreateFeeScheduleRequest struct {
+	// The transaction type the fee is charged on
+	// required: true
+	// enum: wallet.transfer,wallet.withdrawal,wallet.exchange
+	// example: wallet.transfer
+	TransactionType string `json:"transaction_type" validate:"required,oneof=wallet.transfer wallet.withdrawal wallet.exchange"`
+
+	// The currency of the transactions charged (ISO 4217)
+	// required: true
+	// example: USD
+	Currency string `json:"currency" validate:"required,iso4217"`
+
+	// The account tier charged, defaults to standard
+	// max length: 32
+	// example: premium
+	AccountTier string `json:"account_tier" validate:"omitempty,max=32"`
+
+	// How the fee is computed
+	// required: true
+	// enum: flat,percentage,tiered,capped
+	// example: capped
+	FeeType string `json:"fee_type" validate:"required,oneof=flat percentage tiered capped"`
+
+	// The fee of a flat schedule
+	// example: 1.00
+	FlatAmount money.Money `json:"flat_amount"`
+
+	// Fraction of the amount charged by percentage and capped schedules
+	// example: 0.015
+	Rate money.Rate `json:"rate" validate:"gte=0,lte=1"`
+
+	// Smallest fee of a capped schedule
+	// example: 0.50
+	MinAmount money.Money `json:"min_amount"`
+
+	// Largest fee of a capped schedule
+	// example: 25.00
+	MaxAmount money.Money `json:"max_amount"`
+
+	// Bands of a tiered schedule, in ascending order
+	Tiers []FeeTierRequest `json:"tiers" validate:"dive"`
+
+	// When the schedule takes effect (RFC3339 format), defaults to now; it may not be in the past
+	// example: 2024-02-01T00:00:00Z
+	EffectiveFrom time.Time `json:"effective_from"`
+
+	// Who created the schedule
+	// required: true
+	// max length: 255
+	// example: pricing@example.com
+	Actor string `json:"actor" validate:"required,max=255"`
+}
+
+// ToModel converts a CreateFeeScheduleRequest to a models.FeeSchedule
+func (r CreateFeeScheduleRequest) ToModel() *models.FeeSchedule {
+	schedule := &models.FeeSchedule{
+		TransactionType: r.TransactionType,
+		Currency:        r.Currency,
+		AccountTier:     r.AccountTier,
+		FeeType:         models.FeeType(r.FeeType),
+		FlatAmount:      r.FlatAmount,
+		Rate:            r.Rate,
+		MinAmount:       r.MinAmount,
+		MaxAmount:       r.MaxAmount,
+		EffectiveFrom:   r.EffectiveFrom,
+		CreatedBy:       r.Actor,
+	}
+	for _, tier := range r.Tiers {
+		schedule.Tiers = append(schedule.Tiers, models.FeeTier{UpTo: tier.UpTo, Flat: tier.Flat, Rate: tier.Rate})
+	}
+	return schedule
+}
+
+// FeeScheduleResponse represents a fee schedule version in the API response
+// swagger:model FeeScheduleResponse
+type FeeScheduleResponse struct {
+	// The unique identifier of the schedule version
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ID string `json:"id"`
+
+	// The transaction type the fee is charged on
+	// example: wallet.transfer
+	TransactionType string `json:"transaction_type"`
+
+	// The currency of the transactions charged (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// The account tier charged
+	// example: standard
+	AccountTier string `json:"account_tier"`
+
+	// The version of the schedule for its transaction type, currency and tier
+	// example: 3
+	Version int `json:"version"`
+
+	// How the fee is computed
+	// example: capped
+	FeeType string `json:"fee_type"`
+
+	// The fee of a flat schedule
+	// example: 0.00
+	FlatAmount money.Money `json:"flat_amount"`
+
+	// Fraction of the amount charged by percentage and capped schedules
+	// example: 0.015
+	Rate money.Rate `json:"rate"`
+
+	// Smallest fee of a capped schedule
+	// example: 0.50
+	MinAmount money.Money `json:"min_amount"`
+
+	// Largest fee of a capped schedule
+	// example: 25.00
+	MaxAmount money.Money `json:"max_amount"`
+
+	// Bands of a tiered schedule
+	Tiers []FeeTierRequest `json:"tiers,omitempty"`
+
+	// When the schedule takes effect
+	// example: 2024-02-01T00:00:00Z
+	EffectiveFrom time.Time `json:"effective_from"`
+
+	// Who created the schedule
+	// example: pricing@example.com
+	CreatedBy string `json:"created_by"`
+
+	// The date and time when the schedule was created
+	// example: 2024-01-15T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ToFeeScheduleResponse converts a models.FeeSchedule to a FeeScheduleResponse
+func ToFeeScheduleResponse(schedule *models.FeeSchedule) *FeeScheduleResponse {
+	resp := &FeeScheduleResponse{
+		ID:              schedule.ID,
+		TransactionType: schedule.TransactionType,
+		Currency:        schedule.Currency,
+		AccountTier:     schedule.AccountTier,
+		Version:         schedule.Version,
+		FeeType:         string(schedule.FeeType),
+		FlatAmount:      schedule.FlatAmount,
+		Rate:            schedule.Rate,
+		MinAmount:       schedule.MinAmount,
+		MaxAmount:       schedule.MaxAmount,
+		EffectiveFrom:   schedule.EffectiveFrom,
+		CreatedBy:       schedule.CreatedBy,
+		CreatedAt:       schedule.CreatedAt,
+	}
+	for _, tier := range schedule.Tiers {
+		resp.Tiers = append(resp.Tiers, FeeTierRequest{UpTo: tier.UpTo, Flat: tier.Flat, Rate: tier.Rate})
+	}
+	return resp
+}
+
+// ListFeeSchedulesResponse represents a list of fee schedule versions
+// swagger:model ListFeeSchedulesResponse
+type ListFeeSchedulesResponse struct {
+	// The schedules, by transaction type, currency, tier and then version
+	Data []*FeeScheduleResponse `json:"data"`
+}
+
+// ToListFeeSchedulesResponse converts fee schedules to a ListFeeSchedulesResponse
+func ToListFeeSchedulesResponse(schedules []*models.FeeSchedule) *ListFeeSchedulesResponse {
+	resp := &ListFeeSchedulesResponse{Data: make([]*FeeScheduleResponse, 0, len(schedules))}
+	for _, schedule := range schedules {
+		resp.Data = append(resp.Data, ToFeeScheduleResponse(schedule))
+	}
+	return resp
+}
+
+// AssessFeeRequest represents the request payload for working out the fee on a transaction
+// swagger:model AssessFeeRequest
+type AssessFeeRequest struct {
+	// The transaction type
+	// required: true
+	// enum: wallet.transfer,wallet.withdrawal,wallet.exchange
+	// example: wallet.withdrawal
+	TransactionType string `json:"transaction_type" validate:"required,oneof=wallet.transfer wallet.withdrawal wallet.exchange"`
+
+	// The currency of the transaction (ISO 4217)
+	// required: true
+	// example: USD
+	Currency string `json:"currency" validate:"required,iso4217"`
+
+	// The amount of the transaction
+	// required: true
+	// example: 250.00
+	Amount money.Money `json:"amount" validate:"required"`
+
+	// The tier of the account charged, defaults to standard
+	// max length: 32
+	// example: standard
+	AccountTier string `json:"account_tier" validate:"omitempty,max=32"`
+
+	// When the transaction happened (RFC3339 format), defaults to now
+	// example: 2024-02-10T12:00:00Z
+	At time.Time `json:"at"`
+}
+
+// ToServiceRequest converts an AssessFeeRequest to a service.FeeAssessmentRequest
+func (r AssessFeeRequest) ToServiceRequest() service.FeeAssessmentRequest {
+	return service.FeeAssessmentRequest{
+		TransactionType: r.TransactionType,
+		AccountTier:     r.AccountTier,
+		Amount:          r.Amount.WithCurrency(r.Currency),
+		At:              r.At,
+	}
+}
+
+// AssessedFeeResponse represents the fee charged on a transaction in the API response
+// swagger:model AssessedFeeResponse
+type AssessedFeeResponse struct {
+	// The fee charged
+	// example: 3.75
+	Amount money.Money `json:"amount"`
+
+	// The currency of the fee (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// The schedule version that set the fee; empty if no schedule applied
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ScheduleID string `json:"schedule_id,omitempty"`
+
+	// The version of that schedule
+	// example: 3
+	ScheduleVersion int `json:"schedule_version,omitempty"`
+}
+
+// ToAssessedFeeResponse converts a service.AssessedFee to an AssessedFeeResponse.
+// A nil fee is reported as zero.
+func ToAssessedFeeResponse(fee *service.AssessedFee, currency string) *AssessedFeeResponse {
+	if fee == nil {
+		return &AssessedFeeResponse{Amount: money.Zero(currency), Currency: currency}
+	}
+	return &AssessedFeeResponse{
+		Amount:          fee.Amount,
+		Currency:        currency,
+		ScheduleID:      fee.ScheduleID,
+		ScheduleVersion: fee.ScheduleVersion,
+	}
+}
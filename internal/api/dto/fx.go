@@ -0,0 +1,340 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+)
+
+// CreateExchangeRateRequest represents the request payload for storing an exchange rate
+// swagger:model CreateExchangeRateRequest
+type CreateExchangeRateRequest struct {
+	// The currency converted from (ISO 4217)
+	// required: true
+	// example: USD
+	BaseCurrency string `json:"base_currency" validate:"required,iso4217"`
+
+	// The currency converted to (ISO 4217)
+	// required: true
+	// example: EUR
+	QuoteCurrency string `json:"quote_currency" validate:"required,iso4217"`
+
+	// Units of the quote currency per unit of the base currency
+	// required: true
+	// example: 0.92
+	Rate money.Rate `json:"rate" validate:"required,gt=0"`
+
+	// When the rate takes effect (RFC3339 format), defaults to now
+	// example: 2024-01-01T00:00:00Z
+	EffectiveFrom time.Time `json:"effective_from"`
+
+	// Where the rate was obtained
+	// max length: 100
+	// example: treasury
+	Source string `json:"source" validate:"max=100"`
+}
+
+// ToModel converts a CreateExchangeRateRequest to a models.ExchangeRate
+func (r CreateExchangeRateRequest) ToModel() *models.ExchangeRate {
+	return &models.ExchangeRate{
+		BaseCurrency:  r.BaseCurrency,
+		QuoteCurrency: r.QuoteCurrency,
+		Rate:          r.Rate,
+		EffectiveFrom: r.EffectiveFrom,
+		Source:        r.Source,
+	}
+}
+
+// StoredExchangeRateResponse represents a stored exchange rate in the API response
+// swagger:model StoredExchangeRateResponse
+type StoredExchangeRateResponse struct {
+	// The unique identifier of the rate
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ID string `json:"id"`
+
+	// The currency converted from (ISO 4217)
+	// example: USD
+	BaseCurrency string `json:"base_currency"`
+
+	// The currency converted to (ISO 4217)
+	// example: EUR
+	QuoteCurrency string `json:"quote_currency"`
+
+	// Units of the quote currency per unit of the base currency
+	// example: 0.92
+	Rate money.Rate `json:"rate"`
+
+	// When the rate takes effect
+	// example: 2024-01-01T00:00:00Z
+	EffectiveFrom time.Time `json:"effective_from"`
+
+	// Where the rate was obtained
+	// example: treasury
+	Source string `json:"source,omitempty"`
+}
+
+// ToStoredExchangeRateResponse converts a models.ExchangeRate to a StoredExchangeRateResponse
+func ToStoredExchangeRateResponse(rate *models.ExchangeRate) *StoredExchangeRateResponse {
+	return &StoredExchangeRateResponse{
+		ID:            rate.ID,
+		BaseCurrency:  rate.BaseCurrency,
+		QuoteCurrency: rate.QuoteCurrency,
+		Rate:          rate.Rate,
+		EffectiveFrom: rate.EffectiveFrom,
+		Source:        rate.Source,
+	}
+}
+
+// ExchangeRateResponse represents the rate currently served for a currency pair
+// swagger:model ExchangeRateResponse
+type ExchangeRateResponse struct {
+	// The currency converted from (ISO 4217)
+	// example: USD
+	BaseCurrency string `json:"base_currency"`
+
+	// The currency converted to (ISO 4217)
+	// example: EUR
+	QuoteCurrency string `json:"quote_currency"`
+
+	// Units of the quote currency per unit of the base currency
+	// example: 0.92
+	Rate money.Rate `json:"rate"`
+
+	// The provider that served the rate, or how it was derived
+	// example: inverse(db)
+	Source string `json:"source"`
+
+	// When the rate was current
+	// example: 2024-01-01T00:00:00Z
+	AsOf time.Time `json:"as_of"`
+}
+
+// ToExchangeRateResponse converts a service.ResolvedRate to an ExchangeRateResponse
+func ToExchangeRateResponse(rate *service.ResolvedRate) *ExchangeRateResponse {
+	return &ExchangeRateResponse{
+		BaseCurrency:  rate.Base,
+		QuoteCurrency: rate.Quote,
+		Rate:          rate.Rate,
+		Source:        rate.Source,
+		AsOf:          rate.AsOf,
+	}
+}
+
+// CreateQuoteRequest represents the request payload for an FX quote
+// swagger:model CreateQuoteRequest
+type CreateQuoteRequest struct {
+	// The account that will use the quote; if set, no other account may use it
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id" validate:"omitempty,uuid"`
+
+	// The currency converted from (ISO 4217)
+	// required: true
+	// example: USD
+	SourceCurrency string `json:"source_currency" validate:"required,iso4217"`
+
+	// The amount converted, in the source currency
+	// required: true
+	// example: 100.00
+	SourceAmount money.Money `json:"source_amount" validate:"required"`
+
+	// The currency converted to (ISO 4217)
+	// required: true
+	// example: EUR
+	DestinationCurrency string `json:"destination_currency" validate:"required,iso4217"`
+}
+
+// ToServiceRequest converts a CreateQuoteRequest to a service.QuoteRequest
+func (r CreateQuoteRequest) ToServiceRequest() service.QuoteRequest {
+	return service.QuoteRequest{
+		AccountID:           r.AccountID,
+		SourceCurrency:      r.SourceCurrency,
+		SourceAmount:        r.SourceAmount.WithCurrency(r.SourceCurrency),
+		DestinationCurrency: r.DestinationCurrency,
+	}
+}
+
+// QuoteResponse represents an FX quote in the API response
+// swagger:model QuoteResponse
+type QuoteResponse struct {
+	// The quote ID to pass to a wallet.exchange transaction
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ID string `json:"id"`
+
+	// The account that may use the quote, if restricted
+	// example: 550e8400-e29b-41d4-a716-446655440001
+	AccountID string `json:"account_id,omitempty"`
+
+	// The currency converted from (ISO 4217)
+	// example: USD
+	SourceCurrency string `json:"source_currency"`
+
+	// The amount converted
+	// example: 100.00
+	SourceAmount money.Money `json:"source_amount"`
+
+	// The currency converted to (ISO 4217)
+	// example: EUR
+	DestinationCurrency string `json:"destination_currency"`
+
+	// The amount the destination account receives
+	// example: 91.54
+	DestinationAmount money.Money `json:"destination_amount"`
+
+	// Units of the destination currency per unit of the source currency, after the spread
+	// example: 0.91540
+	Rate money.Rate `json:"rate"`
+
+	// The rate before the spread
+	// example: 0.92
+	MidRate money.Rate `json:"mid_rate"`
+
+	// Fraction of the mid rate kept as spread
+	// example: 0.005
+	Spread money.Rate `json:"spread"`
+
+	// The fee charged in the source currency
+	// example: 0.50
+	Fee money.Money `json:"fee"`
+
+	// The provider that served the mid rate, or how it was derived
+	// example: db
+	RateSource string `json:"rate_source"`
+
+	// When the quote stops being usable
+	// example: 2024-01-01T00:00:30Z
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// When the quote was used by an exchange
+	// example: 2024-01-01T00:00:10Z
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+
+	// The date and time when the quote was issued
+	// example: 2024-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ToQuoteResponse converts a models.FXQuote to a QuoteResponse
+func ToQuoteResponse(quote *models.FXQuote) *QuoteResponse {
+	return &QuoteResponse{
+		ID:                  quote.ID,
+		AccountID:           quote.AccountID,
+		SourceCurrency:      quote.SourceCurrency,
+		SourceAmount:        quote.SourceAmount,
+		DestinationCurrency: quote.DestinationCurrency,
+		DestinationAmount:   quote.DestinationAmount,
+		Rate:                quote.Rate,
+		MidRate:             quote.MidRate,
+		Spread:              quote.Spread,
+		Fee:                 quote.Fee,
+		RateSource:          quote.RateSource,
+		ExpiresAt:           quote.ExpiresAt,
+		ConsumedAt:          quote.ConsumedAt,
+		CreatedAt:           quote.CreatedAt,
+	}
+}
+
+// RevaluationRequest represents the request payload for revaluing foreign-currency balances
+// swagger:model RevaluationRequest
+type RevaluationRequest struct {
+	// The date balances are measured and the adjustments are posted at (RFC3339 format)
+	// required: true
+	// example: 2024-01-31T23:59:59Z
+	AsOf time.Time `json:"as_of" validate:"required"`
+
+	// The date the adjustments are reversed at, defaults to the start of the next accounting period
+	// example: 2024-02-01T00:00:00Z
+	ReverseOn time.Time `json:"reverse_on"`
+}
+
+// ToServiceRequest converts a RevaluationRequest to a service.RevaluationRequest
+func (r RevaluationRequest) ToServiceRequest() service.RevaluationRequest {
+	return service.RevaluationRequest{AsOf: r.AsOf, ReverseOn: r.ReverseOn}
+}
+
+// AccountRevaluationResponse represents the revaluation of one account in the API response
+// swagger:model AccountRevaluationResponse
+type AccountRevaluationResponse struct {
+	// The ID of the account
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// The currency of the account (ISO 4217)
+	// example: EUR
+	Currency string `json:"currency"`
+
+	// The balance in the account currency, debits less credits
+	// example: 100.00
+	Balance money.Money `json:"balance"`
+
+	// Units of the reporting currency per unit of the account currency at the as-of date
+	// example: 1.10
+	Rate money.Rate `json:"rate"`
+
+	// The balance converted at the as-of rate
+	// example: 110.00
+	RevaluedAmount money.Money `json:"revalued_amount"`
+
+	// The postings converted at the rates they were made at
+	// example: 108.00
+	HistoricalAmount money.Money `json:"historical_amount"`
+
+	// The unrealized gain, or loss if negative, in the reporting currency
+	// example: 2.00
+	GainLoss money.Money `json:"gain_loss"`
+
+	// The ID of the reporting-currency account the gain or loss is posted to
+	// example: 550e8400-e29b-41d4-a716-446655440001
+	RevaluationAccountID string `json:"revaluation_account_id,omitempty"`
+}
+
+// RevaluationResponse represents a revaluation run in the API response
+// swagger:model RevaluationResponse
+type RevaluationResponse struct {
+	// The date balances were measured at
+	// example: 2024-01-31T23:59:59Z
+	AsOf time.Time `json:"as_of"`
+
+	// The date the adjustments are reversed at
+	// example: 2024-02-01T00:00:00Z
+	ReverseOn time.Time `json:"reverse_on"`
+
+	// The currency balances were measured in (ISO 4217)
+	// example: USD
+	ReportingCurrency string `json:"reporting_currency"`
+
+	// The revalued accounts
+	Accounts []AccountRevaluationResponse `json:"accounts"`
+
+	// The IDs of the adjusting entries and their reversals
+	// example: ["550e8400-e29b-41d4-a716-446655440002", "550e8400-e29b-41d4-a716-446655440003"]
+	EntryIDs []string `json:"entry_ids"`
+}
+
+// ToRevaluationResponse converts a service.Revaluation to a RevaluationResponse
+func ToRevaluationResponse(revaluation *service.Revaluation) *RevaluationResponse {
+	resp := &RevaluationResponse{
+		AsOf:              revaluation.AsOf,
+		ReverseOn:         revaluation.ReverseOn,
+		ReportingCurrency: revaluation.ReportingCurrency,
+		Accounts:          make([]AccountRevaluationResponse, 0, len(revaluation.Accounts)),
+		EntryIDs:          make([]string, 0, len(revaluation.Entries)),
+	}
+	for _, a := range revaluation.Accounts {
+		resp.Accounts = append(resp.Accounts, AccountRevaluationResponse{
+			AccountID:            a.AccountID,
+			Currency:             a.Currency,
+			Balance:              a.Balance,
+			Rate:                 a.Rate,
+			RevaluedAmount:       a.RevaluedAmount,
+			HistoricalAmount:     a.HistoricalAmount,
+			GainLoss:             a.GainLoss,
+			RevaluationAccountID: a.RevaluationAccountID,
+		})
+	}
+	for _, entry := range revaluation.Entries {
+		resp.EntryIDs = append(resp.EntryIDs, entry.ID)
+	}
+	return resp
+}
@@ -0,0 +1,179 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+)
+
+// CreatePeriodRequest represents the request payload for opening an accounting period
+// swagger:model CreatePeriodRequest
+type CreatePeriodRequest struct {
+	// Unique name of the period
+	// required: true
+	// max length: 64
+	// example: 2024-01
+	Name string `json:"name" validate:"required,max=64"`
+
+	// First instant of the period (RFC3339 format), inclusive
+	// required: true
+	// example: 2024-01-01T00:00:00Z
+	StartDate time.Time `json:"start_date" validate:"required"`
+
+	// Last instant of the period (RFC3339 format), inclusive
+	// required: true
+	// example: 2024-01-31T23:59:59Z
+	EndDate time.Time `json:"end_date" validate:"required"`
+}
+
+// ClosePeriodRequest represents the request payload for closing an accounting period
+// swagger:model ClosePeriodRequest
+type ClosePeriodRequest struct {
+	// Close the period for good; a soft close still accepts adjustments
+	// example: false
+	Hard bool `json:"hard"`
+
+	// Move Revenue and Expense balances into retained earnings
+	// example: true
+	PostClosingEntries bool `json:"post_closing_entries"`
+
+	// Who is closing the period
+	// required: true
+	// max length: 255
+	// example: controller@example.com
+	Actor string `json:"actor" validate:"required,max=255"`
+}
+
+// ReopenPeriodRequest represents the request payload for reopening a soft-closed accounting period
+// swagger:model ReopenPeriodRequest
+type ReopenPeriodRequest struct {
+	// Who is reopening the period
+	// required: true
+	// max length: 255
+	// example: controller@example.com
+	Actor string `json:"actor" validate:"required,max=255"`
+}
+
+// PeriodResponse represents an accounting period in API responses
+// swagger:model PeriodResponse
+type PeriodResponse struct {
+	// The unique identifier of the period
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ID string `json:"id"`
+
+	// Name of the period
+	// example: 2024-01
+	Name string `json:"name"`
+
+	// First instant of the period, inclusive
+	// example: 2024-01-01T00:00:00Z
+	StartDate time.Time `json:"start_date"`
+
+	// Last instant of the period, inclusive
+	// example: 2024-01-31T23:59:59Z
+	EndDate time.Time `json:"end_date"`
+
+	// Status of the period
+	// enum: open,soft_closed,hard_closed
+	// example: soft_closed
+	Status string `json:"status"`
+
+	// When the period was closed
+	// example: 2024-02-01T09:00:00Z
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+
+	// Who closed the period
+	// example: controller@example.com
+	ClosedBy string `json:"closed_by,omitempty"`
+}
+
+// ToPeriodResponse converts a models.AccountingPeriod to a PeriodResponse
+func ToPeriodResponse(period *models.AccountingPeriod) *PeriodResponse {
+	if period == nil {
+		return nil
+	}
+
+	return &PeriodResponse{
+		ID:        period.ID,
+		Name:      period.Name,
+		StartDate: period.StartDate,
+		EndDate:   period.EndDate,
+		Status:    string(period.Status),
+		ClosedAt:  period.ClosedAt,
+		ClosedBy:  period.ClosedBy,
+	}
+}
+
+// ListPeriodsResponse represents the list of accounting periods
+// swagger:model ListPeriodsResponse
+type ListPeriodsResponse struct {
+	// The periods, oldest first
+	Data []*PeriodResponse `json:"data"`
+}
+
+// ToListPeriodsResponse converts accounting periods to a ListPeriodsResponse
+func ToListPeriodsResponse(periods []*models.AccountingPeriod) *ListPeriodsResponse {
+	resp := &ListPeriodsResponse{Data: make([]*PeriodResponse, 0, len(periods))}
+	for _, period := range periods {
+		resp.Data = append(resp.Data, ToPeriodResponse(period))
+	}
+	return resp
+}
+
+// ClosingBalanceResponse represents one account's balance when its period was closed
+type ClosingBalanceResponse struct {
+	// The account ID
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// Type of the account
+	// example: Revenue
+	AccountType string `json:"account_type"`
+
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// Total debits posted through the end of the period
+	// example: 0.00
+	TotalDebit money.Money `json:"total_debit"`
+
+	// Total credits posted through the end of the period
+	// example: 1500.00
+	TotalCredit money.Money `json:"total_credit"`
+
+	// Balance in the account's normal direction
+	// example: 1500.00
+	Balance money.Money `json:"balance"`
+}
+
+// ClosingBalancesResponse represents the closing balance snapshot of an accounting period
+// swagger:model ClosingBalancesResponse
+type ClosingBalancesResponse struct {
+	// The period ID
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	PeriodID string `json:"period_id"`
+
+	// The balances, by currency and account
+	Data []ClosingBalanceResponse `json:"data"`
+}
+
+// ToClosingBalancesResponse converts a period's closing balances to a ClosingBalancesResponse
+func ToClosingBalancesResponse(periodID string, balances []*models.PeriodClosingBalance) *ClosingBalancesResponse {
+	resp := &ClosingBalancesResponse{
+		PeriodID: periodID,
+		Data:     make([]ClosingBalanceResponse, 0, len(balances)),
+	}
+	for _, b := range balances {
+		resp.Data = append(resp.Data, ClosingBalanceResponse{
+			AccountID:   b.AccountID,
+			AccountType: string(b.AccountType),
+			Currency:    b.Currency,
+			TotalDebit:  b.TotalDebit,
+			TotalCredit: b.TotalCredit,
+			Balance:     b.Balance,
+		})
+	}
+	return resp
+}
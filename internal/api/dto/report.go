@@ -0,0 +1,295 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+)
+
+// TrialBalanceLineResponse represents one account's row in a trial balance
+// swagger:model TrialBalanceLineResponse
+type TrialBalanceLineResponse struct {
+	// The account ID
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// Chart of accounts code
+	// example: 2000-USER-WALLETS-NGN
+	Code string `json:"code,omitempty"`
+
+	// Display name of the account
+	// example: Alice's USD wallet
+	Name string `json:"name"`
+
+	// Type of account
+	// example: Liability
+	AccountType string `json:"account_type"`
+
+	// The account's balance if it is a debit balance, otherwise zero
+	// example: 0.00
+	Debit money.Money `json:"debit"`
+
+	// The account's balance if it is a credit balance, otherwise zero
+	// example: 750.00
+	Credit money.Money `json:"credit"`
+}
+
+// TrialBalanceSectionResponse represents the trial balance of one currency
+// swagger:model TrialBalanceSectionResponse
+type TrialBalanceSectionResponse struct {
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// One row per account with postings
+	Lines []TrialBalanceLineResponse `json:"lines"`
+
+	// Sum of the debit column
+	// example: 750.00
+	TotalDebit money.Money `json:"total_debit"`
+
+	// Sum of the credit column
+	// example: 750.00
+	TotalCredit money.Money `json:"total_credit"`
+
+	// Whether total debits equal total credits
+	// example: true
+	Balanced bool `json:"balanced"`
+}
+
+// TrialBalanceResponse represents a trial balance report
+// swagger:model TrialBalanceResponse
+type TrialBalanceResponse struct {
+	// The point in time the report was computed for
+	// example: 2023-01-31T23:59:59Z
+	AsOf time.Time `json:"as_of"`
+
+	// One section per currency; amounts are never summed across currencies
+	Sections []TrialBalanceSectionResponse `json:"sections"`
+
+	// Whether every currency section balances
+	// example: true
+	Balanced bool `json:"balanced"`
+}
+
+// ToTrialBalanceResponse converts a service.TrialBalance to a TrialBalanceResponse
+func ToTrialBalanceResponse(report *service.TrialBalance) *TrialBalanceResponse {
+	if report == nil {
+		return nil
+	}
+
+	resp := &TrialBalanceResponse{
+		AsOf:     report.AsOf,
+		Sections: make([]TrialBalanceSectionResponse, 0, len(report.Sections)),
+		Balanced: report.Balanced,
+	}
+	for _, section := range report.Sections {
+		sectionResp := TrialBalanceSectionResponse{
+			Currency:    section.Currency,
+			Lines:       make([]TrialBalanceLineResponse, 0, len(section.Lines)),
+			TotalDebit:  section.TotalDebit,
+			TotalCredit: section.TotalCredit,
+			Balanced:    section.Balanced,
+		}
+		for _, line := range section.Lines {
+			sectionResp.Lines = append(sectionResp.Lines, TrialBalanceLineResponse{
+				AccountID:   line.AccountID,
+				Code:        line.Code,
+				Name:        line.Name,
+				AccountType: string(line.AccountType),
+				Debit:       line.Debit,
+				Credit:      line.Credit,
+			})
+		}
+		resp.Sections = append(resp.Sections, sectionResp)
+	}
+	return resp
+}
+
+// ReportLineResponse represents one account's amount in an income statement or balance sheet
+// swagger:model ReportLineResponse
+type ReportLineResponse struct {
+	// The account ID
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	AccountID string `json:"account_id"`
+
+	// Chart of accounts code
+	// example: 4000-FEE-REVENUE
+	Code string `json:"code,omitempty"`
+
+	// Display name of the account
+	// example: Fee revenue
+	Name string `json:"name"`
+
+	// Type of account
+	// example: Revenue
+	AccountType string `json:"account_type"`
+
+	// The amount, positive in the account's normal direction
+	// example: 125.00
+	Amount money.Money `json:"amount"`
+}
+
+// IncomeStatementSectionResponse represents the income statement of one currency
+// swagger:model IncomeStatementSectionResponse
+type IncomeStatementSectionResponse struct {
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// Revenue accounts with postings in the period
+	Revenue []ReportLineResponse `json:"revenue"`
+
+	// Expense accounts with postings in the period
+	Expenses []ReportLineResponse `json:"expenses"`
+
+	// Sum of revenue
+	// example: 125.00
+	TotalRevenue money.Money `json:"total_revenue"`
+
+	// Sum of expenses
+	// example: 40.00
+	TotalExpenses money.Money `json:"total_expenses"`
+
+	// Revenue minus expenses; negative for a net loss
+	// example: 85.00
+	NetIncome money.Money `json:"net_income"`
+}
+
+// IncomeStatementResponse represents an income statement report
+// swagger:model IncomeStatementResponse
+type IncomeStatementResponse struct {
+	// Start of the period
+	// example: 2023-01-01T00:00:00Z
+	From time.Time `json:"from"`
+
+	// End of the period
+	// example: 2023-01-31T23:59:59Z
+	To time.Time `json:"to"`
+
+	// One section per currency; amounts are never summed across currencies
+	Sections []IncomeStatementSectionResponse `json:"sections"`
+}
+
+// BalanceSheetSectionResponse represents the balance sheet of one currency
+// swagger:model BalanceSheetSectionResponse
+type BalanceSheetSectionResponse struct {
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// Asset accounts, together with System accounts such as FX positions
+	Assets []ReportLineResponse `json:"assets"`
+
+	// Liability accounts
+	Liabilities []ReportLineResponse `json:"liabilities"`
+
+	// Equity accounts
+	Equity []ReportLineResponse `json:"equity"`
+
+	// Revenue minus expenses not yet closed to an equity account
+	// example: 85.00
+	CurrentEarnings money.Money `json:"current_earnings"`
+
+	// Sum of assets
+	// example: 1085.00
+	TotalAssets money.Money `json:"total_assets"`
+
+	// Sum of liabilities
+	// example: 1000.00
+	TotalLiabilities money.Money `json:"total_liabilities"`
+
+	// Sum of equity, including current earnings
+	// example: 85.00
+	TotalEquity money.Money `json:"total_equity"`
+
+	// Sum of liabilities and equity
+	// example: 1085.00
+	TotalLiabilitiesAndEquity money.Money `json:"total_liabilities_and_equity"`
+
+	// Whether assets equal liabilities plus equity
+	// example: true
+	Balanced bool `json:"balanced"`
+}
+
+// BalanceSheetResponse represents a balance sheet report
+// swagger:model BalanceSheetResponse
+type BalanceSheetResponse struct {
+	// The point in time the report was computed for
+	// example: 2023-01-31T23:59:59Z
+	AsOf time.Time `json:"as_of"`
+
+	// One section per currency; amounts are never summed across currencies
+	Sections []BalanceSheetSectionResponse `json:"sections"`
+
+	// Whether every currency section balances
+	// example: true
+	Balanced bool `json:"balanced"`
+}
+
+// ToIncomeStatementResponse converts a service.IncomeStatement to an IncomeStatementResponse
+func ToIncomeStatementResponse(report *service.IncomeStatement) *IncomeStatementResponse {
+	if report == nil {
+		return nil
+	}
+
+	resp := &IncomeStatementResponse{
+		From:     report.From,
+		To:       report.To,
+		Sections: make([]IncomeStatementSectionResponse, 0, len(report.Sections)),
+	}
+	for _, section := range report.Sections {
+		resp.Sections = append(resp.Sections, IncomeStatementSectionResponse{
+			Currency:      section.Currency,
+			Revenue:       toReportLineResponses(section.Revenue),
+			Expenses:      toReportLineResponses(section.Expenses),
+			TotalRevenue:  section.TotalRevenue,
+			TotalExpenses: section.TotalExpenses,
+			NetIncome:     section.NetIncome,
+		})
+	}
+	return resp
+}
+
+// ToBalanceSheetResponse converts a service.BalanceSheet to a BalanceSheetResponse
+func ToBalanceSheetResponse(report *service.BalanceSheet) *BalanceSheetResponse {
+	if report == nil {
+		return nil
+	}
+
+	resp := &BalanceSheetResponse{
+		AsOf:     report.AsOf,
+		Sections: make([]BalanceSheetSectionResponse, 0, len(report.Sections)),
+		Balanced: report.Balanced,
+	}
+	for _, section := range report.Sections {
+		resp.Sections = append(resp.Sections, BalanceSheetSectionResponse{
+			Currency:                  section.Currency,
+			Assets:                    toReportLineResponses(section.Assets),
+			Liabilities:               toReportLineResponses(section.Liabilities),
+			Equity:                    toReportLineResponses(section.Equity),
+			CurrentEarnings:           section.CurrentEarnings,
+			TotalAssets:               section.TotalAssets,
+			TotalLiabilities:          section.TotalLiabilities,
+			TotalEquity:               section.TotalEquity,
+			TotalLiabilitiesAndEquity: section.TotalLiabilitiesAndEquity,
+			Balanced:                  section.Balanced,
+		})
+	}
+	return resp
+}
+
+func toReportLineResponses(lines []service.ReportLine) []ReportLineResponse {
+	resp := make([]ReportLineResponse, 0, len(lines))
+	for _, line := range lines {
+		resp = append(resp, ReportLineResponse{
+			AccountID:   line.AccountID,
+			Code:        line.Code,
+			Name:        line.Name,
+			AccountType: string(line.AccountType),
+			Amount:      line.Amount,
+		})
+	}
+	return resp
+}
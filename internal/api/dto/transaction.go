@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
 	"github.com/google/uuid"
 )
 
@@ -22,7 +24,7 @@ type TransactionLineEntry struct {
 	// The amount for this line (positive for credit, negative for debit)
 	// required: true
 	// example: 100.50
-	Amount float64 `json:"amount" validate:"required,numeric"`
+	Amount money.Money `json:"amount" validate:"required,numeric"`
 
 	// The currency code (ISO 4217)
 	// required: true
@@ -73,7 +75,7 @@ type TransactionLineResponse struct {
 
 	// The amount for this line
 	// example: 100.50
-	Amount float64 `json:"amount"`
+	Amount money.Money `json:"amount"`
 
 	// The currency code (ISO 4217)
 	// example: USD
@@ -119,9 +121,16 @@ type TransactionResponse struct {
 	// example: 2023-01-01T00:00:00Z
 	Date time.Time `json:"date"`
 
+	// The ID of the transaction this one reverses, if it is a reversal
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	ReversalOfID string `json:"reversal_of_id,omitempty"`
+
 	// The transaction lines (debits and credits)
 	Lines []TransactionLineEntry `json:"lines"`
 
+	// The rates of a cross-currency transaction
+	ExchangeRates []ExchangeRateEntry `json:"exchange_rates,omitempty"`
+
 	// The date and time when the transaction was created
 	// example: 2023-01-01T00:00:00Z
 	CreatedAt time.Time `json:"created_at"`
@@ -162,6 +171,53 @@ type ErrorResponse struct {
 	Status int `json:"status"`
 }
 
+// CurrencyImbalanceResponse represents the amount by which one currency of a transaction fails to balance
+type CurrencyImbalanceResponse struct {
+	// The currency code (ISO 4217)
+	// example: USD
+	Currency string `json:"currency"`
+
+	// Total debits in the currency
+	// example: 100.00
+	TotalDebit money.Money `json:"total_debit"`
+
+	// Total credits in the currency
+	// example: 90.00
+	TotalCredit money.Money `json:"total_credit"`
+
+	// Debits minus credits
+	// example: 10.00
+	Difference money.Money `json:"difference"`
+}
+
+// UnbalancedTransactionResponse represents a transaction rejected because it does not balance
+// swagger:model UnbalancedTransactionResponse
+type UnbalancedTransactionResponse struct {
+	// The error message
+	// example: entry does not balance: USD debits 100.00, credits 90.00
+	Error string `json:"error"`
+
+	// The imbalance of each currency that does not balance
+	Imbalances []CurrencyImbalanceResponse `json:"imbalances"`
+}
+
+// ToUnbalancedTransactionResponse converts an UnbalancedEntryError to an UnbalancedTransactionResponse
+func ToUnbalancedTransactionResponse(err *service.UnbalancedEntryError) *UnbalancedTransactionResponse {
+	resp := &UnbalancedTransactionResponse{
+		Error:      err.Error(),
+		Imbalances: make([]CurrencyImbalanceResponse, 0, len(err.Imbalances)),
+	}
+	for _, imb := range err.Imbalances {
+		resp.Imbalances = append(resp.Imbalances, CurrencyImbalanceResponse{
+			Currency:    imb.Currency,
+			TotalDebit:  imb.TotalDebit,
+			TotalCredit: imb.TotalCredit,
+			Difference:  imb.Difference,
+		})
+	}
+	return resp
+}
+
 // CreateTransactionRequest represents the request payload for creating a transaction
 // swagger:model CreateTransactionRequest
 type CreateTransactionRequest struct {
@@ -189,13 +245,46 @@ type CreateTransactionRequest struct {
 	// required: true
 	// min items: 2
 	Lines []TransactionLineEntry `json:"lines" validate:"required,min=2,dive"`
+
+	// Rates linking the currencies of a cross-currency transaction, whose
+	// lines must pass through the FX position account of each currency
+	ExchangeRates []ExchangeRateEntry `json:"exchange_rates,omitempty" validate:"omitempty,dive"`
+}
+
+// ExchangeRateEntry represents the rate a cross-currency transaction converts at
+// swagger:model ExchangeRateEntry
+type ExchangeRateEntry struct {
+	// The currency converted from (ISO 4217)
+	// required: true
+	// example: USD
+	BaseCurrency string `json:"base_currency" validate:"required,iso4217"`
+
+	// The currency converted to (ISO 4217)
+	// required: true
+	// example: EUR
+	QuoteCurrency string `json:"quote_currency" validate:"required,iso4217"`
+
+	// Units of the quote currency per unit of the base currency
+	// required: true
+	// example: 0.92
+	Rate money.Rate `json:"rate" validate:"required,gt=0"`
+}
+
+// ReverseTransactionRequest represents the request payload for reversing a transaction
+// swagger:model ReverseTransactionRequest
+type ReverseTransactionRequest struct {
+	// Why the transaction is being reversed
+	// required: true
+	// max length: 255
+	// example: Duplicate posting
+	Reason string `json:"reason" validate:"required,max=255"`
 }
 
 // TransactionLine represents a single line in a transaction
 type TransactionLine struct {
-	AccountID string  `json:"account_id" validate:"required,uuid4"`
-	Debit     float64 `json:"debit" validate:"gte=0"`
-	Credit    float64 `json:"credit" validate:"gte=0"`
+	AccountID string      `json:"account_id" validate:"required,uuid4"`
+	Debit     money.Money `json:"debit" validate:"gte=0"`
+	Credit    money.Money `json:"credit" validate:"gte=0"`
 }
 
 // ToModel converts a CreateTransactionRequest to a models.Entry
@@ -219,19 +308,29 @@ func (r CreateTransactionRequest) ToModel() *models.Entry {
 			ID:        uuid.New().String(),
 			EntryID:   entry.ID,
 			AccountID: line.AccountID,
+			Currency:  line.Currency,
 			CreatedAt: now,
 		}
 
 		// Set debit or credit based on amount sign
-		if line.Amount > 0 {
-			entryLine.Credit = line.Amount
-		} else if line.Amount < 0 {
-			entryLine.Debit = -line.Amount // Store as positive
+		amount := line.Amount.WithCurrency(line.Currency)
+		if amount.IsPositive() {
+			entryLine.Credit = amount
+		} else if amount.IsNegative() {
+			entryLine.Debit = amount.Neg() // Store as positive
 		}
 
 		entry.Lines = append(entry.Lines, entryLine)
 	}
 
+	for _, rate := range r.ExchangeRates {
+		entry.ExchangeRates = append(entry.ExchangeRates, models.EntryExchangeRate{
+			BaseCurrency:  rate.BaseCurrency,
+			QuoteCurrency: rate.QuoteCurrency,
+			Rate:          rate.Rate,
+		})
+	}
+
 	return entry
 }
 
@@ -248,6 +347,7 @@ func ToResponse(entry *models.Entry) *TransactionResponse {
 		ReferenceID:     entry.ReferenceID,
 		Status:          entry.Status,
 		Date:            entry.Date,
+		ReversalOfID:    entry.ReversalOfID,
 		CreatedAt:       entry.CreatedAt,
 		UpdatedAt:       entry.UpdatedAt,
 		Lines:           make([]TransactionLineEntry, 0, len(entry.Lines)),
@@ -257,18 +357,27 @@ func ToResponse(entry *models.Entry) *TransactionResponse {
 		lineEntry := TransactionLineEntry{
 			ID:        line.ID,
 			AccountID: line.AccountID,
+			Currency:  line.Currency,
 			CreatedAt: line.CreatedAt,
 		}
 
 		// Set amount based on debit/credit
-		if line.Debit > 0 {
-			lineEntry.Amount = -line.Debit // Negative for debits
-		} else if line.Credit > 0 {
+		if line.Debit.IsPositive() {
+			lineEntry.Amount = line.Debit.Neg() // Negative for debits
+		} else if line.Credit.IsPositive() {
 			lineEntry.Amount = line.Credit // Positive for credits
 		}
 
 		resp.Lines = append(resp.Lines, lineEntry)
 	}
 
+	for _, rate := range entry.ExchangeRates {
+		resp.ExchangeRates = append(resp.ExchangeRates, ExchangeRateEntry{
+			BaseCurrency:  rate.BaseCurrency,
+			QuoteCurrency: rate.QuoteCurrency,
+			Rate:          rate.Rate,
+		})
+	}
+
 	return resp
 }
@@ -0,0 +1,665 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/middleware"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// AccountHandler handles HTTP requests for account operations
+// @Description Handles account management and balance queries
+// @Tags accounts
+type AccountHandler struct {
+	accountService service.AccountService
+	balanceService service.BalanceService
+}
+
+// NewAccountHandler creates a new AccountHandler with the given services
+func NewAccountHandler(as service.AccountService, bs service.BalanceService) *AccountHandler {
+	return &AccountHandler{
+		accountService: as,
+		balanceService: bs,
+	}
+}
+
+// CreateAccount handles opening a new account
+// @Summary Create an account
+// @Description Opens a new ledger account
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param account body dto.CreateAccountRequest true "Account details"
+// @Success 201 {object} dto.AccountResponse "Account created successfully"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format or account code"
+// @Failure 409 {object} dto.ErrorResponse "Account code already in use"
+// @Failure 422 {object} dto.ErrorResponse "Parent account cannot take children"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/accounts [post]
+func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.CreateAccountRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	account, err := h.accountService.CreateAccount(ctx, req.ToServiceRequest())
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, dto.ToAccountResponse(account, money.Zero(account.Currency)))
+}
+
+// GetAccount handles retrieving an account by ID
+// @Summary Get an account by ID
+// @Description Retrieves an account with its current balance
+// @Tags accounts
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} dto.AccountResponse "Account found"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/{id} [get]
+func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	account, err := h.accountService.GetAccount(ctx, accountID)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	resp, err := h.toResponse(r, account)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	render.JSON(w, r, resp)
+}
+
+// ListAccounts handles listing the accounts of a user
+// @Summary List a user's accounts
+// @Description Retrieves all accounts owned by a user, with their current balances
+// @Tags accounts
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Success 200 {object} dto.AccountsListResponse "List of accounts"
+// @Failure 400 {object} dto.ErrorResponse "Invalid parameters"
+// @Router /api/v1/accounts [get]
+func (h *AccountHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "user_id is required"})
+		return
+	}
+
+	accounts, err := h.accountService.ListAccountsByUser(ctx, userID)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	resp := dto.AccountsListResponse{Data: make([]dto.AccountResponse, 0, len(accounts))}
+	for _, account := range accounts {
+		accountResp, err := h.toResponse(r, account)
+		if err != nil {
+			h.renderError(w, r, err)
+			return
+		}
+		resp.Data = append(resp.Data, *accountResp)
+	}
+
+	render.JSON(w, r, resp)
+}
+
+// RenameAccount handles changing the name of an account
+// @Summary Rename an account
+// @Description Changes the display name of an account
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param account body dto.RenameAccountRequest true "New name"
+// @Success 200 {object} dto.AccountResponse "Account renamed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/{id} [patch]
+func (h *AccountHandler) RenameAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	var req dto.RenameAccountRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	account, err := h.accountService.RenameAccount(ctx, accountID, req.Name)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	resp, err := h.toResponse(r, account)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	render.JSON(w, r, resp)
+}
+
+// CloseAccount handles closing an account
+// @Summary Close an account
+// @Description Closes an account; its balance must be zero. The account is kept for its history.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param account body dto.CloseAccountRequest true "Reason and actor"
+// @Success 200 {object} dto.AccountResponse "Account closed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Failure 409 {object} dto.ErrorResponse "Account balance is not zero or account already closed"
+// @Router /api/v1/accounts/{id}/close [post]
+func (h *AccountHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	var req dto.CloseAccountRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	account, err := h.accountService.CloseAccount(ctx, accountID, req.Reason, req.Actor)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToAccountResponse(account, money.Zero(account.Currency)))
+}
+
+// ChangeAccountStatus handles moving an account to a new status
+// @Summary Change an account's status
+// @Description Freezes, reactivates, marks dormant or closes an account and records the change
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param status body dto.ChangeAccountStatusRequest true "New status, reason and actor"
+// @Success 200 {object} dto.AccountResponse "Status changed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Failure 409 {object} dto.ErrorResponse "Transition not allowed"
+// @Router /api/v1/accounts/{id}/status [post]
+func (h *AccountHandler) ChangeAccountStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	var req dto.ChangeAccountStatusRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	account, err := h.accountService.ChangeAccountStatus(ctx, accountID, models.AccountStatus(req.Status), req.Reason, req.Actor)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	resp, err := h.toResponse(r, account)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	render.JSON(w, r, resp)
+}
+
+// GetStatusHistory handles retrieving the status history of an account
+// @Summary Get an account's status history
+// @Description Retrieves every status change of an account with its reason and actor
+// @Tags accounts
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} dto.AccountStatusHistoryResponse "Status history"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/{id}/status-history [get]
+func (h *AccountHandler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	history, err := h.accountService.GetStatusHistory(ctx, accountID)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToStatusHistoryResponse(accountID, history))
+}
+
+// GetAccountByCode handles retrieving an account by its chart of accounts code
+// @Summary Get an account by code
+// @Description Retrieves an account by its chart of accounts code, with its current balance
+// @Tags accounts
+// @Produce json
+// @Param code path string true "Account code"
+// @Success 200 {object} dto.AccountResponse "Account found"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/by-code/{code} [get]
+func (h *AccountHandler) GetAccountByCode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	account, err := h.accountService.GetAccountByCode(ctx, chi.URLParam(r, "code"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	resp, err := h.toResponse(r, account)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	render.JSON(w, r, resp)
+}
+
+// ListChildAccounts handles listing the direct children of an account
+// @Summary List child accounts
+// @Description Retrieves the direct children of an account in the chart of accounts
+// @Tags accounts
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} dto.AccountsListResponse "List of child accounts"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/{id}/children [get]
+func (h *AccountHandler) ListChildAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	children, err := h.accountService.ListChildAccounts(ctx, accountID)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	resp := dto.AccountsListResponse{Data: make([]dto.AccountResponse, 0, len(children))}
+	for _, child := range children {
+		childResp, err := h.toResponse(r, child)
+		if err != nil {
+			h.renderError(w, r, err)
+			return
+		}
+		resp.Data = append(resp.Data, *childResp)
+	}
+
+	render.JSON(w, r, resp)
+}
+
+// MoveAccount handles moving an account under a new parent
+// @Summary Move an account in the chart of accounts
+// @Description Places an account under a new parent, or at the top level. Moves that would create a cycle are rejected.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param parent body dto.MoveAccountRequest true "New parent account"
+// @Success 200 {object} dto.AccountResponse "Account moved"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Failure 422 {object} dto.ErrorResponse "Move would create a cycle or parent cannot take children"
+// @Router /api/v1/accounts/{id}/parent [post]
+func (h *AccountHandler) MoveAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	var req dto.MoveAccountRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	account, err := h.accountService.MoveAccount(ctx, accountID, req.ParentAccountID)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	resp, err := h.toResponse(r, account)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	render.JSON(w, r, resp)
+}
+
+// toResponse builds the response for an account, including its current balance
+func (h *AccountHandler) toResponse(r *http.Request, account *models.Account) (*dto.AccountResponse, error) {
+	balance, err := h.balanceService.GetAvailableBalance(r.Context(), account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToAccountResponse(account, balance), nil
+}
+
+// renderError writes the response for an account service error
+func (h *AccountHandler) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountNotFound):
+		render.Status(r, http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidAccountCode),
+		errors.Is(err, service.ErrInvalidStatementCursor),
+		errors.Is(err, service.ErrInvalidReportPeriod):
+		render.Status(r, http.StatusBadRequest)
+	case errors.Is(err, service.ErrAccountHasBalance),
+		errors.Is(err, service.ErrAccountCodeTaken),
+		errors.Is(err, models.ErrInvalidStatusTransition),
+		errors.Is(err, repository.ErrAccountStatusConflict):
+		render.Status(r, http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidAccountHierarchy):
+		render.Status(r, http.StatusUnprocessableEntity)
+	default:
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, map[string]string{"error": err.Error()})
+}
+
+// GetBalance handles retrieving the balance of an account
+// @Summary Get an account balance
+// @Description Retrieves the ledger balance of an account as of a point in time
+// @Tags accounts
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param as_of query string false "Point in time (RFC3339 format), defaults to now" format(date-time)
+// @Success 200 {object} dto.AccountBalanceResponse "Account balance"
+// @Failure 400 {object} dto.ErrorResponse "Invalid parameters"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/{id}/balance [get]
+func (h *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Account ID is required"})
+		return
+	}
+
+	asOf := time.Now()
+	if asOfStr := r.URL.Query().Get("as_of"); asOfStr != "" {
+		parsed, err := time.Parse(time.RFC3339, asOfStr)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid as_of format. Use RFC3339 format (e.g., 2023-01-31T23:59:59Z)"})
+			return
+		}
+		asOf = parsed
+	}
+
+	balance, err := h.balanceService.GetBalance(ctx, accountID, asOf)
+	if err != nil {
+		if errors.Is(err, service.ErrAccountNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, map[string]string{"error": "Account not found"})
+			return
+		}
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+		return
+	}
+
+	render.JSON(w, r, dto.ToBalanceResponse(balance))
+}
+
+// GetRollupBalance handles retrieving the combined balance of an account and its descendants
+// @Summary Get a roll-up balance
+// @Description Retrieves the balance of an account together with all accounts below it in the chart of accounts, per currency
+// @Tags accounts
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param as_of query string false "Point in time (RFC3339 format), defaults to now" format(date-time)
+// @Success 200 {object} dto.RollupBalanceResponse "Roll-up balance"
+// @Failure 400 {object} dto.ErrorResponse "Invalid parameters"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/{id}/rollup [get]
+func (h *AccountHandler) GetRollupBalance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	asOf := time.Now()
+	if asOfStr := r.URL.Query().Get("as_of"); asOfStr != "" {
+		parsed, err := time.Parse(time.RFC3339, asOfStr)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid as_of format. Use RFC3339 format (e.g., 2023-01-31T23:59:59Z)"})
+			return
+		}
+		asOf = parsed
+	}
+
+	rollup, err := h.balanceService.GetRollupBalance(ctx, accountID, asOf)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToRollupBalanceResponse(rollup))
+}
+
+// GetStatement handles retrieving an account statement
+// @Summary Get an account statement
+// @Description Lists the postings to an account over a period with their counterpart accounts and a running balance, between the opening and closing balances. JSON responses are paginated with a cursor; CSV and plain-text exports cover the whole period.
+// @Tags accounts
+// @Produce json
+// @Produce text/csv
+// @Produce text/plain
+// @Param id path string true "Account ID"
+// @Param from query string false "Start of the period (RFC3339 format), defaults to the first posting" format(date-time)
+// @Param to query string false "End of the period (RFC3339 format), defaults to now" format(date-time)
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Param limit query int false "Lines per page (max 500)" default(50)
+// @Param format query string false "Response format" Enums(json, csv, text)
+// @Success 200 {object} dto.StatementResponse "Account statement"
+// @Failure 400 {object} dto.ErrorResponse "Invalid parameters"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Router /api/v1/accounts/{id}/statement [get]
+func (h *AccountHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID := chi.URLParam(r, "id")
+
+	from, ok := parseTimeParam(w, r, "from", time.Time{})
+	if !ok {
+		return
+	}
+	to, ok := parseTimeParam(w, r, "to", time.Now())
+	if !ok {
+		return
+	}
+	format, ok := parseFormatParam(w, r, formatJSON, formatCSV, formatText)
+	if !ok {
+		return
+	}
+
+	req := service.StatementRequest{From: from, To: to, Cursor: r.URL.Query().Get("cursor")}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		req.Limit = limit
+	}
+
+	if format == formatJSON {
+		statement, err := h.balanceService.GetStatement(ctx, accountID, req)
+		if err != nil {
+			h.renderError(w, r, err)
+			return
+		}
+		render.JSON(w, r, dto.ToStatementResponse(statement))
+		return
+	}
+
+	// Exports cover the whole period, so collect every page
+	req.Cursor = ""
+	req.Limit = service.MaxStatementPageSize
+	statement, err := h.balanceService.GetStatement(ctx, accountID, req)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+	for cursor := statement.NextCursor; cursor != ""; {
+		req.Cursor = cursor
+		page, err := h.balanceService.GetStatement(ctx, accountID, req)
+		if err != nil {
+			h.renderError(w, r, err)
+			return
+		}
+		statement.Lines = append(statement.Lines, page.Lines...)
+		cursor = page.NextCursor
+	}
+	statement.NextCursor = ""
+
+	filename := "statement-" + statement.AccountID
+	if format == formatCSV {
+		writeCSV(w, filename+".csv", statementRecords(statement))
+		return
+	}
+	writeText(w, filename+".txt", statementText(statement))
+}
+
+// statementRecords flattens a statement into CSV rows, framed by the opening and closing balances
+func statementRecords(statement *service.Statement) [][]string {
+	records := [][]string{
+		{"date", "entry_id", "description", "reference_id", "transaction_type", "counterparts", "debit", "credit", "balance"},
+		{statement.From.Format(time.RFC3339), "", "Opening balance", "", "", "", "", "", statement.OpeningBalance.Decimal()},
+	}
+	for _, line := range statement.Lines {
+		records = append(records, []string{
+			line.Date.Format(time.RFC3339), line.EntryID, line.Description, line.ReferenceID, line.TransactionType,
+			counterpartNames(line), line.Debit.Decimal(), line.Credit.Decimal(), line.Balance.Decimal(),
+		})
+	}
+	records = append(records, []string{statement.To.Format(time.RFC3339), "", "Closing balance", "", "", "", "", "", statement.ClosingBalance.Decimal()})
+	return records
+}
+
+// statementText renders a statement as an aligned plain-text table
+func statementText(statement *service.Statement) []byte {
+	var buf bytes.Buffer
+	name := statement.Name
+	if statement.Code != "" {
+		name = statement.Code + " " + name
+	}
+	fmt.Fprintf(&buf, "Statement of account %s (%s)\n", name, statement.AccountID)
+	fmt.Fprintf(&buf, "Type: %s  Currency: %s\n", statement.AccountType, statement.Currency)
+	fmt.Fprintf(&buf, "Period: %s to %s\n\n", statement.From.Format(time.RFC3339), statement.To.Format(time.RFC3339))
+
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Date\tDescription\tReference\tCounterparts\tDebit\tCredit\tBalance\t")
+	fmt.Fprintf(tw, "\tOpening balance\t\t\t\t\t%s\t\n", statement.OpeningBalance.Decimal())
+	for _, line := range statement.Lines {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			line.Date.Format("2006-01-02 15:04:05"), line.Description, line.ReferenceID, counterpartNames(line),
+			line.Debit.Decimal(), line.Credit.Decimal(), line.Balance.Decimal())
+	}
+	fmt.Fprintf(tw, "\tClosing balance\t\t\t\t\t%s\t\n", statement.ClosingBalance.Decimal())
+	tw.Flush()
+
+	return buf.Bytes()
+}
+
+// counterpartNames lists the names of a statement line's counterpart accounts
+func counterpartNames(line service.StatementLine) string {
+	names := make([]string, 0, len(line.Counterparts))
+	for _, c := range line.Counterparts {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, "; ")
+}
+
+// RegisterRoutes registers account routes to the router
+func (h *AccountHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/api/v1/accounts", func(r chi.Router) {
+		// Apply JSON middleware
+		r.Use(middleware.JSONMiddleware)
+		r.Use(middleware.ErrorHandler)
+
+		// Create account with validation
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.CreateAccountRequest
+			middleware.ValidateRequest(h.CreateAccount, &req)(w, r)
+		})
+
+		// List a user's accounts
+		r.Get("/", h.ListAccounts)
+
+		// Get account by ID or chart of accounts code
+		r.Get("/{id}", h.GetAccount)
+		r.Get("/by-code/{code}", h.GetAccountByCode)
+
+		// Rename account with validation
+		r.Patch("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.RenameAccountRequest
+			middleware.ValidateRequest(h.RenameAccount, &req)(w, r)
+		})
+
+		// Close account
+		r.Post("/{id}/close", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.CloseAccountRequest
+			middleware.ValidateRequest(h.CloseAccount, &req)(w, r)
+		})
+
+		// Change account status and read its history
+		r.Post("/{id}/status", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.ChangeAccountStatusRequest
+			middleware.ValidateRequest(h.ChangeAccountStatus, &req)(w, r)
+		})
+		r.Get("/{id}/status-history", h.GetStatusHistory)
+
+		// Navigate and rearrange the chart of accounts
+		r.Get("/{id}/children", h.ListChildAccounts)
+		r.Post("/{id}/parent", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.MoveAccountRequest
+			middleware.ValidateRequest(h.MoveAccount, &req)(w, r)
+		})
+
+		// Get account balance, on its own or rolled up with its descendants
+		r.Get("/{id}/balance", h.GetBalance)
+		r.Get("/{id}/rollup", h.GetRollupBalance)
+
+		// Get account statement
+		r.Get("/{id}/statement", h.GetStatement)
+	})
+}
@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	handlers "github.com/ISRAEL-DUFF/fintech-ledger/internal/api/handlers"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db/dbtest"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAccountRouter(t *testing.T) http.Handler {
+	testDB := dbtest.Open(t, &models.Account{}, &models.Entry{}, &models.EntryLine{}, &models.EntryExchangeRate{}, &models.BalanceSnapshot{}, &models.AccountStatusChange{})
+
+	accountRepo := repository.NewAccountRepository(testDB)
+	balanceService := service.NewBalanceService(repository.NewEntryRepository(testDB), accountRepo, repository.NewBalanceRepository(testDB))
+
+	r := chi.NewRouter()
+	handlers.NewAccountHandler(service.NewAccountService(accountRepo, balanceService), balanceService).RegisterRoutes(r)
+	return r
+}
+
+func doJSON(t *testing.T, h http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAccountHandler_Lifecycle(t *testing.T) {
+	r := setupAccountRouter(t)
+
+	rr := doJSON(t, r, http.MethodPost, "/api/v1/accounts", dto.CreateAccountRequest{Name: "Alice wallet", Type: "Liability", UserID: "alice", Currency: "USD"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "Alice wallet", created.Name)
+	assert.True(t, created.Balance.IsZero())
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts", dto.CreateAccountRequest{Name: "Bad", Type: "Cash", Currency: "USD"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = doJSON(t, r, http.MethodPatch, "/api/v1/accounts/"+created.ID, dto.RenameAccountRequest{Name: "Alice main"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts/"+created.ID, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var fetched dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &fetched))
+	assert.Equal(t, "Alice main", fetched.Name)
+	assert.Equal(t, "0.00", fetched.Balance.Decimal())
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts?user_id=alice", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var list dto.AccountsListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts/"+created.ID+"/status", dto.ChangeAccountStatusRequest{Status: "frozen", Reason: "review", Actor: "compliance"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts/"+created.ID+"/close", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "a reason and actor are required")
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts/"+created.ID+"/close", dto.CloseAccountRequest{Reason: "customer request", Actor: "support"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The closed account is still readable, with its history
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts/"+created.ID, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &fetched))
+	assert.Equal(t, "closed", fetched.Status)
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts/"+created.ID+"/status", dto.ChangeAccountStatusRequest{Status: "active", Reason: "reopen", Actor: "support"})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts/"+created.ID+"/status-history", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var history dto.AccountStatusHistoryResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &history))
+	require.Len(t, history.Data, 2)
+	assert.Equal(t, "closed", history.Data[1].ToStatus)
+}
+
+func TestAccountHandler_Hierarchy(t *testing.T) {
+	r := setupAccountRouter(t)
+
+	rr := doJSON(t, r, http.MethodPost, "/api/v1/accounts", dto.CreateAccountRequest{Name: "User wallets", Code: "2000-USER-WALLETS", Type: "Liability", Currency: "NGN"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var parent dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &parent))
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts", dto.CreateAccountRequest{Name: "Alice NGN", Type: "Liability", ParentAccountID: parent.ID, UserID: "alice", Currency: "NGN"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var child dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &child))
+	assert.Equal(t, parent.ID, child.ParentAccountID)
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts", dto.CreateAccountRequest{Name: "Dup", Code: "2000-USER-WALLETS", Type: "Liability", Currency: "NGN"})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts/by-code/2000-USER-WALLETS", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts/"+parent.ID+"/children", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var children dto.AccountsListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &children))
+	require.Len(t, children.Data, 1)
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/accounts/"+parent.ID+"/parent", dto.MoveAccountRequest{ParentAccountID: child.ID})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, "moving a parent under its child creates a cycle")
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts/"+parent.ID+"/rollup", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var rollup dto.RollupBalanceResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rollup))
+	assert.Equal(t, 2, rollup.AccountCount)
+	assert.Empty(t, rollup.Balances)
+}
+
+func TestAccountHandler_Statement(t *testing.T) {
+	r := setupAccountRouter(t)
+
+	rr := doJSON(t, r, http.MethodPost, "/api/v1/accounts", dto.CreateAccountRequest{Name: "Alice wallet", Type: "Liability", UserID: "alice", Currency: "USD"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var account dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &account))
+	path := "/api/v1/accounts/" + account.ID + "/statement"
+
+	rr = doJSON(t, r, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var statement dto.StatementResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statement))
+	assert.Empty(t, statement.Lines)
+	assert.Empty(t, statement.NextCursor)
+	assert.Equal(t, "0.00", statement.ClosingBalance.Decimal())
+
+	rr = doJSON(t, r, http.MethodGet, path+"?format=csv", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv"))
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3, "header, opening and closing balance")
+	assert.Equal(t, "Opening balance", records[1][2])
+
+	rr = doJSON(t, r, http.MethodGet, path+"?format=text", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, rr.Body.String(), "Closing balance")
+
+	rr = doJSON(t, r, http.MethodGet, path+"?cursor=bogus", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(t, r, http.MethodGet, path+"?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/accounts/missing/statement", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
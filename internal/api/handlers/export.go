@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// Response formats selectable with the format query parameter
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatText = "text"
+)
+
+// parseFormatParam reads the format query parameter, defaulting to JSON and
+// writing a 400 response if the format is not one of allowed
+func parseFormatParam(w http.ResponseWriter, r *http.Request, allowed ...string) (string, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		return formatJSON, true
+	}
+	for _, f := range allowed {
+		if format == f {
+			return format, true
+		}
+	}
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, map[string]string{"error": "Invalid format. Use one of: " + strings.Join(allowed, ", ")})
+	return "", false
+}
+
+// parseTimeParam reads an RFC3339 query parameter, falling back to def when it
+// is absent and writing a 400 response when it is malformed
+func parseTimeParam(w http.ResponseWriter, r *http.Request, name string, def time.Time) (time.Time, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, true
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid " + name + " format. Use RFC3339 format (e.g., 2023-01-31T23:59:59Z)"})
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+// writeCSV writes records as a CSV attachment
+func writeCSV(w http.ResponseWriter, filename string, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		log.Printf("Failed to write %s: %v", filename, err)
+	}
+}
+
+// writeText writes a plain-text attachment
+func writeText(w http.ResponseWriter, filename string, body []byte) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(body); err != nil {
+		log.Printf("Failed to write %s: %v", filename, err)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/middleware"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// FeeHandler handles HTTP requests for fee schedules
+// @Description Handles versioning fee schedules and working out the fees they charge
+// @Tags fees
+type FeeHandler struct {
+	feeService service.FeeService
+}
+
+// NewFeeHandler creates a new FeeHandler with the given service
+func NewFeeHandler(fs service.FeeService) *FeeHandler {
+	return &FeeHandler{feeService: fs}
+}
+
+// CreateSchedule handles adding a fee schedule version
+// @Summary Create a fee schedule version
+// @Description Stores a schedule as the next version for its transaction type, currency and account tier. It takes over from its effective time, which may not be in the past; earlier versions keep pricing the transactions made while they were in effect.
+// @Tags fees
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param schedule body dto.CreateFeeScheduleRequest true "Schedule details"
+// @Success 201 {object} dto.FeeScheduleResponse "Schedule created"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format or schedule"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fees/schedules [post]
+func (h *FeeHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateFeeScheduleRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	schedule := req.ToModel()
+	if err := h.feeService.CreateSchedule(r.Context(), schedule); err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, dto.ToFeeScheduleResponse(schedule))
+}
+
+// ListSchedules handles listing fee schedule versions
+// @Summary List fee schedule versions
+// @Description Retrieves every version of the schedules matching the filters
+// @Tags fees
+// @Produce json
+// @Param transaction_type query string false "Transaction type"
+// @Param currency query string false "Currency (ISO 4217)"
+// @Param account_tier query string false "Account tier"
+// @Success 200 {object} dto.ListFeeSchedulesResponse "Schedules"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fees/schedules [get]
+func (h *FeeHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	schedules, err := h.feeService.ListSchedules(r.Context(), repository.FeeScheduleFilter{
+		TransactionType: query.Get("transaction_type"),
+		Currency:        query.Get("currency"),
+		AccountTier:     query.Get("account_tier"),
+	})
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToListFeeSchedulesResponse(schedules))
+}
+
+// GetSchedule handles retrieving a fee schedule version
+// @Summary Get a fee schedule version
+// @Description Retrieves a schedule version, such as the one recorded on a transaction
+// @Tags fees
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} dto.FeeScheduleResponse "Schedule"
+// @Failure 404 {object} dto.ErrorResponse "Schedule not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fees/schedules/{id} [get]
+func (h *FeeHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.feeService.GetSchedule(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToFeeScheduleResponse(schedule))
+}
+
+// AssessFee handles working out the fee on a transaction
+// @Summary Work out a fee
+// @Description Works out the fee on a transaction from the schedule in effect at the given time, reproducing the fee charged on a past transaction or previewing one
+// @Tags fees
+// @Accept json
+// @Produce json
+// @Param transaction body dto.AssessFeeRequest true "Transaction details"
+// @Success 200 {object} dto.AssessedFeeResponse "Fee"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fees/assessments [post]
+func (h *FeeHandler) AssessFee(w http.ResponseWriter, r *http.Request) {
+	var req dto.AssessFeeRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	fee, err := h.feeService.AssessFee(r.Context(), req.ToServiceRequest())
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToAssessedFeeResponse(fee, req.Currency))
+}
+
+// renderError maps fee service errors to HTTP status codes
+func (h *FeeHandler) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidFeeSchedule):
+		render.Status(r, http.StatusBadRequest)
+	case errors.Is(err, service.ErrFeeScheduleNotFound):
+		render.Status(r, http.StatusNotFound)
+	default:
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, map[string]string{"error": err.Error()})
+}
+
+// RegisterRoutes registers fee routes to the router
+func (h *FeeHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/api/v1/fees", func(r chi.Router) {
+		// Apply JSON middleware
+		r.Use(middleware.JSONMiddleware)
+		r.Use(middleware.ErrorHandler)
+
+		// Version and look up schedules
+		r.Post("/schedules", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.CreateFeeScheduleRequest
+			middleware.ValidateRequest(h.CreateSchedule, &req)(w, r)
+		})
+		r.Get("/schedules", h.ListSchedules)
+		r.Get("/schedules/{id}", h.GetSchedule)
+
+		// Work out the fee on a transaction
+		r.Post("/assessments", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.AssessFeeRequest
+			middleware.ValidateRequest(h.AssessFee, &req)(w, r)
+		})
+	})
+}
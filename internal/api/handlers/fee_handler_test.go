@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	handlers "github.com/ISRAEL-DUFF/fintech-ledger/internal/api/handlers"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db/dbtest"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeHandler_VersionAndAssess(t *testing.T) {
+	testDB := dbtest.Open(t, &models.FeeSchedule{})
+
+	r := chi.NewRouter()
+	handlers.NewFeeHandler(service.NewFeeService(repository.NewFeeScheduleRepository(testDB))).RegisterRoutes(r)
+
+	capped := dto.CreateFeeScheduleRequest{
+		TransactionType: "wallet.transfer", Currency: "USD", FeeType: "capped",
+		Rate: money.MustParseRate("0.01"), MinAmount: money.MustParse("0.50", "USD"), MaxAmount: money.MustParse("5", "USD"), Actor: "pricing",
+	}
+	rr := doJSON(t, r, http.MethodPost, "/api/v1/fees/schedules", capped)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var v1 dto.FeeScheduleResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &v1))
+	assert.Equal(t, 1, v1.Version)
+	assert.Equal(t, "standard", v1.AccountTier)
+
+	flat := dto.CreateFeeScheduleRequest{
+		TransactionType: "wallet.transfer", Currency: "USD", FeeType: "flat",
+		FlatAmount: money.MustParse("1", "USD"), EffectiveFrom: time.Now().Add(time.Hour), Actor: "pricing",
+	}
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fees/schedules", flat)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/fees/schedules?transaction_type=wallet.transfer&currency=USD", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var list dto.ListFeeSchedulesResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, 2, list.Data[1].Version)
+
+	// The fee of a transaction is reproduced from the version in effect at the time
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fees/assessments", dto.AssessFeeRequest{
+		TransactionType: "wallet.transfer", Currency: "USD", Amount: money.MustParse("1000", "USD"),
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var fee dto.AssessedFeeResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &fee))
+	assert.Equal(t, "5.00", fee.Amount.WithCurrency(fee.Currency).Decimal())
+	assert.Equal(t, v1.ID, fee.ScheduleID)
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/fees/schedules/"+v1.ID, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/fees/schedules/missing", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+
+	flat.EffectiveFrom = time.Now().Add(-time.Hour)
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fees/schedules", flat)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "schedules cannot be backdated")
+}
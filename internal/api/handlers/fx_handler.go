@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/middleware"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// FXHandler handles HTTP requests for foreign exchange
+// @Description Handles exchange rates, rate quotes and revaluation
+// @Tags fx
+type FXHandler struct {
+	rateService        service.ExchangeRateService
+	quoteService       service.FXQuoteService
+	revaluationService service.RevaluationService
+}
+
+// NewFXHandler creates a new FXHandler with the given services
+func NewFXHandler(rs service.ExchangeRateService, qs service.FXQuoteService, vs service.RevaluationService) *FXHandler {
+	return &FXHandler{rateService: rs, quoteService: qs, revaluationService: vs}
+}
+
+// GetRate handles looking up the current rate of a currency pair
+// @Summary Get an exchange rate
+// @Description Resolves the rate of a currency pair through the provider chain, deriving it from the inverse or through the base currency if no provider quotes it directly
+// @Tags fx
+// @Produce json
+// @Param base query string true "Currency converted from (ISO 4217)"
+// @Param quote query string true "Currency converted to (ISO 4217)"
+// @Success 200 {object} dto.ExchangeRateResponse "Exchange rate"
+// @Failure 400 {object} dto.ErrorResponse "Missing currency"
+// @Failure 404 {object} dto.ErrorResponse "No rate for the pair"
+// @Failure 503 {object} dto.ErrorResponse "Only stale rates are available"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fx/rates [get]
+func (h *FXHandler) GetRate(w http.ResponseWriter, r *http.Request) {
+	base, quote := r.URL.Query().Get("base"), r.URL.Query().Get("quote")
+	if base == "" || quote == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "base and quote currencies are required"})
+		return
+	}
+
+	rate, err := h.rateService.GetRate(r.Context(), base, quote)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToExchangeRateResponse(rate))
+}
+
+// CreateRate handles storing an exchange rate
+// @Summary Store an exchange rate
+// @Description Stores a rate that the database rate provider serves from its effective time until a later rate for the pair takes effect
+// @Tags fx
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param rate body dto.CreateExchangeRateRequest true "Rate details"
+// @Success 201 {object} dto.StoredExchangeRateResponse "Rate stored"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fx/rates [post]
+func (h *FXHandler) CreateRate(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateExchangeRateRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	rate := req.ToModel()
+	if err := h.rateService.CreateRate(r.Context(), rate); err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, dto.ToStoredExchangeRateResponse(rate))
+}
+
+// CreateQuote handles issuing an FX quote
+// @Summary Create an FX quote
+// @Description Prices an exchange at the current rate less the spread, plus the fee, and locks the price until the quote expires. A wallet.exchange transaction uses the quote by its ID, once.
+// @Tags fx
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param quote body dto.CreateQuoteRequest true "Exchange to quote"
+// @Success 201 {object} dto.QuoteResponse "Quote issued"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "No rate for the pair"
+// @Failure 503 {object} dto.ErrorResponse "Only stale rates are available"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fx/quotes [post]
+func (h *FXHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateQuoteRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	quote, err := h.quoteService.CreateQuote(r.Context(), req.ToServiceRequest())
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, dto.ToQuoteResponse(quote))
+}
+
+// GetQuote handles retrieving an FX quote
+// @Summary Get an FX quote
+// @Description Retrieves a quote, including whether it has been used
+// @Tags fx
+// @Produce json
+// @Param id path string true "Quote ID"
+// @Success 200 {object} dto.QuoteResponse "Quote"
+// @Failure 404 {object} dto.ErrorResponse "Quote not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fx/quotes/{id} [get]
+func (h *FXHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	quote, err := h.quoteService.GetQuote(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToQuoteResponse(quote))
+}
+
+// Revalue handles revaluing foreign-currency balances
+// @Summary Revalue foreign-currency balances
+// @Description Values every foreign-currency asset and liability account in the reporting currency at the rate in effect at the as-of date, posts the unrealized gain or loss of each currency and reverses it on the reversal date or, if none is given, at the start of the next accounting period
+// @Tags fx
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param revaluation body dto.RevaluationRequest true "Revaluation dates"
+// @Success 201 {object} dto.RevaluationResponse "Revaluation posted"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format or no period to reverse in"
+// @Failure 404 {object} dto.ErrorResponse "No rate for a currency"
+// @Failure 409 {object} dto.ErrorResponse "Date already revalued"
+// @Failure 422 {object} dto.ErrorResponse "Accounting period closed"
+// @Failure 503 {object} dto.ErrorResponse "Only stale rates are available"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/fx/revaluations [post]
+func (h *FXHandler) Revalue(w http.ResponseWriter, r *http.Request) {
+	var req dto.RevaluationRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	revaluation, err := h.revaluationService.Revalue(r.Context(), req.ToServiceRequest())
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, dto.ToRevaluationResponse(revaluation))
+}
+
+// renderError maps exchange rate, quote and revaluation service errors to HTTP status codes
+func (h *FXHandler) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRate),
+		errors.Is(err, service.ErrInvalidQuoteRequest),
+		errors.Is(err, service.ErrInvalidRevaluation):
+		render.Status(r, http.StatusBadRequest)
+	case errors.Is(err, service.ErrRateNotFound),
+		errors.Is(err, repository.ErrQuoteNotFound):
+		render.Status(r, http.StatusNotFound)
+	case errors.Is(err, service.ErrRevaluationExists):
+		render.Status(r, http.StatusConflict)
+	case errors.Is(err, models.ErrPeriodClosed):
+		render.Status(r, http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrRateStale):
+		render.Status(r, http.StatusServiceUnavailable)
+	default:
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, map[string]string{"error": err.Error()})
+}
+
+// RegisterRoutes registers foreign exchange routes to the router
+func (h *FXHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/api/v1/fx", func(r chi.Router) {
+		// Apply JSON middleware
+		r.Use(middleware.JSONMiddleware)
+		r.Use(middleware.ErrorHandler)
+
+		// Look up and store rates
+		r.Get("/rates", h.GetRate)
+		r.Post("/rates", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.CreateExchangeRateRequest
+			middleware.ValidateRequest(h.CreateRate, &req)(w, r)
+		})
+
+		// Issue and look up quotes
+		r.Post("/quotes", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.CreateQuoteRequest
+			middleware.ValidateRequest(h.CreateQuote, &req)(w, r)
+		})
+		r.Get("/quotes/{id}", h.GetQuote)
+
+		// Revalue foreign-currency balances
+		r.Post("/revaluations", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.RevaluationRequest
+			middleware.ValidateRequest(h.Revalue, &req)(w, r)
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	handlers "github.com/ISRAEL-DUFF/fintech-ledger/internal/api/handlers"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db/dbtest"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFXHandler_RatesAndQuotes(t *testing.T) {
+	testDB := dbtest.Open(t, &models.ExchangeRate{}, &models.ExchangeRateAudit{}, &models.FXQuote{})
+
+	rateRepo := repository.NewExchangeRateRepository(testDB)
+	rates := service.NewExchangeRateService(
+		[]service.RateProvider{service.NewDBRateProvider(rateRepo)},
+		rateRepo,
+		service.ExchangeRateConfig{BaseCurrency: "USD", MaxStaleness: time.Hour},
+	)
+	quotes := service.NewFXQuoteService(repository.NewFXQuoteRepository(testDB), nil, rates, nil, service.QuoteConfig{TTL: time.Minute, Spread: money.MustParseRate("0.005")})
+
+	r := chi.NewRouter()
+	handlers.NewFXHandler(rates, quotes, nil).RegisterRoutes(r)
+
+	rr := doJSON(t, r, http.MethodGet, "/api/v1/fx/rates?base=EUR&quote=USD", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fx/rates", dto.CreateExchangeRateRequest{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: money.MustParseRate("0.8")})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/fx/rates?base=EUR&quote=USD", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var rate dto.ExchangeRateResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rate))
+	assert.Equal(t, "1.25", rate.Rate.String())
+	assert.Equal(t, "inverse(db)", rate.Source)
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fx/rates", dto.CreateExchangeRateRequest{
+		BaseCurrency: "USD", QuoteCurrency: "GBP", Rate: money.MustParseRate("0.75"), EffectiveFrom: time.Now().Add(-2 * time.Hour),
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/fx/rates?base=USD&quote=GBP", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "stale rates are refused")
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fx/quotes", dto.CreateQuoteRequest{
+		SourceCurrency: "USD", SourceAmount: money.MustParse("200", "USD"), DestinationCurrency: "EUR",
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var quote dto.QuoteResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &quote))
+	assert.NotEmpty(t, quote.ID)
+	assert.Equal(t, "0.796", quote.Rate.String())
+	assert.Equal(t, "159.20", quote.DestinationAmount.Decimal())
+	assert.True(t, quote.ExpiresAt.After(time.Now()))
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/fx/quotes/"+quote.ID, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/fx/quotes/missing", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fx/quotes", dto.CreateQuoteRequest{SourceCurrency: "USD", DestinationCurrency: "EUR"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "source amount is required")
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/fx/revaluations", dto.RevaluationRequest{})
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "as-of date is required")
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/middleware"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// PeriodHandler handles HTTP requests for accounting periods
+// @Description Handles opening, closing and reopening accounting periods
+// @Tags periods
+type PeriodHandler struct {
+	periodService service.PeriodService
+}
+
+// NewPeriodHandler creates a new PeriodHandler with the given service
+func NewPeriodHandler(ps service.PeriodService) *PeriodHandler {
+	return &PeriodHandler{periodService: ps}
+}
+
+// CreatePeriod handles opening a new accounting period
+// @Summary Create an accounting period
+// @Description Opens a new accounting period; periods may not overlap
+// @Tags periods
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param period body dto.CreatePeriodRequest true "Period details"
+// @Success 201 {object} dto.PeriodResponse "Period created"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 409 {object} dto.ErrorResponse "Period overlaps another or its name is taken"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/periods [post]
+func (h *PeriodHandler) CreatePeriod(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.CreatePeriodRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	period, err := h.periodService.CreatePeriod(ctx, req.Name, req.StartDate, req.EndDate)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, dto.ToPeriodResponse(period))
+}
+
+// ListPeriods handles listing accounting periods
+// @Summary List accounting periods
+// @Description Retrieves every accounting period, oldest first
+// @Tags periods
+// @Produce json
+// @Success 200 {object} dto.ListPeriodsResponse "Periods"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/periods [get]
+func (h *PeriodHandler) ListPeriods(w http.ResponseWriter, r *http.Request) {
+	periods, err := h.periodService.ListPeriods(r.Context())
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToListPeriodsResponse(periods))
+}
+
+// GetPeriod handles retrieving an accounting period
+// @Summary Get an accounting period
+// @Description Retrieves an accounting period by ID
+// @Tags periods
+// @Produce json
+// @Param id path string true "Period ID"
+// @Success 200 {object} dto.PeriodResponse "Period found"
+// @Failure 404 {object} dto.ErrorResponse "Period not found"
+// @Router /api/v1/periods/{id} [get]
+func (h *PeriodHandler) GetPeriod(w http.ResponseWriter, r *http.Request) {
+	period, err := h.periodService.GetPeriod(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToPeriodResponse(period))
+}
+
+// ClosePeriod handles closing an accounting period
+// @Summary Close an accounting period
+// @Description Soft- or hard-closes a period, optionally posting closing entries into retained earnings, and snapshots its closing balances
+// @Tags periods
+// @Accept json
+// @Produce json
+// @Param id path string true "Period ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param close body dto.ClosePeriodRequest true "How to close the period"
+// @Success 200 {object} dto.PeriodResponse "Period closed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "Period not found"
+// @Failure 409 {object} dto.ErrorResponse "Transition not allowed"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/periods/{id}/close [post]
+func (h *PeriodHandler) ClosePeriod(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.ClosePeriodRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	period, err := h.periodService.ClosePeriod(ctx, chi.URLParam(r, "id"), service.ClosePeriodRequest{
+		Hard:               req.Hard,
+		PostClosingEntries: req.PostClosingEntries,
+		Actor:              req.Actor,
+	})
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToPeriodResponse(period))
+}
+
+// ReopenPeriod handles reopening a soft-closed accounting period
+// @Summary Reopen an accounting period
+// @Description Reopens a soft-closed period; hard-closed periods cannot be reopened
+// @Tags periods
+// @Accept json
+// @Produce json
+// @Param id path string true "Period ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param reopen body dto.ReopenPeriodRequest true "Who is reopening the period"
+// @Success 200 {object} dto.PeriodResponse "Period reopened"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "Period not found"
+// @Failure 409 {object} dto.ErrorResponse "Transition not allowed"
+// @Router /api/v1/periods/{id}/reopen [post]
+func (h *PeriodHandler) ReopenPeriod(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req dto.ReopenPeriodRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	period, err := h.periodService.ReopenPeriod(ctx, chi.URLParam(r, "id"), req.Actor)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToPeriodResponse(period))
+}
+
+// GetClosingBalances handles retrieving the closing balances of an accounting period
+// @Summary Get a period's closing balances
+// @Description Retrieves the balance of every account snapshotted when the period was last closed
+// @Tags periods
+// @Produce json
+// @Param id path string true "Period ID"
+// @Success 200 {object} dto.ClosingBalancesResponse "Closing balances"
+// @Failure 404 {object} dto.ErrorResponse "Period not found"
+// @Router /api/v1/periods/{id}/closing-balances [get]
+func (h *PeriodHandler) GetClosingBalances(w http.ResponseWriter, r *http.Request) {
+	periodID := chi.URLParam(r, "id")
+
+	balances, err := h.periodService.GetClosingBalances(r.Context(), periodID)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, dto.ToClosingBalancesResponse(periodID, balances))
+}
+
+// renderError maps period service errors to HTTP status codes
+func (h *PeriodHandler) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrPeriodNotFound):
+		render.Status(r, http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidPeriod):
+		render.Status(r, http.StatusBadRequest)
+	case errors.Is(err, repository.ErrPeriodOverlap),
+		errors.Is(err, repository.ErrPeriodNameTaken),
+		errors.Is(err, repository.ErrPeriodStatusConflict),
+		errors.Is(err, models.ErrInvalidPeriodTransition):
+		render.Status(r, http.StatusConflict)
+	default:
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, map[string]string{"error": err.Error()})
+}
+
+// RegisterRoutes registers accounting period routes to the router
+func (h *PeriodHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/api/v1/periods", func(r chi.Router) {
+		// Apply JSON middleware
+		r.Use(middleware.JSONMiddleware)
+		r.Use(middleware.ErrorHandler)
+
+		// Create and list periods
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.CreatePeriodRequest
+			middleware.ValidateRequest(h.CreatePeriod, &req)(w, r)
+		})
+		r.Get("/", h.ListPeriods)
+		r.Get("/{id}", h.GetPeriod)
+
+		// Close and reopen a period
+		r.Post("/{id}/close", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.ClosePeriodRequest
+			middleware.ValidateRequest(h.ClosePeriod, &req)(w, r)
+		})
+		r.Post("/{id}/reopen", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.ReopenPeriodRequest
+			middleware.ValidateRequest(h.ReopenPeriod, &req)(w, r)
+		})
+		r.Get("/{id}/closing-balances", h.GetClosingBalances)
+	})
+}
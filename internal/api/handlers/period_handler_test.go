@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	handlers "github.com/ISRAEL-DUFF/fintech-ledger/internal/api/handlers"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db/dbtest"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodHandler_CloseAndAdjust(t *testing.T) {
+	testDB := dbtest.Open(t,
+		&models.Account{}, &models.Entry{}, &models.EntryLine{}, &models.EntryExchangeRate{}, &models.Transaction{}, &models.BalanceSnapshot{},
+		&models.AccountingPeriod{}, &models.PeriodClosingBalance{},
+	)
+
+	entryRepo := repository.NewEntryRepository(testDB)
+	accountRepo := repository.NewAccountRepository(testDB)
+	periodRepo := repository.NewPeriodRepository(testDB)
+	txService := service.NewTransactionService(entryRepo, accountRepo, repository.NewTransactionRepository(testDB), periodRepo)
+	cash := &models.Account{Name: "Cash", Type: models.Asset, Currency: "USD"}
+	rent := &models.Account{Name: "Rent", Type: models.Expense, Currency: "USD"}
+	require.NoError(t, accountRepo.CreateAccount(context.Background(), cash))
+	require.NoError(t, accountRepo.CreateAccount(context.Background(), rent))
+
+	r := chi.NewRouter()
+	handlers.NewPeriodHandler(service.NewPeriodService(periodRepo, entryRepo, accountRepo, txService)).RegisterRoutes(r)
+	handlers.NewTransactionHandler(txService).RegisterRoutes(r)
+
+	january := dto.CreatePeriodRequest{
+		Name:      "2024-01",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC),
+	}
+	rr := doJSON(t, r, http.MethodPost, "/api/v1/periods", january)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var period dto.PeriodResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &period))
+	assert.Equal(t, "open", period.Status)
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/periods", january)
+	assert.Equal(t, http.StatusConflict, rr.Code, "duplicate period")
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/periods/"+period.ID+"/close", dto.ClosePeriodRequest{PostClosingEntries: true})
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "actor is required")
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/periods/"+period.ID+"/close", dto.ClosePeriodRequest{PostClosingEntries: true, Actor: "controller"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &period))
+	assert.Equal(t, "soft_closed", period.Status)
+
+	// Entries dated in the closed period need the adjustment header
+	rent15th := dto.CreateTransactionRequest{
+		Description:     "January rent accrual",
+		TransactionType: "transfer",
+		Date:            time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Lines: []dto.TransactionLineEntry{
+			{AccountID: rent.ID, Amount: money.MustParse("-300", "USD"), Currency: "USD"},
+			{AccountID: cash.ID, Amount: money.MustParse("300", "USD"), Currency: "USD"},
+		},
+	}
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/transactions", rent15th)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+
+	body, err := json.Marshal(rent15th)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Period-Adjustment", "true")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/periods/"+period.ID+"/close", dto.ClosePeriodRequest{Hard: true, Actor: "controller"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(t, r, http.MethodPost, "/api/v1/periods/"+period.ID+"/reopen", dto.ReopenPeriodRequest{Actor: "controller"})
+	assert.Equal(t, http.StatusConflict, rr.Code, "hard-closed periods are final")
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/periods/"+period.ID+"/closing-balances", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var balances dto.ClosingBalancesResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &balances))
+	require.Len(t, balances.Data, 2)
+	for _, b := range balances.Data {
+		assert.Equal(t, "300.00", b.Balance.Abs().Decimal(), b.AccountID)
+	}
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/periods", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var listed dto.ListPeriodsResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, "hard_closed", listed.Data[0].Status)
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/periods/missing", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
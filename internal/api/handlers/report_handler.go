@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/middleware"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ReportHandler handles HTTP requests for financial reports
+// @Description Handles financial reports over the ledger
+// @Tags reports
+type ReportHandler struct {
+	reportService service.ReportService
+}
+
+// NewReportHandler creates a new ReportHandler with the given service
+func NewReportHandler(rs service.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: rs}
+}
+
+// GetTrialBalance handles generating a trial balance
+// @Summary Get the trial balance
+// @Description Lists the debit or credit balance of every account with postings, one section per currency, and whether debits equal credits
+// @Tags reports
+// @Produce json
+// @Produce text/csv
+// @Param as_of query string false "Point in time (RFC3339 format), defaults to now" format(date-time)
+// @Param format query string false "Response format" Enums(json, csv)
+// @Success 200 {object} dto.TrialBalanceResponse "Trial balance"
+// @Failure 400 {object} dto.ErrorResponse "Invalid parameters"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/reports/trial-balance [get]
+func (h *ReportHandler) GetTrialBalance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	asOf, ok := parseTimeParam(w, r, "as_of", time.Now())
+	if !ok {
+		return
+	}
+	format, ok := parseFormatParam(w, r, formatJSON, formatCSV)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.TrialBalance(ctx, asOf)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if format == formatCSV {
+		writeCSV(w, "trial-balance.csv", trialBalanceRecords(report))
+		return
+	}
+	render.JSON(w, r, dto.ToTrialBalanceResponse(report))
+}
+
+// GetIncomeStatement handles generating an income statement
+// @Summary Get the income statement
+// @Description Summarizes revenue, expenses and net income over a period, one section per currency
+// @Tags reports
+// @Produce json
+// @Param from query string true "Start of the period (RFC3339 format)" format(date-time)
+// @Param to query string false "End of the period (RFC3339 format), defaults to now" format(date-time)
+// @Success 200 {object} dto.IncomeStatementResponse "Income statement"
+// @Failure 400 {object} dto.ErrorResponse "Invalid parameters"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/reports/income-statement [get]
+func (h *ReportHandler) GetIncomeStatement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.URL.Query().Get("from") == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "from is required"})
+		return
+	}
+	from, ok := parseTimeParam(w, r, "from", time.Time{})
+	if !ok {
+		return
+	}
+	to, ok := parseTimeParam(w, r, "to", time.Now())
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.IncomeStatement(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidReportPeriod) {
+			render.Status(r, http.StatusBadRequest)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+		return
+	}
+
+	render.JSON(w, r, dto.ToIncomeStatementResponse(report))
+}
+
+// GetBalanceSheet handles generating a balance sheet
+// @Summary Get the balance sheet
+// @Description Shows assets, liabilities and equity, with current earnings rolled into equity, one section per currency
+// @Tags reports
+// @Produce json
+// @Param as_of query string false "Point in time (RFC3339 format), defaults to now" format(date-time)
+// @Success 200 {object} dto.BalanceSheetResponse "Balance sheet"
+// @Failure 400 {object} dto.ErrorResponse "Invalid parameters"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/reports/balance-sheet [get]
+func (h *ReportHandler) GetBalanceSheet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	asOf, ok := parseTimeParam(w, r, "as_of", time.Now())
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.BalanceSheet(ctx, asOf)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+		return
+	}
+
+	render.JSON(w, r, dto.ToBalanceSheetResponse(report))
+}
+
+// trialBalanceRecords flattens a trial balance into CSV rows, closing each
+// currency section with a totals row that is flagged when it does not balance
+func trialBalanceRecords(report *service.TrialBalance) [][]string {
+	records := [][]string{{"currency", "account_id", "code", "name", "account_type", "debit", "credit"}}
+	for _, section := range report.Sections {
+		for _, line := range section.Lines {
+			records = append(records, []string{
+				section.Currency, line.AccountID, line.Code, line.Name, string(line.AccountType),
+				line.Debit.Decimal(), line.Credit.Decimal(),
+			})
+		}
+		label := "TOTAL"
+		if !section.Balanced {
+			label = "TOTAL (OUT OF BALANCE)"
+		}
+		records = append(records, []string{
+			section.Currency, "", "", label, "", section.TotalDebit.Decimal(), section.TotalCredit.Decimal(),
+		})
+	}
+	return records
+}
+
+// RegisterRoutes registers report routes to the router
+func (h *ReportHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/api/v1/reports", func(r chi.Router) {
+		r.Use(middleware.ErrorHandler)
+
+		r.Get("/trial-balance", h.GetTrialBalance)
+		r.Get("/income-statement", h.GetIncomeStatement)
+		r.Get("/balance-sheet", h.GetBalanceSheet)
+	})
+}
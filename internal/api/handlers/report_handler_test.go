@@ -0,0 +1,81 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
+	handlers "github.com/ISRAEL-DUFF/fintech-ledger/internal/api/handlers"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db/dbtest"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportHandler_TrialBalance(t *testing.T) {
+	testDB := dbtest.Open(t, &models.Account{}, &models.Entry{}, &models.EntryLine{}, &models.EntryExchangeRate{}, &models.Transaction{}, &models.BalanceSnapshot{}, &models.AccountingPeriod{})
+
+	entryRepo := repository.NewEntryRepository(testDB)
+	accountRepo := repository.NewAccountRepository(testDB)
+	wallet := &models.Account{Name: "Alice wallet", Type: models.Liability, UserID: "alice", Currency: "USD"}
+	require.NoError(t, accountRepo.CreateAccount(context.Background(), wallet))
+	txService := service.NewTransactionService(entryRepo, accountRepo, repository.NewTransactionRepository(testDB), repository.NewPeriodRepository(testDB))
+	_, err := txService.ProcessDeposit(context.Background(), service.DepositRequest{AccountID: wallet.ID, Amount: money.MustParse("75", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	handlers.NewReportHandler(service.NewReportService(entryRepo)).RegisterRoutes(r)
+
+	rr := doJSON(t, r, http.MethodGet, "/api/v1/reports/trial-balance", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var report dto.TrialBalanceResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.True(t, report.Balanced)
+	require.Len(t, report.Sections, 1)
+	assert.Len(t, report.Sections[0].Lines, 2)
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/reports/trial-balance?format=csv", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv"))
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4, "header, two accounts and the USD total")
+	assert.Equal(t, []string{"USD", "", "", "TOTAL", "", "75.00", "75.00"}, records[3])
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/reports/trial-balance?as_of=yesterday", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/reports/trial-balance?format=pdf", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestReportHandler_IncomeStatementAndBalanceSheet(t *testing.T) {
+	testDB := dbtest.Open(t, &models.Account{}, &models.Entry{}, &models.EntryLine{})
+
+	r := chi.NewRouter()
+	handlers.NewReportHandler(service.NewReportService(repository.NewEntryRepository(testDB))).RegisterRoutes(r)
+
+	rr := doJSON(t, r, http.MethodGet, "/api/v1/reports/income-statement", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "from is required")
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/reports/income-statement?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "period ends before it starts")
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/reports/income-statement?from=2024-01-01T00:00:00Z&to=2024-01-31T23:59:59Z", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var statement dto.IncomeStatementResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statement))
+	assert.Empty(t, statement.Sections)
+
+	rr = doJSON(t, r, http.MethodGet, "/api/v1/reports/balance-sheet?as_of=2024-01-31T23:59:59Z", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var sheet dto.BalanceSheetResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &sheet))
+	assert.True(t, sheet.Balanced)
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/middleware"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -32,14 +37,18 @@ func NewTransactionHandler(ts service.TransactionService) *TransactionHandler {
 // @Tags transactions
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param X-Period-Adjustment header bool false "Allow posting into a soft-closed accounting period"
 // @Param transaction body dto.CreateTransactionRequest true "Transaction details"
 // @Success 201 {object} dto.TransactionResponse "Transaction created successfully"
 // @Failure 400 {object} dto.ErrorResponse "Invalid request format"
-// @Failure 422 {object} dto.ErrorResponse "Validation error"
+// @Failure 404 {object} dto.ErrorResponse "Account not found"
+// @Failure 409 {object} dto.ErrorResponse "Idempotency key reused with a different request"
+// @Failure 422 {object} dto.UnbalancedTransactionResponse "Unbalanced currencies, invalid lines or amounts, invalid cross-currency routing, a forbidden posting or insufficient funds"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /api/v1/transactions [post]
 func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := periodAdjustmentContext(r)
 
 	// Get validated data from context
 	var req dto.CreateTransactionRequest
@@ -54,6 +63,25 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 
 	// Create the transaction
 	if err := h.transactionService.CreateEntry(ctx, entry); err != nil {
+		var unbalanced *service.UnbalancedEntryError
+		if errors.As(err, &unbalanced) {
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, dto.ToUnbalancedTransactionResponse(unbalanced))
+			return
+		}
+		if errors.Is(err, service.ErrAccountNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, map[string]string{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidEntry) || errors.Is(err, money.ErrInvalidAmount) ||
+			errors.Is(err, models.ErrPostingNotAllowed) || errors.Is(err, repository.ErrInsufficientFunds) ||
+			errors.Is(err, service.ErrNonLeafAccount) || errors.Is(err, models.ErrPeriodClosed) ||
+			errors.Is(err, service.ErrLineCurrencyMismatch) || errors.Is(err, service.ErrCrossCurrencyEntry) {
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, map[string]string{"error": err.Error()})
+			return
+		}
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, map[string]string{"error": err.Error()})
 		return
@@ -104,6 +132,60 @@ func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Reque
 	render.JSON(w, r, resp)
 }
 
+// ReverseTransaction handles reversing a posted transaction
+// @Summary Reverse a transaction
+// @Description Posts a mirror transaction with debits and credits swapped and marks the original as reversed
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Param X-Period-Adjustment header bool false "Allow posting into a soft-closed accounting period"
+// @Param reversal body dto.ReverseTransactionRequest true "Reversal details"
+// @Success 201 {object} dto.TransactionResponse "Reversal transaction created"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request format"
+// @Failure 404 {object} dto.ErrorResponse "Transaction not found"
+// @Failure 409 {object} dto.ErrorResponse "Transaction already reversed or not reversible"
+// @Failure 422 {object} dto.ErrorResponse "Account status or accounting period forbids the posting, or insufficient funds"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /api/v1/transactions/{id}/reverse [post]
+func (h *TransactionHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := periodAdjustmentContext(r)
+	transactionID := chi.URLParam(r, "id")
+	if transactionID == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Transaction ID is required"})
+		return
+	}
+
+	var req dto.ReverseTransactionRequest
+	if !middleware.GetValidatedData(r, &req) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request data"})
+		return
+	}
+
+	reversal, err := h.transactionService.ReverseEntry(ctx, transactionID, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEntryNotFound):
+			render.Status(r, http.StatusNotFound)
+		case errors.Is(err, service.ErrEntryAlreadyReversed), errors.Is(err, service.ErrEntryNotReversible):
+			render.Status(r, http.StatusConflict)
+		case errors.Is(err, repository.ErrInsufficientFunds), errors.Is(err, models.ErrPostingNotAllowed),
+			errors.Is(err, service.ErrNonLeafAccount), errors.Is(err, models.ErrPeriodClosed):
+			render.Status(r, http.StatusUnprocessableEntity)
+		default:
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, dto.ToResponse(reversal))
+}
+
 // ListTransactions handles listing transactions with optional filtering
 // @Summary List transactions
 // @Description Retrieves a paginated list of transactions with optional date filtering
@@ -190,6 +272,15 @@ func (h *TransactionHandler) ListTransactions(w http.ResponseWriter, r *http.Req
 	render.JSON(w, r, resp)
 }
 
+// periodAdjustmentContext returns the request context, granting the period
+// adjustment permission when the X-Period-Adjustment header is true
+func periodAdjustmentContext(r *http.Request) context.Context {
+	if allowed, _ := strconv.ParseBool(r.Header.Get("X-Period-Adjustment")); allowed {
+		return service.WithPeriodAdjustment(r.Context())
+	}
+	return r.Context()
+}
+
 // RegisterRoutes registers transaction routes to the router
 func (h *TransactionHandler) RegisterRoutes(router chi.Router) {
 	router.Route("/api/v1/transactions", func(r chi.Router) {
@@ -206,6 +297,12 @@ func (h *TransactionHandler) RegisterRoutes(router chi.Router) {
 		// Get transaction by ID
 		r.Get("/{id}", h.GetTransaction)
 
+		// Reverse a posted transaction
+		r.Post("/{id}/reverse", func(w http.ResponseWriter, r *http.Request) {
+			var req dto.ReverseTransactionRequest
+			middleware.ValidateRequest(h.ReverseTransaction, &req)(w, r)
+		})
+
 		// List transactions with pagination
 		r.Get("/", h.ListTransactions)
 	})
@@ -12,6 +12,7 @@ import (
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/dto"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -22,7 +23,8 @@ import (
 
 // mockTransactionService is a mock implementation of the TransactionService interface
 type mockTransactionService struct {
-	createEntryFunc func(ctx context.Context, entry *models.Entry) error
+	createEntryFunc  func(ctx context.Context, entry *models.Entry) error
+	reverseEntryFunc func(ctx context.Context, entryID, reason string) (*models.Entry, error)
 }
 
 // Ensure mockTransactionService implements service.TransactionService
@@ -35,6 +37,15 @@ func (m *mockTransactionService) CreateEntry(ctx context.Context, entry *models.
 	return nil
 }
 
+func (m *mockTransactionService) CreateEntries(ctx context.Context, entries ...*models.Entry) error {
+	for _, entry := range entries {
+		if err := m.CreateEntry(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *mockTransactionService) GetEntryByID(ctx context.Context, id string) (*models.Entry, error) {
 	// Return a sample entry for testing
 	return &models.Entry{
@@ -71,7 +82,7 @@ func (m *mockTransactionService) ProcessTransfer(ctx context.Context, req servic
 		ID:          "test-transfer-123",
 		Type:        "transfer",
 		Status:      "completed",
-		Description: fmt.Sprintf("Transfer of %f %s", req.Amount, req.Currency),
+		Description: fmt.Sprintf("Transfer of %s %s", req.Amount, req.Currency),
 		CreatedAt:   time.Now(),
 	}, nil
 }
@@ -81,7 +92,7 @@ func (m *mockTransactionService) ProcessDeposit(ctx context.Context, req service
 		ID:          "test-deposit-123",
 		Type:        "deposit",
 		Status:      "completed",
-		Description: fmt.Sprintf("Deposit of %f %s", req.Amount, req.Currency),
+		Description: fmt.Sprintf("Deposit of %s %s", req.Amount, req.Currency),
 		CreatedAt:   time.Now(),
 	}, nil
 }
@@ -91,7 +102,7 @@ func (m *mockTransactionService) ProcessWithdrawal(ctx context.Context, req serv
 		ID:          "test-withdrawal-123",
 		Type:        "withdrawal",
 		Status:      "completed",
-		Description: fmt.Sprintf("Withdrawal of %f %s", req.Amount, req.Currency),
+		Description: fmt.Sprintf("Withdrawal of %s %s", req.Amount, req.Currency),
 		CreatedAt:   time.Now(),
 	}, nil
 }
@@ -101,7 +112,7 @@ func (m *mockTransactionService) ProcessExchange(ctx context.Context, req servic
 		ID:          "test-exchange-123",
 		Type:        "exchange",
 		Status:      "completed",
-		Description: fmt.Sprintf("Exchange %f %s to %f %s", 
+		Description: fmt.Sprintf("Exchange %s %s to %s %s", 
 			req.SourceAmount, req.SourceCurrency, 
 			req.DestinationAmount, req.DestinationCurrency),
 		CreatedAt:   time.Now(),
@@ -113,11 +124,22 @@ func (m *mockTransactionService) ProcessFee(ctx context.Context, req service.Fee
 		ID:          "test-fee-123",
 		Type:        "fee",
 		Status:      "completed",
-		Description: fmt.Sprintf("Fee of %f %s", req.Amount, req.Currency),
+		Description: fmt.Sprintf("Fee of %s %s", req.Amount, req.Currency),
 		CreatedAt:   time.Now(),
 	}, nil
 }
 
+func (m *mockTransactionService) GetTransactionByReference(ctx context.Context, referenceID string) (*models.Transaction, error) {
+	return nil, nil
+}
+
+func (m *mockTransactionService) ReverseEntry(ctx context.Context, entryID, reason string) (*models.Entry, error) {
+	if m.reverseEntryFunc != nil {
+		return m.reverseEntryFunc(ctx, entryID, reason)
+	}
+	return &models.Entry{ID: "reversal-of-" + entryID, ReversalOfID: entryID, Description: reason, Date: time.Now()}, nil
+}
+
 func (m *mockTransactionService) ReverseTransfer(ctx context.Context, transactionID string) error {
 	return nil
 }
@@ -158,8 +180,8 @@ func TestTransactionHandler_CreateTransaction(t *testing.T) {
 				ReferenceID:     "test-ref-123",
 				Date:            now,
 				Lines: []dto.TransactionLineEntry{
-					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: -100.00, Currency: "USD"},
-					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: 100.00, Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: money.MustParse("100.00", "USD"), Currency: "USD"},
 				},
 			},
 			setupMocks: func(mockSvc *mockTransactionService) {
@@ -183,7 +205,7 @@ func TestTransactionHandler_CreateTransaction(t *testing.T) {
 			request: dto.CreateTransactionRequest{
 				Description: "", // Missing required field
 				Lines: []dto.TransactionLineEntry{
-					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: -100.00, Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
 				},
 			},
 			setupMocks:     nil, // No mocks needed for validation failure
@@ -198,8 +220,8 @@ func TestTransactionHandler_CreateTransaction(t *testing.T) {
 				ReferenceID:     "test-ref-124",
 				Date:            now,
 				Lines: []dto.TransactionLineEntry{
-					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: -100.00, Currency: "USD"},
-					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: 100.00, Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: money.MustParse("100.00", "USD"), Currency: "USD"},
 				},
 			},
 			setupMocks: func(mockSvc *mockTransactionService) {
@@ -210,12 +232,92 @@ func TestTransactionHandler_CreateTransaction(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   map[string]interface{}{},
 		},
+		{
+			name: "service error - account not found",
+			request: dto.CreateTransactionRequest{
+				Description:     "Unknown account",
+				TransactionType: "transfer",
+				Date:            now,
+				Lines: []dto.TransactionLineEntry{
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440009", Amount: money.MustParse("100.00", "USD"), Currency: "USD"},
+				},
+			},
+			setupMocks: func(mockSvc *mockTransactionService) {
+				mockSvc.createEntryFunc = func(ctx context.Context, entry *models.Entry) error {
+					return fmt.Errorf("%w: %s", service.ErrAccountNotFound, entry.Lines[1].AccountID)
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   map[string]interface{}{},
+		},
+		{
+			name: "service error - unbalanced entry",
+			request: dto.CreateTransactionRequest{
+				Description:     "Unbalanced",
+				TransactionType: "transfer",
+				Date:            now,
+				Lines: []dto.TransactionLineEntry{
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: money.MustParse("90.00", "USD"), Currency: "USD"},
+				},
+			},
+			setupMocks: func(mockSvc *mockTransactionService) {
+				mockSvc.createEntryFunc = func(ctx context.Context, entry *models.Entry) error {
+					return &service.UnbalancedEntryError{Imbalances: []service.CurrencyImbalance{{
+						Currency:    "USD",
+						TotalDebit:  money.MustParse("90.00", "USD"),
+						TotalCredit: money.MustParse("100.00", "USD"),
+						Difference:  money.MustParse("-10.00", "USD"),
+					}}}
+				}
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   map[string]interface{}{},
+		},
+		{
+			name: "service error - invalid amount",
+			request: dto.CreateTransactionRequest{
+				Description:     "Invalid amount",
+				TransactionType: "transfer",
+				Date:            now,
+				Lines: []dto.TransactionLineEntry{
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: money.MustParse("100.00", "USD"), Currency: "USD"},
+				},
+			},
+			setupMocks: func(mockSvc *mockTransactionService) {
+				mockSvc.createEntryFunc = func(ctx context.Context, entry *models.Entry) error {
+					return fmt.Errorf("invalid debit on account %s: %w", entry.Lines[0].AccountID, money.ErrInvalidAmount)
+				}
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   map[string]interface{}{},
+		},
+		{
+			name: "service error - malformed entry",
+			request: dto.CreateTransactionRequest{
+				Description:     "Malformed",
+				TransactionType: "transfer",
+				Date:            now,
+				Lines: []dto.TransactionLineEntry{
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+				},
+			},
+			setupMocks: func(mockSvc *mockTransactionService) {
+				mockSvc.createEntryFunc = func(ctx context.Context, entry *models.Entry) error {
+					return fmt.Errorf("%w: entry must have at least two lines", service.ErrInvalidEntry)
+				}
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   map[string]interface{}{},
+		},
 		{
 			name: "invalid request body",
 			request: dto.CreateTransactionRequest{
 				Description: "Test transaction",
 				Lines: []dto.TransactionLineEntry{
-					{AccountID: "", Amount: 0, Currency: ""}, // Invalid line data
+					{AccountID: "", Amount: money.Money{}, Currency: ""}, // Invalid line data
 				},
 			},
 			setupMocks:     nil,
@@ -15,6 +15,7 @@ import (
 	handlers "github.com/ISRAEL-DUFF/fintech-ledger/internal/api/handlers"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
 	"github.com/go-chi/chi/v5"
@@ -59,7 +60,7 @@ func setupTestServer(t *testing.T) *testServer {
 	require.NoError(t, accountRepo.CreateAccount(context.Background(), account2))
 
 	// Initialize services
-	transactionService := service.NewTransactionService(entryRepo, accountRepo)
+	transactionService := service.NewTransactionService(entryRepo, accountRepo, repository.NewTransactionRepository(testDB), repository.NewPeriodRepository(testDB))
 
 	// Initialize handler
 	handler := handlers.NewTransactionHandler(transactionService)
@@ -94,6 +95,11 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&models.Account{},
 		&models.Entry{},
 		&models.EntryLine{},
+		&models.EntryExchangeRate{},
+		&models.Transaction{},
+		&models.BalanceSnapshot{},
+		&models.AccountStatusChange{},
+		&models.AccountingPeriod{},
 	)
 	require.NoError(t, err, "Failed to run migrations")
 
@@ -131,8 +137,8 @@ func TestTransactionHandler_CreateTransaction_Integration(t *testing.T) {
 				ReferenceID:     "test-ref-123",
 				Date:            time.Now(),
 				Lines: []dto.TransactionLineEntry{
-					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: -100.00, Currency: "USD"},
-					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: 100.00, Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: money.MustParse("100.00", "USD"), Currency: "USD"},
 				},
 			},
 			expectedStatus: http.StatusCreated,
@@ -146,7 +152,7 @@ func TestTransactionHandler_CreateTransaction_Integration(t *testing.T) {
 			request: dto.CreateTransactionRequest{
 				// Missing description
 				Lines: []dto.TransactionLineEntry{
-					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: -100.00, Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
 				},
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -159,11 +165,25 @@ func TestTransactionHandler_CreateTransaction_Integration(t *testing.T) {
 				ReferenceID:     "test-ref-124",
 				Date:            time.Now(),
 				Lines: []dto.TransactionLineEntry{
-					{AccountID: "invalid-account-id", Amount: -100.00, Currency: "USD"},
+					{AccountID: "invalid-account-id", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
 				},
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "validation error - unbalanced",
+			request: dto.CreateTransactionRequest{
+				Description:     "Test transaction",
+				TransactionType: "transfer",
+				ReferenceID:     "test-ref-125",
+				Date:            time.Now(),
+				Lines: []dto.TransactionLineEntry{
+					{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+					{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: money.MustParse("90.00", "USD"), Currency: "USD"},
+				},
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
 	}
 
 	for _, tt := range tests {
@@ -184,6 +204,8 @@ func TestTransactionHandler_CreateTransaction_Integration(t *testing.T) {
 				service.NewTransactionService(
 					repository.NewEntryRepository(ts.db),
 					repository.NewAccountRepository(ts.db),
+					repository.NewTransactionRepository(ts.db),
+					repository.NewPeriodRepository(ts.db),
 				),
 			)
 			handler.RegisterRoutes(r)
@@ -226,8 +248,8 @@ func TestTransactionHandler_GetTransaction_Integration(t *testing.T) {
 		ReferenceID:     "test-get-ref-123",
 		Date:            time.Now(),
 		Lines: []dto.TransactionLineEntry{
-			{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: -100.00, Currency: "USD"},
-			{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: 100.00, Currency: "USD"},
+			{AccountID: "550e8400-e29b-41d4-a716-446655440000", Amount: money.MustParse("-100.00", "USD"), Currency: "USD"},
+			{AccountID: "550e8400-e29b-41d4-a716-446655440001", Amount: money.MustParse("100.00", "USD"), Currency: "USD"},
 		},
 	}
 
@@ -245,6 +267,8 @@ func TestTransactionHandler_GetTransaction_Integration(t *testing.T) {
 		service.NewTransactionService(
 			repository.NewEntryRepository(ts.db),
 			repository.NewAccountRepository(ts.db),
+			repository.NewTransactionRepository(ts.db),
+			repository.NewPeriodRepository(ts.db),
 		),
 	)
 	handler.RegisterRoutes(r)
@@ -3,6 +3,7 @@ package middleware
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"log"
 	"net/http"
 
@@ -68,6 +69,11 @@ func handleError(w http.ResponseWriter, err error) {
 	case *json.SyntaxError, *json.UnmarshalTypeError:
 		statusCode = http.StatusBadRequest
 		err = errors.New("invalid JSON payload")
+	default:
+		if errors.Is(err, io.EOF) {
+			statusCode = http.StatusBadRequest
+			err = errors.New("request body is required")
+		}
 	}
 
 	// Log the error for server-side debugging
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// DefaultIdempotencyLease is how long a request may hold its key before
+	// a retry presumes it lost and takes the key over. It should comfortably
+	// exceed the longest request.
+	DefaultIdempotencyLease = 5 * time.Minute
+)
+
+// Idempotency makes mutating requests that carry an Idempotency-Key header
+// safe to retry. The first request with a key is executed and its response
+// stored; a later request with the same key and body gets the stored response
+// back, while the same key with a different body is rejected with 409.
+// A key whose request is still running is rejected with 409 too, until lease
+// has passed; the request is then presumed lost, for example with a crashed
+// server, and a retry runs it again. Requests without the header are passed
+// through unchanged.
+func Idempotency(store repository.IdempotencyRepository, lease time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				handleError(w, NewAPIError(http.StatusBadRequest, "Idempotency-Key must be at most 255 characters", nil))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				handleError(w, NewAPIError(http.StatusBadRequest, "failed to read request body", nil))
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record := &models.IdempotencyKey{
+				Key:         key,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				RequestHash: hashRequest(r.Method, r.URL.Path, body),
+			}
+
+			ctx := r.Context()
+			if err := store.Reserve(ctx, record, lease); err != nil {
+				if errors.Is(err, repository.ErrIdempotencyKeyExists) {
+					replay(w, r, store, record)
+					return
+				}
+				handleError(w, err)
+				return
+			}
+
+			// The outcome must be stored even if the client has gone away
+			storeCtx := context.WithoutCancel(ctx)
+			rec := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					releaseKey(storeCtx, store, record)
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			// Server errors are not stored so that the client can retry them
+			if rec.statusCode() >= http.StatusInternalServerError {
+				releaseKey(storeCtx, store, record)
+				return
+			}
+			if err := store.Complete(storeCtx, key, record.Reservation, rec.statusCode(), rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+				// A key left reserved would turn retries away until its lease ran out
+				log.Printf("Failed to store response for idempotency key %s: %v", key, err)
+				releaseKey(storeCtx, store, record)
+			}
+		})
+	}
+}
+
+// replay answers a request whose key has already been used
+func replay(w http.ResponseWriter, r *http.Request, store repository.IdempotencyRepository, request *models.IdempotencyKey) {
+	original, err := store.Get(r.Context(), request.Key)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if original == nil {
+		// The original request failed and released the key between our reserve and read
+		handleError(w, NewAPIError(http.StatusConflict, "a request with this Idempotency-Key is still being processed", nil))
+		return
+	}
+
+	if original.RequestHash != request.RequestHash {
+		handleError(w, NewAPIError(http.StatusConflict, "Idempotency-Key has already been used with a different request", nil))
+		return
+	}
+	if !original.Completed() {
+		handleError(w, NewAPIError(http.StatusConflict, "a request with this Idempotency-Key is still being processed", nil))
+		return
+	}
+
+	if original.ContentType != "" {
+		w.Header().Set("Content-Type", original.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(original.StatusCode)
+	if _, err := w.Write(original.ResponseBody); err != nil {
+		log.Printf("Failed to write replayed response: %v", err)
+	}
+}
+
+// releaseKey gives up the reservation of record's key
+func releaseKey(ctx context.Context, store repository.IdempotencyRepository, record *models.IdempotencyKey) {
+	if err := store.Release(ctx, record.Key, record.Reservation); err != nil {
+		log.Printf("Failed to release idempotency key %s: %v", record.Key, err)
+	}
+}
+
+// isMutating reports whether requests with the given method change state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// hashRequest fingerprints a request so that a reused key can be matched to its original body
+func hashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db/dbtest"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupIdempotency(t *testing.T, handler http.HandlerFunc) http.Handler {
+	testDB := dbtest.Open(t, &models.IdempotencyKey{})
+	return Idempotency(repository.NewIdempotencyRepository(testDB), DefaultIdempotencyLease)(handler)
+}
+
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transactions", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotency_ReplaysOriginalResponse(t *testing.T) {
+	calls := 0
+	h := setupIdempotency(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"entry-1"}`))
+	})
+
+	first := post(h, "key-1", `{"amount":"10.00"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	replayed := post(h, "key-1", `{"amount":"10.00"}`)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, `{"id":"entry-1"}`, replayed.Body.String())
+	assert.Equal(t, "application/json", replayed.Header().Get("Content-Type"))
+	assert.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls, "the handler must run only once")
+
+	conflict := post(h, "key-1", `{"amount":"99.00"}`)
+	assert.Equal(t, http.StatusConflict, conflict.Code)
+
+	// Requests without a key are never deduplicated
+	post(h, "", `{"amount":"10.00"}`)
+	post(h, "", `{"amount":"10.00"}`)
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotency_ServerErrorsCanBeRetried(t *testing.T) {
+	fail := true
+	h := setupIdempotency(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, post(h, "key-1", `{}`).Code)
+
+	fail = false
+	assert.Equal(t, http.StatusCreated, post(h, "key-1", `{}`).Code)
+}
+
+func TestIdempotency_LostRequestsReleaseTheirKeyAfterTheLease(t *testing.T) {
+	store := repository.NewIdempotencyRepository(dbtest.Open(t, &models.IdempotencyKey{}))
+	calls := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	// A request that reserved the key and was lost before completing
+	lost := &models.IdempotencyKey{Key: "key-1", Method: http.MethodPost, Path: "/api/v1/transactions",
+		RequestHash: hashRequest(http.MethodPost, "/api/v1/transactions", []byte(`{}`))}
+	assert.NoError(t, store.Reserve(context.Background(), lost, time.Hour))
+
+	assert.Equal(t, http.StatusConflict, post(Idempotency(store, time.Hour)(http.HandlerFunc(handler)), "key-1", `{}`).Code)
+	assert.Equal(t, 0, calls)
+
+	time.Sleep(10 * time.Millisecond)
+	h := Idempotency(store, 5*time.Millisecond)(http.HandlerFunc(handler))
+	assert.Equal(t, http.StatusCreated, post(h, "key-1", `{}`).Code)
+	assert.Equal(t, 1, calls)
+
+	// The completed request is replayed from then on, lease or not
+	replayed := post(h, "key-1", `{}`)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_TakenOverKeysIgnoreTheirFirstHolder(t *testing.T) {
+	store := repository.NewIdempotencyRepository(dbtest.Open(t, &models.IdempotencyKey{}))
+	ctx := context.Background()
+	reserve := func(lease time.Duration) (*models.IdempotencyKey, error) {
+		key := &models.IdempotencyKey{Key: "key-1", Method: http.MethodPost, Path: "/api/v1/transactions",
+			RequestHash: hashRequest(http.MethodPost, "/api/v1/transactions", []byte(`{}`))}
+		return key, store.Reserve(ctx, key, lease)
+	}
+
+	first, err := reserve(time.Hour)
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	second, err := reserve(5 * time.Millisecond)
+	assert.NoError(t, err)
+
+	// The first holder finishes late; neither its release nor its response
+	// may touch the key now held by the second
+	assert.NoError(t, store.Release(ctx, first.Key, first.Reservation))
+	assert.NoError(t, store.Complete(ctx, first.Key, first.Reservation, http.StatusInternalServerError, "", nil))
+	stored, err := store.Get(ctx, "key-1")
+	assert.NoError(t, err)
+	assert.False(t, stored.Completed())
+	assert.Equal(t, second.Reservation, stored.Reservation)
+
+	assert.NoError(t, store.Complete(ctx, second.Key, second.Reservation, http.StatusCreated, "", nil))
+	stored, err = store.Get(ctx, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, stored.StatusCode)
+}
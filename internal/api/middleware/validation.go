@@ -4,11 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"reflect"
 
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New()
+var validate = newValidator()
+
+// newValidator creates the request validator. Money fields are validated by
+// their sign, so tags such as required (non-zero) and gte=0 keep working.
+// Rates are validated by their value, so bounds such as lte=1 work too.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if m, ok := field.Interface().(money.Money); ok {
+			return m.Sign()
+		}
+		return nil
+	}, money.Money{})
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if r, ok := field.Interface().(money.Rate); ok {
+			return r.Float64()
+		}
+		return nil
+	}, money.Rate{})
+	return v
+}
 
 // ValidateRequest validates the request body against the provided struct
 func ValidateRequest(next http.HandlerFunc, data interface{}) http.HandlerFunc {
@@ -31,6 +31,12 @@ func NewServer() *Server {
 	}
 }
 
+// Use appends middlewares to the server's router. It must be called before any
+// handlers are mounted.
+func (s *Server) Use(middlewares ...func(http.Handler) http.Handler) {
+	s.router.Use(middlewares...)
+}
+
 func (s *Server) MountHandlers(handlers ...func(chi.Router)) {
 	for _, h := range handlers {
 		h(s.router)
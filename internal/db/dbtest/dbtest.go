@@ -0,0 +1,36 @@
+// Package dbtest provides in-memory databases for tests
+package dbtest
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db"
+	"gorm.io/gorm"
+)
+
+// opened numbers the databases opened, so that tests whose names differ only
+// in characters replaced in the database name still get databases of their own
+var opened atomic.Int64
+
+// Open returns an in-memory SQLite database private to the test, with the
+// given models migrated, and closes it when the test finishes
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	name := fmt.Sprintf("%s_%d", strings.ReplaceAll(t.Name(), "/", "_"), opened.Add(1))
+	testDB, err := db.InitTestDB("file:" + name + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := testDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := testDB.AutoMigrate(models...); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return testDB
+}
@@ -0,0 +1,269 @@
+package cte
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrInvalidCondition is returned for conditions that cannot be evaluated
+	ErrInvalidCondition = errors.New("invalid transaction condition")
+	// ErrConditionNotMet is recorded on transactions skipped because of their conditions
+	ErrConditionNotMet = errors.New("transaction condition not met")
+)
+
+// ConditionOperator compares a field of a dependency with a Condition's value
+type ConditionOperator string
+
+const (
+	// ConditionEqual holds if the field equals the value
+	ConditionEqual ConditionOperator = "eq"
+	// ConditionNotEqual holds if the field differs from the value
+	ConditionNotEqual ConditionOperator = "ne"
+	// ConditionGreater holds if the field is a number greater than the value
+	ConditionGreater ConditionOperator = "gt"
+	// ConditionGreaterOrEqual holds if the field is a number at least the value
+	ConditionGreaterOrEqual ConditionOperator = "gte"
+	// ConditionLess holds if the field is a number less than the value
+	ConditionLess ConditionOperator = "lt"
+	// ConditionLessOrEqual holds if the field is a number at most the value
+	ConditionLessOrEqual ConditionOperator = "lte"
+)
+
+// Condition is checked against one of a transaction's dependencies once the
+// dependency has finished. A transaction whose conditions do not all hold is
+// SKIPPED instead of run.
+//
+// Without a condition naming its State, a dependency must have COMPLETED or
+// been SKIPPED for the transaction to run. A condition with State FAILED lets
+// the transaction run as a fallback for a failed dependency; that failure
+// then no longer fails the event.
+type Condition struct {
+	// DependsOn is the ID of the dependency checked; it must be listed in the
+	// transaction's Dependencies
+	DependsOn string `json:"depends_on"`
+	// State, if set, is the state the dependency must have ended in:
+	// COMPLETED, FAILED or SKIPPED
+	State string `json:"state,omitempty"`
+	// Field, if set, is a dotted path into the dependency's "result" or
+	// "payload", such as "payload.amount", compared with Value by Operator.
+	// Numbers, and strings holding numbers, are compared exactly.
+	Field    string            `json:"field,omitempty"`
+	Operator ConditionOperator `json:"operator,omitempty"`
+	Value    interface{}       `json:"value,omitempty"`
+}
+
+// String describes the condition for skip reasons and error reports
+func (c Condition) String() string {
+	var parts []string
+	if c.State != "" {
+		parts = append(parts, fmt.Sprintf("%s is %s", c.DependsOn, c.State))
+	}
+	if c.Field != "" {
+		parts = append(parts, fmt.Sprintf("%s %s %s %v", c.DependsOn, c.Field, c.Operator, c.Value))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// validate checks that the condition can be evaluated for tx
+func (c Condition) validate(tx *Transaction) error {
+	listed := false
+	for _, depID := range tx.Dependencies {
+		listed = listed || depID == c.DependsOn
+	}
+	if !listed {
+		return fmt.Errorf("%w: %s is not a dependency of %s", ErrInvalidCondition, c.DependsOn, tx.ID)
+	}
+
+	switch TransactionState(c.State) {
+	case "", TransactionStateCompleted, TransactionStateFailed, TransactionStateSkipped:
+	default:
+		return fmt.Errorf("%w: a dependency cannot end in state %s", ErrInvalidCondition, c.State)
+	}
+
+	if c.State == "" && c.Field == "" {
+		return fmt.Errorf("%w: condition on %s checks neither a state nor a field", ErrInvalidCondition, c.DependsOn)
+	}
+	if c.Field == "" {
+		return nil
+	}
+	if root, _, _ := strings.Cut(c.Field, "."); root != "result" && root != "payload" {
+		return fmt.Errorf("%w: field %s must start with result or payload", ErrInvalidCondition, c.Field)
+	}
+	switch c.Operator {
+	case ConditionEqual, ConditionNotEqual:
+	case ConditionGreater, ConditionGreaterOrEqual, ConditionLess, ConditionLessOrEqual:
+		if _, ok := toRat(normalize(c.Value)); !ok {
+			return fmt.Errorf("%w: %s needs a number, got %v", ErrInvalidCondition, c.Operator, c.Value)
+		}
+	default:
+		return fmt.Errorf("%w: unknown operator %q", ErrInvalidCondition, c.Operator)
+	}
+	return nil
+}
+
+// holds reports whether the condition holds for dep, a finished dependency
+func (c Condition) holds(dep *Transaction) (bool, error) {
+	if c.State != "" && dep.State != c.State {
+		return false, nil
+	}
+	if c.Field == "" {
+		return true, nil
+	}
+
+	root, path, _ := strings.Cut(c.Field, ".")
+	var value interface{}
+	switch root {
+	case "result":
+		value = normalize(dep.Result)
+	case "payload":
+		value = normalize(dep.Payload)
+	default:
+		return false, fmt.Errorf("%w: field %s must start with result or payload", ErrInvalidCondition, c.Field)
+	}
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			// A field missing from the dependency never matches
+			return false, nil
+		}
+		if value, ok = fields[key]; !ok {
+			return false, nil
+		}
+	}
+	return compare(value, c.Operator, normalize(c.Value))
+}
+
+// compare applies op to a and b, comparing them as numbers when both are numbers
+func compare(a interface{}, op ConditionOperator, b interface{}) (bool, error) {
+	x, aNum := toRat(a)
+	y, bNum := toRat(b)
+	if aNum && bNum {
+		cmp := x.Cmp(y)
+		switch op {
+		case ConditionEqual:
+			return cmp == 0, nil
+		case ConditionNotEqual:
+			return cmp != 0, nil
+		case ConditionGreater:
+			return cmp > 0, nil
+		case ConditionGreaterOrEqual:
+			return cmp >= 0, nil
+		case ConditionLess:
+			return cmp < 0, nil
+		case ConditionLessOrEqual:
+			return cmp <= 0, nil
+		}
+	}
+
+	switch op {
+	case ConditionEqual:
+		return reflect.DeepEqual(a, b), nil
+	case ConditionNotEqual:
+		return !reflect.DeepEqual(a, b), nil
+	case ConditionGreater, ConditionGreaterOrEqual, ConditionLess, ConditionLessOrEqual:
+		// Only numbers are ordered
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: unknown operator %q", ErrInvalidCondition, op)
+}
+
+// normalize turns a payload, result or value into the form it has after a
+// round trip through JSON, numbers kept exact as json.Number, so that values
+// read back from the event store compare like the ones first saved
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var normalized interface{}
+	if err := decoder.Decode(&normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+// toRat reads a normalized number, or a string holding one, exactly
+func toRat(v interface{}) (*big.Rat, bool) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// conditionsHold reports whether tx may run now that its dependencies have
+// finished, and if not, why it is skipped
+func (g *executionGraph) conditionsHold(tx *Transaction) (bool, string, error) {
+	stateChecked := make(map[string]bool, len(tx.Conditions))
+	for _, c := range tx.Conditions {
+		if c.State != "" {
+			stateChecked[c.DependsOn] = true
+		}
+	}
+	for _, depID := range tx.Dependencies {
+		dep := g.nodes[depID]
+		if stateChecked[depID] || dep.State == string(TransactionStateCompleted) || dep.State == string(TransactionStateSkipped) {
+			continue
+		}
+		return false, fmt.Sprintf("dependency %s ended %s", depID, dep.State), nil
+	}
+
+	for _, c := range tx.Conditions {
+		dep, ok := g.nodes[c.DependsOn]
+		if !ok {
+			return false, "", fmt.Errorf("%w: %s is not a dependency of %s", ErrInvalidCondition, c.DependsOn, tx.ID)
+		}
+		ok, err := c.holds(dep)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			return false, fmt.Sprintf("condition not met: %s", c), nil
+		}
+	}
+	return true, "", nil
+}
+
+// toleratesFailure reports whether a failure of tx is handled by a dependent
+// whose condition expects it to fail, so that it does not fail the event
+func (g *executionGraph) toleratesFailure(tx *Transaction) bool {
+	for _, id := range g.dependents[tx.ID] {
+		for _, c := range g.nodes[id].Conditions {
+			if c.DependsOn == tx.ID && c.State == string(TransactionStateFailed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// failureHandled reports whether tx, found FAILED when an event is resumed,
+// is a tolerated failure that its dependents already moved past, rather than
+// a transaction interrupted by a lost worker that must run again
+func (g *executionGraph) failureHandled(tx *Transaction) bool {
+	if tx.State != string(TransactionStateFailed) || !g.toleratesFailure(tx) {
+		return false
+	}
+	for _, id := range g.dependents[tx.ID] {
+		if state := g.nodes[id].State; state != "" && state != string(TransactionStatePending) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package cte
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTransactions creates an event with txs, whose dependencies and
+// conditions name other transactions by Name, then validates and starts it
+func startTransactions(t *testing.T, engine *Engine, txs ...*Transaction) *Event {
+	t.Helper()
+	ctx := context.Background()
+	event, err := engine.CreateEvent(ctx, "conditional", "", time.Minute, nil, "")
+	require.NoError(t, err)
+	for i, tx := range txs {
+		tx.ID, tx.EventID, tx.Type, tx.Order = event.ID+"-"+tx.Name, event.ID, "test", i
+		for j := range tx.Dependencies {
+			tx.Dependencies[j] = event.ID + "-" + tx.Dependencies[j]
+		}
+		for j := range tx.Conditions {
+			tx.Conditions[j].DependsOn = event.ID + "-" + tx.Conditions[j].DependsOn
+		}
+		require.NoError(t, engine.AddTransaction(ctx, event.ID, tx))
+	}
+	require.NoError(t, engine.ValidateEvent(ctx, event.ID))
+	require.NoError(t, engine.StartEvent(ctx, event.ID))
+	return event
+}
+
+// transactionStates maps the names of an event's transactions to their states
+func transactionStates(t *testing.T, engine *Engine, eventID string) map[string]string {
+	t.Helper()
+	txs, err := engine.GetEventTransactions(context.Background(), eventID)
+	require.NoError(t, err)
+	states := make(map[string]string)
+	for _, tx := range txs {
+		states[tx.Name] = tx.State
+	}
+	return states
+}
+
+func TestConditions_SkipOnResultsOfDependencies(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	executor := &recordingExecutor{}
+	engine.RegisterExecutor("test", executor)
+	startWorkers(t, engine, WorkerConfig{})
+
+	// The fee is only charged on transfers over 100; the receipt always goes out
+	transfer := func(amount string) *Event {
+		return startTransactions(t, engine,
+			&Transaction{Name: "transfer", Payload: map[string]interface{}{"amount": amount, "currency": "USD"}},
+			&Transaction{Name: "fee", Dependencies: []string{"transfer"}, Conditions: []Condition{
+				{DependsOn: "transfer", Field: "payload.amount", Operator: ConditionGreater, Value: 100},
+			}},
+			&Transaction{Name: "receipt", Dependencies: []string{"fee"}},
+		)
+	}
+
+	large := transfer("150.00")
+	waitForState(t, engine, large.ID, EventStateCompleted)
+	assert.Equal(t, map[string]string{"transfer": "COMPLETED", "fee": "COMPLETED", "receipt": "COMPLETED"}, transactionStates(t, engine, large.ID))
+
+	small := transfer("100.00")
+	waitForState(t, engine, small.ID, EventStateCompleted)
+	assert.Equal(t, map[string]string{"transfer": "COMPLETED", "fee": "SKIPPED", "receipt": "COMPLETED"}, transactionStates(t, engine, small.ID))
+	fee, err := store.GetTransaction(context.Background(), small.ID+"-fee")
+	require.NoError(t, err)
+	assert.ErrorIs(t, fee.Error, ErrConditionNotMet)
+	attempts, err := store.GetTransactionAttempts(context.Background(), fee.ID)
+	require.NoError(t, err)
+	assert.Empty(t, attempts, "skipped transactions are never attempted")
+}
+
+func TestConditions_FallbackForFailedDependency(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithRetryPolicy(fastRetries))
+	primaryDown := true
+	executor := &recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+		if tx.Name == "primary" && primaryDown {
+			return Permanent(assert.AnError)
+		}
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+	startWorkers(t, engine, WorkerConfig{})
+
+	payout := func() *Event {
+		return startTransactions(t, engine,
+			&Transaction{Name: "primary"},
+			&Transaction{Name: "fallback", Dependencies: []string{"primary"}, Conditions: []Condition{
+				{DependsOn: "primary", State: "FAILED"},
+			}},
+			&Transaction{Name: "confirm", Dependencies: []string{"primary"}},
+		)
+	}
+
+	event := payout()
+	waitForState(t, engine, event.ID, EventStateCompleted)
+	assert.Equal(t, map[string]string{"primary": "FAILED", "fallback": "COMPLETED", "confirm": "SKIPPED"}, transactionStates(t, engine, event.ID))
+
+	executor.mu.Lock()
+	primaryDown = false
+	executor.mu.Unlock()
+	event = payout()
+	waitForState(t, engine, event.ID, EventStateCompleted)
+	assert.Equal(t, map[string]string{"primary": "COMPLETED", "fallback": "SKIPPED", "confirm": "COMPLETED"}, transactionStates(t, engine, event.ID))
+}
+
+func TestConditions_SkippedTransactionsAreNotCompensated(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithMaxWorkers(1), WithRetryPolicy(fastRetries))
+	executor := &recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+		if tx.Name == "settle" {
+			return Permanent(assert.AnError)
+		}
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+	startWorkers(t, engine, WorkerConfig{})
+
+	event := startTransactions(t, engine,
+		&Transaction{Name: "transfer", Payload: map[string]interface{}{"amount": 10}},
+		&Transaction{Name: "fee", Dependencies: []string{"transfer"}, Conditions: []Condition{
+			{DependsOn: "transfer", Field: "payload.amount", Operator: ConditionGreaterOrEqual, Value: "100"},
+		}},
+		&Transaction{Name: "settle", Dependencies: []string{"fee"}},
+	)
+	waitForState(t, engine, event.ID, EventStateRolledBack)
+	assert.Equal(t, map[string]string{"transfer": "COMPENSATED", "fee": "SKIPPED", "settle": "FAILED"}, transactionStates(t, engine, event.ID))
+	assert.Equal(t, []string{"transfer"}, executor.compensated)
+}
+
+func TestConditions_Validation(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	engine.RegisterExecutor("test", &recordingExecutor{})
+
+	invalid := []Condition{
+		{DependsOn: "other", State: "FAILED"},
+		{DependsOn: "a", State: "COMPENSATED"},
+		{DependsOn: "a"},
+		{DependsOn: "a", Field: "amount", Operator: ConditionEqual, Value: 1},
+		{DependsOn: "a", Field: "payload.amount", Operator: "between", Value: 1},
+		{DependsOn: "a", Field: "payload.amount", Operator: ConditionLess, Value: "lots"},
+	}
+	for _, c := range invalid {
+		event := addTransactions(t, engine, map[string][]string{"b": {"a"}}, nil, "a", "b", "other")
+		tx, err := store.GetTransaction(context.Background(), event.ID+"-b")
+		require.NoError(t, err)
+		c.DependsOn = event.ID + "-" + c.DependsOn
+		tx.Conditions = []Condition{c}
+		require.NoError(t, store.UpdateTransaction(context.Background(), tx))
+
+		err = engine.ValidateEvent(context.Background(), event.ID)
+		assert.ErrorIs(t, err, ErrInvalidCondition, "%+v", c)
+	}
+}
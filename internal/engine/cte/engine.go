@@ -2,6 +2,9 @@ package cte
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -19,25 +22,72 @@ var (
 	ErrInvalidEventState = errors.New("invalid event state for operation")
 	// ErrTransactionDependencyNotMet is returned when a transaction's dependencies are not met
 	ErrTransactionDependencyNotMet = errors.New("transaction dependencies not met")
+	// ErrIdempotencyKeyConflict is returned when an idempotency key is reused with different event parameters
+	ErrIdempotencyKeyConflict = errors.New("idempotency key already used with different parameters")
+	// ErrEventTimedOut is returned when an event passes its deadline
+	ErrEventTimedOut = errors.New("event timed out")
+	// ErrCompensationIncomplete is returned when some of an event's completed transactions could not be compensated
+	ErrCompensationIncomplete = errors.New("event compensation incomplete")
 )
 
+// TimeoutHandler is told about each event that times out, after the timeout
+// is recorded and before its completed transactions are compensated, so
+// that whatever is held for the event, such as liens, can be let go. It must
+// be safe to call more than once for the same event.
+type TimeoutHandler func(ctx context.Context, eventID string) error
+
+// DefaultMaxWorkers is how many of an event's transactions run at once unless configured otherwise
+const DefaultMaxWorkers = 4
+
 // Engine implements the EventCoordinator interface
 type Engine struct {
 	eventStore  EventStore
 	txExecutors map[string]TransactionExecutor
-	maxRetries  int
-	retryDelay  time.Duration
+	retryPolicy RetryPolicy
+	maxWorkers  int
+	onTimeout   TimeoutHandler
 	mu          sync.RWMutex
 }
 
+// Option configures an Engine
+type Option func(*Engine)
+
+// WithMaxWorkers limits how many independent transactions of an event run at once
+func WithMaxWorkers(n int) Option {
+	return func(e *Engine) {
+		if n < 1 {
+			n = 1
+		}
+		e.maxWorkers = n
+	}
+}
+
+// WithRetryPolicy sets the RetryPolicy for executors that do not set their own
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(e *Engine) {
+		e.retryPolicy = p
+	}
+}
+
+// WithTimeoutHandler sets the handler told about events that time out
+func WithTimeoutHandler(h TimeoutHandler) Option {
+	return func(e *Engine) {
+		e.onTimeout = h
+	}
+}
+
 // NewEngine creates a new CTE engine
-func NewEngine(eventStore EventStore) *Engine {
-	return &Engine{
+func NewEngine(eventStore EventStore, opts ...Option) *Engine {
+	e := &Engine{
 		eventStore:  eventStore,
 		txExecutors: make(map[string]TransactionExecutor),
-		maxRetries:  3,
-		retryDelay:  100 * time.Millisecond,
+		retryPolicy: DefaultRetryPolicy,
+		maxWorkers:  DefaultMaxWorkers,
+	}
+	for _, opt := range opts {
+		opt(e)
 	}
+	return e
 }
 
 // RegisterExecutor registers a transaction executor for a specific transaction type
@@ -47,26 +97,82 @@ func (e *Engine) RegisterExecutor(txType string, executor TransactionExecutor) {
 	e.txExecutors[txType] = executor
 }
 
-// CreateEvent creates a new CTE event
-func (e *Engine) CreateEvent(ctx context.Context, name, description string, timeout time.Duration, metadata map[string]interface{}) (*Event, error) {
+// CreateEvent creates a new CTE event. When idempotencyKey is set and an event
+// was already created with it, that event is returned instead of a new one,
+// provided the parameters match; otherwise ErrIdempotencyKeyConflict is returned.
+func (e *Engine) CreateEvent(ctx context.Context, name, description string, timeout time.Duration, metadata map[string]interface{}, idempotencyKey string) (*Event, error) {
+	var requestHash string
+	if idempotencyKey != "" {
+		var err error
+		requestHash, err = hashEventRequest(name, description, timeout, metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash event parameters: %w", err)
+		}
+
+		existing, err := e.findIdempotentEvent(ctx, idempotencyKey, requestHash)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
+
 	event := &Event{
-		ID:          uuid.New().String(),
-		Name:        name,
-		Description: description,
-		State:       EventStateCreated,
-		Timeout:     timeout,
-		Metadata:    metadata,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:             uuid.New().String(),
+		Name:           name,
+		Description:    description,
+		State:          EventStateCreated,
+		Timeout:        timeout,
+		Metadata:       metadata,
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    requestHash,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
 	if err := e.eventStore.SaveEvent(ctx, event); err != nil {
+		if idempotencyKey != "" {
+			// A concurrent call with the same key may have created the event first
+			if existing, findErr := e.findIdempotentEvent(ctx, idempotencyKey, requestHash); findErr != nil || existing != nil {
+				return existing, findErr
+			}
+		}
 		return nil, fmt.Errorf("failed to save event: %w", err)
 	}
 
 	return event, nil
 }
 
+// findIdempotentEvent returns the event previously created with key, or nil if
+// there is none. The event must have been created with the same parameters.
+func (e *Engine) findIdempotentEvent(ctx context.Context, key, requestHash string) (*Event, error) {
+	existing, err := e.eventStore.GetEventByIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	if existing.RequestHash != requestHash {
+		return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyConflict, key)
+	}
+	return existing, nil
+}
+
+// hashEventRequest fingerprints the parameters of a CreateEvent call
+func hashEventRequest(name, description string, timeout time.Duration, metadata map[string]interface{}) (string, error) {
+	// encoding/json sorts map keys, so equal metadata always hashes the same
+	data, err := json.Marshal(struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Timeout     time.Duration          `json:"timeout"`
+		Metadata    map[string]interface{} `json:"metadata"`
+	}{name, description, timeout, metadata})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 // GetEvent retrieves an event by ID
 func (e *Engine) GetEvent(ctx context.Context, id string) (*Event, error) {
 	event, err := e.eventStore.GetEvent(ctx, id)
@@ -120,7 +226,10 @@ func (e *Engine) AddTransaction(ctx context.Context, eventID string, tx *Transac
 	return nil
 }
 
-// StartEvent begins the execution of an event
+// StartEvent queues an event for execution and returns without waiting for
+// it; a WorkerPool runs it. Events whose transaction dependencies form a
+// cycle or name unknown transactions are rejected, and events past their
+// deadline are failed instead.
 func (e *Engine) StartEvent(ctx context.Context, eventID string) error {
 	event, err := e.GetEvent(ctx, eventID)
 	if err != nil {
@@ -133,52 +242,75 @@ func (e *Engine) StartEvent(ctx context.Context, eventID string) error {
 			ErrInvalidEventState, event.State)
 	}
 
-	// Update event state to EXECUTING
+	if deadline, ok := event.Deadline(); ok && time.Now().After(deadline) {
+		return e.timeOutEvent(ctx, eventID)
+	}
+
+	transactions, err := e.eventStore.GetEventTransactions(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to get event transactions: %w", err)
+	}
+	if _, err := buildGraph(transactions); err != nil {
+		return fmt.Errorf("cannot start event %s: %w", eventID, err)
+	}
+
+	// Queue the event: a WorkerPool claims EXECUTING events and runs them
 	event.State = EventStateExecuting
 	event.UpdatedAt = time.Now()
 	if err := e.eventStore.UpdateEvent(ctx, event); err != nil {
 		return fmt.Errorf("failed to update event state: %w", err)
 	}
 
-	// Start executing transactions in a separate goroutine
-	go e.executeEvent(context.Background(), eventID)
-
 	return nil
 }
 
-// executeEvent executes all transactions in an event
+// executeEvent executes all transactions in an event before its deadline. If
+// ctx is cancelled, because the worker is stopping or lost its lease, it
+// stops starting transactions and leaves the event EXECUTING for the next
+// worker to resume.
 func (e *Engine) executeEvent(ctx context.Context, eventID string) error {
+	event, err := e.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	runCtx := ctx
+	if deadline, ok := event.Deadline(); ok {
+		if time.Now().After(deadline) {
+			return e.timeOutEvent(ctx, eventID)
+		}
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
 	// Get all transactions for the event
 	transactions, err := e.eventStore.GetEventTransactions(ctx, eventID)
 	if err != nil {
 		return fmt.Errorf("failed to get event transactions: %w", err)
 	}
 
-	// Execute transactions in order, respecting dependencies
-	for _, tx := range transactions {
-		// Check if all dependencies are met
-		if err := e.checkDependencies(ctx, tx); err != nil {
-			if errors.Is(err, ErrTransactionDependencyNotMet) {
-				// Skip this transaction for now, it will be retried later
-				continue
-			}
-			// For other errors, mark the event as failed
-			return e.failEvent(ctx, eventID, fmt.Errorf("failed to check dependencies: %w", err))
-		}
+	graph, err := buildGraph(transactions)
+	if err != nil {
+		return e.failEvent(ctx, eventID, fmt.Errorf("failed to build dependency graph: %w", err))
+	}
 
-		// Execute the transaction with retries
-		if err := e.executeTransactionWithRetry(ctx, tx); err != nil {
-			// If execution fails, trigger compensation
-			if compErr := e.compensateEvent(ctx, eventID); compErr != nil {
-				return fmt.Errorf("failed to compensate event after transaction failure: %v (original error: %w)",
-					compErr, err)
-			}
-			return err
+	if err := e.runGraph(runCtx, graph); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("abandoned event %s: %w", eventID, context.Cause(ctx))
+		}
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return e.timeOutEvent(ctx, eventID)
 		}
+		// If execution fails, trigger compensation
+		if compErr := e.compensateEvent(ctx, eventID); compErr != nil {
+			return fmt.Errorf("failed to compensate event after transaction failure: %w (original error: %w)",
+				compErr, err)
+		}
+		return err
 	}
 
 	// If we get here, all transactions completed successfully
-	event, err := e.GetEvent(ctx, eventID)
+	event, err = e.GetEvent(ctx, eventID)
 	if err != nil {
 		return fmt.Errorf("failed to get event for completion: %w", err)
 	}
@@ -192,81 +324,205 @@ func (e *Engine) executeEvent(ctx context.Context, eventID string) error {
 	return nil
 }
 
-// executeTransactionWithRetry executes a transaction with retries
-func (e *Engine) executeTransactionWithRetry(ctx context.Context, tx *Transaction) error {
-	var lastErr error
+// nodeResult is the outcome of running one transaction of a graph
+type nodeResult struct {
+	tx  *Transaction
+	err error
+}
 
-	for attempt := 0; attempt < e.maxRetries; attempt++ {
-		if attempt > 0 {
-			time.Sleep(e.retryDelay)
+// runGraph executes the transactions of a graph, each once its dependencies
+// have finished, running up to maxWorkers at a time. Transactions whose
+// conditions do not hold are skipped. After the first failure not tolerated
+// by a condition, or once ctx is cancelled, no further transactions are
+// started; runGraph waits for the running ones and returns the failure.
+// Transactions already finished, by this run or an earlier one, are not run
+// again.
+func (e *Engine) runGraph(ctx context.Context, g *executionGraph) error {
+	pending := make(map[string]int, len(g.indegree))
+	for id, n := range g.indegree {
+		pending[id] = n
+	}
+	ready := g.roots()
+	results := make(chan nodeResult)
+	running := 0
+	var firstErr error
+
+	// complete makes ready the dependents waiting only on tx
+	complete := func(tx *Transaction) {
+		for _, id := range g.dependents[tx.ID] {
+			pending[id]--
+			if pending[id] == 0 {
+				ready = append(ready, g.nodes[id])
+			}
 		}
+	}
 
-		tx.State = "EXECUTING"
-		tx.UpdatedAt = time.Now()
-		if err := e.eventStore.UpdateTransaction(ctx, tx); err != nil {
-			lastErr = fmt.Errorf("failed to update transaction state: %w", err)
-			continue
+	for {
+		if firstErr == nil && ctx.Err() != nil {
+			firstErr = context.Cause(ctx)
 		}
+		for firstErr == nil && len(ready) > 0 && running < e.maxWorkers {
+			sortByOrder(ready)
+			tx := ready[0]
+			ready = ready[1:]
+			if tx.State == string(TransactionStateCompleted) || tx.State == string(TransactionStateSkipped) || g.failureHandled(tx) {
+				complete(tx)
+				continue
+			}
 
-		// Get the executor for this transaction type
-		e.mu.RLock()
-		executor, ok := e.txExecutors[tx.Type]
-		e.mu.RUnlock()
+			run, reason, err := g.conditionsHold(tx)
+			if err == nil && !run {
+				err = e.skipTransaction(ctx, tx, reason)
+			}
+			if err != nil {
+				firstErr = fmt.Errorf("transaction %s failed: %w", tx.ID, err)
+				continue
+			}
+			if !run {
+				complete(tx)
+				continue
+			}
 
-		if !ok {
-			lastErr = fmt.Errorf("no executor registered for transaction type: %s", tx.Type)
-			continue
+			running++
+			go func(tx *Transaction) {
+				results <- nodeResult{tx: tx, err: e.executeTransactionWithRetry(ctx, tx)}
+			}(tx)
+		}
+		if running == 0 {
+			return firstErr
 		}
 
-		// Execute the transaction
-		if err := executor.Execute(ctx, tx); err != nil {
-			lastErr = err
-			tx.State = "FAILED"
-			tx.Error = err
-			tx.UpdatedAt = time.Now()
-			if updateErr := e.eventStore.UpdateTransaction(ctx, tx); updateErr != nil {
-				// Log the error but continue with the original error
-				lastErr = fmt.Errorf("failed to update failed transaction: %v (original error: %w)",
-					updateErr, lastErr)
+		result := <-results
+		running--
+		if result.err != nil {
+			if ctx.Err() == nil && g.toleratesFailure(result.tx) {
+				// A dependent runs as the fallback for this failure
+				complete(result.tx)
+				continue
+			}
+			if firstErr == nil {
+				firstErr = fmt.Errorf("transaction %s failed: %w", result.tx.ID, result.err)
 			}
 			continue
 		}
-
-		// If we get here, the transaction was successful
-		tx.State = "COMPLETED"
-		tx.UpdatedAt = time.Now()
-		if err := e.eventStore.UpdateTransaction(ctx, tx); err != nil {
-			return fmt.Errorf("failed to update completed transaction: %w", err)
-		}
-
-		return nil
+		complete(result.tx)
 	}
+}
 
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+// skipTransaction records that a transaction's conditions did not hold
+func (e *Engine) skipTransaction(ctx context.Context, tx *Transaction, reason string) error {
+	tx.State = string(TransactionStateSkipped)
+	tx.Error = fmt.Errorf("%w: %s", ErrConditionNotMet, reason)
+	tx.UpdatedAt = time.Now()
+	if err := e.eventStore.UpdateTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("failed to mark transaction skipped: %w", err)
+	}
+	return nil
 }
 
-// checkDependencies verifies that all of a transaction's dependencies are met
-func (e *Engine) checkDependencies(ctx context.Context, tx *Transaction) error {
-	if len(tx.Dependencies) == 0 {
-		return nil // No dependencies to check
+// executeTransactionWithRetry executes a transaction, retrying failed
+// attempts as the RetryPolicy of its executor allows. Every attempt is
+// recorded in the event store, numbered after those of earlier runs, such as
+// before the event was resumed. Before a transaction is run again its
+// executor, if it is a TransactionResolver, is asked whether a failed or
+// interrupted attempt took effect after all; if one did, the transaction is
+// completed without another attempt.
+func (e *Engine) executeTransactionWithRetry(ctx context.Context, tx *Transaction) error {
+	// Get the executor for this transaction type
+	e.mu.RLock()
+	executor, ok := e.txExecutors[tx.Type]
+	e.mu.RUnlock()
+
+	policy := e.retryPolicy
+	if provider, ok := executor.(RetryPolicyProvider); ok {
+		policy = provider.RetryPolicy()
 	}
 
-	for _, depID := range tx.Dependencies {
-		depTx, err := e.eventStore.GetTransaction(ctx, depID)
+	prior, err := e.eventStore.GetTransactionAttempts(ctx, tx.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction attempts: %w", err)
+	}
+
+	firstStarted := time.Now()
+	for attempt := 1; ; attempt++ {
+		rerun := attempt > 1 || len(prior) > 0 ||
+			tx.State == string(TransactionStateExecuting) || tx.State == string(TransactionStateFailed)
+		if rerun {
+			applied, err := e.resolveTransaction(ctx, tx)
+			if err != nil {
+				return fmt.Errorf("failed to resolve transaction before running it again: %w", err)
+			}
+			if applied {
+				return e.completeTransaction(ctx, tx)
+			}
+		}
+
+		started := time.Now()
+		tx.State = string(TransactionStateExecuting)
+		tx.UpdatedAt = started
+		if err := e.eventStore.UpdateTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("failed to update transaction state: %w", err)
+		}
+
+		var err error
+		if ok {
+			err = executor.Execute(ctx, tx)
+		} else {
+			err = fmt.Errorf("%w: %s", ErrNoExecutor, tx.Type)
+		}
+
+		record := &TransactionAttempt{
+			TransactionID: tx.ID,
+			EventID:       tx.EventID,
+			Attempt:       len(prior) + attempt,
+			StartedAt:     started,
+			FinishedAt:    time.Now(),
+		}
 		if err != nil {
-			return fmt.Errorf("failed to get dependency transaction %s: %w", depID, err)
+			record.Error = err.Error()
+		}
+		if recordErr := e.eventStore.RecordAttempt(ctx, record); recordErr != nil {
+			return fmt.Errorf("failed to record attempt %d: %v (attempt error: %w)", record.Attempt, recordErr, err)
 		}
 
-		if depTx == nil {
-			return fmt.Errorf("dependency transaction %s not found", depID)
+		if err == nil {
+			return e.completeTransaction(ctx, tx)
 		}
 
-		if depTx.State != "COMPLETED" {
-			return fmt.Errorf("%w: dependency %s is in state %s",
-				ErrTransactionDependencyNotMet, depID, depTx.State)
+		tx.State = string(TransactionStateFailed)
+		tx.Error = err
+		tx.UpdatedAt = time.Now()
+		if updateErr := e.eventStore.UpdateTransaction(ctx, tx); updateErr != nil {
+			return fmt.Errorf("failed to update failed transaction: %v (original error: %w)", updateErr, err)
+		}
+
+		delay := policy.Backoff(attempt)
+		if !policy.shouldRetry(attempt, err, started.Sub(firstStarted), delay) {
+			if IsPermanent(err) {
+				return err
+			}
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("stopped retrying after %d attempts: %w", attempt, err)
+		case <-timer.C:
 		}
 	}
+}
 
+// completeTransaction marks a transaction completed, clearing the error of
+// any earlier attempt
+func (e *Engine) completeTransaction(ctx context.Context, tx *Transaction) error {
+	tx.State = string(TransactionStateCompleted)
+	tx.Error = nil
+	tx.UpdatedAt = time.Now()
+	if err := e.eventStore.UpdateTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("failed to update completed transaction: %w", err)
+	}
 	return nil
 }
 
@@ -286,7 +542,14 @@ func (e *Engine) failEvent(ctx context.Context, eventID string, err error) error
 	return err
 }
 
-// compensateEvent compensates for all completed transactions in an event
+// compensateEvent compensates for all completed transactions in an event,
+// and for the failed or interrupted ones a TransactionResolver shows took
+// effect, leaving it ROLLED_BACK, or FAILED if the engine failed it with a
+// reason. If
+// ctx is cancelled it stops, leaving the event ROLLING_BACK for the next
+// worker to finish. Transactions that fail to compensate do not stop the
+// others; the event is left ROLLING_BACK and ErrCompensationIncomplete is
+// returned so that it is compensated again.
 func (e *Engine) compensateEvent(ctx context.Context, eventID string) error {
 	// Mark the event as rolling back
 	event, err := e.GetEvent(ctx, eventID)
@@ -306,12 +569,32 @@ func (e *Engine) compensateEvent(ctx context.Context, eventID string) error {
 		return fmt.Errorf("failed to get event transactions for compensation: %w", err)
 	}
 
-	// Execute compensation in reverse order
-	for i := len(transactions) - 1; i >= 0; i-- {
-		tx := transactions[i]
+	// Compensate dependents before their dependencies. Events whose graph is
+	// invalid never ran, so there is nothing to order.
+	order := transactions
+	if graph, err := buildGraph(transactions); err == nil {
+		order = graph.reverseOrder()
+	}
+	var failures []error
+	for _, tx := range order {
+		if ctx.Err() != nil {
+			return fmt.Errorf("abandoned compensation of event %s: %w", eventID, context.Cause(ctx))
+		}
 
-		// Only compensate completed transactions
-		if tx.State != "COMPLETED" {
+		// Only compensate completed transactions, and failed or interrupted
+		// ones that took effect before their outcome was recorded
+		switch tx.State {
+		case "COMPLETED":
+		case "FAILED", "EXECUTING":
+			applied, err := e.resolveTransaction(ctx, tx)
+			if err != nil {
+				failures = append(failures, fmt.Errorf("failed to resolve transaction %s: %w", tx.ID, err))
+				continue
+			}
+			if !applied {
+				continue
+			}
+		default:
 			continue
 		}
 
@@ -321,13 +604,13 @@ func (e *Engine) compensateEvent(ctx context.Context, eventID string) error {
 		e.mu.RUnlock()
 
 		if !ok {
-			// Log the error but continue with other transactions
+			failures = append(failures, fmt.Errorf("transaction %s: %w: %s", tx.ID, ErrNoExecutor, tx.Type))
 			continue
 		}
 
 		// Execute the compensation
 		if err := executor.Compensate(ctx, tx); err != nil {
-			// Log the error but continue with other transactions
+			failures = append(failures, fmt.Errorf("failed to compensate transaction %s: %w", tx.ID, err))
 			continue
 		}
 
@@ -335,21 +618,83 @@ func (e *Engine) compensateEvent(ctx context.Context, eventID string) error {
 		tx.State = "COMPENSATED"
 		tx.UpdatedAt = time.Now()
 		if err := e.eventStore.UpdateTransaction(ctx, tx); err != nil {
-			// Log the error but continue with other transactions
+			failures = append(failures, fmt.Errorf("failed to mark transaction %s compensated: %w", tx.ID, err))
 			continue
 		}
 	}
 
+	if len(failures) > 0 {
+		return fmt.Errorf("%w: event %s: %w", ErrCompensationIncomplete, eventID, errors.Join(failures...))
+	}
+
 	// Mark the event as rolled back
 	event.State = EventStateRolledBack
+	if event.FailureReason != "" {
+		event.State = EventStateFailed
+	}
 	event.UpdatedAt = time.Now()
 	if err := e.eventStore.UpdateEvent(ctx, event); err != nil {
-		return fmt.Errorf("failed to mark event as rolled back: %w", err)
+		return fmt.Errorf("failed to mark event as %s: %w", event.State, err)
 	}
 
 	return nil
 }
 
+// timeOutEvent fails an event that passed its deadline: it records the
+// timeout as the failure reason, tells the timeout handler, and compensates
+// the completed transactions, leaving the event FAILED. Events that were
+// never started have nothing to compensate and are failed right away.
+func (e *Engine) timeOutEvent(ctx context.Context, eventID string) error {
+	event, err := e.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	deadline, _ := event.Deadline()
+	timeoutErr := fmt.Errorf("%w: %s passed its deadline of %s", ErrEventTimedOut, eventID, deadline.Format(time.RFC3339))
+
+	started := event.State == EventStateExecuting || event.State == EventStateRollingBack
+	event.FailureReason = timeoutErr.Error()
+	if !started {
+		event.State = EventStateFailed
+	}
+	event.UpdatedAt = time.Now()
+	if err := e.eventStore.UpdateEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to record timeout: %v (original error: %w)", err, timeoutErr)
+	}
+
+	if e.onTimeout != nil {
+		if err := e.onTimeout(ctx, eventID); err != nil {
+			return fmt.Errorf("timeout handler failed: %v (original error: %w)", err, timeoutErr)
+		}
+	}
+
+	if started {
+		if err := e.compensateEvent(ctx, eventID); err != nil {
+			return fmt.Errorf("failed to compensate event after timeout: %w (original error: %w)", err, timeoutErr)
+		}
+	}
+	return timeoutErr
+}
+
+// ExpireEvents fails the events that passed their deadline before they were
+// started, returning them as they were before. Started events are timed out
+// by the worker running them.
+func (e *Engine) ExpireEvents(ctx context.Context) ([]*Event, error) {
+	events, err := e.eventStore.ListExpiredEvents(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired events: %w", err)
+	}
+
+	var expired []*Event
+	for _, event := range events {
+		if err := e.timeOutEvent(ctx, event.ID); err != nil && !errors.Is(err, ErrEventTimedOut) {
+			return expired, err
+		}
+		expired = append(expired, event)
+	}
+	return expired, nil
+}
+
 // GetEventTransactions retrieves all transactions for an event
 func (e *Engine) GetEventTransactions(ctx context.Context, eventID string) ([]*Transaction, error) {
 	transactions, err := e.eventStore.GetEventTransactions(ctx, eventID)
@@ -360,6 +705,16 @@ func (e *Engine) GetEventTransactions(ctx context.Context, eventID string) ([]*T
 	return transactions, nil
 }
 
+// GetTransactionAttempts retrieves the attempts at executing a transaction, first to last
+func (e *Engine) GetTransactionAttempts(ctx context.Context, transactionID string) ([]*TransactionAttempt, error) {
+	attempts, err := e.eventStore.GetTransactionAttempts(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
 // GetEventState retrieves the current state of an event
 func (e *Engine) GetEventState(ctx context.Context, eventID string) (EventState, error) {
 	event, err := e.GetEvent(ctx, eventID)
@@ -370,7 +725,23 @@ func (e *Engine) GetEventState(ctx context.Context, eventID string) (EventState,
 	return event.State, nil
 }
 
-// CompensateEvent triggers compensation for a failed event
+// CompensateEvent queues compensation of an event's completed transactions
+// and returns without waiting for it; a WorkerPool carries it out. Only
+// FAILED events, and ROLLING_BACK ones already queued, can be compensated.
+// Events still executing are compensated by their worker if they fail.
 func (e *Engine) CompensateEvent(ctx context.Context, eventID string) error {
-	return e.compensateEvent(ctx, eventID)
+	event, err := e.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if event.State != EventStateFailed && event.State != EventStateRollingBack {
+		return fmt.Errorf("%w: cannot compensate event in state %s", ErrInvalidEventState, event.State)
+	}
+
+	event.State = EventStateRollingBack
+	event.UpdatedAt = time.Now()
+	if err := e.eventStore.UpdateEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to update event state to rolling back: %w", err)
+	}
+	return nil
 }
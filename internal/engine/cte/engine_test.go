@@ -0,0 +1,824 @@
+package cte
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryEventStore is an in-memory EventStore for engine tests
+type memoryEventStore struct {
+	mu       sync.Mutex
+	events   map[string]Event
+	txs      map[string]Transaction
+	attempts map[string][]TransactionAttempt
+}
+
+func newMemoryEventStore() *memoryEventStore {
+	return &memoryEventStore{
+		events:   make(map[string]Event),
+		txs:      make(map[string]Transaction),
+		attempts: make(map[string][]TransactionAttempt),
+	}
+}
+
+func (s *memoryEventStore) SaveEvent(ctx context.Context, event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[event.ID] = *event
+	return nil
+}
+
+func (s *memoryEventStore) GetEvent(ctx context.Context, id string) (*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event, ok := s.events[id]
+	if !ok {
+		return nil, nil
+	}
+	return &event, nil
+}
+
+func (s *memoryEventStore) GetEventByIdempotencyKey(ctx context.Context, key string) (*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range s.events {
+		if event.IdempotencyKey == key {
+			return &event, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryEventStore) UpdateEvent(ctx context.Context, event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updated := *event
+	existing := s.events[event.ID]
+	updated.LeaseOwner, updated.LeaseExpiresAt = existing.LeaseOwner, existing.LeaseExpiresAt
+	s.events[event.ID] = updated
+	return nil
+}
+
+func (s *memoryEventStore) ClaimEvent(ctx context.Context, owner string, lease time.Duration) (*Event, error) {
+	return s.claim(owner, lease, func(event Event, now time.Time) bool { return event.LeaseExpiresAt == nil })
+}
+
+func (s *memoryEventStore) ClaimOrphanedEvent(ctx context.Context, owner string, lease time.Duration) (*Event, error) {
+	return s.claim(owner, lease, func(event Event, now time.Time) bool {
+		return event.LeaseExpiresAt != nil && event.LeaseExpiresAt.Before(now)
+	})
+}
+
+func (s *memoryEventStore) claim(owner string, lease time.Duration, claimable func(event Event, now time.Time) bool) (*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, event := range s.events {
+		if event.State != EventStateExecuting && event.State != EventStateRollingBack {
+			continue
+		}
+		if !claimable(event, now) {
+			continue
+		}
+		expiresAt := now.Add(lease)
+		event.LeaseOwner, event.LeaseExpiresAt = owner, &expiresAt
+		s.events[id] = event
+		return &event, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryEventStore) RenewLease(ctx context.Context, eventID, owner string, lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event, ok := s.events[eventID]
+	if !ok || event.LeaseOwner != owner {
+		return ErrLeaseLost
+	}
+	expiresAt := time.Now().Add(lease)
+	event.LeaseExpiresAt = &expiresAt
+	s.events[eventID] = event
+	return nil
+}
+
+func (s *memoryEventStore) ReleaseLease(ctx context.Context, eventID, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event, ok := s.events[eventID]
+	if ok && event.LeaseOwner == owner {
+		event.LeaseOwner, event.LeaseExpiresAt = "", nil
+		s.events[eventID] = event
+	}
+	return nil
+}
+
+func (s *memoryEventStore) ListExpiredEvents(ctx context.Context, now time.Time) ([]*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []*Event
+	for _, event := range s.events {
+		if event.State != EventStateCreated && event.State != EventStateValidating && event.State != EventStateValidated {
+			continue
+		}
+		if deadline, ok := event.Deadline(); ok && deadline.Before(now) {
+			event := event
+			expired = append(expired, &event)
+		}
+	}
+	return expired, nil
+}
+
+func (s *memoryEventStore) SaveTransaction(ctx context.Context, tx *Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txs[tx.ID] = *tx
+	return nil
+}
+
+func (s *memoryEventStore) GetTransaction(ctx context.Context, id string) (*Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, ok := s.txs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &tx, nil
+}
+
+func (s *memoryEventStore) UpdateTransaction(ctx context.Context, tx *Transaction) error {
+	return s.SaveTransaction(ctx, tx)
+}
+
+func (s *memoryEventStore) GetEventTransactions(ctx context.Context, eventID string) ([]*Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var txs []*Transaction
+	for _, tx := range s.txs {
+		if tx.EventID == eventID {
+			tx := tx
+			txs = append(txs, &tx)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Order < txs[j].Order })
+	return txs, nil
+}
+
+func (s *memoryEventStore) RecordAttempt(ctx context.Context, attempt *TransactionAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[attempt.TransactionID] = append(s.attempts[attempt.TransactionID], *attempt)
+	return nil
+}
+
+func (s *memoryEventStore) GetTransactionAttempts(ctx context.Context, transactionID string) ([]*TransactionAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var attempts []*TransactionAttempt
+	for _, attempt := range s.attempts[transactionID] {
+		attempt := attempt
+		attempts = append(attempts, &attempt)
+	}
+	return attempts, nil
+}
+
+func TestCreateEvent_IdempotencyKey(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	ctx := context.Background()
+	metadata := map[string]interface{}{"user_id": "u-1", "amount": "10.00"}
+
+	first, err := engine.CreateEvent(ctx, "wallet-transfer", "Transfer", time.Minute, metadata, "key-1")
+	require.NoError(t, err)
+
+	retry, err := engine.CreateEvent(ctx, "wallet-transfer", "Transfer", time.Minute, metadata, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retry.ID, "a retry must return the original event")
+	assert.Len(t, store.events, 1)
+
+	_, err = engine.CreateEvent(ctx, "wallet-transfer", "Transfer", time.Minute, map[string]interface{}{"amount": "20.00"}, "key-1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+
+	// Events without a key are never deduplicated
+	a, err := engine.CreateEvent(ctx, "wallet-transfer", "Transfer", time.Minute, metadata, "")
+	require.NoError(t, err)
+	b, err := engine.CreateEvent(ctx, "wallet-transfer", "Transfer", time.Minute, metadata, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, a.ID, b.ID)
+}
+
+// recordingExecutor runs transactions and compensations through
+// per-transaction functions and records the order of the successful ones
+type recordingExecutor struct {
+	mu          sync.Mutex
+	run         func(ctx context.Context, tx *Transaction) error
+	compensate  func(ctx context.Context, tx *Transaction) error
+	running     int
+	maxRunning  int
+	executed    []string
+	compensated []string
+}
+
+func (x *recordingExecutor) Execute(ctx context.Context, tx *Transaction) error {
+	x.mu.Lock()
+	x.running++
+	if x.running > x.maxRunning {
+		x.maxRunning = x.running
+	}
+	x.mu.Unlock()
+
+	var err error
+	if x.run != nil {
+		err = x.run(ctx, tx)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.running--
+	if err == nil {
+		x.executed = append(x.executed, tx.Name)
+	}
+	return err
+}
+
+func (x *recordingExecutor) Compensate(ctx context.Context, tx *Transaction) error {
+	if x.compensate != nil {
+		if err := x.compensate(ctx, tx); err != nil {
+			return err
+		}
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.compensated = append(x.compensated, tx.Name)
+	return nil
+}
+
+// startGraph creates a validated event with one transaction per name, each
+// depending on the transactions named in deps, and starts it
+func startGraph(t *testing.T, engine *Engine, store *memoryEventStore, deps map[string][]string, names ...string) (*Event, error) {
+	t.Helper()
+	ctx := context.Background()
+	event, err := engine.CreateEvent(ctx, "graph", "", time.Minute, nil, "")
+	require.NoError(t, err)
+	for i, name := range names {
+		tx := &Transaction{ID: event.ID + "-" + name, EventID: event.ID, Name: name, Type: "test", Order: i}
+		for _, dep := range deps[name] {
+			tx.Dependencies = append(tx.Dependencies, event.ID+"-"+dep)
+		}
+		require.NoError(t, engine.AddTransaction(ctx, event.ID, tx))
+	}
+	event, err = engine.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	event.State = EventStateValidated
+	require.NoError(t, store.UpdateEvent(ctx, event))
+	return event, engine.StartEvent(ctx, event.ID)
+}
+
+// startWorkers runs a worker pool on engine for the rest of the test
+func startWorkers(t *testing.T, engine *Engine, config WorkerConfig) *WorkerPool {
+	t.Helper()
+	if config.PollInterval == 0 {
+		config.PollInterval = time.Millisecond
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := NewWorkerPool(engine, config)
+	pool.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		pool.Wait()
+	})
+	return pool
+}
+
+// waitForState waits for an event to reach a final state
+func waitForState(t *testing.T, engine *Engine, eventID string, want EventState) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		state, err := engine.GetEventState(context.Background(), eventID)
+		return err == nil && state == want
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+// fastRetries retries failed transactions without slowing tests down
+var fastRetries = RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond, Multiplier: 1}
+
+func TestStartEvent_RunsIndependentBranchesConcurrently(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	// b and c each wait for the other to start, so they must run at once
+	started := map[string]chan struct{}{"b": make(chan struct{}), "c": make(chan struct{})}
+	executor := &recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+		if ch, ok := started[tx.Name]; ok {
+			close(ch)
+			other := map[string]string{"b": "c", "c": "b"}[tx.Name]
+			select {
+			case <-started[other]:
+			case <-time.After(2 * time.Second):
+				return assert.AnError
+			}
+		}
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+	startWorkers(t, engine, WorkerConfig{})
+
+	// a -> (b, c) -> d
+	event, err := startGraph(t, engine, store, map[string][]string{"b": {"a"}, "c": {"a"}, "d": {"b", "c"}}, "d", "c", "b", "a")
+	require.NoError(t, err)
+	waitForState(t, engine, event.ID, EventStateCompleted)
+
+	assert.Equal(t, 2, executor.maxRunning)
+	require.Len(t, executor.executed, 4)
+	assert.Equal(t, "a", executor.executed[0])
+	assert.Equal(t, "d", executor.executed[3])
+}
+
+func TestStartEvent_WorkerLimit(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithMaxWorkers(2))
+	executor := &recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+	startWorkers(t, engine, WorkerConfig{})
+
+	event, err := startGraph(t, engine, store, nil, "a", "b", "c", "d", "e")
+	require.NoError(t, err)
+	waitForState(t, engine, event.ID, EventStateCompleted)
+
+	assert.Len(t, executor.executed, 5)
+	assert.LessOrEqual(t, executor.maxRunning, 2)
+}
+
+func TestStartEvent_RejectsInvalidGraphs(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	engine.RegisterExecutor("test", &recordingExecutor{})
+
+	event, err := startGraph(t, engine, store, map[string][]string{"a": {"c"}, "b": {"a"}, "c": {"b"}}, "a", "b", "c")
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+	state, err := engine.GetEventState(context.Background(), event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStateValidated, state, "a rejected event is not started")
+
+	_, err = startGraph(t, engine, store, map[string][]string{"a": {"a"}}, "a")
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+
+	_, err = startGraph(t, engine, store, map[string][]string{"a": {"missing"}}, "a")
+	assert.ErrorIs(t, err, ErrUnknownDependency)
+}
+
+func TestStartEvent_CompensatesCompletedInReverseTopologicalOrder(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithMaxWorkers(1), WithRetryPolicy(fastRetries))
+	executor := &recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+		if tx.Name == "fail" {
+			return assert.AnError
+		}
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+	startWorkers(t, engine, WorkerConfig{})
+
+	// a -> b -> fail -> never, with c independent. With one worker, c runs
+	// before fail by Order and never is not started.
+	event, err := startGraph(t, engine, store,
+		map[string][]string{"b": {"a"}, "fail": {"b"}, "never": {"fail"}},
+		"c", "b", "a", "fail", "never")
+	require.NoError(t, err)
+	waitForState(t, engine, event.ID, EventStateRolledBack)
+
+	assert.Equal(t, []string{"c", "a", "b"}, executor.executed)
+	assert.Equal(t, []string{"b", "a", "c"}, executor.compensated)
+
+	txs, err := engine.GetEventTransactions(context.Background(), event.ID)
+	require.NoError(t, err)
+	states := make(map[string]string)
+	for _, tx := range txs {
+		states[tx.Name] = tx.State
+	}
+	assert.Equal(t, map[string]string{
+		"a": "COMPENSATED", "b": "COMPENSATED", "c": "COMPENSATED", "fail": "FAILED", "never": "",
+	}, states)
+}
+
+func TestStartEvent_QueuesForWorkers(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	executor := &recordingExecutor{}
+	engine.RegisterExecutor("test", executor)
+
+	event, err := startGraph(t, engine, store, nil, "a")
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	state, err := engine.GetEventState(context.Background(), event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStateExecuting, state)
+	assert.Empty(t, executor.executed, "nothing runs without workers")
+
+	startWorkers(t, engine, WorkerConfig{})
+	waitForState(t, engine, event.ID, EventStateCompleted)
+	stored, err := engine.GetEvent(context.Background(), event.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.LeaseOwner, "the lease is released")
+}
+
+func TestStartEvent_RetriesIncompleteCompensation(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithRetryPolicy(fastRetries))
+	var mu sync.Mutex
+	compensatedA := 0
+	executor := &recordingExecutor{
+		run: func(ctx context.Context, tx *Transaction) error {
+			if tx.Name == "fail" {
+				return Permanent(assert.AnError)
+			}
+			return nil
+		},
+		compensate: func(ctx context.Context, tx *Transaction) error {
+			if tx.Name != "a" {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			compensatedA++
+			if compensatedA == 1 {
+				return assert.AnError
+			}
+			return nil
+		},
+	}
+	engine.RegisterExecutor("test", executor)
+	startWorkers(t, engine, WorkerConfig{Lease: 20 * time.Millisecond})
+
+	event, err := startGraph(t, engine, store, map[string][]string{"b": {"a"}, "fail": {"b"}}, "a", "b", "fail")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return compensatedA == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	stored, err := engine.GetEvent(context.Background(), event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStateRollingBack, stored.State, "a partly compensated event is not rolled back")
+	assert.NotEmpty(t, stored.LeaseOwner, "the lease is kept so that the event is not retried right away")
+	a, err := store.GetTransaction(context.Background(), event.ID+"-a")
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLETED", a.State)
+
+	// Once the lease lapses, recovery queues the event again
+	scanner := NewRecoveryScanner(engine, RecoveryConfig{})
+	require.Eventually(t, func() bool {
+		recovered, err := scanner.Scan(context.Background())
+		return err == nil && len(recovered) == 1
+	}, time.Second, 5*time.Millisecond)
+	waitForState(t, engine, event.ID, EventStateRolledBack)
+	assert.Equal(t, []string{"b", "a"}, executor.compensated)
+}
+
+func TestCompensateEvent_RejectsStatesItCannotCompensate(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	ctx := context.Background()
+
+	for _, state := range []EventState{
+		EventStateCreated, EventStateValidating, EventStateValidated,
+		EventStateExecuting, EventStateCompleted, EventStateRolledBack,
+	} {
+		event, err := engine.CreateEvent(ctx, "compensate", "", time.Minute, nil, "")
+		require.NoError(t, err)
+		event.State = state
+		require.NoError(t, store.UpdateEvent(ctx, event))
+
+		err = engine.CompensateEvent(ctx, event.ID)
+		assert.ErrorIs(t, err, ErrInvalidEventState, "state %s", state)
+		stored, err := engine.GetEventState(ctx, event.ID)
+		require.NoError(t, err)
+		assert.Equal(t, state, stored)
+	}
+
+	for _, state := range []EventState{EventStateFailed, EventStateRollingBack} {
+		event, err := engine.CreateEvent(ctx, "compensate", "", time.Minute, nil, "")
+		require.NoError(t, err)
+		event.State = state
+		require.NoError(t, store.UpdateEvent(ctx, event))
+
+		require.NoError(t, engine.CompensateEvent(ctx, event.ID), "state %s", state)
+		stored, err := engine.GetEventState(ctx, event.ID)
+		require.NoError(t, err)
+		assert.Equal(t, EventStateRollingBack, stored)
+	}
+}
+
+// orphan leaves an event as a crashed worker would: in state, with the given
+// transactions completed and a lapsed lease
+func orphan(t *testing.T, store *memoryEventStore, eventID string, state EventState, leaseExpiresAt time.Time, txStates map[string]string) {
+	t.Helper()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	event := store.events[eventID]
+	event.State, event.LeaseOwner, event.LeaseExpiresAt = state, "crashed", &leaseExpiresAt
+	store.events[eventID] = event
+	for id, tx := range store.txs {
+		if txState, ok := txStates[tx.Name]; ok && tx.EventID == eventID {
+			tx.State = txState
+			store.txs[id] = tx
+		}
+	}
+}
+
+func TestRecoveryScanner_RequeuesOrphanedEvents(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	executor := &recordingExecutor{}
+	engine.RegisterExecutor("test", executor)
+	deps := map[string][]string{"b": {"a"}, "c": {"a"}}
+	lapsed := time.Now().Add(-time.Second)
+
+	resumed, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, resumed.ID, EventStateExecuting, lapsed, map[string]string{"a": "COMPLETED", "b": "EXECUTING"})
+	store.events[resumed.ID] = withName(store.events[resumed.ID], "resumable")
+
+	compensated, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, compensated.ID, EventStateExecuting, lapsed, map[string]string{"a": "COMPLETED", "b": "COMPLETED", "c": "EXECUTING"})
+
+	rollingBack, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, rollingBack.ID, EventStateRollingBack, lapsed, map[string]string{"a": "COMPLETED", "b": "COMPENSATED"})
+	store.events[rollingBack.ID] = withName(store.events[rollingBack.ID], "resumable")
+
+	finished, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, finished.ID, EventStateExecuting, lapsed, map[string]string{"a": "COMPLETED", "b": "COMPLETED", "c": "COMPLETED"})
+
+	held, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, held.ID, EventStateExecuting, time.Now().Add(time.Hour), nil)
+
+	scanner := NewRecoveryScanner(engine, RecoveryConfig{Policy: RecoveryPolicy{
+		ByName: map[string]RecoveryAction{"resumable": RecoveryResume},
+	}})
+	recovered, err := scanner.Scan(context.Background())
+	require.NoError(t, err)
+	to := make(map[string]EventState)
+	for _, r := range recovered {
+		to[r.EventID] = r.To
+	}
+	assert.Equal(t, map[string]EventState{
+		resumed.ID:     EventStateExecuting,
+		compensated.ID: EventStateRollingBack,
+		rollingBack.ID: EventStateRollingBack,
+		finished.ID:    EventStateCompleted,
+	}, to, "events under a live lease are left alone")
+
+	interrupted, err := store.GetTransaction(context.Background(), compensated.ID+"-c")
+	require.NoError(t, err)
+	assert.Equal(t, "FAILED", interrupted.State)
+	assert.ErrorIs(t, interrupted.Error, ErrTransactionInterrupted)
+
+	startWorkers(t, engine, WorkerConfig{Workers: 2})
+	waitForState(t, engine, resumed.ID, EventStateCompleted)
+	waitForState(t, engine, compensated.ID, EventStateRolledBack)
+	waitForState(t, engine, rollingBack.ID, EventStateRolledBack)
+
+	assert.ElementsMatch(t, []string{"b", "c"}, executor.executed, "only transactions that had not completed run again")
+	assert.ElementsMatch(t, []string{"b", "a", "a"}, executor.compensated)
+	state, err := engine.GetEventState(context.Background(), held.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStateExecuting, state)
+}
+
+// resolvingExecutor is a recordingExecutor that resolves the transactions
+// named in applied as having taken effect
+type resolvingExecutor struct {
+	recordingExecutor
+	applied map[string]bool
+}
+
+func (x *resolvingExecutor) Resolve(ctx context.Context, tx *Transaction) (bool, error) {
+	if !x.applied[tx.Name] {
+		return false, nil
+	}
+	tx.Result = map[string]interface{}{"posted": tx.ID}
+	return true, nil
+}
+
+func TestRecoveryScanner_ResolvesInterruptedTransactions(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	executor := &resolvingExecutor{applied: map[string]bool{"b": true}}
+	engine.RegisterExecutor("test", executor)
+	deps := map[string][]string{"b": {"a"}, "c": {"a"}}
+	lapsed := time.Now().Add(-time.Second)
+
+	resumed, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, resumed.ID, EventStateExecuting, lapsed, map[string]string{"a": "COMPLETED", "b": "EXECUTING"})
+	store.events[resumed.ID] = withName(store.events[resumed.ID], "resumable")
+
+	compensated, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, compensated.ID, EventStateExecuting, lapsed, map[string]string{"a": "COMPLETED", "b": "EXECUTING", "c": "EXECUTING"})
+
+	// b failed after taking effect, as when its outcome could not be recorded
+	rollingBack, err := startGraph(t, engine, store, deps, "a", "b", "c")
+	require.NoError(t, err)
+	orphan(t, store, rollingBack.ID, EventStateRollingBack, lapsed, map[string]string{"a": "COMPLETED", "b": "FAILED", "c": "FAILED"})
+
+	scanner := NewRecoveryScanner(engine, RecoveryConfig{Policy: RecoveryPolicy{
+		ByName: map[string]RecoveryAction{"resumable": RecoveryResume},
+	}})
+	_, err = scanner.Scan(context.Background())
+	require.NoError(t, err)
+
+	b, err := store.GetTransaction(context.Background(), compensated.ID+"-b")
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLETED", b.State, "an interrupted transaction that took effect completed")
+	assert.NoError(t, b.Error)
+	assert.Equal(t, map[string]interface{}{"posted": b.ID}, b.Result)
+	c, err := store.GetTransaction(context.Background(), compensated.ID+"-c")
+	require.NoError(t, err)
+	assert.Equal(t, "FAILED", c.State)
+	assert.ErrorIs(t, c.Error, ErrTransactionInterrupted)
+
+	startWorkers(t, engine, WorkerConfig{Workers: 2})
+	waitForState(t, engine, resumed.ID, EventStateCompleted)
+	waitForState(t, engine, compensated.ID, EventStateRolledBack)
+	waitForState(t, engine, rollingBack.ID, EventStateRolledBack)
+
+	assert.Equal(t, []string{"c"}, executor.executed, "transactions that took effect are not run again")
+	assert.ElementsMatch(t, []string{"b", "a", "b", "a"}, executor.compensated)
+	assert.Equal(t, map[string]string{"a": "COMPENSATED", "b": "COMPENSATED", "c": "FAILED"}, transactionStates(t, engine, rollingBack.ID))
+}
+
+func TestRecoveryScanner_FollowsParsedPolicy(t *testing.T) {
+	policy, err := ParseRecoveryPolicy(" *=compensate, wallet.payout=RESUME ,")
+	require.NoError(t, err)
+	assert.Equal(t, RecoveryPolicy{
+		Default: RecoveryCompensate,
+		ByName:  map[string]RecoveryAction{"wallet.payout": RecoveryResume},
+	}, policy)
+	for _, spec := range []string{"wallet.payout", "=RESUME", "wallet.payout=RETRY"} {
+		_, err := ParseRecoveryPolicy(spec)
+		assert.Error(t, err, spec)
+	}
+
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	engine.RegisterExecutor("test", &recordingExecutor{})
+	lapsed := time.Now().Add(-time.Second)
+
+	resumed, err := startGraph(t, engine, store, nil, "a", "b")
+	require.NoError(t, err)
+	orphan(t, store, resumed.ID, EventStateExecuting, lapsed, map[string]string{"a": "COMPLETED"})
+	store.events[resumed.ID] = withName(store.events[resumed.ID], "wallet.payout")
+
+	compensated, err := startGraph(t, engine, store, nil, "a", "b")
+	require.NoError(t, err)
+	orphan(t, store, compensated.ID, EventStateExecuting, lapsed, map[string]string{"a": "COMPLETED"})
+
+	recovered, err := NewRecoveryScanner(engine, RecoveryConfig{Policy: policy}).Scan(context.Background())
+	require.NoError(t, err)
+	to := make(map[string]EventState)
+	for _, r := range recovered {
+		to[r.EventID] = r.To
+	}
+	assert.Equal(t, map[string]EventState{
+		resumed.ID:     EventStateExecuting,
+		compensated.ID: EventStateRollingBack,
+	}, to)
+}
+
+// withName renames an event
+func withName(event Event, name string) Event {
+	event.Name = name
+	return event
+}
+
+func TestWorkerPool_StopsWhenLeaseIsLost(t *testing.T) {
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithRetryPolicy(fastRetries))
+	executor := &recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+		if tx.Name == "a" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+
+	event, err := startGraph(t, engine, store, map[string][]string{"b": {"a"}}, "a", "b")
+	require.NoError(t, err)
+	startWorkers(t, engine, WorkerConfig{Lease: time.Hour, HeartbeatInterval: time.Millisecond})
+
+	// Another worker takes the event over
+	require.Eventually(t, func() bool {
+		stored, err := engine.GetEvent(context.Background(), event.ID)
+		return err == nil && stored.LeaseOwner != ""
+	}, time.Second, time.Millisecond)
+	takeover := time.Now().Add(time.Hour)
+	orphan(t, store, event.ID, EventStateExecuting, takeover, nil)
+
+	require.Eventually(t, func() bool {
+		tx, err := store.GetTransaction(context.Background(), event.ID+"-a")
+		return err == nil && tx.State == "FAILED"
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	stored, err := engine.GetEvent(context.Background(), event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStateExecuting, stored.State, "the event is left to its new owner")
+	assert.Equal(t, "crashed", stored.LeaseOwner)
+	assert.Empty(t, executor.executed)
+	assert.Empty(t, executor.compensated)
+}
+
+func TestEventTimeout_CompensatesAndExpiresLiens(t *testing.T) {
+	store := newMemoryEventStore()
+	var timedOut []string
+	engine := NewEngine(store, WithTimeoutHandler(func(ctx context.Context, eventID string) error {
+		timedOut = append(timedOut, eventID)
+		return nil
+	}))
+	executor := &recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+		if tx.Name == "slow" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+
+	event, err := startGraph(t, engine, store, map[string][]string{"slow": {"a"}, "never": {"slow"}}, "a", "slow", "never")
+	require.NoError(t, err)
+	stored := store.events[event.ID]
+	stored.Timeout = 50 * time.Millisecond
+	store.events[event.ID] = stored
+	startWorkers(t, engine, WorkerConfig{})
+
+	waitForState(t, engine, event.ID, EventStateFailed)
+	failed, err := engine.GetEvent(context.Background(), event.ID)
+	require.NoError(t, err)
+	assert.Contains(t, failed.FailureReason, "timed out")
+	assert.Equal(t, []string{event.ID}, timedOut)
+	assert.Equal(t, []string{"a"}, executor.executed)
+	assert.Equal(t, []string{"a"}, executor.compensated)
+}
+
+func TestEventTimeout_ExpiresEventsNeverStarted(t *testing.T) {
+	store := newMemoryEventStore()
+	var timedOut []string
+	engine := NewEngine(store, WithTimeoutHandler(func(ctx context.Context, eventID string) error {
+		timedOut = append(timedOut, eventID)
+		return nil
+	}))
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour)
+
+	stuck, err := engine.CreateEvent(ctx, "stuck", "", time.Minute, nil, "")
+	require.NoError(t, err)
+	stored := store.events[stuck.ID]
+	stored.State, stored.CreatedAt = EventStateValidating, created
+	store.events[stuck.ID] = stored
+
+	late, err := engine.CreateEvent(ctx, "late", "", time.Minute, nil, "")
+	require.NoError(t, err)
+	stored = store.events[late.ID]
+	stored.State, stored.CreatedAt = EventStateValidated, created
+	store.events[late.ID] = stored
+
+	unlimited, err := engine.CreateEvent(ctx, "unlimited", "", 0, nil, "")
+	require.NoError(t, err)
+	stored = store.events[unlimited.ID]
+	stored.CreatedAt = created
+	store.events[unlimited.ID] = stored
+
+	err = engine.StartEvent(ctx, late.ID)
+	assert.ErrorIs(t, err, ErrEventTimedOut)
+
+	recovered, err := NewRecoveryScanner(engine, RecoveryConfig{}).Scan(ctx)
+	require.NoError(t, err)
+	require.Len(t, recovered, 1)
+	assert.Equal(t, RecoveredEvent{EventID: stuck.ID, Name: "stuck", From: EventStateValidating, To: EventStateFailed}, recovered[0])
+
+	for _, id := range []string{stuck.ID, late.ID} {
+		event, err := engine.GetEvent(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, EventStateFailed, event.State)
+		assert.Contains(t, event.FailureReason, "timed out")
+	}
+	assert.ElementsMatch(t, []string{stuck.ID, late.ID}, timedOut)
+	state, err := engine.GetEventState(ctx, unlimited.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStateCreated, state, "events without a timeout never expire")
+}
@@ -43,6 +43,8 @@ const (
 	TransactionStateCompensating TransactionState = "COMPENSATING"
 	// TransactionStateCompensated indicates the transaction has been compensated
 	TransactionStateCompensated TransactionState = "COMPENSATED"
+	// TransactionStateSkipped indicates the transaction's conditions did not hold, so it never ran
+	TransactionStateSkipped TransactionState = "SKIPPED"
 )
 
 // Event represents a Chained Transaction Event
@@ -61,10 +63,28 @@ type Event struct {
 	CreatedAt time.Time `json:"created_at"`
 	// UpdatedAt is the timestamp when the event was last updated
 	UpdatedAt time.Time `json:"updated_at"`
-	// Timeout is the duration after which the event will time out
+	// Timeout is the duration after which the event will time out, counted from CreatedAt
 	Timeout time.Duration `json:"timeout,omitempty"`
+	// FailureReason says why the event failed, for events failed by the engine itself such as on a timeout
+	FailureReason string `json:"failure_reason,omitempty"`
 	// Metadata contains additional context or parameters for the event
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// IdempotencyKey, if set, makes a retried CreateEvent with the same key return this event
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// RequestHash fingerprints the parameters the event was created with
+	RequestHash string `json:"-"`
+	// LeaseOwner is the worker running the event, if any
+	LeaseOwner string `json:"lease_owner,omitempty"`
+	// LeaseExpiresAt is when the worker's claim on the event lapses unless renewed
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+}
+
+// Deadline returns when the event times out, and false if it has no timeout
+func (e *Event) Deadline() (time.Time, bool) {
+	if e.Timeout <= 0 {
+		return time.Time{}, false
+	}
+	return e.CreatedAt.Add(e.Timeout), true
 }
 
 // Transaction represents a single transaction within an event
@@ -85,11 +105,13 @@ type Transaction struct {
 	Order int `json:"order"`
 	// Dependencies is a list of transaction IDs that must complete before this transaction can start
 	Dependencies []string `json:"dependencies,omitempty"`
+	// Conditions must all hold, once the dependencies have finished, for the transaction to run; otherwise it is skipped
+	Conditions []Condition `json:"conditions,omitempty"`
 	// Payload contains the data needed to execute the transaction
 	Payload interface{} `json:"payload,omitempty"`
 	// Result contains the result of the transaction execution
 	Result interface{} `json:"result,omitempty"`
-	// Error contains any error that occurred during transaction execution
+	// Error contains any error that occurred during transaction execution, or why it was skipped
 	Error error `json:"error,omitempty"`
 	// CreatedAt is the timestamp when the transaction was created
 	CreatedAt time.Time `json:"created_at"`
@@ -99,19 +121,22 @@ type Transaction struct {
 
 // EventCoordinator orchestrates the execution of chained transaction events
 type EventCoordinator interface {
-	// CreateEvent creates a new CTE event
-	CreateEvent(ctx context.Context, name, description string, timeout time.Duration, metadata map[string]interface{}) (*Event, error)
+	// CreateEvent creates a new CTE event. A non-empty idempotency key makes the
+	// call safe to retry: the event created by the first call is returned.
+	CreateEvent(ctx context.Context, name, description string, timeout time.Duration, metadata map[string]interface{}, idempotencyKey string) (*Event, error)
 	// GetEvent retrieves an event by ID
 	GetEvent(ctx context.Context, id string) (*Event, error)
 	// AddTransaction adds a new transaction to an event
 	AddTransaction(ctx context.Context, eventID string, tx *Transaction) error
-	// StartEvent begins the execution of an event
+	// ValidateEvent checks an event and its transactions, moving it to VALIDATED or FAILED
+	ValidateEvent(ctx context.Context, eventID string) error
+	// StartEvent queues an event for execution by a WorkerPool
 	StartEvent(ctx context.Context, eventID string) error
 	// GetEventTransactions retrieves all transactions for an event
 	GetEventTransactions(ctx context.Context, eventID string) ([]*Transaction, error)
 	// GetEventState retrieves the current state of an event
 	GetEventState(ctx context.Context, eventID string) (EventState, error)
-	// CompensateEvent triggers compensation for a failed event
+	// CompensateEvent queues compensation of an event's completed transactions
 	CompensateEvent(ctx context.Context, eventID string) error
 }
 
@@ -129,8 +154,25 @@ type EventStore interface {
 	SaveEvent(ctx context.Context, event *Event) error
 	// GetEvent retrieves an event by ID
 	GetEvent(ctx context.Context, id string) (*Event, error)
-	// UpdateEvent updates an existing event
+	// GetEventByIdempotencyKey retrieves the event created with an idempotency key, or nil if there is none
+	GetEventByIdempotencyKey(ctx context.Context, key string) (*Event, error)
+	// UpdateEvent updates an existing event, leaving its lease untouched
 	UpdateEvent(ctx context.Context, event *Event) error
+	// ClaimEvent leases a queued event, one EXECUTING or ROLLING_BACK with no
+	// lease, to owner until lease from now. It returns nil if there is none.
+	// Concurrent claims never return the same event.
+	ClaimEvent(ctx context.Context, owner string, lease time.Duration) (*Event, error)
+	// ClaimOrphanedEvent leases an EXECUTING or ROLLING_BACK event whose lease
+	// has lapsed to owner until lease from now. It returns nil if there is none.
+	ClaimOrphanedEvent(ctx context.Context, owner string, lease time.Duration) (*Event, error)
+	// RenewLease extends owner's lease on an event until lease from now. It
+	// returns ErrLeaseLost if owner no longer holds the lease.
+	RenewLease(ctx context.Context, eventID, owner string, lease time.Duration) error
+	// ReleaseLease gives up owner's lease on an event
+	ReleaseLease(ctx context.Context, eventID, owner string) error
+	// ListExpiredEvents retrieves the CREATED, VALIDATING and VALIDATED events
+	// whose deadline passed before now
+	ListExpiredEvents(ctx context.Context, now time.Time) ([]*Event, error)
 	// SaveTransaction saves a transaction to the store
 	SaveTransaction(ctx context.Context, tx *Transaction) error
 	// GetTransaction retrieves a transaction by ID
@@ -139,4 +181,8 @@ type EventStore interface {
 	UpdateTransaction(ctx context.Context, tx *Transaction) error
 	// GetEventTransactions retrieves all transactions for an event
 	GetEventTransactions(ctx context.Context, eventID string) ([]*Transaction, error)
+	// RecordAttempt records an attempt at executing a transaction
+	RecordAttempt(ctx context.Context, attempt *TransactionAttempt) error
+	// GetTransactionAttempts retrieves the attempts at a transaction, first to last
+	GetTransactionAttempts(ctx context.Context, transactionID string) ([]*TransactionAttempt, error)
 }
@@ -0,0 +1,123 @@
+package cte
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrDependencyCycle is returned when an event's transactions depend on each other in a cycle
+	ErrDependencyCycle = errors.New("transaction dependencies form a cycle")
+	// ErrUnknownDependency is returned when a transaction depends on one outside its event
+	ErrUnknownDependency = errors.New("transaction depends on an unknown transaction")
+)
+
+// executionGraph is the DAG of an event's transactions, with an edge from
+// each transaction to the ones that depend on it
+type executionGraph struct {
+	nodes      map[string]*Transaction
+	dependents map[string][]string
+	indegree   map[string]int
+	// order lists the transactions so that each comes after its dependencies.
+	// Ties are broken by Transaction.Order, then ID.
+	order []*Transaction
+}
+
+// buildGraph builds the DAG of an event's transactions from their
+// dependencies. Dependencies must name transactions of the same event and may
+// not form a cycle.
+func buildGraph(transactions []*Transaction) (*executionGraph, error) {
+	g := &executionGraph{
+		nodes:      make(map[string]*Transaction, len(transactions)),
+		dependents: make(map[string][]string, len(transactions)),
+		indegree:   make(map[string]int, len(transactions)),
+	}
+	for _, tx := range transactions {
+		g.nodes[tx.ID] = tx
+	}
+
+	for _, tx := range transactions {
+		seen := make(map[string]bool, len(tx.Dependencies))
+		for _, depID := range tx.Dependencies {
+			if seen[depID] {
+				continue
+			}
+			seen[depID] = true
+			if _, ok := g.nodes[depID]; !ok {
+				return nil, fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, tx.ID, depID)
+			}
+			g.dependents[depID] = append(g.dependents[depID], tx.ID)
+			g.indegree[tx.ID]++
+		}
+	}
+
+	// Kahn's algorithm; whatever is never freed of its dependencies is on a cycle
+	remaining := make(map[string]int, len(g.indegree))
+	for id, n := range g.indegree {
+		remaining[id] = n
+	}
+	var ready []*Transaction
+	for _, tx := range transactions {
+		if remaining[tx.ID] == 0 {
+			ready = append(ready, tx)
+		}
+	}
+	for len(ready) > 0 {
+		sortByOrder(ready)
+		tx := ready[0]
+		ready = ready[1:]
+		g.order = append(g.order, tx)
+		for _, id := range g.dependents[tx.ID] {
+			remaining[id]--
+			if remaining[id] == 0 {
+				ready = append(ready, g.nodes[id])
+			}
+		}
+	}
+
+	if len(g.order) < len(transactions) {
+		var cyclic []string
+		for id, n := range remaining {
+			if n > 0 {
+				cyclic = append(cyclic, id)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cyclic, ", "))
+	}
+
+	return g, nil
+}
+
+// roots returns the transactions without dependencies, in execution order
+func (g *executionGraph) roots() []*Transaction {
+	var roots []*Transaction
+	for _, tx := range g.order {
+		if g.indegree[tx.ID] == 0 {
+			roots = append(roots, tx)
+		}
+	}
+	return roots
+}
+
+// reverseOrder returns the transactions so that each comes before its
+// dependencies, the order in which they are compensated
+func (g *executionGraph) reverseOrder() []*Transaction {
+	reversed := make([]*Transaction, len(g.order))
+	for i, tx := range g.order {
+		reversed[len(g.order)-1-i] = tx
+	}
+	return reversed
+}
+
+// sortByOrder sorts transactions by Order, then ID
+func sortByOrder(transactions []*Transaction) {
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].Order != transactions[j].Order {
+			return transactions[i].Order < transactions[j].Order
+		}
+		return transactions[i].ID < transactions[j].ID
+	})
+}
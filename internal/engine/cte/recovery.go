@@ -0,0 +1,267 @@
+package cte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrTransactionInterrupted is recorded on transactions that were executing
+// when their worker died and that their executor cannot show took effect
+var ErrTransactionInterrupted = errors.New("transaction interrupted by a lost worker")
+
+// TransactionResolver is implemented by executors that key their effects on
+// the transaction ID, such as ledger postings referenced by it, so that the
+// outcome of an attempt that was never recorded can be found out
+type TransactionResolver interface {
+	// Resolve reports whether tx took effect and, if it did, sets tx.Result
+	// as Execute would have
+	Resolve(ctx context.Context, tx *Transaction) (bool, error)
+}
+
+// resolveTransaction reports whether tx took effect although its outcome
+// was not recorded. Transactions whose executor is not a
+// TransactionResolver are taken not to have.
+func (e *Engine) resolveTransaction(ctx context.Context, tx *Transaction) (bool, error) {
+	e.mu.RLock()
+	executor := e.txExecutors[tx.Type]
+	e.mu.RUnlock()
+
+	resolver, ok := executor.(TransactionResolver)
+	if !ok {
+		return false, nil
+	}
+	return resolver.Resolve(ctx, tx)
+}
+
+// RecoveryAction is what recovery does with an event orphaned while executing
+type RecoveryAction string
+
+const (
+	// RecoveryResume runs the transactions that had not completed, including
+	// interrupted ones that did not take effect; executors that are not a
+	// TransactionResolver must be idempotent
+	RecoveryResume RecoveryAction = "RESUME"
+	// RecoveryCompensate compensates the transactions that had completed
+	RecoveryCompensate RecoveryAction = "COMPENSATE"
+)
+
+// RecoveryPolicy picks the RecoveryAction for orphaned events by event name
+type RecoveryPolicy struct {
+	// Default applies to events not listed in ByName; defaults to RecoveryCompensate
+	Default RecoveryAction
+	// ByName overrides Default for events of the given names
+	ByName map[string]RecoveryAction
+}
+
+// Action returns the action for events named name
+func (p RecoveryPolicy) Action(name string) RecoveryAction {
+	if action, ok := p.ByName[name]; ok {
+		return action
+	}
+	if p.Default == "" {
+		return RecoveryCompensate
+	}
+	return p.Default
+}
+
+// ParseRecoveryPolicy parses a policy written as comma-separated
+// name=ACTION pairs, such as "*=COMPENSATE,wallet.payout=RESUME", where the
+// name * sets the default. Actions are case-insensitive.
+func ParseRecoveryPolicy(spec string) (RecoveryPolicy, error) {
+	policy := RecoveryPolicy{ByName: make(map[string]RecoveryAction)}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return RecoveryPolicy{}, fmt.Errorf("invalid recovery policy entry %q: want name=ACTION", pair)
+		}
+		action := RecoveryAction(strings.ToUpper(strings.TrimSpace(value)))
+		if action != RecoveryResume && action != RecoveryCompensate {
+			return RecoveryPolicy{}, fmt.Errorf("invalid recovery action %q for %s: want %s or %s", value, name, RecoveryResume, RecoveryCompensate)
+		}
+		if name == "*" {
+			policy.Default = action
+		} else {
+			policy.ByName[name] = action
+		}
+	}
+	return policy, nil
+}
+
+// Defaults for RecoveryConfig
+const (
+	DefaultRecoveryLease    = 30 * time.Second
+	DefaultRecoveryInterval = time.Minute
+)
+
+// RecoveryConfig configures a RecoveryScanner
+type RecoveryConfig struct {
+	Policy   RecoveryPolicy
+	Lease    time.Duration // How long the scanner holds an event while recovering it; defaults to DefaultRecoveryLease
+	Interval time.Duration // Time between scans; defaults to DefaultRecoveryInterval
+}
+
+// RecoveredEvent reports what recovery did with one orphaned or expired event
+type RecoveredEvent struct {
+	EventID string
+	Name    string
+	// From is the state the event was orphaned in
+	From EventState
+	// To is the state recovery left it in: EXECUTING or ROLLING_BACK to be
+	// picked up by a WorkerPool, COMPLETED if nothing was left to run, or
+	// FAILED if it passed its deadline before it was started
+	To EventState
+	// Interrupted lists the transactions that were executing when the worker
+	// died, whether or not they took effect
+	Interrupted []string
+}
+
+// RecoveryScanner finds events left EXECUTING or ROLLING_BACK by a worker that
+// died, meaning their lease has lapsed without being renewed, and queues
+// them again for a WorkerPool. Rolling back events always finish their
+// compensation; executing events resume or are compensated as the policy for
+// their name says. It also fails the events that passed their deadline
+// without being started.
+type RecoveryScanner struct {
+	engine *Engine
+	config RecoveryConfig
+	owner  string
+}
+
+// NewRecoveryScanner creates a new RecoveryScanner for engine's events
+func NewRecoveryScanner(engine *Engine, config RecoveryConfig) *RecoveryScanner {
+	if config.Lease <= 0 {
+		config.Lease = DefaultRecoveryLease
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultRecoveryInterval
+	}
+	return &RecoveryScanner{
+		engine: engine,
+		config: config,
+		owner:  "recovery/" + uuid.New().String(),
+	}
+}
+
+// Scan recovers every orphaned event, expires the events never started in
+// time, and reports what it did
+func (s *RecoveryScanner) Scan(ctx context.Context) ([]RecoveredEvent, error) {
+	var recovered []RecoveredEvent
+	expired, err := s.engine.ExpireEvents(ctx)
+	for _, event := range expired {
+		recovered = append(recovered, RecoveredEvent{EventID: event.ID, Name: event.Name, From: event.State, To: EventStateFailed})
+	}
+	if err != nil {
+		return recovered, err
+	}
+
+	for {
+		event, err := s.engine.eventStore.ClaimOrphanedEvent(ctx, s.owner, s.config.Lease)
+		if err != nil {
+			return recovered, fmt.Errorf("failed to claim orphaned event: %w", err)
+		}
+		if event == nil {
+			return recovered, nil
+		}
+
+		report, err := s.recover(ctx, event)
+		if releaseErr := s.engine.eventStore.ReleaseLease(context.WithoutCancel(ctx), event.ID, s.owner); releaseErr != nil && err == nil {
+			err = fmt.Errorf("failed to release lease: %w", releaseErr)
+		}
+		if err != nil {
+			return recovered, fmt.Errorf("failed to recover event %s: %w", event.ID, err)
+		}
+		recovered = append(recovered, report)
+	}
+}
+
+// recover resolves the interrupted transactions of a claimed event, marking
+// those that took effect completed and the rest failed, and moves the event
+// to the state its recovery action calls for
+func (s *RecoveryScanner) recover(ctx context.Context, event *Event) (RecoveredEvent, error) {
+	report := RecoveredEvent{EventID: event.ID, Name: event.Name, From: event.State, To: event.State}
+
+	transactions, err := s.engine.eventStore.GetEventTransactions(ctx, event.ID)
+	if err != nil {
+		return report, fmt.Errorf("failed to get event transactions: %w", err)
+	}
+
+	allCompleted := true
+	for _, tx := range transactions {
+		if tx.State == string(TransactionStateExecuting) {
+			applied, err := s.engine.resolveTransaction(ctx, tx)
+			if err != nil {
+				return report, fmt.Errorf("failed to resolve interrupted transaction %s: %w", tx.ID, err)
+			}
+			if applied {
+				tx.State = string(TransactionStateCompleted)
+				tx.Error = nil
+			} else {
+				tx.State = string(TransactionStateFailed)
+				tx.Error = ErrTransactionInterrupted
+			}
+			tx.UpdatedAt = time.Now()
+			if err := s.engine.eventStore.UpdateTransaction(ctx, tx); err != nil {
+				return report, fmt.Errorf("failed to mark transaction %s interrupted: %w", tx.ID, err)
+			}
+			report.Interrupted = append(report.Interrupted, tx.ID)
+		}
+		if tx.State != string(TransactionStateCompleted) && tx.State != string(TransactionStateSkipped) {
+			allCompleted = false
+		}
+	}
+
+	if event.State == EventStateExecuting {
+		switch {
+		case allCompleted:
+			// The worker died after the last transaction but before recording it
+			report.To = EventStateCompleted
+		case s.config.Policy.Action(event.Name) == RecoveryCompensate:
+			report.To = EventStateRollingBack
+		}
+	}
+
+	if report.To != report.From {
+		event.State = report.To
+		event.UpdatedAt = time.Now()
+		if err := s.engine.eventStore.UpdateEvent(ctx, event); err != nil {
+			return report, fmt.Errorf("failed to update event state: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// Start runs Scan right away and then on every tick until the context is cancelled
+func (s *RecoveryScanner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			recovered, err := s.Scan(ctx)
+			for _, r := range recovered {
+				log.Printf("Recovered CTE event %s (%s) from %s to %s, %d interrupted transactions",
+					r.EventID, r.Name, r.From, r.To, len(r.Interrupted))
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("CTE recovery scan failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
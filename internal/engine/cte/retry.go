@@ -0,0 +1,120 @@
+package cte
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrNoExecutor is returned for transactions of a type no executor is registered for
+var ErrNoExecutor = errors.New("no executor registered for transaction type")
+
+// RetryPolicy controls how often a failed transaction is attempted again
+type RetryPolicy struct {
+	// MaxAttempts is the most times a transaction is attempted, the first
+	// attempt included; 1 disables retries
+	MaxAttempts int
+	// InitialInterval is the wait before the first retry
+	InitialInterval time.Duration
+	// MaxInterval caps the wait between attempts
+	MaxInterval time.Duration
+	// Multiplier grows the wait after each retry
+	Multiplier float64
+	// Jitter spreads each wait randomly by up to this fraction of it, either way
+	Jitter float64
+	// MaxElapsedTime stops retries that would start this long after the first
+	// attempt; zero means no limit besides the event's deadline
+	MaxElapsedTime time.Duration
+}
+
+// DefaultRetryPolicy is used for executors that do not set their own
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     3,
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     5 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.2,
+	MaxElapsedTime:  time.Minute,
+}
+
+// RetryPolicyProvider is implemented by executors with a RetryPolicy of their own
+type RetryPolicyProvider interface {
+	RetryPolicy() RetryPolicy
+}
+
+// Backoff returns how long to wait after attempt, counted from 1, fails
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	interval := float64(p.InitialInterval)
+	for i := 1; i < attempt; i++ {
+		interval *= p.Multiplier
+		if p.MaxInterval > 0 && interval >= float64(p.MaxInterval) {
+			break
+		}
+	}
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+		interval = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		interval += interval * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(interval)
+}
+
+// shouldRetry reports whether another attempt may follow attempt, which
+// failed with err, started after elapsed since the first, and would wait delay
+func (p RetryPolicy) shouldRetry(attempt int, err error, elapsed, delay time.Duration) bool {
+	if IsPermanent(err) || attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.MaxElapsedTime <= 0 || elapsed+delay <= p.MaxElapsedTime
+}
+
+// classifiedError marks an error as worth retrying or not
+type classifiedError struct {
+	err       error
+	permanent bool
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+func (e *classifiedError) Unwrap() error { return e.err }
+
+// Permanent marks an error that no retry can fix, such as an invalid payload,
+// so the transaction fails without being attempted again
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, permanent: true}
+}
+
+// Retryable marks an error as worth retrying, overriding a Permanent marker
+// further down its chain. Errors without a marker are retried too.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err}
+}
+
+// IsPermanent reports whether err should not be retried: its outermost
+// marker is Permanent, or it is a context cancellation or a missing executor
+func IsPermanent(err error) bool {
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.permanent
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrNoExecutor)
+}
+
+// TransactionAttempt records one attempt at executing a transaction
+type TransactionAttempt struct {
+	TransactionID string `json:"transaction_id"`
+	EventID       string `json:"event_id"`
+	// Attempt counts the attempts at the transaction from 1
+	Attempt int `json:"attempt"`
+	// Error is empty if the attempt succeeded
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
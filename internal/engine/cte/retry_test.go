@@ -0,0 +1,187 @@
+package cte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 800*time.Millisecond, policy.Backoff(4))
+	assert.Equal(t, time.Second, policy.Backoff(5))
+	assert.Equal(t, time.Second, policy.Backoff(50))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.Backoff(2)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.LessOrEqual(t, delay, 300*time.Millisecond)
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	invalid := errors.New("invalid payload")
+	assert.False(t, IsPermanent(invalid), "errors without a marker are retried")
+	assert.True(t, IsPermanent(Permanent(invalid)))
+	assert.True(t, IsPermanent(fmt.Errorf("wrapped: %w", Permanent(invalid))))
+	assert.False(t, IsPermanent(Retryable(Permanent(invalid))), "the outermost marker wins")
+	assert.ErrorIs(t, Permanent(invalid), invalid)
+	assert.True(t, IsPermanent(context.Canceled))
+	assert.True(t, IsPermanent(fmt.Errorf("%w: wallet", ErrNoExecutor)))
+	assert.Nil(t, Permanent(nil))
+}
+
+// policyExecutor is a recordingExecutor with its own RetryPolicy
+type policyExecutor struct {
+	recordingExecutor
+	policy RetryPolicy
+}
+
+func (x *policyExecutor) RetryPolicy() RetryPolicy {
+	return x.policy
+}
+
+func TestExecuteTransaction_Retries(t *testing.T) {
+	ctx := context.Background()
+	flaky := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		// errs are returned by the executor's attempts in turn; later attempts succeed
+		errs     []error
+		policy   *RetryPolicy
+		wantErr  error
+		attempts int
+	}{
+		{name: "retried until it succeeds", errs: []error{flaky, flaky}, attempts: 3},
+		{name: "gives up after max attempts", errs: []error{flaky, flaky, flaky, flaky}, wantErr: flaky, attempts: 3},
+		{name: "permanent errors are not retried", errs: []error{Permanent(flaky)}, wantErr: flaky, attempts: 1},
+		{name: "retryable overrides permanent", errs: []error{Retryable(Permanent(flaky))}, attempts: 2},
+		{
+			name:     "executor policy overrides the engine's",
+			errs:     []error{flaky, flaky, flaky, flaky},
+			policy:   &RetryPolicy{MaxAttempts: 5, InitialInterval: time.Millisecond},
+			attempts: 5,
+		},
+		{
+			name:    "stops at max elapsed time",
+			errs:    []error{flaky, flaky, flaky},
+			policy:  &RetryPolicy{MaxAttempts: 10, InitialInterval: 20 * time.Millisecond, Multiplier: 1, MaxElapsedTime: 30 * time.Millisecond},
+			wantErr: flaky, attempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryEventStore()
+			engine := NewEngine(store, WithRetryPolicy(fastRetries))
+			calls := 0
+			executor := &policyExecutor{recordingExecutor: recordingExecutor{run: func(ctx context.Context, tx *Transaction) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}}}
+			if tt.policy != nil {
+				executor.policy = *tt.policy
+				engine.RegisterExecutor("test", executor)
+			} else {
+				engine.RegisterExecutor("test", &executor.recordingExecutor)
+			}
+
+			tx := &Transaction{ID: "tx-1", EventID: "event-1", Type: "test"}
+			require.NoError(t, store.SaveTransaction(ctx, tx))
+			err := engine.executeTransactionWithRetry(ctx, tx)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, "FAILED", tx.State)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "COMPLETED", tx.State)
+				stored, err := store.GetTransaction(ctx, tx.ID)
+				require.NoError(t, err)
+				assert.NoError(t, stored.Error, "a completed transaction keeps no error from earlier attempts")
+			}
+
+			attempts, err := store.GetTransactionAttempts(ctx, tx.ID)
+			require.NoError(t, err)
+			require.Len(t, attempts, tt.attempts)
+			for i, attempt := range attempts {
+				assert.Equal(t, i+1, attempt.Attempt)
+				if i < len(tt.errs) {
+					assert.Equal(t, tt.errs[i].Error(), attempt.Error)
+				} else {
+					assert.Empty(t, attempt.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteTransaction_ResolvesBeforeRunningAgain(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithRetryPolicy(fastRetries))
+	executor := &resolvingExecutor{applied: make(map[string]bool)}
+	executor.run = func(ctx context.Context, tx *Transaction) error {
+		// The posting is made, but the attempt fails before reporting it
+		executor.applied[tx.Name] = true
+		return errors.New("connection reset")
+	}
+	engine.RegisterExecutor("test", executor)
+
+	tx := &Transaction{ID: "tx-1", EventID: "event-1", Name: "post", Type: "test"}
+	require.NoError(t, store.SaveTransaction(ctx, tx))
+	require.NoError(t, engine.executeTransactionWithRetry(ctx, tx))
+
+	stored, err := store.GetTransaction(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLETED", stored.State)
+	assert.NoError(t, stored.Error)
+	assert.Equal(t, map[string]interface{}{"posted": tx.ID}, stored.Result)
+	attempts, err := store.GetTransactionAttempts(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Len(t, attempts, 1, "a transaction that took effect is not run again")
+
+	// A resumed transaction is resolved before its first attempt of the run
+	resumed := &Transaction{ID: "tx-2", EventID: "event-1", Name: "resumed", Type: "test", State: "EXECUTING"}
+	require.NoError(t, store.SaveTransaction(ctx, resumed))
+	executor.applied["resumed"] = true
+	require.NoError(t, engine.executeTransactionWithRetry(ctx, resumed))
+	attempts, err = store.GetTransactionAttempts(ctx, resumed.ID)
+	require.NoError(t, err)
+	assert.Empty(t, attempts)
+	assert.Equal(t, "COMPLETED", resumed.State)
+}
+
+func TestExecuteTransaction_MissingExecutorIsNotRetried(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryEventStore()
+	engine := NewEngine(store, WithRetryPolicy(fastRetries))
+
+	tx := &Transaction{ID: "tx-1", EventID: "event-1", Type: "unknown"}
+	require.NoError(t, store.SaveTransaction(ctx, tx))
+	err := engine.executeTransactionWithRetry(ctx, tx)
+	assert.ErrorIs(t, err, ErrNoExecutor)
+	assert.Equal(t, "FAILED", tx.State)
+
+	attempts, err := store.GetTransactionAttempts(ctx, tx.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+
+	// A resumed transaction numbers its attempts after the earlier ones
+	engine.RegisterExecutor("unknown", &recordingExecutor{})
+	require.NoError(t, engine.executeTransactionWithRetry(ctx, tx))
+	attempts, err = store.GetTransactionAttempts(ctx, tx.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, 2, attempts[1].Attempt)
+}
@@ -0,0 +1,170 @@
+package cte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrEventInvalid is returned when an event fails validation
+var ErrEventInvalid = errors.New("event failed validation")
+
+// TransactionValidator is implemented by executors that can check a
+// transaction before its event is started, such as its payload, accounts,
+// currencies and funds
+type TransactionValidator interface {
+	// Validate returns why tx cannot be executed, or nil if it can
+	Validate(ctx context.Context, tx *Transaction) error
+}
+
+// ValidationError reports every reason an event failed validation
+type ValidationError struct {
+	EventID string
+	// Event lists the errors of the event as a whole, such as a dependency cycle
+	Event []error
+	// Transactions maps the IDs of invalid transactions to their errors
+	Transactions map[string][]error
+}
+
+// Error lists the event's errors, then each invalid transaction's in ID order
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event %s failed validation", e.EventID)
+	for _, err := range e.Event {
+		fmt.Fprintf(&b, "; %v", err)
+	}
+	ids := make([]string, 0, len(e.Transactions))
+	for id := range e.Transactions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, err := range e.Transactions[id] {
+			fmt.Fprintf(&b, "; transaction %s: %v", id, err)
+		}
+	}
+	return b.String()
+}
+
+// Is makes a ValidationError match ErrEventInvalid
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrEventInvalid
+}
+
+// Unwrap returns every error in the report, so that errors.Is finds causes
+// such as ErrDependencyCycle
+func (e *ValidationError) Unwrap() []error {
+	errs := append([]error(nil), e.Event...)
+	for _, txErrs := range e.Transactions {
+		errs = append(errs, txErrs...)
+	}
+	return errs
+}
+
+// ValidateEvent checks an event before it is started: its dependency graph,
+// the conditions of its transactions, and each transaction with its
+// executor's Validate method if it has one.
+// Every error is collected rather than stopping at the first. A valid event
+// moves to VALIDATED. An invalid one moves to FAILED, its invalid transactions
+// are marked FAILED with their errors, and a *ValidationError is returned.
+func (e *Engine) ValidateEvent(ctx context.Context, eventID string) error {
+	event, err := e.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	if event.State != EventStateCreated && event.State != EventStateValidating {
+		return fmt.Errorf("%w: cannot validate event in state %s",
+			ErrInvalidEventState, event.State)
+	}
+
+	if deadline, ok := event.Deadline(); ok && time.Now().After(deadline) {
+		return e.timeOutEvent(ctx, eventID)
+	}
+
+	transactions, err := e.eventStore.GetEventTransactions(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to get event transactions: %w", err)
+	}
+
+	report := &ValidationError{EventID: eventID, Transactions: make(map[string][]error)}
+	if len(transactions) == 0 {
+		report.Event = append(report.Event, errors.New("event has no transactions"))
+	}
+	ids := make(map[string]bool, len(transactions))
+	for _, tx := range transactions {
+		ids[tx.ID] = true
+	}
+	unknownDeps := false
+	for _, tx := range transactions {
+		for _, depID := range tx.Dependencies {
+			if !ids[depID] {
+				unknownDeps = true
+				report.Transactions[tx.ID] = append(report.Transactions[tx.ID],
+					fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, tx.ID, depID))
+			}
+		}
+		for _, c := range tx.Conditions {
+			if err := c.validate(tx); err != nil {
+				report.Transactions[tx.ID] = append(report.Transactions[tx.ID], err)
+			}
+		}
+		if err := e.validateTransaction(ctx, tx); err != nil {
+			report.Transactions[tx.ID] = append(report.Transactions[tx.ID], err)
+		}
+	}
+	if !unknownDeps {
+		// Cycles can only be found once every dependency is known
+		if _, err := buildGraph(transactions); err != nil {
+			report.Event = append(report.Event, err)
+		}
+	}
+
+	if len(report.Event) == 0 && len(report.Transactions) == 0 {
+		event.State = EventStateValidated
+		event.UpdatedAt = time.Now()
+		if err := e.eventStore.UpdateEvent(ctx, event); err != nil {
+			return fmt.Errorf("failed to mark event as validated: %w", err)
+		}
+		return nil
+	}
+
+	for _, tx := range transactions {
+		txErrs, ok := report.Transactions[tx.ID]
+		if !ok {
+			continue
+		}
+		tx.State = string(TransactionStateFailed)
+		tx.Error = errors.Join(txErrs...)
+		tx.UpdatedAt = time.Now()
+		if err := e.eventStore.UpdateTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("failed to mark transaction %s invalid: %v (original error: %w)", tx.ID, err, report)
+		}
+	}
+
+	event.State = EventStateFailed
+	event.FailureReason = report.Error()
+	event.UpdatedAt = time.Now()
+	if err := e.eventStore.UpdateEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to mark event as failed: %v (original error: %w)", err, report)
+	}
+	return report
+}
+
+// validateTransaction checks a transaction with its executor, which must be registered
+func (e *Engine) validateTransaction(ctx context.Context, tx *Transaction) error {
+	e.mu.RLock()
+	executor, ok := e.txExecutors[tx.Type]
+	e.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoExecutor, tx.Type)
+	}
+	if validator, ok := executor.(TransactionValidator); ok {
+		return validator.Validate(ctx, tx)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cte
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validatingExecutor is a recordingExecutor that validates transactions
+type validatingExecutor struct {
+	recordingExecutor
+	validate func(tx *Transaction) error
+}
+
+func (x *validatingExecutor) Validate(ctx context.Context, tx *Transaction) error {
+	return x.validate(tx)
+}
+
+// addTransactions creates an event with one transaction per name, of the type
+// in types or "test", each depending on the transactions named in deps
+func addTransactions(t *testing.T, engine *Engine, deps map[string][]string, types map[string]string, names ...string) *Event {
+	t.Helper()
+	ctx := context.Background()
+	event, err := engine.CreateEvent(ctx, "validated", "", time.Minute, nil, "")
+	require.NoError(t, err)
+	for i, name := range names {
+		txType := types[name]
+		if txType == "" {
+			txType = "test"
+		}
+		tx := &Transaction{ID: event.ID + "-" + name, EventID: event.ID, Name: name, Type: txType, Order: i}
+		for _, dep := range deps[name] {
+			tx.Dependencies = append(tx.Dependencies, event.ID+"-"+dep)
+		}
+		require.NoError(t, engine.AddTransaction(ctx, event.ID, tx))
+	}
+	return event
+}
+
+func TestValidateEvent(t *testing.T) {
+	ctx := context.Background()
+	errNoFunds := errors.New("insufficient funds")
+	store := newMemoryEventStore()
+	engine := NewEngine(store)
+	executor := &validatingExecutor{validate: func(tx *Transaction) error {
+		if tx.Name == "broke" {
+			return errNoFunds
+		}
+		return nil
+	}}
+	engine.RegisterExecutor("test", executor)
+	engine.RegisterExecutor("unchecked", &recordingExecutor{})
+
+	t.Run("valid events can be started", func(t *testing.T) {
+		event := addTransactions(t, engine, map[string][]string{"b": {"a"}}, map[string]string{"b": "unchecked"}, "a", "b")
+		require.NoError(t, engine.ValidateEvent(ctx, event.ID))
+		state, err := engine.GetEventState(ctx, event.ID)
+		require.NoError(t, err)
+		assert.Equal(t, EventStateValidated, state)
+		assert.NoError(t, engine.StartEvent(ctx, event.ID))
+
+		assert.ErrorIs(t, engine.ValidateEvent(ctx, event.ID), ErrInvalidEventState)
+	})
+
+	t.Run("every error is reported", func(t *testing.T) {
+		event := addTransactions(t, engine,
+			map[string][]string{"orphan": {"missing"}},
+			map[string]string{"untyped": "unknown"},
+			"ok", "broke", "orphan", "untyped")
+		err := engine.ValidateEvent(ctx, event.ID)
+		var report *ValidationError
+		require.ErrorAs(t, err, &report)
+		assert.ErrorIs(t, err, ErrEventInvalid)
+		assert.ErrorIs(t, err, errNoFunds)
+		assert.ErrorIs(t, err, ErrUnknownDependency)
+		assert.ErrorIs(t, err, ErrNoExecutor)
+		assert.Empty(t, report.Event)
+		assert.Len(t, report.Transactions, 3)
+
+		failed, err := engine.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		assert.Equal(t, EventStateFailed, failed.State)
+		assert.Equal(t, report.Error(), failed.FailureReason)
+
+		txs, err := engine.GetEventTransactions(ctx, event.ID)
+		require.NoError(t, err)
+		states := make(map[string]string)
+		for _, tx := range txs {
+			states[tx.Name] = tx.State
+		}
+		assert.Equal(t, map[string]string{"ok": "", "broke": "FAILED", "orphan": "FAILED", "untyped": "FAILED"}, states)
+	})
+
+	t.Run("cycles fail the event", func(t *testing.T) {
+		event := addTransactions(t, engine, map[string][]string{"a": {"b"}, "b": {"a"}}, nil, "a", "b", "broke")
+		err := engine.ValidateEvent(ctx, event.ID)
+		var report *ValidationError
+		require.ErrorAs(t, err, &report)
+		assert.ErrorIs(t, err, ErrDependencyCycle)
+		assert.Len(t, report.Event, 1)
+		assert.Len(t, report.Transactions, 1, "transactions are still checked")
+	})
+}
@@ -0,0 +1,171 @@
+package cte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrLeaseLost is returned when a worker no longer holds the lease on an event
+var ErrLeaseLost = errors.New("event lease lost")
+
+// Defaults for WorkerConfig
+const (
+	DefaultWorkerLease        = 30 * time.Second
+	DefaultWorkerPollInterval = time.Second
+)
+
+// WorkerConfig configures a WorkerPool
+type WorkerConfig struct {
+	ID                string        // Identifies this process in leases; defaults to a random ID
+	Workers           int           // Events run at once; defaults to 1
+	Lease             time.Duration // How long a claim lasts without a heartbeat; defaults to DefaultWorkerLease
+	HeartbeatInterval time.Duration // How often claims are renewed; defaults to a third of Lease
+	PollInterval      time.Duration // How long an idle worker waits before looking for events again; defaults to DefaultWorkerPollInterval
+}
+
+// WorkerPool runs the events queued by StartEvent and CompensateEvent. Each
+// worker claims an EXECUTING or ROLLING_BACK event from the event store under
+// a lease, renews the lease while it works, and releases it when done, so
+// pools in several processes can share one store. When a worker dies its
+// lease lapses, and a RecoveryScanner queues the event again.
+type WorkerPool struct {
+	engine *Engine
+	config WorkerConfig
+	wg     sync.WaitGroup
+}
+
+// NewWorkerPool creates a new WorkerPool running events on engine
+func NewWorkerPool(engine *Engine, config WorkerConfig) *WorkerPool {
+	if config.ID == "" {
+		config.ID = uuid.New().String()
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.Lease <= 0 {
+		config.Lease = DefaultWorkerLease
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = config.Lease / 3
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultWorkerPollInterval
+	}
+	return &WorkerPool{engine: engine, config: config}
+}
+
+// Start runs the workers until the context is cancelled. Events a worker is
+// running when that happens are left for the next worker to pick up.
+func (p *WorkerPool) Start(ctx context.Context) {
+	for i := 0; i < p.config.Workers; i++ {
+		owner := fmt.Sprintf("%s/%d", p.config.ID, i)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx, owner)
+		}()
+	}
+}
+
+// Wait blocks until the workers have stopped
+func (p *WorkerPool) Wait() {
+	p.wg.Wait()
+}
+
+// work claims and runs events until the context is cancelled
+func (p *WorkerPool) work(ctx context.Context, owner string) {
+	for {
+		claimed, err := p.runNext(ctx, owner)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("CTE worker %s: %v", owner, err)
+		}
+		if claimed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.PollInterval):
+		}
+	}
+}
+
+// runNext claims one event and runs it, reporting whether there was one
+func (p *WorkerPool) runNext(ctx context.Context, owner string) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	event, err := p.engine.eventStore.ClaimEvent(ctx, owner, p.config.Lease)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event: %w", err)
+	}
+	if event == nil {
+		return false, nil
+	}
+	return true, p.run(ctx, owner, event)
+}
+
+// run executes or compensates a claimed event, renewing the lease until it is
+// done. Losing the lease cancels the run. An event whose compensation is
+// incomplete keeps its lease until it lapses, so it is retried by recovery.
+func (p *WorkerPool) run(ctx context.Context, owner string, event *Event) error {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		p.heartbeat(runCtx, cancel, owner, event.ID)
+	}()
+
+	var err error
+	switch event.State {
+	case EventStateExecuting:
+		err = p.engine.executeEvent(runCtx, event.ID)
+	case EventStateRollingBack:
+		err = p.engine.compensateEvent(runCtx, event.ID)
+	default:
+		err = fmt.Errorf("%w: claimed event %s in state %s", ErrInvalidEventState, event.ID, event.State)
+	}
+
+	cancel(nil)
+	<-heartbeatDone
+	if errors.Is(err, ErrCompensationIncomplete) {
+		// Keep the lease so that the event is not claimed again right away;
+		// once it lapses the recovery scanner queues the event again
+		return err
+	}
+	if releaseErr := p.engine.eventStore.ReleaseLease(context.WithoutCancel(ctx), event.ID, owner); releaseErr != nil {
+		log.Printf("CTE worker %s: failed to release lease on event %s: %v", owner, event.ID, releaseErr)
+	}
+	return err
+}
+
+// heartbeat renews the lease on an event until the context is done. If the
+// lease is lost it cancels the run.
+func (p *WorkerPool) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, owner, eventID string) {
+	ticker := time.NewTicker(p.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := p.engine.eventStore.RenewLease(ctx, eventID, owner, p.config.Lease)
+			if errors.Is(err, ErrLeaseLost) {
+				cancel(fmt.Errorf("%w: %s", ErrLeaseLost, eventID))
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				// The lease still has time left; try again on the next beat
+				log.Printf("CTE worker %s: failed to renew lease on event %s: %v", owner, eventID, err)
+			}
+		}
+	}
+}
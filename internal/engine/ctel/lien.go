@@ -3,6 +3,8 @@ package ctel
 import (
 	"context"
 	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 )
 
 // LienState represents the state of a CTEL lien
@@ -30,7 +32,7 @@ type Lien struct {
 	// AccountID is the ID of the account the lien is placed on
 	AccountID string `json:"account_id"`
 	// Amount is the amount of funds reserved by the lien
-	Amount float64 `json:"amount"`
+	Amount money.Money `json:"amount"`
 	// Currency is the currency of the lien amount
 	Currency string `json:"currency"`
 	// State is the current state of the lien
@@ -52,7 +54,7 @@ type ILienManager interface {
 		ctx context.Context,
 		eventID string,
 		accountID string,
-		amount float64,
+		amount money.Money,
 		currency string,
 		expiresAt time.Time,
 		metadata map[string]interface{},
@@ -76,13 +78,16 @@ type ILienManager interface {
 	// ExpireLien marks an expired lien as expired
 	ExpireLien(ctx context.Context, id string) error
 
+	// ExpireEventLiens expires the pending and active liens of a CTE event
+	ExpireEventLiens(ctx context.Context, eventID string) error
+
 	// GetAvailableBalance calculates the available balance for an account,
 	// taking into account active liens within the context of a CTE event
 	GetAvailableBalance(
 		ctx context.Context,
 		eventID string,
 		accountID string,
-	) (float64, error)
+	) (money.Money, error)
 }
 
 // LienStore persists the state of CTE-liens
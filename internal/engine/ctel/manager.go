@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/google/uuid"
 )
 
@@ -35,7 +36,7 @@ func NewLienManager(store LienStore, accountService AccountService) *LienManager
 // AccountService defines the interface for account operations needed by the LienManager
 type AccountService interface {
 	// GetAvailableBalance returns the available balance for an account
-	GetAvailableBalance(ctx context.Context, accountID string) (float64, error)
+	GetAvailableBalance(ctx context.Context, accountID string) (money.Money, error)
 }
 
 // CreateLien creates a new lien for a CTE event
@@ -43,7 +44,7 @@ func (m *LienManager) CreateLien(
 	ctx context.Context,
 	eventID string,
 	accountID string,
-	amount float64,
+	amount money.Money,
 	currency string,
 	expiresAt time.Time,
 	metadata map[string]interface{},
@@ -55,12 +56,16 @@ func (m *LienManager) CreateLien(
 	if accountID == "" {
 		return nil, errors.New("account ID is required")
 	}
-	if amount <= 0 {
+	if !amount.IsPositive() {
 		return nil, errors.New("amount must be greater than zero")
 	}
 	if currency == "" {
 		return nil, errors.New("currency is required")
 	}
+	if !amount.SameCurrency(money.Zero(currency)) {
+		return nil, fmt.Errorf("amount is in %s, not %s", amount.Currency(), currency)
+	}
+	amount = amount.WithCurrency(currency)
 	if expiresAt.Before(time.Now()) {
 		return nil, errors.New("expiration time must be in the future")
 	}
@@ -78,16 +83,22 @@ func (m *LienManager) CreateLien(
 	}
 
 	// Calculate total reserved amount
-	var reservedAmount float64
+	reservedAmount := money.Zero(currency)
 	for _, lien := range activeLiens {
 		if lien.State == LienStateActive || lien.State == LienStatePending {
-			reservedAmount += lien.Amount
+			if reservedAmount, err = reservedAmount.Add(lien.Amount); err != nil {
+				return nil, fmt.Errorf("failed to total active liens: %w", err)
+			}
 		}
 	}
 
 	// Check if there are sufficient available funds
-	if available-reservedAmount < amount {
-		return nil, fmt.Errorf("%w: available=%.2f, requested=%.2f, reserved=%.2f",
+	unreserved, err := available.Sub(reservedAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute unreserved funds: %w", err)
+	}
+	if unreserved.Cmp(amount) < 0 {
+		return nil, fmt.Errorf("%w: available=%s, requested=%s, reserved=%s",
 			ErrInsufficientFunds, available, amount, reservedAmount)
 	}
 
@@ -231,27 +242,50 @@ func (m *LienManager) ExpireLien(ctx context.Context, id string) error {
 	return nil
 }
 
+// ExpireEventLiens expires the pending and active liens of a CTE event, such
+// as one that timed out. Liens already released or expired are left as they
+// are, so it can be called more than once.
+func (m *LienManager) ExpireEventLiens(ctx context.Context, eventID string) error {
+	liens, err := m.GetLiensByEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	for _, lien := range liens {
+		if lien.State != LienStatePending && lien.State != LienStateActive {
+			continue
+		}
+		lien.State = LienStateExpired
+		lien.UpdatedAt = time.Now()
+		if err := m.store.UpdateLien(ctx, lien); err != nil {
+			return fmt.Errorf("failed to expire lien %s: %w", lien.ID, err)
+		}
+	}
+
+	return nil
+}
+
 // GetAvailableBalance calculates the available balance for an account,
 // taking into account active liens within the context of a CTE event
 func (m *LienManager) GetAvailableBalance(
 	ctx context.Context,
 	eventID string,
 	accountID string,
-) (float64, error) {
+) (money.Money, error) {
 	// Get the current available balance from the account service
 	available, err := m.accountService.GetAvailableBalance(ctx, accountID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get available balance: %w", err)
+		return money.Money{}, fmt.Errorf("failed to get available balance: %w", err)
 	}
 
 	// Get all active liens for this account
 	activeLiens, err := m.store.GetLiensByAccount(ctx, accountID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get active liens: %w", err)
+		return money.Money{}, fmt.Errorf("failed to get active liens: %w", err)
 	}
 
 	// Calculate total reserved amount from other events
-	var reservedAmount float64
+	reservedAmount := money.Zero(available.Currency())
 	for _, lien := range activeLiens {
 		// Skip liens from the current event (they're already considered in the available balance)
 		if lien.EventID == eventID {
@@ -259,10 +293,12 @@ func (m *LienManager) GetAvailableBalance(
 		}
 
 		if lien.State == LienStateActive || lien.State == LienStatePending {
-			reservedAmount += lien.Amount
+			if reservedAmount, err = reservedAmount.Add(lien.Amount); err != nil {
+				return money.Money{}, fmt.Errorf("failed to total active liens: %w", err)
+			}
 		}
 	}
 
 	// The available balance is the total available minus reserved amounts from other events
-	return available - reservedAmount, nil
+	return available.Sub(reservedAmount)
 }
@@ -71,12 +71,12 @@ func (e *BatchOperationExecutor) Execute(ctx context.Context, tx *cte.Transactio
 	// Parse the batch operation payload
 	payloadBytes, err := json.Marshal(tx.Payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to marshal payload: %w", err))
 	}
 
 	var payload BatchOperationPayload
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal batch operation payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to unmarshal batch operation payload: %w", err))
 	}
 
 	// Generate a batch ID if not provided
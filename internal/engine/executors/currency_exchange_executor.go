@@ -3,85 +3,88 @@ package executors
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/cte"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/ctel"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
-	"gorm.io/gorm"
 )
 
 // CurrencyExchangePayload defines the structure for currency exchange transaction payload
 type CurrencyExchangePayload struct {
-	SourceAccountID      string  `json:"source_account_id"`
-	SourceCurrency       string  `json:"source_currency"`
-	SourceAmount         float64 `json:"source_amount"`
-	DestinationAccountID string  `json:"destination_account_id"`
-	DestinationCurrency  string  `json:"destination_currency"`
-	ExchangeRate         float64 `json:"exchange_rate"`
-	Reference            string  `json:"reference,omitempty"`
-	FeeAccountID         string  `json:"fee_account_id,omitempty"`
-	FeeAmount            float64 `json:"fee_amount,omitempty"`
-	FeeCurrency          string  `json:"fee_currency,omitempty"`
+	SourceAccountID      string      `json:"source_account_id"`
+	SourceCurrency       string      `json:"source_currency"`
+	SourceAmount         money.Money `json:"source_amount"`
+	DestinationAccountID string      `json:"destination_account_id"`
+	DestinationCurrency  string      `json:"destination_currency"`
+	QuoteID              string      `json:"quote_id"` // FX quote fixing the rate and fee; see POST /api/v1/fx/quotes
+	Reference            string      `json:"reference,omitempty"`
 }
 
 // CurrencyExchangeResult represents the result of a currency exchange transaction
 type CurrencyExchangeResult struct {
-	ID                   string    `json:"id"`
-	Status               string    `json:"status"`
-	SourceAccountID      string    `json:"source_account_id"`
-	DestinationAccountID string    `json:"destination_account_id"`
-	SourceAmount         float64   `json:"source_amount"`
-	DestinationAmount    float64   `json:"destination_amount"`
-	ExchangeRate         float64   `json:"exchange_rate"`
-	FeeAmount            float64   `json:"fee_amount"`
-	ProcessedAt          time.Time `json:"processed_at"`
-	Error                string    `json:"error,omitempty"`
+	ID                   string      `json:"id"`
+	TransactionID        string      `json:"transaction_id,omitempty"`
+	FeeTransactionID     string      `json:"fee_transaction_id,omitempty"` // Only set by exchanges that posted their fee separately
+	Status               string      `json:"status"`
+	QuoteID              string      `json:"quote_id"`
+	SourceAccountID      string      `json:"source_account_id"`
+	DestinationAccountID string      `json:"destination_account_id"`
+	SourceAmount         money.Money `json:"source_amount"`
+	DestinationAmount    money.Money `json:"destination_amount"`
+	ExchangeRate         money.Rate  `json:"exchange_rate"`
+	FeeAmount            money.Money `json:"fee_amount"`
+	ProcessedAt          time.Time   `json:"processed_at"`
+	Error                string      `json:"error,omitempty"`
 }
 
 // CurrencyExchangeExecutor handles currency exchange transactions
 type CurrencyExchangeExecutor struct {
-	txService     service.TransactionService
-	accountRepo   repository.AccountRepository
-	exchangeSvc   service.ExchangeRateService
-	db            *gorm.DB
-	lienManager   ctel.LienManager
+	accountRepo    repository.AccountRepository
+	quoteSvc       service.FXQuoteService
+	lienManager    ctel.LienManager
 	transactionSvc service.TransactionService
 }
 
 // NewCurrencyExchangeExecutor creates a new currency exchange executor
 func NewCurrencyExchangeExecutor(
-	db *gorm.DB,
 	accountRepo repository.AccountRepository,
 	transactionRepo repository.TransactionRepository,
 	transactionSvc service.TransactionService,
-	rateSvc service.ExchangeRateService,
+	quoteSvc service.FXQuoteService,
 	lienManager ctel.LienManager,
 ) *CurrencyExchangeExecutor {
 	return &CurrencyExchangeExecutor{
-		db:            db,
-		accountRepo:   accountRepo,
-		transactionSvc:  transactionSvc,
-		exchangeSvc:   rateSvc,
-		lienManager:   lienManager,
+		accountRepo:    accountRepo,
+		transactionSvc: transactionSvc,
+		quoteSvc:       quoteSvc,
+		lienManager:    lienManager,
 	}
 }
 
 // Execute performs a currency exchange between two accounts
 func (e *CurrencyExchangeExecutor) Execute(ctx context.Context, tx *cte.Transaction) error {
+	// An earlier attempt may have posted the exchange, using up its quote,
+	// without recording it
+	if posted, err := e.Resolve(ctx, tx); err != nil || posted {
+		return err
+	}
+
 	var err error
 
 	// Parse the payload
 	payloadBytes, err := json.Marshal(tx.Payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to marshal payload: %w", err))
 	}
 
 	var payload CurrencyExchangePayload
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to unmarshal payload: %w", err))
 	}
 
 	// Set default destination account if not provided
@@ -91,114 +94,133 @@ func (e *CurrencyExchangeExecutor) Execute(ctx context.Context, tx *cte.Transact
 
 	// Validate payload
 	if err := validateCurrencyExchangePayload(&payload); err != nil {
-		return fmt.Errorf("invalid payload: %w", err)
+		return cte.Permanent(fmt.Errorf("invalid payload: %w", err))
 	}
 
-	// Start a database transaction
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
-	}
-
-	// Deferred rollback in case of panic or error
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-			panic(r)
-		} else if err != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// Get the source account
-	sourceAccount, err := e.accountRepo.GetAccountByID(ctx, payload.SourceAccountID)
+	sourceAccount, err := getPostableAccount(ctx, e.accountRepo, payload.SourceAccountID, true, false)
 	if err != nil {
-		return fmt.Errorf("failed to get source account: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to get source account: %w", err))
 	}
 
 	// Get the destination account
-	destAccount, err := e.accountRepo.GetAccountByID(ctx, payload.DestinationAccountID)
+	destAccount, err := getPostableAccount(ctx, e.accountRepo, payload.DestinationAccountID, false, true)
 	if err != nil {
-		return fmt.Errorf("failed to get destination account: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to get destination account: %w", err))
 	}
 
 	// Check if accounts support the specified currencies
 	if !accountSupportsCurrency(sourceAccount, payload.SourceCurrency) {
-		return fmt.Errorf("source account does not support currency %s", payload.SourceCurrency)
+		return cte.Permanent(fmt.Errorf("source account does not support currency %s", payload.SourceCurrency))
 	}
 
 	if !accountSupportsCurrency(destAccount, payload.DestinationCurrency) {
-		return fmt.Errorf("destination account does not support currency %s", payload.DestinationCurrency)
+		return cte.Permanent(fmt.Errorf("destination account does not support currency %s", payload.DestinationCurrency))
 	}
 
-	// Calculate destination amount
-	destinationAmount := payload.SourceAmount * payload.ExchangeRate
-
 	// Create exchange request
 	exchangeReq := service.ExchangeRequest{
 		SourceAccountID:      payload.SourceAccountID,
 		SourceCurrency:       payload.SourceCurrency,
 		SourceAmount:         payload.SourceAmount,
 		DestinationAccountID: payload.DestinationAccountID,
-		DestinationAmount:    destinationAmount,
 		DestinationCurrency:  payload.DestinationCurrency,
-		ExchangeRate:         payload.ExchangeRate,
 		Reference:            payload.Reference,
+		ReferenceID:          tx.ID,
+		QuoteID:              payload.QuoteID,
 	}
 
-	// Process the exchange transaction
-	if _, err := e.transactionSvc.ProcessExchange(ctx, exchangeReq); err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to process exchange: %w", err)
-	}
-
-	// Process fee if applicable
-	if payload.FeeAmount > 0 {
-		_, err = e.txService.ProcessFee(ctx, service.FeeRequest{
-			AccountID: payload.SourceAccountID,
-			Amount:    payload.FeeAmount,
-			Currency:  payload.SourceCurrency,
-			Reference: fmt.Sprintf("Exchange fee for %s", payload.Reference),
-		})
-
-		if err != nil {
-			dbTx.Rollback()
-			return fmt.Errorf("failed to process fee: %w", err)
-		}
+	// The quote fixes the rate, destination amount and fee; the posting uses it
+	// up, charges the fee and books the spread against the mid rate as
+	// realized gain, all in one entry
+	quote, err := e.quoteSvc.GetUsableQuote(ctx, payload.QuoteID, exchangeReq)
+	if err != nil {
+		return classifyPostingError(fmt.Errorf("invalid quote: %w", err))
 	}
+	exchangeReq.ExchangeRate = quote.Rate
+	exchangeReq.DestinationAmount = quote.DestinationAmount
+	exchangeReq.MidRate = &quote.MidRate
+	exchangeReq.Fee = &service.AssessedFee{Amount: quote.Fee, ScheduleID: quote.FeeScheduleID}
 
-	// Commit the transaction
-	if err := dbTx.Commit().Error; err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Process the exchange transaction
+	exchangeTx, err := e.transactionSvc.ProcessExchange(ctx, exchangeReq)
+	if err != nil {
+		return classifyPostingError(fmt.Errorf("failed to process exchange: %w", err))
 	}
 
 	// Update the transaction result
 	txResult := &CurrencyExchangeResult{
 		ID:                   tx.ID,
+		TransactionID:        exchangeTx.ID,
 		Status:               "completed",
+		QuoteID:              quote.ID,
 		SourceAccountID:      payload.SourceAccountID,
 		DestinationAccountID: payload.DestinationAccountID,
 		SourceAmount:         payload.SourceAmount,
-		DestinationAmount:    destinationAmount,
-		ExchangeRate:         payload.ExchangeRate,
-		FeeAmount:            payload.FeeAmount,
+		DestinationAmount:    quote.DestinationAmount,
+		ExchangeRate:         quote.Rate,
+		FeeAmount:            quote.Fee,
 		ProcessedAt:          time.Now(),
 	}
 
 	// Update the transaction result
-	resultJSON, err := json.Marshal(txResult)
+	resultMap, err := toResultMap(txResult)
 	if err != nil {
 		return fmt.Errorf("failed to marshal transaction result: %w", err)
 	}
-	tx.Result = resultJSON
+	tx.Result = resultMap
 
-	// Commit the transaction
-	if err := dbTx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	return nil
+}
+
+// Resolve reports whether a currency exchange was posted under tx's ID and,
+// if it was, records the posted transaction as Execute does. The quote was
+// used up by the posting, so its terms are taken from the transaction.
+func (e *CurrencyExchangeExecutor) Resolve(ctx context.Context, tx *cte.Transaction) (bool, error) {
+	exchangeTx, err := e.transactionSvc.GetTransactionByReference(ctx, tx.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up posted exchange: %w", err)
+	}
+	if exchangeTx == nil {
+		return false, nil
 	}
 
-	return nil
+	var payload CurrencyExchangePayload
+	if err := decodePayload(tx.Payload, &payload); err != nil {
+		return false, err
+	}
+	if payload.DestinationAccountID == "" {
+		payload.DestinationAccountID = payload.SourceAccountID
+	}
+
+	txResult := &CurrencyExchangeResult{
+		ID:                   tx.ID,
+		TransactionID:        exchangeTx.ID,
+		Status:               "completed",
+		QuoteID:              payload.QuoteID,
+		SourceAccountID:      payload.SourceAccountID,
+		DestinationAccountID: payload.DestinationAccountID,
+		SourceAmount:         exchangeTx.Amount,
+		FeeAmount:            exchangeTx.Fee,
+		ProcessedAt:          time.Now(),
+	}
+	if rate, ok := exchangeTx.Metadata["exchange_rate"].(string); ok {
+		if txResult.ExchangeRate, err = money.ParseRate(rate); err != nil {
+			return false, fmt.Errorf("invalid exchange rate on exchange %s: %w", exchangeTx.ID, err)
+		}
+	}
+	if amount, ok := exchangeTx.Metadata["destination_amount"].(string); ok {
+		if txResult.DestinationAmount, err = money.Parse(amount, payload.DestinationCurrency); err != nil {
+			return false, fmt.Errorf("invalid destination amount on exchange %s: %w", exchangeTx.ID, err)
+		}
+	}
+
+	resultMap, err := toResultMap(txResult)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal transaction result: %w", err)
+	}
+	tx.Result = resultMap
+	return true, nil
 }
 
 // Compensate handles the rollback of a currency exchange
@@ -226,37 +248,17 @@ func (e *CurrencyExchangeExecutor) Compensate(ctx context.Context, tx *cte.Trans
 		}
 	}
 
-	// Start a database transaction
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
-	}
-
-	// Store the error that might occur during compensation
-	var compErr error
-
-	// Use a closure to handle the deferred rollback
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-			panic(r)
-		} else if compErr != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// Reverse the exchange transaction if we have a transaction ID
-	if txResult.ID != "" {
-		// Use the transaction ID directly as per the TransactionService interface
-		if err := e.transactionSvc.ReverseExchange(ctx, txResult.ID); err != nil {
-			compErr = fmt.Errorf("failed to reverse exchange transaction: %w", err)
-			return compErr
+	if txResult.TransactionID != "" {
+		if err := e.transactionSvc.ReverseExchange(ctx, txResult.TransactionID); err != nil {
+			return fmt.Errorf("failed to reverse exchange transaction: %w", err)
 		}
 
 		// Reverse the fee transaction if it exists
-		if payload.FeeAmount > 0 {
-			// Since we don't have the fee transaction ID in the result, we'll skip this for now
-			// In a real implementation, we would store the fee transaction ID in the result
+		if txResult.FeeTransactionID != "" {
+			if err := e.transactionSvc.ReverseFee(ctx, txResult.FeeTransactionID); err != nil {
+				return fmt.Errorf("failed to reverse exchange fee transaction: %w", err)
+			}
 		}
 	}
 
@@ -268,15 +270,37 @@ func (e *CurrencyExchangeExecutor) Compensate(ctx context.Context, tx *cte.Trans
 		// TODO: Implement proper lien release logic when LienManager interface is updated
 	}
 
-	// Commit the transaction if no errors occurred
-	if compErr == nil {
-		if err := dbTx.Commit().Error; err != nil {
-			compErr = fmt.Errorf("failed to commit transaction: %w", err)
-			return compErr
-		}
+	return nil
+}
+
+// Validate checks a currency exchange before its event is started: the
+// payload, both accounts and their currencies, and that the quote can still
+// be used for the exchange
+func (e *CurrencyExchangeExecutor) Validate(ctx context.Context, tx *cte.Transaction) error {
+	var payload CurrencyExchangePayload
+	if err := decodePayload(tx.Payload, &payload); err != nil {
+		return err
+	}
+	if err := validateCurrencyExchangePayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
 	}
 
-	return compErr
+	_, sourceErr := checkAccount(ctx, e.accountRepo, "source", payload.SourceAccountID, payload.SourceCurrency, true, false)
+	_, destErr := checkAccount(ctx, e.accountRepo, "destination", payload.DestinationAccountID, payload.DestinationCurrency, false, true)
+	_, quoteErr := e.quoteSvc.GetUsableQuote(ctx, payload.QuoteID, service.ExchangeRequest{
+		SourceAccountID:      payload.SourceAccountID,
+		SourceCurrency:       payload.SourceCurrency,
+		SourceAmount:         payload.SourceAmount,
+		DestinationAccountID: payload.DestinationAccountID,
+		DestinationCurrency:  payload.DestinationCurrency,
+		Reference:            payload.Reference,
+		ReferenceID:          tx.ID,
+		QuoteID:              payload.QuoteID,
+	})
+	if quoteErr != nil {
+		quoteErr = fmt.Errorf("invalid quote: %w", quoteErr)
+	}
+	return errors.Join(sourceErr, destErr, quoteErr)
 }
 
 // validateCurrencyExchangePayload validates the currency exchange payload
@@ -293,7 +317,7 @@ func validateCurrencyExchangePayload(payload *CurrencyExchangePayload) error {
 		return fmt.Errorf("source_currency is required")
 	}
 
-	if payload.SourceAmount <= 0 {
+	if !payload.SourceAmount.IsPositive() {
 		return fmt.Errorf("source_amount must be greater than 0")
 	}
 
@@ -305,18 +329,8 @@ func validateCurrencyExchangePayload(payload *CurrencyExchangePayload) error {
 		return fmt.Errorf("destination_currency is required")
 	}
 
-	if payload.ExchangeRate <= 0 {
-		return fmt.Errorf("exchange_rate must be greater than 0")
-	}
-
-	// If fee is provided, validate fee fields
-	if payload.FeeAmount > 0 {
-		if payload.FeeAccountID == "" {
-			return fmt.Errorf("fee_account_id is required when fee_amount is provided")
-		}
-		if payload.FeeCurrency == "" {
-			return fmt.Errorf("fee_currency is required when fee_amount is provided")
-		}
+	if payload.QuoteID == "" {
+		return fmt.Errorf("quote_id is required")
 	}
 
 	return nil
@@ -9,15 +9,15 @@ import (
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/ctel"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
-	"gorm.io/gorm"
 )
 
 // ExecutorFactory manages the creation and retrieval of transaction executors
 type ExecutorFactory struct {
-	db              *gorm.DB
 	accountRepo     repository.AccountRepository
 	transactionRepo repository.TransactionRepository
 	transactionSvc  service.TransactionService
+	quoteSvc        service.FXQuoteService
+	feeSvc          service.FeeService
 	lienManager     ctel.LienManager
 	executors       map[string]cte.TransactionExecutor
 	mu             sync.RWMutex
@@ -25,17 +25,19 @@ type ExecutorFactory struct {
 
 // NewExecutorFactory creates a new executor factory
 func NewExecutorFactory(
-	db *gorm.DB,
 	accountRepo repository.AccountRepository,
 	transactionRepo repository.TransactionRepository,
 	transactionSvc service.TransactionService,
+	quoteSvc service.FXQuoteService,
+	feeSvc service.FeeService,
 	lienManager ctel.LienManager,
 ) *ExecutorFactory {
 	return &ExecutorFactory{
-		db:             db,
 		accountRepo:    accountRepo,
 		transactionRepo: transactionRepo,
 		transactionSvc:  transactionSvc,
+		quoteSvc:        quoteSvc,
+		feeSvc:          feeSvc,
 		lienManager:    lienManager,
 		executors:      make(map[string]cte.TransactionExecutor),
 	}
@@ -68,27 +70,20 @@ func (f *ExecutorFactory) GetAllExecutors() map[string]cte.TransactionExecutor {
 
 // InitializeDefaultExecutors registers all default transaction executors
 func (f *ExecutorFactory) InitializeDefaultExecutors(ctx context.Context) error {
-	// Get exchange rate service if available
-	var rateSvc service.ExchangeRateService
-	if svc, ok := f.transactionSvc.(interface{ GetExchangeRateService() service.ExchangeRateService }); ok {
-		rateSvc = svc.GetExchangeRateService()
-	}
-
 	// Register batch operation executor first (it will be used by other executors)
 	batchOpExecutor := NewBatchOperationExecutor(f)
 	f.RegisterExecutor("batch.operation", batchOpExecutor)
 
 	// Register wallet transfer executor
 	transferExecutor := NewWalletTransferExecutor(
-		f.db,
 		f.accountRepo,
 		f.transactionSvc,
+		f.feeSvc,
 	)
 	f.RegisterExecutor("wallet.transfer", transferExecutor)
 
 	// Register wallet deposit executor
 	walletDepositExecutor := NewWalletDepositExecutor(
-		f.db,
 		f.accountRepo,
 		f.transactionSvc,
 	)
@@ -96,21 +91,20 @@ func (f *ExecutorFactory) InitializeDefaultExecutors(ctx context.Context) error
 
 	// Register wallet withdrawal executor
 	walletWithdrawalExecutor := NewWalletWithdrawalExecutor(
-		f.db,
 		f.accountRepo,
 		f.transactionSvc,
+		f.feeSvc,
 		f.lienManager,
 	)
 	f.RegisterExecutor("wallet.withdrawal", walletWithdrawalExecutor)
 
-	// Register currency exchange executor if rate service is available
-	if rateSvc != nil {
+	// Register currency exchange executor if quotes are available
+	if f.quoteSvc != nil {
 		currencyExchangeExecutor := NewCurrencyExchangeExecutor(
-			f.db,
-			f.accountRepo,
+				f.accountRepo,
 			f.transactionRepo,
 			f.transactionSvc,
-			rateSvc,
+			f.quoteSvc,
 			f.lienManager,
 		)
 		f.RegisterExecutor("wallet.exchange", currencyExchangeExecutor)
@@ -1,11 +1,133 @@
 package executors
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/cte"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/ctel"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
 )
 
+// getPostableAccount retrieves an account and checks that it is a leaf of the
+// chart of accounts and that its status allows the debit and/or credit the
+// executor is about to post. A status rejection is returned as a
+// *models.AccountStatusError.
+func getPostableAccount(ctx context.Context, accountRepo repository.AccountRepository, accountID string, debit, credit bool) (*models.Account, error) {
+	account, err := accountRepo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: %s", service.ErrAccountNotFound, accountID)
+	}
+	hasChildren, err := accountRepo.HasChildAccounts(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if hasChildren {
+		return nil, fmt.Errorf("%w: account %s has child accounts", service.ErrNonLeafAccount, accountID)
+	}
+	if err := account.CheckPosting(debit, credit); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// rejectedPostingErrors are the errors of a posting that the ledger turned
+// down on its merits; attempting it again cannot succeed
+var rejectedPostingErrors = []error{
+	service.ErrAccountNotFound,
+	models.ErrPostingNotAllowed,
+	service.ErrNonLeafAccount,
+	service.ErrUnbalancedEntry,
+	service.ErrLineCurrencyMismatch,
+	service.ErrCrossCurrencyEntry,
+	money.ErrCurrencyMismatch,
+	models.ErrPeriodClosed,
+	repository.ErrInsufficientFunds,
+	ctel.ErrInsufficientFunds,
+	repository.ErrQuoteNotFound,
+	models.ErrQuoteExpired,
+	models.ErrQuoteConsumed,
+	service.ErrQuoteMismatch,
+}
+
+// classifyPostingError marks err as cte.Permanent if the ledger rejected the
+// posting, so it is not retried. Other errors, such as those of the database,
+// are left retryable.
+func classifyPostingError(err error) error {
+	for _, rejected := range rejectedPostingErrors {
+		if errors.Is(err, rejected) {
+			return cte.Permanent(err)
+		}
+	}
+	return err
+}
+
+// checkAccount checks that the role account, such as "source", exists and
+// can take a posting in currency
+func checkAccount(ctx context.Context, accountRepo repository.AccountRepository, role, accountID, currency string, debit, credit bool) (*models.Account, error) {
+	account, err := getPostableAccount(ctx, accountRepo, accountID, debit, credit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s account: %w", role, err)
+	}
+	if !accountSupportsCurrency(account, currency) {
+		return nil, fmt.Errorf("%s account does not support currency %s", role, currency)
+	}
+	return account, nil
+}
+
+// decodePayload decodes the generic payload of a cte.Transaction into v
+func decodePayload(payload interface{}, v interface{}) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	if err := json.Unmarshal(payloadBytes, v); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	return nil
+}
+
 // accountSupportsCurrency checks if an account supports a specific currency
 func accountSupportsCurrency(account *models.Account, currency string) bool {
 	// Check if the account's currency matches the requested currency
 	return account.Currency == currency
 }
+
+// assessFee prices the fee an account is charged on a transaction. It returns
+// nil if feeSvc is nil or no fee schedule applies.
+func assessFee(ctx context.Context, feeSvc service.FeeService, txType string, account *models.Account, amount money.Money) (*service.AssessedFee, error) {
+	if feeSvc == nil {
+		return nil, nil
+	}
+	fee, err := feeSvc.AssessFee(ctx, service.FeeAssessmentRequest{
+		TransactionType: txType,
+		AccountTier:     account.Tier,
+		Amount:          amount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to assess %s fee: %w", txType, err)
+	}
+	return fee, nil
+}
+
+// toResultMap converts an executor result struct into the generic map stored on cte.Transaction.Result
+func toResultMap(result interface{}) (map[string]interface{}, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultMap map[string]interface{}
+	if err := json.Unmarshal(resultBytes, &resultMap); err != nil {
+		return nil, err
+	}
+	return resultMap, nil
+}
@@ -7,18 +7,19 @@ import (
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/cte"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
-	"gorm.io/gorm"
 )
 
 // WalletDepositPayload defines the structure for wallet deposit transaction payload
 type WalletDepositPayload struct {
-	AccountID string  `json:"account_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Reference string  `json:"reference,omitempty"`
-	Source    string  `json:"source,omitempty"`
+	AccountID string      `json:"account_id"`
+	Amount    money.Money `json:"amount"`
+	Currency  string      `json:"currency"`
+	Reference string      `json:"reference,omitempty"`
+	Source    string      `json:"source,omitempty"`
 }
 
 // WalletDepositResult defines the structure for wallet deposit transaction result
@@ -30,19 +31,16 @@ type WalletDepositResult struct {
 
 // WalletDepositExecutor handles wallet deposit transactions
 type WalletDepositExecutor struct {
-	db             *gorm.DB
 	accountRepo    repository.AccountRepository
 	transactionSvc service.TransactionService
 }
 
 // NewWalletDepositExecutor creates a new wallet deposit executor
 func NewWalletDepositExecutor(
-	db *gorm.DB,
 	accountRepo repository.AccountRepository,
 	transactionSvc service.TransactionService,
 ) *WalletDepositExecutor {
 	return &WalletDepositExecutor{
-		db:             db,
 		accountRepo:    accountRepo,
 		transactionSvc: transactionSvc,
 	}
@@ -50,54 +48,45 @@ func NewWalletDepositExecutor(
 
 // Execute processes a wallet deposit transaction
 func (e *WalletDepositExecutor) Execute(ctx context.Context, tx *cte.Transaction) error {
+	// An earlier attempt may have posted the deposit without recording it
+	if posted, err := e.Resolve(ctx, tx); err != nil || posted {
+		return err
+	}
+
 	// Parse the payload
 	var payload WalletDepositPayload
 	payloadBytes, err := json.Marshal(tx.Payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to marshal payload: %w", err))
 	}
 
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to unmarshal payload: %w", err))
 	}
 
 	// Validate the payload
 	if err := validateWalletDepositPayload(&payload); err != nil {
-		return fmt.Errorf("invalid payload: %w", err)
+		return cte.Permanent(fmt.Errorf("invalid payload: %w", err))
 	}
 
-	// Begin a database transaction
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
-	}
-
-	// Defer a rollback in case anything fails
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// Get the account
-	account, err := e.accountRepo.GetAccountByID(ctx, payload.AccountID)
+	account, err := getPostableAccount(ctx, e.accountRepo, payload.AccountID, false, true)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to get account: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to get account: %w", err))
 	}
 
 	// Check if account supports the specified currency
 	if !accountSupportsCurrency(account, payload.Currency) {
-		dbTx.Rollback()
-		return fmt.Errorf("account does not support currency %s", payload.Currency)
+		return cte.Permanent(fmt.Errorf("account does not support currency %s", payload.Currency))
 	}
 
 	// Process the deposit using the transaction service
 	depositReq := service.DepositRequest{
-		AccountID: payload.AccountID,
-		Amount:    payload.Amount,
-		Currency:  payload.Currency,
-		Reference: payload.Reference,
+		AccountID:   payload.AccountID,
+		Amount:      payload.Amount,
+		Currency:    payload.Currency,
+		Reference:   payload.Reference,
+		ReferenceID: tx.ID,
 	}
 
 	// Add source to reference if provided
@@ -109,17 +98,39 @@ func (e *WalletDepositExecutor) Execute(ctx context.Context, tx *cte.Transaction
 		}
 	}
 
-	_, err = e.transactionSvc.ProcessDeposit(ctx, depositReq)
+	transaction, err := e.transactionSvc.ProcessDeposit(ctx, depositReq)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to process deposit: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to process deposit: %w", err))
 	}
 
-	// Commit the transaction
-	if commitErr := dbTx.Commit().Error; commitErr != nil {
-		return fmt.Errorf("failed to commit transaction: %w", commitErr)
+	return setDepositResult(tx, transaction)
+}
+
+// Resolve reports whether a wallet deposit was posted under tx's ID and, if
+// it was, records the posted transaction as Execute does
+func (e *WalletDepositExecutor) Resolve(ctx context.Context, tx *cte.Transaction) (bool, error) {
+	transaction, err := e.transactionSvc.GetTransactionByReference(ctx, tx.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up posted deposit: %w", err)
 	}
+	if transaction == nil {
+		return false, nil
+	}
+	return true, setDepositResult(tx, transaction)
+}
 
+// setDepositResult records the posted transaction so compensation can reverse it
+func setDepositResult(tx *cte.Transaction, transaction *models.Transaction) error {
+	resultMap, err := toResultMap(WalletDepositResult{
+		TransactionID: transaction.ID,
+		Status:        "COMPLETED",
+		ProcessedAt:   time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	tx.Result = resultMap
+	tx.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -154,18 +165,6 @@ func (e *WalletDepositExecutor) Compensate(ctx context.Context, tx *cte.Transact
 	}
 
 	// If we don't have a transaction ID, manually reverse the deposit
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
-	}
-
-	// Defer a rollback in case anything fails
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// Create a withdrawal to reverse the deposit
 	withdrawalReq := service.WithdrawalRequest{
 		AccountID: payload.AccountID,
@@ -186,16 +185,25 @@ func (e *WalletDepositExecutor) Compensate(ctx context.Context, tx *cte.Transact
 	// Process the withdrawal to reverse the deposit
 	_, err = e.transactionSvc.ProcessWithdrawal(ctx, withdrawalReq)
 	if err != nil {
-		dbTx.Rollback()
 		return fmt.Errorf("failed to process withdrawal to reverse deposit: %w", err)
 	}
 
-	// Commit the transaction
-	if commitErr := dbTx.Commit().Error; commitErr != nil {
-		return fmt.Errorf("failed to commit transaction: %w", commitErr)
+	return nil
+}
+
+// Validate checks a wallet deposit before its event is started: the payload,
+// and that the account exists, accepts credits and holds the currency
+func (e *WalletDepositExecutor) Validate(ctx context.Context, tx *cte.Transaction) error {
+	var payload WalletDepositPayload
+	if err := decodePayload(tx.Payload, &payload); err != nil {
+		return err
+	}
+	if err := validateWalletDepositPayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
 	}
 
-	return nil
+	_, err := checkAccount(ctx, e.accountRepo, "deposit", payload.AccountID, payload.Currency, false, true)
+	return err
 }
 
 // validateWalletDepositPayload validates the wallet deposit payload
@@ -204,7 +212,7 @@ func validateWalletDepositPayload(payload *WalletDepositPayload) error {
 		return fmt.Errorf("account ID is required")
 	}
 
-	if payload.Amount <= 0 {
+	if !payload.Amount.IsPositive() {
 		return fmt.Errorf("amount must be greater than zero")
 	}
 
@@ -214,5 +222,3 @@ func validateWalletDepositPayload(payload *WalletDepositPayload) error {
 
 	return nil
 }
-
-
@@ -3,104 +3,103 @@ package executors
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/cte"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
-	"gorm.io/gorm"
 )
 
 // WalletTransferPayload defines the structure for wallet transfer transaction payload
 type WalletTransferPayload struct {
-	SourceAccountID      string  `json:"source_account_id"`
-	DestinationAccountID string  `json:"destination_account_id"`
-	Amount               float64 `json:"amount"`
-	Currency             string  `json:"currency"`
-	Reference            string  `json:"reference,omitempty"`
+	SourceAccountID      string      `json:"source_account_id"`
+	DestinationAccountID string      `json:"destination_account_id"`
+	Amount               money.Money `json:"amount"`
+	Currency             string      `json:"currency"`
+	Reference            string      `json:"reference,omitempty"`
 }
 
 // WalletTransferResult defines the structure for wallet transfer transaction result
 type WalletTransferResult struct {
-	TransactionID string    `json:"transaction_id"`
-	Status        string    `json:"status"`
-	ProcessedAt   time.Time `json:"processed_at"`
+	TransactionID string      `json:"transaction_id"`
+	Status        string      `json:"status"`
+	FeeAmount     money.Money `json:"fee_amount"`
+	FeeScheduleID string      `json:"fee_schedule_id,omitempty"`
+	ProcessedAt   time.Time   `json:"processed_at"`
 }
 
 // WalletTransferExecutor handles wallet transfer transactions
 type WalletTransferExecutor struct {
-	db             *gorm.DB
 	accountRepo    repository.AccountRepository
 	transactionSvc service.TransactionService
+	feeSvc         service.FeeService
 }
 
-// NewWalletTransferExecutor creates a new wallet transfer executor
+// NewWalletTransferExecutor creates a new wallet transfer executor. A nil
+// feeSvc charges no fees.
 func NewWalletTransferExecutor(
-	db *gorm.DB,
 	accountRepo repository.AccountRepository,
 	transactionSvc service.TransactionService,
+	feeSvc service.FeeService,
 ) *WalletTransferExecutor {
 	return &WalletTransferExecutor{
-		db:             db,
 		accountRepo:    accountRepo,
 		transactionSvc: transactionSvc,
+		feeSvc:         feeSvc,
 	}
 }
 
 // Execute processes a wallet transfer transaction
 func (e *WalletTransferExecutor) Execute(ctx context.Context, tx *cte.Transaction) error {
+	// An earlier attempt may have posted the transfer without recording it
+	if posted, err := e.Resolve(ctx, tx); err != nil || posted {
+		return err
+	}
+
 	// Parse the payload
 	var payload WalletTransferPayload
 	payloadBytes, err := json.Marshal(tx.Payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to marshal payload: %w", err))
 	}
 
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to unmarshal payload: %w", err))
 	}
 
 	// Validate the payload
 	if err := validateWalletTransferPayload(&payload); err != nil {
-		return fmt.Errorf("invalid payload: %w", err)
-	}
-
-	// Begin a database transaction
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
+		return cte.Permanent(fmt.Errorf("invalid payload: %w", err))
 	}
 
-	// Defer a rollback in case anything fails
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// Get the source and destination accounts
-	sourceAccount, err := e.accountRepo.GetAccountByID(ctx, payload.SourceAccountID)
+	sourceAccount, err := getPostableAccount(ctx, e.accountRepo, payload.SourceAccountID, true, false)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to get source account: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to get source account: %w", err))
 	}
 
-	destAccount, err := e.accountRepo.GetAccountByID(ctx, payload.DestinationAccountID)
+	destAccount, err := getPostableAccount(ctx, e.accountRepo, payload.DestinationAccountID, false, true)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to get destination account: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to get destination account: %w", err))
 	}
 
 	// Check if accounts support the specified currency
 	if !accountSupportsCurrency(sourceAccount, payload.Currency) {
-		dbTx.Rollback()
-		return fmt.Errorf("source account does not support currency %s", payload.Currency)
+		return cte.Permanent(fmt.Errorf("source account does not support currency %s", payload.Currency))
 	}
 
 	if !accountSupportsCurrency(destAccount, payload.Currency) {
-		dbTx.Rollback()
-		return fmt.Errorf("destination account does not support currency %s", payload.Currency)
+		return cte.Permanent(fmt.Errorf("destination account does not support currency %s", payload.Currency))
+	}
+
+	// The source account's fee schedule prices the fee, posted with the transfer
+	fee, err := assessFee(ctx, e.feeSvc, models.FeeTransactionTransfer, sourceAccount, payload.Amount.WithCurrency(payload.Currency))
+	if err != nil {
+		return err
 	}
 
 	// Process the transfer using the transaction service
@@ -110,19 +109,45 @@ func (e *WalletTransferExecutor) Execute(ctx context.Context, tx *cte.Transactio
 		Amount:               payload.Amount,
 		Currency:             payload.Currency,
 		Reference:            payload.Reference,
+		ReferenceID:          tx.ID,
+		Fee:                  fee,
 	}
 
-	_, err = e.transactionSvc.ProcessTransfer(ctx, transferReq)
+	transaction, err := e.transactionSvc.ProcessTransfer(ctx, transferReq)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to process transfer: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to process transfer: %w", err))
 	}
 
-	// Commit the transaction
-	if commitErr := dbTx.Commit().Error; commitErr != nil {
-		return fmt.Errorf("failed to commit transaction: %w", commitErr)
+	return setTransferResult(tx, transaction)
+}
+
+// Resolve reports whether a wallet transfer was posted under tx's ID and, if
+// it was, records the posted transaction as Execute does
+func (e *WalletTransferExecutor) Resolve(ctx context.Context, tx *cte.Transaction) (bool, error) {
+	transaction, err := e.transactionSvc.GetTransactionByReference(ctx, tx.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up posted transfer: %w", err)
+	}
+	if transaction == nil {
+		return false, nil
 	}
+	return true, setTransferResult(tx, transaction)
+}
 
+// setTransferResult records the posted transaction so compensation can reverse it
+func setTransferResult(tx *cte.Transaction, transaction *models.Transaction) error {
+	resultMap, err := toResultMap(WalletTransferResult{
+		TransactionID: transaction.ID,
+		Status:        "COMPLETED",
+		FeeAmount:     transaction.Fee,
+		FeeScheduleID: transaction.FeeScheduleID,
+		ProcessedAt:   time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	tx.Result = resultMap
+	tx.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -157,18 +182,6 @@ func (e *WalletTransferExecutor) Compensate(ctx context.Context, tx *cte.Transac
 	}
 
 	// If we don't have a transaction ID, manually reverse the transfer
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
-	}
-
-	// Defer a rollback in case anything fails
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// Create a reverse transfer request
 	reverseReq := service.TransferRequest{
 		SourceAccountID:      payload.DestinationAccountID, // Reverse source and target
@@ -181,16 +194,26 @@ func (e *WalletTransferExecutor) Compensate(ctx context.Context, tx *cte.Transac
 	// Process the reverse transfer
 	_, err = e.transactionSvc.ProcessTransfer(ctx, reverseReq)
 	if err != nil {
-		dbTx.Rollback()
 		return fmt.Errorf("failed to process reverse transfer: %w", err)
 	}
 
-	// Commit the transaction
-	if commitErr := dbTx.Commit().Error; commitErr != nil {
-		return fmt.Errorf("failed to commit transaction: %w", commitErr)
+	return nil
+}
+
+// Validate checks a wallet transfer before its event is started: the payload,
+// and that both accounts exist, accept the postings and hold the currency
+func (e *WalletTransferExecutor) Validate(ctx context.Context, tx *cte.Transaction) error {
+	var payload WalletTransferPayload
+	if err := decodePayload(tx.Payload, &payload); err != nil {
+		return err
+	}
+	if err := validateWalletTransferPayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
 	}
 
-	return nil
+	_, sourceErr := checkAccount(ctx, e.accountRepo, "source", payload.SourceAccountID, payload.Currency, true, false)
+	_, destErr := checkAccount(ctx, e.accountRepo, "destination", payload.DestinationAccountID, payload.Currency, false, true)
+	return errors.Join(sourceErr, destErr)
 }
 
 // validateWalletTransferPayload validates the wallet transfer payload
@@ -207,7 +230,7 @@ func validateWalletTransferPayload(payload *WalletTransferPayload) error {
 		return fmt.Errorf("source and destination accounts cannot be the same")
 	}
 
-	if payload.Amount <= 0 {
+	if !payload.Amount.IsPositive() {
 		return fmt.Errorf("amount must be greater than zero")
 	}
 
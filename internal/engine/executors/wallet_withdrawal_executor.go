@@ -8,158 +8,189 @@ import (
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/cte"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/ctel"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
-	"gorm.io/gorm"
 )
 
 // WalletWithdrawalPayload defines the structure for wallet withdrawal transaction payload
 type WalletWithdrawalPayload struct {
-	AccountID string  `json:"account_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Reference string  `json:"reference,omitempty"`
-	Target    string  `json:"target,omitempty"`
+	AccountID string      `json:"account_id"`
+	Amount    money.Money `json:"amount"`
+	Currency  string      `json:"currency"`
+	Reference string      `json:"reference,omitempty"`
+	Target    string      `json:"target,omitempty"`
 }
 
 // WalletWithdrawalResult defines the structure for wallet withdrawal transaction result
 type WalletWithdrawalResult struct {
-	TransactionID string    `json:"transaction_id"`
-	Status        string    `json:"status"`
-	ProcessedAt   time.Time `json:"processed_at"`
+	TransactionID string      `json:"transaction_id"`
+	Status        string      `json:"status"`
+	FeeAmount     money.Money `json:"fee_amount"`
+	FeeScheduleID string      `json:"fee_schedule_id,omitempty"`
+	ProcessedAt   time.Time   `json:"processed_at"`
 }
 
 // WalletWithdrawalExecutor handles wallet withdrawal transactions
 type WalletWithdrawalExecutor struct {
-	db             *gorm.DB
 	accountRepo    repository.AccountRepository
 	transactionSvc service.TransactionService
+	feeSvc         service.FeeService
 	lienManager    ctel.LienManager
 }
 
-// NewWalletWithdrawalExecutor creates a new wallet withdrawal executor
+// NewWalletWithdrawalExecutor creates a new wallet withdrawal executor. A nil
+// feeSvc charges no fees.
 func NewWalletWithdrawalExecutor(
-	db *gorm.DB,
 	accountRepo repository.AccountRepository,
 	transactionSvc service.TransactionService,
+	feeSvc service.FeeService,
 	lienManager ctel.LienManager,
 ) *WalletWithdrawalExecutor {
 	return &WalletWithdrawalExecutor{
-		db:             db,
 		accountRepo:    accountRepo,
 		transactionSvc: transactionSvc,
+		feeSvc:         feeSvc,
 		lienManager:    lienManager,
 	}
 }
 
 // Execute processes a wallet withdrawal transaction
 func (e *WalletWithdrawalExecutor) Execute(ctx context.Context, tx *cte.Transaction) error {
+	// An earlier attempt may have posted the withdrawal without recording it;
+	// its funds must not be reserved again
+	if posted, err := e.Resolve(ctx, tx); err != nil || posted {
+		return err
+	}
+
 	// Parse the payload
 	var payload WalletWithdrawalPayload
 	payloadBytes, err := json.Marshal(tx.Payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to marshal payload: %w", err))
 	}
 
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+		return cte.Permanent(fmt.Errorf("failed to unmarshal payload: %w", err))
 	}
 
 	// Validate the payload
 	if err := validateWalletWithdrawalPayload(&payload); err != nil {
-		return fmt.Errorf("invalid payload: %w", err)
+		return cte.Permanent(fmt.Errorf("invalid payload: %w", err))
 	}
 
-	// Begin a database transaction
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
-	}
-
-	// Defer a rollback in case anything fails
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// Get the account
-	account, err := e.accountRepo.GetAccountByID(ctx, payload.AccountID)
+	account, err := getPostableAccount(ctx, e.accountRepo, payload.AccountID, true, false)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to get account: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to get account: %w", err))
 	}
 
 	// Check if account supports the specified currency
 	if !accountSupportsCurrency(account, payload.Currency) {
-		dbTx.Rollback()
-		return fmt.Errorf("account does not support currency %s", payload.Currency)
+		return cte.Permanent(fmt.Errorf("account does not support currency %s", payload.Currency))
 	}
 
-	// Create a lien to reserve the funds
+	// The account's fee schedule prices the fee, posted with the withdrawal
+	amount := payload.Amount.WithCurrency(payload.Currency)
+	fee, err := assessFee(ctx, e.feeSvc, models.FeeTransactionWithdrawal, account, amount)
+	if err != nil {
+		return err
+	}
+	reserved := amount
+	if fee != nil {
+		if reserved, err = amount.Add(fee.Amount); err != nil {
+			return fmt.Errorf("failed to add fee to withdrawal: %w", err)
+		}
+	}
+
+	// Create a lien to reserve the funds, fee included
 	lien, err := e.lienManager.CreateLien(
 		ctx,
 		tx.EventID,
 		payload.AccountID,
-		payload.Amount,
+		reserved,
 		payload.Currency,
 		time.Now().Add(30*time.Minute), // 30-minute lien expiration
-		nil,
+		map[string]interface{}{"transaction_id": tx.ID},
 	)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to create lien: %w", err)
+		return classifyPostingError(fmt.Errorf("failed to create lien: %w", err))
 	}
 
 	// Create withdrawal request
 	withdrawalReq := service.WithdrawalRequest{
-		AccountID: payload.AccountID,
-		Amount:    payload.Amount,
-		Currency:  payload.Currency,
-		Reference: payload.Reference,
+		AccountID:   payload.AccountID,
+		Amount:      payload.Amount,
+		Currency:    payload.Currency,
+		Reference:   payload.Reference,
+		ReferenceID: tx.ID,
+		Fee:         fee,
 	}
 
 	// Process the withdrawal using the transaction service
 	transaction, err := e.transactionSvc.ProcessWithdrawal(ctx, withdrawalReq)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to process withdrawal: %w", err)
+		// The lien must not outlive the attempt: a retry reserves the funds
+		// again, and the lien would be counted against them
+		if releaseErr := e.lienManager.ReleaseLien(context.WithoutCancel(ctx), lien.ID); releaseErr != nil {
+			return fmt.Errorf("failed to process withdrawal: %w (and to release lien %s: %v)", err, lien.ID, releaseErr)
+		}
+		return classifyPostingError(fmt.Errorf("failed to process withdrawal: %w", err))
 	}
 
 	// Release the lien since the withdrawal was successful
 	if err := e.lienManager.ReleaseLien(ctx, lien.ID); err != nil {
-		dbTx.Rollback()
 		return fmt.Errorf("failed to release lien: %w", err)
 	}
 
-	// Create the result
-	result := WalletWithdrawalResult{
-		TransactionID: transaction.ID,
-		Status:        "COMPLETED",
-		ProcessedAt:   time.Now(),
-	}
+	return setWithdrawalResult(tx, transaction)
+}
 
-	// Convert result to map for storage
-	resultBytes, err := json.Marshal(result)
+// Resolve reports whether a wallet withdrawal was posted under tx's ID and,
+// if it was, releases the lien still reserving its funds and records the
+// posted transaction as Execute does
+func (e *WalletWithdrawalExecutor) Resolve(ctx context.Context, tx *cte.Transaction) (bool, error) {
+	transaction, err := e.transactionSvc.GetTransactionByReference(ctx, tx.ID)
 	if err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to marshal result: %w", err)
+		return false, fmt.Errorf("failed to look up posted withdrawal: %w", err)
+	}
+	if transaction == nil {
+		return false, nil
 	}
 
-	var resultMap map[string]interface{}
-	if err := json.Unmarshal(resultBytes, &resultMap); err != nil {
-		dbTx.Rollback()
-		return fmt.Errorf("failed to unmarshal result: %w", err)
+	liens, err := e.lienManager.GetLiensByEvent(ctx, tx.EventID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get liens for event: %w", err)
+	}
+	for _, lien := range liens {
+		if lien.Metadata["transaction_id"] != tx.ID {
+			continue
+		}
+		if lien.State == ctel.LienStateActive || lien.State == ctel.LienStatePending {
+			if err := e.lienManager.ReleaseLien(ctx, lien.ID); err != nil {
+				return false, fmt.Errorf("failed to release lien %s: %w", lien.ID, err)
+			}
+		}
 	}
 
-	tx.Result = resultMap
-	tx.UpdatedAt = time.Now()
+	return true, setWithdrawalResult(tx, transaction)
+}
 
-	// Commit the transaction
-	if commitErr := dbTx.Commit().Error; commitErr != nil {
-		return fmt.Errorf("failed to commit transaction: %w", commitErr)
+// setWithdrawalResult records the posted transaction so compensation can reverse it
+func setWithdrawalResult(tx *cte.Transaction, transaction *models.Transaction) error {
+	resultMap, err := toResultMap(WalletWithdrawalResult{
+		TransactionID: transaction.ID,
+		Status:        "COMPLETED",
+		FeeAmount:     transaction.Fee,
+		FeeScheduleID: transaction.FeeScheduleID,
+		ProcessedAt:   time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
 	}
-
+	tx.Result = resultMap
+	tx.UpdatedAt = time.Now()
 	return nil
 }
 
@@ -189,24 +220,10 @@ func (e *WalletWithdrawalExecutor) Compensate(ctx context.Context, tx *cte.Trans
 		}
 	}
 
-	// Begin a database transaction
-	dbTx := e.db.Begin()
-	if dbTx.Error != nil {
-		return fmt.Errorf("failed to begin database transaction: %w", dbTx.Error)
-	}
-
-	// Defer a rollback in case anything fails
-	defer func() {
-		if r := recover(); r != nil {
-			dbTx.Rollback()
-		}
-	}()
-
 	// If the transaction was completed, we need to reverse it
 	if result.Status == "COMPLETED" {
 		// Reverse the withdrawal
 		if err := e.transactionSvc.ReverseWithdrawal(ctx, result.TransactionID); err != nil {
-			dbTx.Rollback()
 			return fmt.Errorf("failed to reverse withdrawal: %w", err)
 		}
 
@@ -216,13 +233,11 @@ func (e *WalletWithdrawalExecutor) Compensate(ctx context.Context, tx *cte.Trans
 
 		resultBytes, err := json.Marshal(result)
 		if err != nil {
-			dbTx.Rollback()
 			return fmt.Errorf("failed to marshal result: %w", err)
 		}
 
 		var resultMap map[string]interface{}
 		if err := json.Unmarshal(resultBytes, &resultMap); err != nil {
-			dbTx.Rollback()
 			return fmt.Errorf("failed to unmarshal result: %w", err)
 		}
 
@@ -233,24 +248,55 @@ func (e *WalletWithdrawalExecutor) Compensate(ctx context.Context, tx *cte.Trans
 	// Release any active liens for this event
 	liens, err := e.lienManager.GetLiensByEvent(ctx, tx.EventID)
 	if err != nil {
-		dbTx.Rollback()
 		return fmt.Errorf("failed to get liens for event: %w", err)
 	}
 
 	for _, lien := range liens {
 		if lien.State == ctel.LienStateActive || lien.State == ctel.LienStatePending {
 			if err := e.lienManager.ReleaseLien(ctx, lien.ID); err != nil {
-				dbTx.Rollback()
 				return fmt.Errorf("failed to release lien %s: %w", lien.ID, err)
 			}
 		}
 	}
 
-	// Commit the transaction
-	if commitErr := dbTx.Commit().Error; commitErr != nil {
-		return fmt.Errorf("failed to commit transaction: %w", commitErr)
+	return nil
+}
+
+// Validate checks a wallet withdrawal before its event is started: the
+// payload, that the account exists, accepts debits and holds the currency,
+// and that its available balance, net of other events' liens, covers the
+// amount and fee
+func (e *WalletWithdrawalExecutor) Validate(ctx context.Context, tx *cte.Transaction) error {
+	var payload WalletWithdrawalPayload
+	if err := decodePayload(tx.Payload, &payload); err != nil {
+		return err
+	}
+	if err := validateWalletWithdrawalPayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	account, err := checkAccount(ctx, e.accountRepo, "withdrawal", payload.AccountID, payload.Currency, true, false)
+	if err != nil {
+		return err
 	}
 
+	required := payload.Amount.WithCurrency(payload.Currency)
+	fee, err := assessFee(ctx, e.feeSvc, models.FeeTransactionWithdrawal, account, required)
+	if err != nil {
+		return err
+	}
+	if fee != nil {
+		if required, err = required.Add(fee.Amount); err != nil {
+			return fmt.Errorf("failed to add fee to withdrawal: %w", err)
+		}
+	}
+	available, err := e.lienManager.GetAvailableBalance(ctx, tx.EventID, payload.AccountID)
+	if err != nil {
+		return err
+	}
+	if available.Cmp(required) < 0 {
+		return fmt.Errorf("%w: available %s, required %s", ctel.ErrInsufficientFunds, available, required)
+	}
 	return nil
 }
 
@@ -260,7 +306,7 @@ func validateWalletWithdrawalPayload(payload *WalletWithdrawalPayload) error {
 		return fmt.Errorf("account ID is required")
 	}
 
-	if payload.Amount <= 0 {
+	if !payload.Amount.IsPositive() {
 		return fmt.Errorf("amount must be greater than zero")
 	}
 
@@ -270,5 +316,3 @@ func validateWalletWithdrawalPayload(payload *WalletWithdrawalPayload) error {
 
 	return nil
 }
-
-
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/cte"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // EventModel represents the database model for CTE events
@@ -20,8 +21,23 @@ type EventModel struct {
 	Metadata    []byte         `gorm:"type:jsonb"`
 	CreatedAt   time.Time      `gorm:"not null;default:now()"`
 	UpdatedAt   time.Time      `gorm:"not null;default:now()"`
+
+	// IdempotencyKey is NULL for events created without a key
+	IdempotencyKey *string `gorm:"uniqueIndex"`
+	RequestHash    string  `gorm:"type:varchar(64)"`
+
+	// FailureReason is set on events failed by the engine itself, such as on a timeout
+	FailureReason string `gorm:"type:text"`
+
+	// LeaseOwner and LeaseExpiresAt are NULL unless a worker holds the event
+	LeaseOwner     *string    `gorm:"type:varchar(255)"`
+	LeaseExpiresAt *time.Time `gorm:"index"`
 }
 
+// leaseColumns are only written by the lease methods, so that saving an event
+// never undoes a heartbeat
+var leaseColumns = []string{"LeaseOwner", "LeaseExpiresAt"}
+
 // TableName specifies the table name for the EventModel
 func (EventModel) TableName() string {
 	return "cte_events"
@@ -42,16 +58,27 @@ func (e *EventModel) ToDomain() (*cte.Event, error) {
 		timeout = *e.Timeout
 	}
 
-	return &cte.Event{
-		ID:          e.ID,
-		Name:        e.Name,
-		Description: e.Description,
-		State:       e.State,
-		Timeout:     timeout,
-		Metadata:    metadata,
-		CreatedAt:   e.CreatedAt,
-		UpdatedAt:   e.UpdatedAt,
-	}, nil
+	event := &cte.Event{
+		ID:            e.ID,
+		Name:          e.Name,
+		Description:   e.Description,
+		State:         e.State,
+		Timeout:       timeout,
+		Metadata:      metadata,
+		RequestHash:   e.RequestHash,
+		FailureReason: e.FailureReason,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+	if e.IdempotencyKey != nil {
+		event.IdempotencyKey = *e.IdempotencyKey
+	}
+	if e.LeaseOwner != nil {
+		event.LeaseOwner = *e.LeaseOwner
+	}
+	event.LeaseExpiresAt = e.LeaseExpiresAt
+
+	return event, nil
 }
 
 // FromDomain converts a domain model to a database model
@@ -72,6 +99,15 @@ func (e *EventModel) FromDomain(event *cte.Event) error {
 	e.CreatedAt = event.CreatedAt
 	e.UpdatedAt = event.UpdatedAt
 
+	if event.IdempotencyKey != "" {
+		key := event.IdempotencyKey
+		e.IdempotencyKey = &key
+	} else {
+		e.IdempotencyKey = nil
+	}
+	e.RequestHash = event.RequestHash
+	e.FailureReason = event.FailureReason
+
 	if event.Metadata != nil {
 		metadata, err := json.Marshal(event.Metadata)
 		if err != nil {
@@ -93,6 +129,7 @@ type TransactionModel struct {
 	State         string    `gorm:"type:varchar(20);not null;default:'PENDING'"`
 	Order         int       `gorm:"not null"`
 	Dependencies  []byte    `gorm:"type:jsonb"`
+	Conditions    []byte    `gorm:"type:jsonb"`
 	Payload       []byte    `gorm:"type:jsonb"`
 	Result        []byte    `gorm:"type:jsonb"`
 	Error         string    `gorm:"type:text"`
@@ -105,6 +142,22 @@ func (TransactionModel) TableName() string {
 	return "cte_transactions"
 }
 
+// AttemptModel represents the database model for attempts at CTE transactions
+type AttemptModel struct {
+	ID            uint      `gorm:"primaryKey"`
+	TransactionID string    `gorm:"type:uuid;not null;uniqueIndex:idx_cte_transaction_attempts_attempt"`
+	EventID       string    `gorm:"type:uuid;not null;index"`
+	Attempt       int       `gorm:"not null;uniqueIndex:idx_cte_transaction_attempts_attempt"`
+	Error         string    `gorm:"type:text"`
+	StartedAt     time.Time `gorm:"not null"`
+	FinishedAt    time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for the AttemptModel
+func (AttemptModel) TableName() string {
+	return "cte_transaction_attempts"
+}
+
 // ToDomain converts the database model to a domain model
 func (t *TransactionModel) ToDomain() (*cte.Transaction, error) {
 	tx := &cte.Transaction{
@@ -128,6 +181,15 @@ func (t *TransactionModel) ToDomain() (*cte.Transaction, error) {
 		tx.Dependencies = deps
 	}
 
+	// Unmarshal conditions
+	if len(t.Conditions) > 0 {
+		var conditions []cte.Condition
+		if err := json.Unmarshal(t.Conditions, &conditions); err != nil {
+			return nil, err
+		}
+		tx.Conditions = conditions
+	}
+
 	// Unmarshal payload if present
 	if len(t.Payload) > 0 {
 		var payload interface{}
@@ -175,6 +237,15 @@ func (t *TransactionModel) FromDomain(tx *cte.Transaction) error {
 		t.Dependencies = deps
 	}
 
+	// Marshal conditions
+	if len(tx.Conditions) > 0 {
+		conditions, err := json.Marshal(tx.Conditions)
+		if err != nil {
+			return err
+		}
+		t.Conditions = conditions
+	}
+
 	// Marshal payload if present
 	if tx.Payload != nil {
 		payload, err := json.Marshal(tx.Payload)
@@ -193,7 +264,8 @@ func (t *TransactionModel) FromDomain(tx *cte.Transaction) error {
 		t.Result = result
 	}
 
-	// Set error message if present
+	// Set error message if present, clearing the one of an earlier attempt
+	t.Error = ""
 	if tx.Error != nil {
 		t.Error = tx.Error.Error()
 	}
@@ -237,7 +309,20 @@ func (s *EventStore) GetEvent(ctx context.Context, id string) (*cte.Event, error
 	return model.ToDomain()
 }
 
-// UpdateEvent updates an existing event
+// GetEventByIdempotencyKey retrieves the event created with an idempotency key
+func (s *EventStore) GetEventByIdempotencyKey(ctx context.Context, key string) (*cte.Event, error) {
+	var model EventModel
+	if err := s.db.WithContext(ctx).First(&model, "idempotency_key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return model.ToDomain()
+}
+
+// UpdateEvent updates an existing event, leaving its lease untouched
 func (s *EventStore) UpdateEvent(ctx context.Context, event *cte.Event) error {
 	var model EventModel
 	if err := s.db.WithContext(ctx).First(&model, "id = ?", event.ID).Error; err != nil {
@@ -251,7 +336,86 @@ func (s *EventStore) UpdateEvent(ctx context.Context, event *cte.Event) error {
 	// Set updated timestamp
 	model.UpdatedAt = time.Now()
 
-	return s.db.WithContext(ctx).Save(&model).Error
+	return s.db.WithContext(ctx).Omit(leaseColumns...).Save(&model).Error
+}
+
+// ClaimEvent leases a queued EXECUTING or ROLLING_BACK event to owner
+func (s *EventStore) ClaimEvent(ctx context.Context, owner string, lease time.Duration) (*cte.Event, error) {
+	return s.claim(ctx, owner, lease, func(db *gorm.DB, now time.Time) *gorm.DB {
+		return db.Where("lease_expires_at IS NULL")
+	})
+}
+
+// ClaimOrphanedEvent leases an EXECUTING or ROLLING_BACK event whose lease has lapsed to owner
+func (s *EventStore) ClaimOrphanedEvent(ctx context.Context, owner string, lease time.Duration) (*cte.Event, error) {
+	return s.claim(ctx, owner, lease, func(db *gorm.DB, now time.Time) *gorm.DB {
+		return db.Where("lease_expires_at < ?", now)
+	})
+}
+
+// claim leases the least recently updated EXECUTING or ROLLING_BACK event
+// matching leaseFilter to owner. SKIP LOCKED lets concurrent claims pass over
+// the rows others are claiming.
+func (s *EventStore) claim(ctx context.Context, owner string, lease time.Duration, leaseFilter func(db *gorm.DB, now time.Time) *gorm.DB) (*cte.Event, error) {
+	var claimed *cte.Event
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var model EventModel
+		err := leaseFilter(tx, now).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("state IN ?", []cte.EventState{cte.EventStateExecuting, cte.EventStateRollingBack}).
+			Order("updated_at ASC").
+			Take(&model).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		expiresAt := now.Add(lease)
+		err = tx.Model(&EventModel{}).
+			Where("id = ?", model.ID).
+			UpdateColumns(map[string]interface{}{"lease_owner": owner, "lease_expires_at": expiresAt}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		model.LeaseOwner = &owner
+		model.LeaseExpiresAt = &expiresAt
+		claimed, err = model.ToDomain()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// RenewLease extends owner's lease on an event
+func (s *EventStore) RenewLease(ctx context.Context, eventID, owner string, lease time.Duration) error {
+	result := s.db.WithContext(ctx).
+		Model(&EventModel{}).
+		Where("id = ? AND lease_owner = ?", eventID, owner).
+		UpdateColumn("lease_expires_at", time.Now().Add(lease))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return cte.ErrLeaseLost
+	}
+	return nil
+}
+
+// ReleaseLease gives up owner's lease on an event
+func (s *EventStore) ReleaseLease(ctx context.Context, eventID, owner string) error {
+	return s.db.WithContext(ctx).
+		Model(&EventModel{}).
+		Where("id = ? AND lease_owner = ?", eventID, owner).
+		UpdateColumns(map[string]interface{}{"lease_owner": nil, "lease_expires_at": nil}).
+		Error
 }
 
 // SaveTransaction saves a transaction to the store
@@ -319,10 +483,72 @@ func (s *EventStore) GetEventTransactions(ctx context.Context, eventID string) (
 	return transactions, nil
 }
 
+// RecordAttempt records an attempt at executing a transaction
+func (s *EventStore) RecordAttempt(ctx context.Context, attempt *cte.TransactionAttempt) error {
+	model := AttemptModel{
+		TransactionID: attempt.TransactionID,
+		EventID:       attempt.EventID,
+		Attempt:       attempt.Attempt,
+		Error:         attempt.Error,
+		StartedAt:     attempt.StartedAt,
+		FinishedAt:    attempt.FinishedAt,
+	}
+	return s.db.WithContext(ctx).Create(&model).Error
+}
+
+// GetTransactionAttempts retrieves the attempts at a transaction, first to last
+func (s *EventStore) GetTransactionAttempts(ctx context.Context, transactionID string) ([]*cte.TransactionAttempt, error) {
+	var models []AttemptModel
+	if err := s.db.WithContext(ctx).
+		Where("transaction_id = ?", transactionID).
+		Order("attempt ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	attempts := make([]*cte.TransactionAttempt, 0, len(models))
+	for _, model := range models {
+		attempts = append(attempts, &cte.TransactionAttempt{
+			TransactionID: model.TransactionID,
+			EventID:       model.EventID,
+			Attempt:       model.Attempt,
+			Error:         model.Error,
+			StartedAt:     model.StartedAt,
+			FinishedAt:    model.FinishedAt,
+		})
+	}
+	return attempts, nil
+}
+
+// ListExpiredEvents retrieves the events never started whose deadline passed before now
+func (s *EventStore) ListExpiredEvents(ctx context.Context, now time.Time) ([]*cte.Event, error) {
+	var models []EventModel
+	err := s.db.WithContext(ctx).
+		Where("state IN ?", []cte.EventState{cte.EventStateCreated, cte.EventStateValidating, cte.EventStateValidated}).
+		Where("timeout IS NOT NULL AND created_at + timeout < ?", now).
+		Order("created_at ASC").
+		Find(&models).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*cte.Event, 0, len(models))
+	for _, model := range models {
+		event, err := model.ToDomain()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
 // Migrate creates the necessary database tables
 func (s *EventStore) Migrate() error {
 	return s.db.AutoMigrate(
 		&EventModel{},
 		&TransactionModel{},
+		&AttemptModel{},
 	)
 }
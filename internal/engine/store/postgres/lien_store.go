@@ -7,21 +7,22 @@ import (
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/ctel"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"gorm.io/gorm"
 )
 
 // LienModel represents the database model for CTE-liens
 type LienModel struct {
-	ID        string          `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	EventID   string          `gorm:"type:uuid;not null;index"`
-	AccountID string          `gorm:"type:uuid;not null;index"`
-	Amount    float64         `gorm:"type:decimal(20,8);not null"`
-	Currency  string          `gorm:"type:varchar(3);not null"`
-	State     ctel.LienState  `gorm:"type:varchar(20);not null;default:'PENDING'"`
-	ExpiresAt time.Time       `gorm:"not null"`
-	Metadata  []byte          `gorm:"type:jsonb"`
-	CreatedAt time.Time       `gorm:"not null;default:now()"`
-	UpdatedAt time.Time       `gorm:"not null;default:now()"`
+	ID        string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	EventID   string         `gorm:"type:uuid;not null;index"`
+	AccountID string         `gorm:"type:uuid;not null;index"`
+	Amount    money.Money    `gorm:"type:decimal(19,4);not null"`
+	Currency  string         `gorm:"type:varchar(3);not null"`
+	State     ctel.LienState `gorm:"type:varchar(20);not null;default:'PENDING'"`
+	ExpiresAt time.Time      `gorm:"not null"`
+	Metadata  []byte         `gorm:"type:jsonb"`
+	CreatedAt time.Time      `gorm:"not null;default:now()"`
+	UpdatedAt time.Time      `gorm:"not null;default:now()"`
 }
 
 // TableName specifies the table name for the LienModel
@@ -42,7 +43,7 @@ func (l *LienModel) ToDomain() (*ctel.Lien, error) {
 		ID:        l.ID,
 		EventID:   l.EventID,
 		AccountID: l.AccountID,
-		Amount:    l.Amount,
+		Amount:    l.Amount.WithCurrency(l.Currency),
 		Currency:  l.Currency,
 		State:     l.State,
 		ExpiresAt: l.ExpiresAt,
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AccountStatus is the lifecycle state of an account, which decides what may be posted to it
+type AccountStatus string
+
+const (
+	AccountStatusActive  AccountStatus = "active"  // Debits and credits are allowed
+	AccountStatusFrozen  AccountStatus = "frozen"  // Credits only; debits are blocked, e.g. by compliance
+	AccountStatusDormant AccountStatus = "dormant" // Credits only until the account is reactivated
+	AccountStatusClosed  AccountStatus = "closed"  // Nothing may be posted; requires a zero balance
+)
+
+var (
+	// ErrPostingNotAllowed is wrapped by every AccountStatusError
+	ErrPostingNotAllowed = errors.New("account status does not allow posting")
+	// ErrInvalidStatusTransition is returned when an account cannot move to the requested status
+	ErrInvalidStatusTransition = errors.New("invalid account status transition")
+)
+
+// accountStatusTransitions lists the statuses each status may move to
+var accountStatusTransitions = map[AccountStatus][]AccountStatus{
+	AccountStatusActive:  {AccountStatusFrozen, AccountStatusDormant, AccountStatusClosed},
+	AccountStatusFrozen:  {AccountStatusActive, AccountStatusClosed},
+	AccountStatusDormant: {AccountStatusActive, AccountStatusFrozen, AccountStatusClosed},
+	AccountStatusClosed:  {},
+}
+
+// IsValid reports whether s is one of the known account statuses
+func (s AccountStatus) IsValid() bool {
+	_, ok := accountStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an account in status s may move to next
+func (s AccountStatus) CanTransitionTo(next AccountStatus) bool {
+	for _, allowed := range accountStatusTransitions[s.orActive()] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsDebit reports whether debits may be posted to an account in status s
+func (s AccountStatus) AllowsDebit() bool {
+	return s.orActive() == AccountStatusActive
+}
+
+// AllowsCredit reports whether credits may be posted to an account in status s
+func (s AccountStatus) AllowsCredit() bool {
+	return s.orActive() != AccountStatusClosed
+}
+
+// orActive treats accounts created before statuses existed as active
+func (s AccountStatus) orActive() AccountStatus {
+	if s == "" {
+		return AccountStatusActive
+	}
+	return s
+}
+
+// AccountStatusError reports a posting rejected because of an account's status
+type AccountStatusError struct {
+	AccountID string
+	Status    AccountStatus
+	Operation string // "debit" or "credit"
+}
+
+func (e *AccountStatusError) Error() string {
+	return fmt.Sprintf("cannot %s account %s: account is %s", e.Operation, e.AccountID, e.Status)
+}
+
+// Unwrap lets callers match any status rejection with errors.Is(err, ErrPostingNotAllowed)
+func (e *AccountStatusError) Unwrap() error {
+	return ErrPostingNotAllowed
+}
+
+// CheckPosting returns an *AccountStatusError if the account's status forbids
+// a debit (when debit is true) or a credit (when credit is true)
+func (a *Account) CheckPosting(debit, credit bool) error {
+	status := a.Status.orActive()
+	if debit && !status.AllowsDebit() {
+		return &AccountStatusError{AccountID: a.ID, Status: status, Operation: "debit"}
+	}
+	if credit && !status.AllowsCredit() {
+		return &AccountStatusError{AccountID: a.ID, Status: status, Operation: "credit"}
+	}
+	return nil
+}
+
+// AccountStatusChange is an entry in an account's status history
+type AccountStatusChange struct {
+	ID         string        `json:"id" gorm:"primaryKey"`
+	AccountID  string        `json:"account_id" gorm:"not null;index"`
+	FromStatus AccountStatus `json:"from_status" gorm:"type:varchar(20);not null"`
+	ToStatus   AccountStatus `json:"to_status" gorm:"type:varchar(20);not null"`
+	Reason     string        `json:"reason" gorm:"not null"`
+	Actor      string        `json:"actor" gorm:"not null"` // Who made the change
+	CreatedAt  time.Time     `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for AccountStatusChange
+func (AccountStatusChange) TableName() string {
+	return "account_status_history"
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+)
+
+// PeriodStatus is the state of an accounting period, which decides whether
+// entries dated inside it may still be posted
+type PeriodStatus string
+
+const (
+	PeriodStatusOpen       PeriodStatus = "open"        // Entries may be posted
+	PeriodStatusSoftClosed PeriodStatus = "soft_closed" // Only adjustments with explicit permission may be posted
+	PeriodStatusHardClosed PeriodStatus = "hard_closed" // Nothing may be posted; the period is final
+)
+
+var (
+	// ErrPeriodClosed is wrapped by every PeriodClosedError
+	ErrPeriodClosed = errors.New("accounting period is closed")
+	// ErrInvalidPeriodTransition is returned when a period cannot move to the requested status
+	ErrInvalidPeriodTransition = errors.New("invalid accounting period status transition")
+)
+
+// periodStatusTransitions lists the statuses each status may move to
+var periodStatusTransitions = map[PeriodStatus][]PeriodStatus{
+	PeriodStatusOpen:       {PeriodStatusSoftClosed, PeriodStatusHardClosed},
+	PeriodStatusSoftClosed: {PeriodStatusOpen, PeriodStatusHardClosed},
+	PeriodStatusHardClosed: {},
+}
+
+// IsValid reports whether s is one of the known period statuses
+func (s PeriodStatus) IsValid() bool {
+	_, ok := periodStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a period in status s may move to next
+func (s PeriodStatus) CanTransitionTo(next PeriodStatus) bool {
+	for _, allowed := range periodStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsPosting reports whether entries dated in a period with status s may
+// be posted, given whether the poster holds the adjustment permission
+func (s PeriodStatus) AllowsPosting(adjustment bool) bool {
+	switch s {
+	case PeriodStatusOpen:
+		return true
+	case PeriodStatusSoftClosed:
+		return adjustment
+	default:
+		return false
+	}
+}
+
+// AccountingPeriod is a span of entry dates that finance reports on and then
+// closes. StartDate and EndDate are both inclusive.
+type AccountingPeriod struct {
+	ID        string       `json:"id" gorm:"primaryKey"`
+	Name      string       `json:"name" gorm:"type:varchar(64);not null;uniqueIndex"` // e.g. 2024-01
+	StartDate time.Time    `json:"start_date" gorm:"not null;index"`
+	EndDate   time.Time    `json:"end_date" gorm:"not null;index"`
+	Status    PeriodStatus `json:"status" gorm:"type:varchar(20);not null;default:'open'"`
+	ClosedAt  *time.Time   `json:"closed_at,omitempty"`
+	ClosedBy  string       `json:"closed_by,omitempty"`
+	CreatedAt time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Contains reports whether date falls inside the period
+func (p *AccountingPeriod) Contains(date time.Time) bool {
+	return !date.Before(p.StartDate) && !date.After(p.EndDate)
+}
+
+// CheckPosting returns a *PeriodClosedError if an entry dated in the period may
+// not be posted, given whether the poster holds the adjustment permission
+func (p *AccountingPeriod) CheckPosting(adjustment bool) error {
+	if p.Status.AllowsPosting(adjustment) {
+		return nil
+	}
+	return &PeriodClosedError{PeriodID: p.ID, PeriodName: p.Name, Status: p.Status}
+}
+
+// PeriodClosedError reports an entry rejected because its date falls in a closed period
+type PeriodClosedError struct {
+	PeriodID   string
+	PeriodName string
+	Status     PeriodStatus
+}
+
+func (e *PeriodClosedError) Error() string {
+	if e.Status == PeriodStatusSoftClosed {
+		return fmt.Sprintf("accounting period %s is soft-closed; posting requires the adjustment permission", e.PeriodName)
+	}
+	return fmt.Sprintf("accounting period %s is %s", e.PeriodName, e.Status)
+}
+
+// Unwrap lets callers match any period rejection with errors.Is(err, ErrPeriodClosed)
+func (e *PeriodClosedError) Unwrap() error {
+	return ErrPeriodClosed
+}
+
+// PeriodClosingBalance is an account's balance snapshotted when its period was closed
+type PeriodClosingBalance struct {
+	ID          string      `json:"id" gorm:"primaryKey"`
+	PeriodID    string      `json:"period_id" gorm:"not null;uniqueIndex:idx_period_closing_balances_period_account"`
+	AccountID   string      `json:"account_id" gorm:"not null;uniqueIndex:idx_period_closing_balances_period_account"`
+	AccountType AccountType `json:"account_type" gorm:"type:varchar(20);not null"`
+	Currency    string      `json:"currency" gorm:"type:varchar(3);not null"`
+	TotalDebit  money.Money `json:"total_debit" gorm:"type:decimal(19,4);not null;default:0"`
+	TotalCredit money.Money `json:"total_credit" gorm:"type:decimal(19,4);not null;default:0"`
+	Balance     money.Money `json:"balance" gorm:"type:decimal(19,4);not null;default:0"` // In the account's normal direction
+	CreatedAt   time.Time   `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for PeriodClosingBalance
+func (PeriodClosingBalance) TableName() string {
+	return "period_closing_balances"
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+)
+
+// ExchangeRate is a stored rate between two currencies that applies from its
+// effective time until a later rate for the same pair takes over
+type ExchangeRate struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	BaseCurrency  string     `json:"base_currency" gorm:"type:varchar(3);not null;index:idx_exchange_rates_pair"`
+	QuoteCurrency string     `json:"quote_currency" gorm:"type:varchar(3);not null;index:idx_exchange_rates_pair"`
+	Rate          money.Rate `json:"rate" gorm:"type:decimal(19,8);not null"` // Units of the quote currency per unit of the base currency
+	EffectiveFrom time.Time  `json:"effective_from" gorm:"not null;index:idx_exchange_rates_pair"`
+	Source        string     `json:"source" gorm:"type:varchar(100)"` // Where the rate was obtained, e.g. "treasury"
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for ExchangeRate
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+// ExchangeRateAudit records the rate, and where it came from, that served one
+// currency conversion
+type ExchangeRateAudit struct {
+	ID              string      `json:"id" gorm:"primaryKey"`
+	FromCurrency    string      `json:"from_currency" gorm:"type:varchar(3);not null"`
+	ToCurrency      string      `json:"to_currency" gorm:"type:varchar(3);not null"`
+	Amount          money.Money `json:"amount" gorm:"type:decimal(19,4);not null"`
+	ConvertedAmount money.Money `json:"converted_amount" gorm:"type:decimal(19,4);not null"`
+	Rate            money.Rate  `json:"rate" gorm:"type:decimal(19,8);not null"`
+	Source          string      `json:"source" gorm:"type:varchar(255);not null"` // Provider chain path, e.g. "db" or "inverse(static)"
+	RateAsOf        time.Time   `json:"rate_as_of" gorm:"not null"`
+	CreatedAt       time.Time   `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName specifies the table name for ExchangeRateAudit
+func (ExchangeRateAudit) TableName() string {
+	return "exchange_rate_audits"
+}
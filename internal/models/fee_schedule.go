@@ -0,0 +1,253 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidFeeSchedule is returned when a fee schedule is malformed
+var ErrInvalidFeeSchedule = errors.New("invalid fee schedule")
+
+// FeeType is how a fee schedule computes its fee
+type FeeType string
+
+const (
+	FeeTypeFlat       FeeType = "flat"       // A fixed amount
+	FeeTypePercentage FeeType = "percentage" // A fraction of the amount
+	FeeTypeTiered     FeeType = "tiered"     // A fixed amount plus a fraction, set by the band the amount falls in
+	FeeTypeCapped     FeeType = "capped"     // A fraction of the amount, kept between a minimum and a maximum
+)
+
+// Transaction types fees are charged on, named after the executors that charge them
+const (
+	FeeTransactionTransfer   = "wallet.transfer"
+	FeeTransactionWithdrawal = "wallet.withdrawal"
+	FeeTransactionExchange   = "wallet.exchange"
+)
+
+// IsFeeTransactionType reports whether fees can be scheduled for a transaction type
+func IsFeeTransactionType(txType string) bool {
+	switch txType {
+	case FeeTransactionTransfer, FeeTransactionWithdrawal, FeeTransactionExchange:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultAccountTier is the tier of accounts created without one. Its fee
+// schedules also apply to accounts of tiers that have none of their own.
+const DefaultAccountTier = "standard"
+
+// FeeTier is one band of a tiered fee
+type FeeTier struct {
+	UpTo money.Money `json:"up_to"` // Largest amount in the band; zero on the last band means no limit
+	Flat money.Money `json:"flat"`
+	Rate money.Rate  `json:"rate"` // Fraction of the amount
+}
+
+// FeeTiers are the bands of a tiered fee, in ascending order
+type FeeTiers []FeeTier
+
+// Value implements driver.Valuer so FeeTiers can be written to a JSON column
+func (t FeeTiers) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner so FeeTiers can be read from a JSON column
+func (t *FeeTiers) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into FeeTiers", value)
+	}
+
+	return json.Unmarshal(data, t)
+}
+
+// FeeSchedule prices the fee charged on one transaction type in one currency
+// for accounts of one tier. Schedules are never changed once stored: a new
+// version takes over from its effective time, so the fee charged on any
+// transaction can be worked out again from the schedule it records.
+type FeeSchedule struct {
+	ID              string      `json:"id" gorm:"primaryKey"`
+	TransactionType string      `json:"transaction_type" gorm:"type:varchar(64);not null;uniqueIndex:idx_fee_schedules_version"`
+	Currency        string      `json:"currency" gorm:"type:varchar(3);not null;uniqueIndex:idx_fee_schedules_version"`
+	AccountTier     string      `json:"account_tier" gorm:"type:varchar(32);not null;uniqueIndex:idx_fee_schedules_version"`
+	Version         int         `json:"version" gorm:"not null;uniqueIndex:idx_fee_schedules_version"`
+	FeeType         FeeType     `json:"fee_type" gorm:"type:varchar(20);not null"`
+	FlatAmount      money.Money `json:"flat_amount" gorm:"type:decimal(19,4);not null;default:0"` // Flat fees
+	Rate            money.Rate  `json:"rate" gorm:"type:decimal(9,6);not null;default:0"`         // Percentage and capped fees
+	MinAmount       money.Money `json:"min_amount" gorm:"type:decimal(19,4);not null;default:0"`  // Capped fees; zero means no minimum
+	MaxAmount       money.Money `json:"max_amount" gorm:"type:decimal(19,4);not null;default:0"`  // Capped fees
+	Tiers           FeeTiers    `json:"tiers,omitempty" gorm:"type:jsonb"`                        // Tiered fees
+	EffectiveFrom   time.Time   `json:"effective_from" gorm:"not null;index"`
+	CreatedBy       string      `json:"created_by" gorm:"type:varchar(255);not null"`
+	CreatedAt       time.Time   `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for FeeSchedule
+func (FeeSchedule) TableName() string {
+	return "fee_schedules"
+}
+
+// AfterFind attaches the schedule's currency to the amounts read from their decimal columns
+func (s *FeeSchedule) AfterFind(tx *gorm.DB) error {
+	s.AttachCurrency()
+	return nil
+}
+
+// AttachCurrency sets the schedule's currency on all of its amounts
+func (s *FeeSchedule) AttachCurrency() {
+	s.FlatAmount = s.FlatAmount.WithCurrency(s.Currency)
+	s.MinAmount = s.MinAmount.WithCurrency(s.Currency)
+	s.MaxAmount = s.MaxAmount.WithCurrency(s.Currency)
+	for i := range s.Tiers {
+		s.Tiers[i].UpTo = s.Tiers[i].UpTo.WithCurrency(s.Currency)
+		s.Tiers[i].Flat = s.Tiers[i].Flat.WithCurrency(s.Currency)
+	}
+}
+
+// Validate checks that the schedule has what its fee type needs
+func (s *FeeSchedule) Validate() error {
+	if !IsFeeTransactionType(s.TransactionType) {
+		return fmt.Errorf("%w: fees cannot be charged on %q", ErrInvalidFeeSchedule, s.TransactionType)
+	}
+	if s.Currency == "" || s.AccountTier == "" {
+		return fmt.Errorf("%w: currency and account tier are required", ErrInvalidFeeSchedule)
+	}
+	amounts := []money.Money{s.FlatAmount, s.MinAmount, s.MaxAmount}
+	for _, tier := range s.Tiers {
+		amounts = append(amounts, tier.UpTo, tier.Flat)
+	}
+	for _, amount := range amounts {
+		if !amount.HasValidPrecision() {
+			return fmt.Errorf("%w: %s has more decimal places than %s allows", ErrInvalidFeeSchedule, amount.Decimal(), s.Currency)
+		}
+	}
+
+	switch s.FeeType {
+	case FeeTypeFlat:
+		if !s.FlatAmount.IsPositive() {
+			return fmt.Errorf("%w: flat fee must be positive", ErrInvalidFeeSchedule)
+		}
+	case FeeTypePercentage:
+		if err := checkFeeRate(s.Rate, false); err != nil {
+			return err
+		}
+	case FeeTypeCapped:
+		if err := checkFeeRate(s.Rate, false); err != nil {
+			return err
+		}
+		if !s.MaxAmount.IsPositive() {
+			return fmt.Errorf("%w: capped fee needs a positive maximum", ErrInvalidFeeSchedule)
+		}
+		if s.MinAmount.IsNegative() || s.MinAmount.Cmp(s.MaxAmount) > 0 {
+			return fmt.Errorf("%w: minimum fee must be between zero and the maximum", ErrInvalidFeeSchedule)
+		}
+	case FeeTypeTiered:
+		return s.validateTiers()
+	default:
+		return fmt.Errorf("%w: unknown fee type %q", ErrInvalidFeeSchedule, s.FeeType)
+	}
+	return nil
+}
+
+// validateTiers checks that the bands ascend and that the last one has no limit
+func (s *FeeSchedule) validateTiers() error {
+	if len(s.Tiers) == 0 {
+		return fmt.Errorf("%w: tiered fee needs at least one tier", ErrInvalidFeeSchedule)
+	}
+	for i, tier := range s.Tiers {
+		last := i == len(s.Tiers)-1
+		switch {
+		case last && !tier.UpTo.IsZero():
+			return fmt.Errorf("%w: the last tier must have no upper limit", ErrInvalidFeeSchedule)
+		case !last && !tier.UpTo.IsPositive():
+			return fmt.Errorf("%w: tier %d needs a positive upper limit", ErrInvalidFeeSchedule, i+1)
+		case i > 0 && !last && tier.UpTo.Cmp(s.Tiers[i-1].UpTo) <= 0:
+			return fmt.Errorf("%w: tier limits must ascend", ErrInvalidFeeSchedule)
+		case tier.Flat.IsNegative():
+			return fmt.Errorf("%w: tier %d has a negative flat fee", ErrInvalidFeeSchedule, i+1)
+		}
+		if err := checkFeeRate(tier.Rate, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxFeeRate is the whole amount
+var maxFeeRate = money.MustParseRate("1")
+
+// feeRatePlaces is the precision of the DECIMAL(9,6) fee rate columns
+const feeRatePlaces = 6
+
+// checkFeeRate checks that a fee rate is a fraction of the amount that can be stored exactly
+func checkFeeRate(rate money.Rate, allowZero bool) error {
+	if rate.Sign() < 0 || rate.Cmp(maxFeeRate) > 0 || (rate.IsZero() && !allowZero) {
+		return fmt.Errorf("%w: rate %v must be a fraction of the amount", ErrInvalidFeeSchedule, rate)
+	}
+	if rate.Round(feeRatePlaces).Cmp(rate) != 0 {
+		return fmt.Errorf("%w: rate %v has more than %d decimal places", ErrInvalidFeeSchedule, rate, feeRatePlaces)
+	}
+	return nil
+}
+
+// Calculate returns the fee the schedule charges on amount
+func (s *FeeSchedule) Calculate(amount money.Money) (money.Money, error) {
+	switch s.FeeType {
+	case FeeTypeFlat:
+		return s.FlatAmount, nil
+	case FeeTypePercentage:
+		return amount.ApplyRate(s.Rate, s.Currency)
+	case FeeTypeCapped:
+		fee, err := amount.ApplyRate(s.Rate, s.Currency)
+		if err != nil {
+			return money.Money{}, err
+		}
+		if fee.Cmp(s.MinAmount) < 0 {
+			return s.MinAmount, nil
+		}
+		if fee.Cmp(s.MaxAmount) > 0 {
+			return s.MaxAmount, nil
+		}
+		return fee, nil
+	case FeeTypeTiered:
+		for _, tier := range s.Tiers {
+			if !tier.UpTo.IsZero() && amount.Cmp(tier.UpTo) > 0 {
+				continue
+			}
+			variable, err := amount.ApplyRate(tier.Rate, s.Currency)
+			if err != nil {
+				return money.Money{}, err
+			}
+			return tier.Flat.Add(variable)
+		}
+		return money.Money{}, fmt.Errorf("%w: no tier covers %s", ErrInvalidFeeSchedule, amount)
+	default:
+		return money.Money{}, fmt.Errorf("%w: unknown fee type %q", ErrInvalidFeeSchedule, s.FeeType)
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+)
+
+var (
+	// ErrQuoteExpired is returned when a quote is used after its expiry
+	ErrQuoteExpired = errors.New("fx quote has expired")
+	// ErrQuoteConsumed is returned when a quote has already been used by an exchange
+	ErrQuoteConsumed = errors.New("fx quote has already been used")
+)
+
+// FXQuote locks the rate, spread and fee of one currency exchange until it
+// expires. A quote can be used by a single exchange only.
+type FXQuote struct {
+	ID                  string      `json:"id" gorm:"primaryKey"`
+	AccountID           string      `json:"account_id,omitempty" gorm:"index"` // If set, only this account may use the quote
+	SourceCurrency      string      `json:"source_currency" gorm:"type:varchar(3);not null"`
+	DestinationCurrency string      `json:"destination_currency" gorm:"type:varchar(3);not null"`
+	SourceAmount        money.Money `json:"source_amount" gorm:"type:decimal(19,4);not null"`
+	DestinationAmount   money.Money `json:"destination_amount" gorm:"type:decimal(19,4);not null"`
+	MidRate             money.Rate  `json:"mid_rate" gorm:"type:decimal(19,8);not null"`   // Rate served by the rate providers
+	Spread              money.Rate  `json:"spread" gorm:"type:decimal(9,6);not null"`      // Fraction of the mid rate kept by the platform
+	Rate                money.Rate  `json:"rate" gorm:"type:decimal(19,8);not null"`       // Rate the exchange converts at, after the spread
+	Fee                 money.Money `json:"fee" gorm:"type:decimal(19,4);not null"`        // In the source currency
+	FeeScheduleID       string      `json:"fee_schedule_id,omitempty" gorm:"default:null"` // Fee schedule version that set Fee
+	RateSource          string      `json:"rate_source" gorm:"type:varchar(255);not null"`
+	RateAsOf            time.Time   `json:"rate_as_of" gorm:"not null"`
+	ExpiresAt           time.Time   `json:"expires_at" gorm:"not null;index"`
+	ConsumedAt          *time.Time  `json:"consumed_at,omitempty"`
+	EntryID             string      `json:"entry_id,omitempty" gorm:"index;default:null"` // Entry of the exchange that used the quote
+	CreatedAt           time.Time   `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for FXQuote
+func (FXQuote) TableName() string {
+	return "fx_quotes"
+}
+
+// CheckUsable reports why the quote cannot be used at now, if it cannot
+func (q *FXQuote) CheckUsable(now time.Time) error {
+	if q.ConsumedAt != nil {
+		return fmt.Errorf("%w: quote %s was used by entry %s", ErrQuoteConsumed, q.ID, q.EntryID)
+	}
+	if !now.Before(q.ExpiresAt) {
+		return fmt.Errorf("%w: quote %s expired at %s", ErrQuoteExpired, q.ID, q.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
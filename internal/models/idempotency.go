@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// IdempotencyKey records a mutating API request made with an Idempotency-Key
+// header, so that a retry with the same key replays the original response
+// instead of repeating the operation.
+type IdempotencyKey struct {
+	Key          string    `json:"key" gorm:"primaryKey"`
+	Method       string    `json:"method" gorm:"type:varchar(10);not null"`
+	Path         string    `json:"path" gorm:"not null"`
+	RequestHash  string    `json:"request_hash" gorm:"type:char(64);not null"` // SHA-256 of method, path and body
+	StatusCode   int       `json:"status_code" gorm:"not null;default:0"`      // 0 while the request is still in flight
+	ContentType  string    `json:"content_type,omitempty"`
+	ResponseBody []byte    `json:"-"`
+	ReservedAt   time.Time `json:"reserved_at" gorm:"not null"` // When the in-flight request took the key
+	Reservation  string    `json:"-" gorm:"type:varchar(36)"`   // Identifies the request holding the key
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed reports whether the original request has finished and its response was stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 )
 
 // AccountType is an enumeration for different financial account types.
@@ -16,40 +18,104 @@ const (
 	System    AccountType = "System" // For internal platform accounts (e.g., fees, clearing)
 )
 
+// IsValid reports whether t is one of the known account types
+func (t AccountType) IsValid() bool {
+	switch t {
+	case Asset, Liability, Equity, Revenue, Expense, System:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsDebitNormal reports whether balances of this account type increase with debits.
+// Asset and Expense accounts (and internal System accounts) are debit-normal;
+// Liability, Equity and Revenue accounts are credit-normal.
+func (t AccountType) IsDebitNormal() bool {
+	switch t {
+	case Asset, Expense, System:
+		return true
+	default:
+		return false
+	}
+}
+
 // Account represents a financial account in the ledger.
 type Account struct {
-	ID        string      `json:"id" gorm:"primaryKey"`
-	Name      string      `json:"name" gorm:"not null"`
-	Type      AccountType `json:"type" gorm:"type:varchar(20);not null;index"`
-	UserID    string      `json:"user_id,omitempty" gorm:"index"`   // Optional: For user-specific wallet accounts
-	Currency  string      `json:"currency" gorm:"type:varchar(3);not null"`
-	CreatedAt time.Time   `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
-	// ParentAccountID string      `json:"parent_account_id,omitempty" gorm:"index"` // For hierarchical accounts
+	ID              string        `json:"id" gorm:"primaryKey"`
+	Code            string        `json:"code,omitempty" gorm:"type:varchar(64);uniqueIndex;default:null"` // Chart of accounts code, e.g. 2000-USER-WALLETS
+	Name            string        `json:"name" gorm:"not null"`
+	Type            AccountType   `json:"type" gorm:"type:varchar(20);not null;index"`
+	ParentAccountID string        `json:"parent_account_id,omitempty" gorm:"index;default:null"` // Parent node in the chart of accounts
+	UserID          string        `json:"user_id,omitempty" gorm:"index"`                        // Optional: For user-specific wallet accounts
+	Currency        string        `json:"currency" gorm:"type:varchar(3);not null"`
+	Status          AccountStatus `json:"status" gorm:"type:varchar(20);not null;default:'active';index"`
+	Tier            string        `json:"tier" gorm:"type:varchar(32);not null;default:'standard'"` // Selects the fee schedules that apply
+	CreatedAt       time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // EntryLine represents a single line within an Entry, affecting one account.
 type EntryLine struct {
-	ID        string    `json:"id" gorm:"primaryKey"`
-	EntryID   string    `json:"entry_id" gorm:"index"`
-	AccountID string    `json:"account_id" gorm:"index"`
-	Debit     float64   `json:"debit" gorm:"type:decimal(19,4)"`  // Amount debited from AccountID
-	Credit    float64   `json:"credit" gorm:"type:decimal(19,4)"` // Amount credited to AccountID
-	CreatedAt time.Time `json:"created_at"`
+	ID        string      `json:"id" gorm:"primaryKey"`
+	EntryID   string      `json:"entry_id" gorm:"index"`
+	AccountID string      `json:"account_id" gorm:"index"`
+	Currency  string      `json:"currency" gorm:"type:varchar(3);not null;default:''"` // Currency of Debit and Credit; must match the account's
+	Debit     money.Money `json:"debit" gorm:"type:decimal(19,4)"`                     // Amount debited from AccountID
+	Credit    money.Money `json:"credit" gorm:"type:decimal(19,4)"`                    // Amount credited to AccountID
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// EntryExchangeRate records the rate at which a cross-currency entry converted
+// between two of its currencies through the FX position accounts.
+type EntryExchangeRate struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	EntryID       string     `json:"entry_id" gorm:"index"`
+	BaseCurrency  string     `json:"base_currency" gorm:"type:varchar(3);not null"`
+	QuoteCurrency string     `json:"quote_currency" gorm:"type:varchar(3);not null"`
+	Rate          money.Rate `json:"rate" gorm:"type:decimal(19,8);not null"` // Units of QuoteCurrency per unit of BaseCurrency
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
+// Entry statuses
+const (
+	EntryStatusPosted   = "posted"
+	EntryStatusPending  = "pending"
+	EntryStatusVoided   = "voided"
+	EntryStatusReversed = "reversed" // Posted, then offset by a reversal entry
+)
+
 // Entry represents a single atomic financial transaction (e.g., a ledger entry).
 // In double-entry bookkeeping, the sum of debits must equal the sum of credits across all lines.
 type Entry struct {
-	ID              string      `json:"id" gorm:"primaryKey"`
-	Description     string      `json:"description" gorm:"not null"`
-	Date            time.Time   `json:"date" gorm:"index;not null"`
-	Lines           []EntryLine `json:"lines" gorm:"foreignKey:EntryID;constraint:OnDelete:CASCADE"`
-	TransactionType string      `json:"transaction_type" gorm:"not null"` // e.g., "deposit", "withdrawal", "transfer", "fee"
-	ReferenceID     string      `json:"reference_id,omitempty"`            // ID from an external system or parent CTE
-	Status          string      `json:"status" gorm:"not null;default:'posted'"` // e.g., "posted", "voided", "pending"
-	CreatedAt       time.Time   `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt       time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+	ID              string              `json:"id" gorm:"primaryKey"`
+	Description     string              `json:"description" gorm:"not null"`
+	Date            time.Time           `json:"date" gorm:"index;not null"`
+	Lines           []EntryLine         `json:"lines" gorm:"foreignKey:EntryID;constraint:OnDelete:CASCADE"`
+	ExchangeRates   []EntryExchangeRate `json:"exchange_rates,omitempty" gorm:"foreignKey:EntryID;constraint:OnDelete:CASCADE"` // Required when the lines span currencies
+	TransactionType string              `json:"transaction_type" gorm:"not null"`                                               // e.g., "deposit", "withdrawal", "transfer", "fee"
+	ReferenceID     string              `json:"reference_id,omitempty"`                                                         // ID from an external system or parent CTE
+	Status          string              `json:"status" gorm:"not null;default:'posted'"`                                        // e.g., "posted", "voided", "pending"
+	ReversalOfID    string              `json:"reversal_of_id,omitempty" gorm:"index;default:null"`                             // Set on a reversal entry to the entry it reverses
+	CreatedAt       time.Time           `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time           `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BalanceSnapshot is the materialized running balance of an account.
+// It is updated in the same database transaction as every posted entry and
+// guarded by Version for optimistic concurrency control.
+type BalanceSnapshot struct {
+	AccountID   string    `json:"account_id" gorm:"primaryKey"`
+	TotalDebit  money.Money `json:"total_debit" gorm:"type:decimal(19,4);not null;default:0"`
+	TotalCredit money.Money `json:"total_credit" gorm:"type:decimal(19,4);not null;default:0"`
+	Version     int64       `json:"version" gorm:"not null;default:0"`
+	LastEntryID string      `json:"last_entry_id,omitempty"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// TableName specifies the table name for the BalanceSnapshot model
+func (BalanceSnapshot) TableName() string {
+	return "account_balances"
 }
 
 // CTEStatus is an enumeration for the state of a Chained Transaction Event.
@@ -80,12 +146,12 @@ const (
 
 // CompensationInfo defines the details for compensating a failed CTETransaction.
 type CompensationInfo struct {
-	Type            string  `json:"type"`              // e.g., "CreditWallet", "ReverseEntry"
-	Amount          float64 `json:"amount,omitempty"`  // Amount for compensation if applicable
-	TargetAccountID string  `json:"target_account_id,omitempty"` // Account to be affected by compensation
-	Executed        bool    `json:"executed"`          // True if compensation has been performed
-	EntryID         string  `json:"entry_id,omitempty"` // ID of the compensation entry
-	Error           string  `json:"error,omitempty"`   // Error during compensation, if any
+	Type            string       `json:"type"`                        // e.g., "CreditWallet", "ReverseEntry"
+	Amount          *money.Money `json:"amount,omitempty"`            // Amount for compensation if applicable
+	TargetAccountID string       `json:"target_account_id,omitempty"` // Account to be affected by compensation
+	Executed        bool         `json:"executed"`                    // True if compensation has been performed
+	EntryID         string       `json:"entry_id,omitempty"`          // ID of the compensation entry
+	Error           string       `json:"error,omitempty"`             // Error during compensation, if any
 }
 
 // CTETransaction represents a single step/transaction within a Chained Transaction Event.
@@ -135,7 +201,7 @@ type CTELien struct {
 	ID          string        `json:"id"`
 	CTEID       string        `json:"cte_id"`     // Link to the parent CTE
 	AccountID   string        `json:"account_id"` // The account on which the lien is placed
-	Amount      float64       `json:"amount"`
+	Amount      money.Money   `json:"amount"`
 	Currency    string        `json:"currency"`
 	Status      CTELienStatus `json:"status"`
 	Description string        `json:"description,omitempty"`
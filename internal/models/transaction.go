@@ -1,6 +1,14 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"gorm.io/gorm"
+)
 
 // TransactionType represents the type of a transaction
 type TransactionType string
@@ -11,6 +19,7 @@ const (
 	TransactionTypeDeposit    TransactionType = "DEPOSIT"
 	TransactionTypeWithdrawal TransactionType = "WITHDRAWAL"
 	TransactionTypeFee        TransactionType = "FEE"
+	TransactionTypeExchange   TransactionType = "EXCHANGE"
 )
 
 // TransactionStatus represents the status of a transaction
@@ -32,21 +41,62 @@ type Transaction struct {
 	Status          TransactionStatus `json:"status" gorm:"type:varchar(20);not null;index"`
 	SourceAccountID string          `json:"source_account_id,omitempty" gorm:"index"`
 	TargetAccountID string          `json:"target_account_id,omitempty" gorm:"index"`
-	Amount          float64         `json:"amount" gorm:"type:decimal(19,4);not null"`
+	Amount          money.Money     `json:"amount" gorm:"type:decimal(19,4);not null"`
 	Currency        string          `json:"currency" gorm:"type:varchar(3);not null"`
-	Fee             float64         `json:ee,omitempty" gorm:"type:decimal(19,4);default:0"`
+	Fee             money.Money     `json:"fee,omitempty" gorm:"type:decimal(19,4);default:0"`
 	FeeCurrency     string          `json:"fee_currency,omitempty" gorm:"type:varchar(3)"`
+	FeeScheduleID   string          `json:"fee_schedule_id,omitempty" gorm:"index;default:null"` // Fee schedule version that set Fee
 	Reference       string          `json:"reference,omitempty" gorm:"type:varchar(255)"`
 	Description     string          `json:"description,omitempty" gorm:"type:text"`
+	EntryID         string          `json:"entry_id,omitempty" gorm:"index"` // Ledger entry posted for this transaction
 	Metadata        JSONMap         `json:"metadata,omitempty" gorm:"type:jsonb"`
 	CreatedAt       time.Time       `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
 	CompletedAt     *time.Time      `json:"completed_at,omitempty"`
 }
 
+// AfterFind attaches the currencies to the amounts read from their decimal columns
+func (t *Transaction) AfterFind(tx *gorm.DB) error {
+	t.Amount = t.Amount.WithCurrency(t.Currency)
+	t.Fee = t.Fee.WithCurrency(t.FeeCurrency)
+	return nil
+}
+
 // JSONMap is a map that can be stored as JSON in the database
 type JSONMap map[string]interface{}
 
+// Value implements driver.Valuer so JSONMap can be written to a JSON column
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner so JSONMap can be read from a JSON column
+func (m *JSONMap) Scan(value interface{}) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONMap", value)
+	}
+
+	return json.Unmarshal(data, m)
+}
+
 // TableName specifies the table name for the Transaction model
 func (Transaction) TableName() string {
 	return "transactions"
@@ -0,0 +1,43 @@
+package money
+
+// defaultMinorUnits is used for currencies that are not listed in minorUnits
+const defaultMinorUnits = 2
+
+// minorUnits lists the ISO 4217 currencies whose minor unit is not 1/100
+var minorUnits = map[string]int{
+	"BIF": 0,
+	"CLP": 0,
+	"DJF": 0,
+	"GNF": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KMF": 0,
+	"KRW": 0,
+	"PYG": 0,
+	"RWF": 0,
+	"UGX": 0,
+	"UYI": 0,
+	"VND": 0,
+	"VUV": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"XPF": 0,
+	"BHD": 3,
+	"IQD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"CLF": 4,
+	"UYW": 4,
+}
+
+// MinorUnits returns the number of decimal places of a currency's minor
+// unit per ISO 4217, e.g. 2 for USD, 0 for JPY and 3 for KWD.
+func MinorUnits(currency string) int {
+	if units, ok := minorUnits[currency]; ok {
+		return units
+	}
+	return defaultMinorUnits
+}
@@ -0,0 +1,465 @@
+// Package money provides an exact fixed-point monetary amount.
+//
+// Amounts are held as an integer number of ten-thousandths of a currency unit,
+// matching the DECIMAL(19,4) columns used throughout the ledger, so that sums
+// and comparisons never suffer from binary floating point rounding.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of fractional digits stored for every amount
+const Scale = 4
+
+// scaleFactor is 10^Scale
+const scaleFactor int64 = 10000
+
+// maxIntegerDigits is the largest number of integer digits a DECIMAL(19,4) column holds
+const maxIntegerDigits = 19 - Scale
+
+var (
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrOverflow is returned when an amount is outside the supported range
+	ErrOverflow = errors.New("amount out of range")
+	// ErrInvalidAmount is returned when a decimal string cannot be parsed
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Money is an exact amount of a currency. The zero value is a zero amount
+// with no currency; amounts read back from a single database column carry no
+// currency until one is attached with WithCurrency.
+type Money struct {
+	units    int64 // amount in 1/10^Scale of the currency unit
+	currency string
+}
+
+// New creates an amount from a count of the currency's minor units,
+// e.g. New(1050, "USD") is 10.50 USD and New(1050, "JPY") is 1050 JPY.
+func New(minor int64, currency string) Money {
+	factor := pow10(Scale - MinorUnits(currency))
+	return Money{units: minor * factor, currency: currency}
+}
+
+// Zero returns a zero amount in the given currency
+func Zero(currency string) Money {
+	return Money{currency: currency}
+}
+
+// Parse parses a decimal string such as "-12.345" into an amount
+func Parse(s, currency string) (Money, error) {
+	units, err := parseUnits(s)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{units: units, currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on error. It is intended for constants and tests.
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromFloat converts a float to the nearest representable amount. It exists
+// for boundaries that still speak float64 and should not be used for arithmetic.
+func FromFloat(f float64, currency string) (Money, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Money{}, fmt.Errorf("%w: %v", ErrInvalidAmount, f)
+	}
+	return Parse(strconv.FormatFloat(f, 'f', Scale, 64), currency)
+}
+
+// Currency returns the ISO 4217 code of the amount, or "" if unknown
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// WithCurrency returns the same amount tagged with the given currency
+func (m Money) WithCurrency(currency string) Money {
+	m.currency = currency
+	return m
+}
+
+// MinorUnits returns the number of minor units of the amount's currency
+func (m Money) MinorUnits() int {
+	return MinorUnits(m.currency)
+}
+
+// Add returns m + o. Amounts without a currency adopt the other operand's.
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.commonCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	sum := m.units + o.units
+	if (o.units > 0 && sum < m.units) || (o.units < 0 && sum > m.units) || !inRange(sum) {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: sum, currency: currency}, nil
+}
+
+// Sub returns m - o. Amounts without a currency adopt the other operand's.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(o.Neg())
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	m.units = -m.units
+	return m
+}
+
+// Abs returns |m|
+func (m Money) Abs() Money {
+	if m.units < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Cmp compares the amounts of m and o, returning -1, 0 or +1. Currencies are
+// not compared; use SameCurrency when they may differ.
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.units < o.units:
+		return -1
+	case m.units > o.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Equal reports whether m and o have the same amount and compatible currencies
+func (m Money) Equal(o Money) bool {
+	return m.units == o.units && m.SameCurrency(o)
+}
+
+// SameCurrency reports whether m and o can be combined. An amount without a
+// currency is compatible with any other.
+func (m Money) SameCurrency(o Money) bool {
+	return m.currency == "" || o.currency == "" || m.currency == o.currency
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the amount
+func (m Money) Sign() int {
+	return m.Cmp(Money{})
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.units > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m.units < 0
+}
+
+// HasValidPrecision reports whether the amount has no more fractional digits
+// than its currency's minor units allow, e.g. 10.001 USD is not valid.
+func (m Money) HasValidPrecision() bool {
+	return m.units%pow10(Scale-m.MinorUnits()) == 0
+}
+
+// Round rounds the amount half away from zero to its currency's minor units
+func (m Money) Round() Money {
+	m.units = roundUnits(m.units, pow10(Scale-m.MinorUnits()))
+	return m
+}
+
+// Convert multiplies the amount by an exchange rate and returns the result in
+// currency, rounded half away from zero to that currency's minor units.
+func (m Money) Convert(rate Rate, currency string) (Money, error) {
+	if rate.Sign() <= 0 {
+		return Money{}, fmt.Errorf("invalid exchange rate %s", rate)
+	}
+	return m.ApplyRate(rate, currency)
+}
+
+// convertRat multiplies the amount by r and returns the result in currency,
+// rounded half away from zero to that currency's minor units
+func (m Money) convertRat(r *big.Rat, currency string) (Money, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.units), r)
+	step := big.NewInt(pow10(Scale - MinorUnits(currency)))
+
+	// Round the product to a whole number of steps
+	q, rem := new(big.Int).QuoRem(product.Num(), new(big.Int).Mul(product.Denom(), step), new(big.Int))
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	if twice.Cmp(new(big.Int).Mul(product.Denom(), step)) >= 0 {
+		if product.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	q.Mul(q, step)
+
+	if !q.IsInt64() || !inRange(q.Int64()) {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: q.Int64(), currency: currency}, nil
+}
+
+// Decimal formats the amount as a plain decimal string with at least the
+// currency's minor units, e.g. "10.50" for USD or "1050" for JPY.
+func (m Money) Decimal() string {
+	units := m.units
+	neg := units < 0
+	if neg {
+		units = -units
+	}
+
+	intPart := units / scaleFactor
+	frac := fmt.Sprintf("%0*d", Scale, units%scaleFactor)
+
+	// Trim trailing zeros but keep the currency's minor units
+	keep := m.MinorUnits()
+	for len(frac) > keep && frac[len(frac)-1] == '0' {
+		frac = frac[:len(frac)-1]
+	}
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	b.WriteString(strconv.FormatInt(intPart, 10))
+	if frac != "" {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// String formats the amount followed by its currency, e.g. "10.50 USD"
+func (m Money) String() string {
+	if m.currency == "" {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + m.currency
+}
+
+// MarshalJSON encodes the amount as an exact JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or numeric string without going
+// through float64. The currency is left unset.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*m = Money{}
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	units, err := parseUnits(s)
+	if err != nil {
+		return err
+	}
+	m.units = units
+	return nil
+}
+
+// Value implements driver.Valuer for DECIMAL(19,4) columns
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// Scan implements sql.Scanner for DECIMAL(19,4) columns. The currency is left unset.
+func (m *Money) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		if v > maxWhole || v < -maxWhole {
+			return ErrOverflow
+		}
+		*m = Money{units: v * scaleFactor}
+		return nil
+	case float64:
+		// Drivers without a decimal type (e.g. SQLite) return floats
+		s = strconv.FormatFloat(v, 'f', Scale, 64)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+
+	units, err := parseUnits(s)
+	if err != nil {
+		return err
+	}
+	*m = Money{units: units}
+	return nil
+}
+
+// Sum adds up amounts, which must share a currency
+func Sum(amounts ...Money) (Money, error) {
+	var total Money
+	for _, a := range amounts {
+		var err error
+		if total, err = total.Add(a); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// commonCurrency returns the currency of a combination of m and o
+func (m Money) commonCurrency(o Money) (string, error) {
+	if !m.SameCurrency(o) {
+		return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, o.currency)
+	}
+	if m.currency != "" {
+		return m.currency, nil
+	}
+	return o.currency, nil
+}
+
+// parseUnits parses a decimal string into units of 1/10^Scale
+func parseUnits(s string) (int64, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+
+	// Exponent notation, e.g. "1e-05" as produced when floats are re-encoded
+	if strings.ContainsAny(s, "eE") {
+		return parseExponent(s, raw)
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(intPart) > maxIntegerDigits {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, raw)
+	}
+
+	// Extra fractional digits are only accepted when they are zero, as
+	// produced by wider DECIMAL columns
+	if len(fracPart) > Scale {
+		if strings.Trim(fracPart[Scale:], "0") != "" {
+			return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, raw, Scale)
+		}
+		fracPart = fracPart[:Scale]
+	}
+	fracPart += strings.Repeat("0", Scale-len(fracPart))
+
+	var units int64
+	for _, c := range intPart + fracPart {
+		d := int64(c - '0')
+		if units > (maxUnits-d)/10 {
+			return 0, fmt.Errorf("%w: %q", ErrOverflow, raw)
+		}
+		units = units*10 + d
+	}
+	if neg {
+		units = -units
+	}
+	return units, nil
+}
+
+// parseExponent parses a decimal in exponent notation into units of 1/10^Scale
+func parseExponent(s, raw string) (int64, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(scaleFactor))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, raw, Scale)
+	}
+	if !r.Num().IsInt64() || !inRange(r.Num().Int64()) {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, raw)
+	}
+	return r.Num().Int64(), nil
+}
+
+// isDigits reports whether s consists only of ASCII digits
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// maxWhole is the largest whole amount held. It is bounded by int64 rather
+// than by the 15 integer digits of DECIMAL(19,4).
+const maxWhole = math.MaxInt64 / scaleFactor
+
+// maxUnits is the largest absolute amount held, in units of 1/10^Scale
+const maxUnits = maxWhole * scaleFactor
+
+// inRange reports whether units are within the supported range
+func inRange(units int64) bool {
+	return units >= -maxUnits && units <= maxUnits
+}
+
+// roundUnits rounds units half away from zero to a multiple of step
+func roundUnits(units, step int64) int64 {
+	if step <= 1 {
+		return units
+	}
+	rem := units % step
+	units -= rem
+	if rem < 0 {
+		rem = -rem
+		if rem*2 >= step {
+			units -= step
+		}
+	} else if rem*2 >= step {
+		units += step
+	}
+	return units
+}
+
+// pow10 returns 10^n for small non-negative n
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
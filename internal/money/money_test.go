@@ -0,0 +1,179 @@
+package money_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     string
+	}{
+		{"10.5", "USD", "10.50 USD"},
+		{"-0.01", "USD", "-0.01 USD"},
+		{"1050", "JPY", "1050 JPY"},
+		{"1.2345", "USD", "1.2345 USD"},
+		{"0.125", "KWD", "0.125 KWD"},
+		{"100.50000000", "", "100.50"},
+		{"1e-04", "USD", "0.0001 USD"},
+		{"2.5E3", "USD", "2500.00 USD"},
+	}
+
+	for _, tt := range tests {
+		m, err := money.Parse(tt.input, tt.currency)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, m.String(), tt.input)
+	}
+
+	for _, bad := range []string{"", "abc", "1.2.3", "1.23456", "-", "1e-5"} {
+		_, err := money.Parse(bad, "USD")
+		assert.Error(t, err, bad)
+	}
+
+	_, err := money.Parse("99999999999999999", "USD")
+	assert.True(t, errors.Is(err, money.ErrOverflow))
+}
+
+func TestArithmeticIsExact(t *testing.T) {
+	// 0.1 + 0.2 is the classic float64 failure
+	sum, err := money.Sum(money.MustParse("0.1", "USD"), money.MustParse("0.2", "USD"))
+	require.NoError(t, err)
+	assert.True(t, sum.Equal(money.MustParse("0.3", "USD")))
+
+	diff, err := sum.Sub(money.New(30, "USD"))
+	require.NoError(t, err)
+	assert.True(t, diff.IsZero())
+
+	// Amounts without a currency adopt the other operand's
+	untagged, err := money.Parse("1", "")
+	require.NoError(t, err)
+	sum, err = untagged.Add(money.New(1, "EUR"))
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", sum.Currency())
+
+	_, err = money.New(1, "USD").Add(money.New(1, "EUR"))
+	assert.True(t, errors.Is(err, money.ErrCurrencyMismatch))
+}
+
+func TestPrecisionAndRounding(t *testing.T) {
+	assert.True(t, money.MustParse("10.25", "USD").HasValidPrecision())
+	assert.False(t, money.MustParse("10.255", "USD").HasValidPrecision())
+	assert.False(t, money.MustParse("10.5", "JPY").HasValidPrecision())
+	assert.True(t, money.MustParse("10.255", "BHD").HasValidPrecision())
+
+	assert.Equal(t, "10.26", money.MustParse("10.255", "USD").Round().Decimal())
+	assert.Equal(t, "-10.26", money.MustParse("-10.255", "USD").Round().Decimal())
+	assert.Equal(t, "11", money.MustParse("10.5", "JPY").Round().Decimal())
+
+	converted, err := money.MustParse("100", "USD").Convert(money.MustParseRate("0.9"), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "90.00 EUR", converted.String())
+
+	converted, err = money.MustParse("33.33", "USD").Convert(money.MustParseRate("149.875"), "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, "4995 JPY", converted.String())
+}
+
+func TestJSONAndSQL(t *testing.T) {
+	var payload struct {
+		Amount money.Money `json:"amount"`
+		Quoted money.Money `json:"quoted"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 19.99, "quoted": "0.07"}`), &payload))
+	assert.Equal(t, "19.99", payload.Amount.Decimal())
+	assert.Equal(t, "0.07", payload.Quoted.Decimal())
+
+	out, err := json.Marshal(payload)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 19.99, "quoted": 0.07}`, string(out))
+
+	value, err := money.MustParse("12.3", "USD").Value()
+	require.NoError(t, err)
+	assert.Equal(t, "12.30", value)
+
+	var scanned money.Money
+	require.NoError(t, scanned.Scan([]byte("12.3000")))
+	assert.Equal(t, "12.30", scanned.Decimal())
+	require.NoError(t, scanned.Scan(0.1+0.2))
+	assert.Equal(t, "0.30", scanned.Decimal())
+	require.NoError(t, scanned.Scan(int64(7)))
+	assert.Equal(t, "7.00", scanned.Decimal())
+}
+
+func TestRate(t *testing.T) {
+	rate := money.MustParseRate("0.015")
+	assert.Equal(t, "0.015", rate.String())
+	assert.Equal(t, "1", money.MustParseRate("1.000000").Decimal())
+	assert.Equal(t, 1, money.MustParseRate("1").Cmp(rate))
+	assert.True(t, money.Rate{}.IsZero())
+	for _, bad := range []string{"", "abc", "0.000000001"} {
+		_, err := money.ParseRate(bad)
+		assert.Error(t, err, bad)
+	}
+
+	// Half a cent and more rounds up, just under it rounds down
+	fee, err := money.MustParse("0.50", "USD").ApplyRate(rate, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "0.01 USD", fee.String())
+	fee, err = money.MustParse("0.33", "USD").ApplyRate(rate, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "0.00 USD", fee.String())
+	fee, err = money.MustParse("100", "USD").ApplyRate(money.Rate{}, "USD")
+	require.NoError(t, err)
+	assert.True(t, fee.IsZero())
+
+	var payload struct {
+		Rate   money.Rate `json:"rate"`
+		Quoted money.Rate `json:"quoted"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"rate": 0.015, "quoted": "0.0025"}`), &payload))
+	assert.Equal(t, rate, payload.Rate)
+	out, err := json.Marshal(payload)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"rate": 0.015, "quoted": 0.0025}`, string(out))
+
+	value, err := rate.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "0.015", value)
+	var scanned money.Rate
+	require.NoError(t, scanned.Scan([]byte("0.015000")))
+	assert.Equal(t, rate, scanned)
+	require.NoError(t, scanned.Scan(0.015))
+	assert.Equal(t, rate, scanned)
+}
+
+func TestRateArithmetic(t *testing.T) {
+	eurUSD := money.MustParseRate("1.0842")
+	inverse, err := eurUSD.Inverse()
+	require.NoError(t, err)
+	assert.Equal(t, "0.92233905", inverse.String())
+	_, err = money.Rate{}.Inverse()
+	assert.Error(t, err)
+
+	cross, err := eurUSD.Mul(money.MustParseRate("149.875"))
+	require.NoError(t, err)
+	assert.Equal(t, "162.494475", cross.String())
+
+	spread, err := money.MustParseRate("1").Sub(money.MustParseRate("0.005"))
+	require.NoError(t, err)
+	assert.Equal(t, "0.995", spread.String())
+	assert.Equal(t, "0.9223391", inverse.Round(7).String())
+
+	// Float rates from feeds are rounded to the rate scale
+	fed, err := money.RateFromFloat(0.1 + 0.2)
+	require.NoError(t, err)
+	assert.Equal(t, "0.3", fed.String())
+	_, err = money.RateFromFloat(math.NaN())
+	assert.Error(t, err)
+
+	_, err = money.MustParse("100", "USD").Convert(money.Rate{}, "EUR")
+	assert.Error(t, err)
+}
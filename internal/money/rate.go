@@ -0,0 +1,224 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RateScale is the number of fractional digits held by a Rate, matching the
+// DECIMAL(19,8) columns exchange rates are stored in
+const RateScale = 8
+
+// rateFactor is 10^RateScale
+const rateFactor int64 = 100000000
+
+// Rate is an exact decimal multiplier, such as a fee rate of 0.015 or an
+// exchange rate of 1.0842. It is held as an integer number of units of
+// 10^-RateScale, so that applying it to an amount never suffers from binary
+// floating point rounding. The zero value is a zero rate.
+type Rate struct {
+	units int64
+}
+
+// ParseRate parses a decimal string such as "0.015" into a rate
+func ParseRate(s string) (Rate, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: invalid rate %q", ErrInvalidAmount, s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(rateFactor))
+	if !r.IsInt() {
+		return Rate{}, fmt.Errorf("%w: rate %q has more than %d decimal places", ErrInvalidAmount, s, RateScale)
+	}
+	if !r.Num().IsInt64() {
+		return Rate{}, fmt.Errorf("%w: rate %q", ErrOverflow, s)
+	}
+	return Rate{units: r.Num().Int64()}, nil
+}
+
+// RateFromFloat converts a float to the nearest rate, rounding half away from
+// zero to RateScale places. It exists for boundaries that still speak float64,
+// such as rate feeds, and should not be used for arithmetic.
+func RateFromFloat(f float64) (Rate, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Rate{}, fmt.Errorf("%w: invalid rate %v", ErrInvalidAmount, f)
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: invalid rate %v", ErrInvalidAmount, f)
+	}
+	return rateFromRat(r)
+}
+
+// rateFromRat rounds r half away from zero to a rate
+func rateFromRat(r *big.Rat) (Rate, error) {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt64(rateFactor))
+	q, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(scaled.Denom()) >= 0 {
+		if scaled.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return Rate{}, ErrOverflow
+	}
+	return Rate{units: q.Int64()}, nil
+}
+
+// rat returns the rate as a fraction
+func (r Rate) rat() *big.Rat {
+	return new(big.Rat).SetFrac64(r.units, rateFactor)
+}
+
+// MustParseRate is like ParseRate but panics on error. It is intended for constants and tests.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Cmp compares r and o, returning -1, 0 or +1
+func (r Rate) Cmp(o Rate) int {
+	switch {
+	case r.units < o.units:
+		return -1
+	case r.units > o.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the rate
+func (r Rate) Sign() int {
+	return r.Cmp(Rate{})
+}
+
+// IsZero reports whether the rate is zero
+func (r Rate) IsZero() bool {
+	return r.units == 0
+}
+
+// Sub returns r less o
+func (r Rate) Sub(o Rate) (Rate, error) {
+	diff := r.units - o.units
+	if (o.units > 0 && diff > r.units) || (o.units < 0 && diff < r.units) {
+		return Rate{}, ErrOverflow
+	}
+	return Rate{units: diff}, nil
+}
+
+// Mul returns the product of r and o, rounded half away from zero to RateScale places
+func (r Rate) Mul(o Rate) (Rate, error) {
+	return rateFromRat(new(big.Rat).Mul(r.rat(), o.rat()))
+}
+
+// Inverse returns 1/r, rounded half away from zero to RateScale places, as
+// used to turn a rate from A to B into one from B to A
+func (r Rate) Inverse() (Rate, error) {
+	if r.IsZero() {
+		return Rate{}, fmt.Errorf("%w: zero rate has no inverse", ErrInvalidAmount)
+	}
+	return rateFromRat(new(big.Rat).Inv(r.rat()))
+}
+
+// Round rounds the rate half away from zero to places fractional digits
+func (r Rate) Round(places int) Rate {
+	if places >= RateScale {
+		return r
+	}
+	return Rate{units: roundUnits(r.units, pow10(RateScale-places))}
+}
+
+// Float64 returns the nearest float to the rate. It exists for boundaries
+// that still speak float64 and should not be used for arithmetic.
+func (r Rate) Float64() float64 {
+	f, _ := r.rat().Float64()
+	return f
+}
+
+// Decimal formats the rate as a plain decimal string without trailing zeros,
+// e.g. "0.015"
+func (r Rate) Decimal() string {
+	s := r.rat().FloatString(RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// String formats the rate as Decimal does
+func (r Rate) String() string {
+	return r.Decimal()
+}
+
+// MarshalJSON encodes the rate as an exact JSON number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.Decimal()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or numeric string without going
+// through float64
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*r = Rate{}
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value implements driver.Valuer for DECIMAL columns
+func (r Rate) Value() (driver.Value, error) {
+	return r.Decimal(), nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns
+func (r *Rate) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		// Drivers without a decimal type (e.g. SQLite) return floats
+		s = strconv.FormatFloat(v, 'f', RateScale, 64)
+	default:
+		return fmt.Errorf("cannot scan %T into Rate", value)
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// ApplyRate multiplies the amount by rate and returns the result in currency,
+// rounded half away from zero to that currency's minor units. Unlike Convert,
+// the rate may be zero.
+func (m Money) ApplyRate(rate Rate, currency string) (Money, error) {
+	return m.convertRat(rate.rat(), currency)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -16,9 +17,35 @@ type AccountRepository interface {
 	GetAccountByID(ctx context.Context, id string) (*models.Account, error)
 	GetAccountsByUserID(ctx context.Context, userID string) ([]*models.Account, error)
 	UpdateAccount(ctx context.Context, account *models.Account) error
-	DeleteAccount(ctx context.Context, id string) error // Soft delete might be preferred in production
+	// ChangeAccountStatus moves an account from change.FromStatus to change.ToStatus
+	// and records the change in its status history. It returns
+	// ErrAccountStatusConflict if the account is no longer in change.FromStatus.
+	ChangeAccountStatus(ctx context.Context, change *models.AccountStatusChange) error
+	GetAccountStatusHistory(ctx context.Context, accountID string) ([]*models.AccountStatusChange, error)
+
+	// GetAccountByCode retrieves an account by its chart of accounts code, or nil if none has it
+	GetAccountByCode(ctx context.Context, code string) (*models.Account, error)
+	// GetChildAccounts retrieves the direct children of an account
+	GetChildAccounts(ctx context.Context, parentID string) ([]*models.Account, error)
+	// GetSubtreeAccountIDs returns the ID of an account and of all its descendants
+	GetSubtreeAccountIDs(ctx context.Context, rootID string) ([]string, error)
+	// HasChildAccounts reports whether an account is a parent node
+	HasChildAccounts(ctx context.Context, id string) (bool, error)
+	// HasPostings reports whether any entry line references an account
+	HasPostings(ctx context.Context, id string) (bool, error)
+	// SetParentAccount moves an account under parentID, or to the top level if
+	// parentID is empty. It returns ErrAccountCycle if parentID is the account
+	// itself or one of its descendants.
+	SetParentAccount(ctx context.Context, id, parentID string) error
 }
 
+var (
+	// ErrAccountStatusConflict is returned when an account's status changed concurrently
+	ErrAccountStatusConflict = errors.New("account status was changed concurrently")
+	// ErrAccountCycle is returned when a parent change would make an account its own ancestor
+	ErrAccountCycle = errors.New("account hierarchy would contain a cycle")
+)
+
 // accountRepository implements AccountRepository using GORM.
 type accountRepository struct {
 	db *gorm.DB
@@ -41,6 +68,12 @@ func (r *accountRepository) CreateAccount(ctx context.Context, account *models.A
 		account.CreatedAt = time.Now()
 	}
 	account.UpdatedAt = time.Now() // Ensure UpdatedAt is set on creation as well
+	if account.Status == "" {
+		account.Status = models.AccountStatusActive
+	}
+	if account.Tier == "" {
+		account.Tier = models.DefaultAccountTier
+	}
 
 	result := r.db.WithContext(ctx).Create(account)
 	if result.Error != nil {
@@ -87,17 +120,144 @@ func (r *accountRepository) UpdateAccount(ctx context.Context, account *models.A
 	return nil
 }
 
-// DeleteAccount deletes an account by its ID using GORM.
-// In a production financial system, a 'soft delete' (marking as inactive) is almost always preferred
-// over a hard delete to maintain historical integrity and audit trails.
-func (r *accountRepository) DeleteAccount(ctx context.Context, id string) error {
-	// For production, consider adding an 'is_active' or 'status' column and updating it to 'inactive'
-	result := r.db.WithContext(ctx).Delete(&models.Account{}, "id = ?", id)
+// ChangeAccountStatus updates an account's status and appends to its status history
+// in a single database transaction. Accounts are never deleted: entry_lines keep
+// referencing them, so closing an account is a status change like any other.
+func (r *accountRepository) ChangeAccountStatus(ctx context.Context, change *models.AccountStatusChange) error {
+	if change.ID == "" {
+		change.ID = uuid.New().String()
+	}
+	if change.CreatedAt.IsZero() {
+		change.CreatedAt = time.Now()
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Account{}).
+			Where("id = ? AND status = ?", change.AccountID, change.FromStatus).
+			Updates(map[string]interface{}{
+				"status":     change.ToStatus,
+				"updated_at": change.CreatedAt,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update account status: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: account %s is no longer %s", ErrAccountStatusConflict, change.AccountID, change.FromStatus)
+		}
+
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("failed to record account status change: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetAccountStatusHistory retrieves the status changes of an account, oldest first
+func (r *accountRepository) GetAccountStatusHistory(ctx context.Context, accountID string) ([]*models.AccountStatusChange, error) {
+	var history []*models.AccountStatusChange
+	result := r.db.WithContext(ctx).Where("account_id = ?", accountID).Order("created_at ASC").Find(&history)
 	if result.Error != nil {
-		return fmt.Errorf("failed to delete account: %w", result.Error)
+		return nil, fmt.Errorf("failed to get account status history: %w", result.Error)
 	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("account with ID %s not found for deletion", id)
+	return history, nil
+}
+
+// GetAccountByCode retrieves an account by its chart of accounts code.
+func (r *accountRepository) GetAccountByCode(ctx context.Context, code string) (*models.Account, error) {
+	account := &models.Account{}
+	result := r.db.WithContext(ctx).First(account, "code = ?", code)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get account by code: %w", result.Error)
 	}
-	return nil
+	return account, nil
+}
+
+// GetChildAccounts retrieves the direct children of an account, ordered by code.
+func (r *accountRepository) GetChildAccounts(ctx context.Context, parentID string) ([]*models.Account, error) {
+	var accounts []*models.Account
+	result := r.db.WithContext(ctx).Where("parent_account_id = ?", parentID).Order("code ASC, name ASC").Find(&accounts)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get child accounts: %w", result.Error)
+	}
+	return accounts, nil
+}
+
+// GetSubtreeAccountIDs walks the hierarchy below an account with a recursive query.
+func (r *accountRepository) GetSubtreeAccountIDs(ctx context.Context, rootID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM accounts WHERE id = ?
+			UNION
+			SELECT accounts.id FROM accounts JOIN subtree ON accounts.parent_account_id = subtree.id
+		)
+		SELECT id FROM subtree`, rootID).
+		Scan(&ids).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account subtree: %w", err)
+	}
+	return ids, nil
+}
+
+// HasChildAccounts reports whether an account is a parent node.
+func (r *accountRepository) HasChildAccounts(ctx context.Context, id string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Account{}).Where("parent_account_id = ?", id).Limit(1).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to count child accounts: %w", err)
+	}
+	return count > 0, nil
+}
+
+// HasPostings reports whether any entry line references an account.
+func (r *accountRepository) HasPostings(ctx context.Context, id string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.EntryLine{}).Where("account_id = ?", id).Limit(1).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to count entry lines: %w", err)
+	}
+	return count > 0, nil
+}
+
+// SetParentAccount checks for cycles and updates the parent within one database transaction.
+func (r *accountRepository) SetParentAccount(ctx context.Context, id, parentID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var parent interface{}
+		if parentID != "" {
+			// The new parent must not be the account itself or sit below it
+			var ancestors []string
+			err := tx.Raw(`
+				WITH RECURSIVE ancestors(id, parent_account_id) AS (
+					SELECT id, parent_account_id FROM accounts WHERE id = ?
+					UNION
+					SELECT accounts.id, accounts.parent_account_id FROM accounts JOIN ancestors ON accounts.id = ancestors.parent_account_id
+				)
+				SELECT id FROM ancestors`, parentID).
+				Scan(&ancestors).
+				Error
+			if err != nil {
+				return fmt.Errorf("failed to get account ancestors: %w", err)
+			}
+			for _, ancestor := range ancestors {
+				if ancestor == id {
+					return fmt.Errorf("%w: %s cannot be moved under %s", ErrAccountCycle, id, parentID)
+				}
+			}
+			parent = parentID
+		}
+
+		result := tx.Model(&models.Account{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"parent_account_id": parent,
+			"updated_at":        time.Now(),
+		})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update parent account: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("account with ID %s not found for update", id)
+		}
+		return nil
+	})
 }
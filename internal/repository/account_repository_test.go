@@ -54,7 +54,7 @@ func TestMain(m *testing.M) {
 	accountRepo = repository.NewAccountRepository(testDB)
 
 	// Migrate the schema for the test database
-	err = testDB.AutoMigrate(&models.Account{})
+	err = testDB.AutoMigrate(&models.Account{}, &models.AccountStatusChange{})
 	if err != nil {
 		log.Fatalf("Failed to auto migrate schema for test database: %v", err)
 	}
@@ -198,34 +198,47 @@ func TestUpdateAccount(t *testing.T) {
 	assert.Contains(t, err.Error(), "not found for update")
 }
 
-func TestDeleteAccount(t *testing.T) {
+func TestChangeAccountStatus(t *testing.T) {
 	clearAccountsTable(t)
 	ctx := context.Background()
 
 	account := &models.Account{
-		Name:     "Account to Delete",
+		Name:     "Account to Close",
 		Type:     models.Expense,
 		UserID:   uuid.New().String(),
 		Currency: "AUD",
 	}
 	require.NoError(t, accountRepo.CreateAccount(ctx, account))
-
-	// Verify it exists before deletion
-	retrievedAccount, err := accountRepo.GetAccountByID(ctx, account.ID)
+	assert.Equal(t, models.AccountStatusActive, account.Status)
+
+	// Close the account
+	err := accountRepo.ChangeAccountStatus(ctx, &models.AccountStatusChange{
+		AccountID:  account.ID,
+		FromStatus: models.AccountStatusActive,
+		ToStatus:   models.AccountStatusClosed,
+		Reason:     "customer request",
+		Actor:      "ops@example.com",
+	})
 	require.NoError(t, err)
-	assert.NotNil(t, retrievedAccount)
 
-	// Perform deletion
-	err = accountRepo.DeleteAccount(ctx, account.ID)
+	// The account is kept, only its status changes
+	closedAccount, err := accountRepo.GetAccountByID(ctx, account.ID)
 	require.NoError(t, err)
+	require.NotNil(t, closedAccount, "Account should not be deleted")
+	assert.Equal(t, models.AccountStatusClosed, closedAccount.Status)
 
-	// Verify it no longer exists
-	deletedAccount, err := accountRepo.GetAccountByID(ctx, account.ID)
+	history, err := accountRepo.GetAccountStatusHistory(ctx, account.ID)
 	require.NoError(t, err)
-	assert.Nil(t, deletedAccount, "Account should be deleted")
-
-	// Test deleting non-existent account
-	err = accountRepo.DeleteAccount(ctx, uuid.New().String())
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "not found for deletion")
+	require.Len(t, history, 1)
+	assert.Equal(t, "customer request", history[0].Reason)
+
+	// A change from a stale status is rejected
+	err = accountRepo.ChangeAccountStatus(ctx, &models.AccountStatusChange{
+		AccountID:  account.ID,
+		FromStatus: models.AccountStatusActive,
+		ToStatus:   models.AccountStatusFrozen,
+		Reason:     "stale",
+		Actor:      "ops@example.com",
+	})
+	assert.ErrorIs(t, err, repository.ErrAccountStatusConflict)
 }
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrBalanceVersionConflict is returned when a balance snapshot was modified by a concurrent posting
+	ErrBalanceVersionConflict = errors.New("balance snapshot was modified concurrently")
+	// ErrInsufficientFunds is returned when a posting would overdraw a user wallet
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// BalanceDrift describes an account whose snapshot disagrees with its entry lines
+type BalanceDrift struct {
+	AccountID      string      `json:"account_id"`
+	SnapshotDebit  money.Money `json:"snapshot_debit"`
+	SnapshotCredit money.Money `json:"snapshot_credit"`
+	LedgerDebit    money.Money `json:"ledger_debit"`
+	LedgerCredit   money.Money `json:"ledger_credit"`
+	Version        int64       `json:"version"`
+}
+
+// BalanceRepository defines the interface for materialized balance operations
+type BalanceRepository interface {
+	// GetSnapshot retrieves the balance snapshot of an account, or nil if none exists yet
+	GetSnapshot(ctx context.Context, accountID string) (*models.BalanceSnapshot, error)
+	// FindDrift recomputes every account's totals from entry_lines and returns the
+	// accounts whose snapshot does not match
+	FindDrift(ctx context.Context) ([]BalanceDrift, error)
+}
+
+type balanceRepository struct {
+	db *gorm.DB
+}
+
+// NewBalanceRepository creates a new BalanceRepository
+func NewBalanceRepository(db *gorm.DB) BalanceRepository {
+	return &balanceRepository{db: db}
+}
+
+// lineTotals holds summed debits and credits for an account
+type lineTotals struct {
+	AccountID   string
+	TotalDebit  money.Money
+	TotalCredit money.Money
+}
+
+// countsTowardBalance reports whether entries in the given status affect balances
+func countsTowardBalance(status string) bool {
+	return status != models.EntryStatusPending && status != models.EntryStatusVoided
+}
+
+func (r *balanceRepository) GetSnapshot(ctx context.Context, accountID string) (*models.BalanceSnapshot, error) {
+	var snapshot models.BalanceSnapshot
+	err := r.db.WithContext(ctx).First(&snapshot, "account_id = ?", accountID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get balance snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func (r *balanceRepository) FindDrift(ctx context.Context) ([]BalanceDrift, error) {
+	var drifts []BalanceDrift
+
+	// Read snapshots and ledger totals from one repeatable-read snapshot so
+	// concurrent postings are either fully visible or not at all; under read
+	// committed each query would see the postings committed before it ran
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var snapshots []models.BalanceSnapshot
+		if err := tx.Find(&snapshots).Error; err != nil {
+			return err
+		}
+
+		var totals []lineTotals
+		err := tx.Table("entry_lines").
+			Select("entry_lines.account_id AS account_id, COALESCE(SUM(entry_lines.debit), 0) AS total_debit, COALESCE(SUM(entry_lines.credit), 0) AS total_credit").
+			Joins("JOIN entries ON entries.id = entry_lines.entry_id").
+			Where("entries.status NOT IN ?", []string{models.EntryStatusPending, models.EntryStatusVoided}).
+			Group("entry_lines.account_id").
+			Scan(&totals).
+			Error
+		if err != nil {
+			return err
+		}
+
+		ledger := make(map[string]lineTotals, len(totals))
+		for _, t := range totals {
+			ledger[t.AccountID] = t
+		}
+
+		for _, snapshot := range snapshots {
+			actual := ledger[snapshot.AccountID]
+			delete(ledger, snapshot.AccountID)
+			if actual.TotalDebit.Cmp(snapshot.TotalDebit) != 0 || actual.TotalCredit.Cmp(snapshot.TotalCredit) != 0 {
+				drifts = append(drifts, BalanceDrift{
+					AccountID:      snapshot.AccountID,
+					SnapshotDebit:  snapshot.TotalDebit,
+					SnapshotCredit: snapshot.TotalCredit,
+					LedgerDebit:    actual.TotalDebit,
+					LedgerCredit:   actual.TotalCredit,
+					Version:        snapshot.Version,
+				})
+			}
+		}
+
+		// Accounts with postings but no snapshot at all
+		for accountID, actual := range ledger {
+			drifts = append(drifts, BalanceDrift{
+				AccountID:    accountID,
+				LedgerDebit:  actual.TotalDebit,
+				LedgerCredit: actual.TotalCredit,
+			})
+		}
+
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare balance snapshots: %w", err)
+	}
+
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].AccountID < drifts[j].AccountID })
+	return drifts, nil
+}
+
+// applyBalanceDeltas updates the snapshot of every account touched by lines
+// inside the caller's database transaction. Each snapshot update is
+// conditional on the version read, so a concurrent posting to the same account
+// makes this return ErrBalanceVersionConflict and the caller must retry.
+func applyBalanceDeltas(tx *gorm.DB, entryID string, lines []models.EntryLine) error {
+	deltas := make(map[string]*lineTotals)
+	accountIDs := make([]string, 0, len(lines))
+	for _, line := range lines {
+		d, ok := deltas[line.AccountID]
+		if !ok {
+			d = &lineTotals{AccountID: line.AccountID}
+			deltas[line.AccountID] = d
+			accountIDs = append(accountIDs, line.AccountID)
+		}
+		var err error
+		if d.TotalDebit, err = d.TotalDebit.Add(line.Debit); err != nil {
+			return fmt.Errorf("account %s: %w", line.AccountID, err)
+		}
+		if d.TotalCredit, err = d.TotalCredit.Add(line.Credit); err != nil {
+			return fmt.Errorf("account %s: %w", line.AccountID, err)
+		}
+	}
+
+	// Touch accounts in a stable order so concurrent postings cannot deadlock
+	sort.Strings(accountIDs)
+
+	var accounts []models.Account
+	if err := tx.Where("id IN ?", accountIDs).Find(&accounts).Error; err != nil {
+		return fmt.Errorf("failed to load accounts: %w", err)
+	}
+	accountsByID := make(map[string]models.Account, len(accounts))
+	for _, account := range accounts {
+		accountsByID[account.ID] = account
+	}
+
+	now := time.Now()
+	for _, accountID := range accountIDs {
+		delta := deltas[accountID]
+
+		snapshot, err := loadOrInitSnapshot(tx, accountID)
+		if err != nil {
+			return err
+		}
+
+		newDebit, err := snapshot.TotalDebit.Add(delta.TotalDebit)
+		if err != nil {
+			return fmt.Errorf("account %s: %w", accountID, err)
+		}
+		newCredit, err := snapshot.TotalCredit.Add(delta.TotalCredit)
+		if err != nil {
+			return fmt.Errorf("account %s: %w", accountID, err)
+		}
+
+		if account, ok := accountsByID[accountID]; ok {
+			// Re-check the status inside the posting transaction in case it
+			// changed after the entry was validated
+			if err := account.CheckPosting(delta.TotalDebit.IsPositive(), delta.TotalCredit.IsPositive()); err != nil {
+				return err
+			}
+			if account.UserID != "" {
+				if err := checkOverdraw(account, delta, newDebit, newCredit); err != nil {
+					return err
+				}
+			}
+		}
+
+		if snapshot.Version == 0 {
+			snapshot.TotalDebit = newDebit
+			snapshot.TotalCredit = newCredit
+			snapshot.Version = 1
+			snapshot.LastEntryID = entryID
+			snapshot.UpdatedAt = now
+
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(snapshot)
+			if result.Error != nil {
+				return fmt.Errorf("failed to create balance snapshot: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: account %s", ErrBalanceVersionConflict, accountID)
+			}
+			continue
+		}
+
+		result := tx.Model(&models.BalanceSnapshot{}).
+			Where("account_id = ? AND version = ?", accountID, snapshot.Version).
+			Updates(map[string]interface{}{
+				"total_debit":   newDebit,
+				"total_credit":  newCredit,
+				"version":       snapshot.Version + 1,
+				"last_entry_id": entryID,
+				"updated_at":    now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update balance snapshot: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: account %s", ErrBalanceVersionConflict, accountID)
+		}
+	}
+
+	return nil
+}
+
+// loadOrInitSnapshot reads an account's snapshot. Accounts without one get an
+// unsaved snapshot (version 0) seeded from the lines already posted to them.
+func loadOrInitSnapshot(tx *gorm.DB, accountID string) (*models.BalanceSnapshot, error) {
+	var snapshot models.BalanceSnapshot
+	err := tx.First(&snapshot, "account_id = ?", accountID).Error
+	if err == nil {
+		return &snapshot, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load balance snapshot: %w", err)
+	}
+
+	var totals lineTotals
+	err = tx.Table("entry_lines").
+		Select("COALESCE(SUM(entry_lines.debit), 0) AS total_debit, COALESCE(SUM(entry_lines.credit), 0) AS total_credit").
+		Joins("JOIN entries ON entries.id = entry_lines.entry_id").
+		Where("entry_lines.account_id = ?", accountID).
+		Where("entries.status NOT IN ?", []string{models.EntryStatusPending, models.EntryStatusVoided}).
+		Scan(&totals).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed balance snapshot: %w", err)
+	}
+
+	return &models.BalanceSnapshot{
+		AccountID:   accountID,
+		TotalDebit:  totals.TotalDebit,
+		TotalCredit: totals.TotalCredit,
+	}, nil
+}
+
+// checkOverdraw rejects a posting that would take a user wallet below zero
+// in its normal direction. Postings that only increase the balance are
+// always accepted.
+func checkOverdraw(account models.Account, delta *lineTotals, newDebit, newCredit money.Money) error {
+	balance, err := newCredit.Sub(newDebit)
+	if err != nil {
+		return err
+	}
+	change, err := delta.TotalCredit.Sub(delta.TotalDebit)
+	if err != nil {
+		return err
+	}
+	if account.Type.IsDebitNormal() {
+		balance = balance.Neg()
+		change = change.Neg()
+	}
+
+	if change.IsNegative() && balance.IsNegative() {
+		return fmt.Errorf("%w: account %s would have a balance of %s", ErrInsufficientFunds, account.ID, balance.WithCurrency(account.Currency))
+	}
+	return nil
+}
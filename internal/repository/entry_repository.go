@@ -2,14 +2,116 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 )
 
+// ErrEntryNotReversible is returned when an entry is no longer in the posted state
+var ErrEntryNotReversible = errors.New("entry is not in a reversible state")
+
 // EntryRepository defines the interface for entry data operations
 type EntryRepository interface {
 	CreateEntry(ctx context.Context, entry *models.Entry) error
+	// CreateReversal marks a posted entry as reversed and creates its reversal entry in one transaction
+	CreateReversal(ctx context.Context, originalID string, reversal *models.Entry) error
+	// CreateTransactionEntry records a wallet transaction, posts its entry and,
+	// if quoteID is set, marks the FX quote the entry converts at as used, all
+	// in one transaction. The quote must be unused and unexpired at at.
+	CreateTransactionEntry(ctx context.Context, walletTx *models.Transaction, entry *models.Entry, quoteID string, at time.Time) error
+	// CreateEntries creates several entries in one transaction, so either all of them are posted or none is
+	CreateEntries(ctx context.Context, entries []*models.Entry) error
+	// HasPostedEntries reports whether a posted entry of the transaction type is dated exactly date
+	HasPostedEntries(ctx context.Context, transactionType string, date time.Time) (bool, error)
 	GetEntryByID(ctx context.Context, id string) (*models.Entry, error)
 	GetEntriesByDateRange(ctx context.Context, startDate, endDate time.Time, page, pageSize int) ([]*models.Entry, int64, error)
+	// SumAccountLines returns the total debits and credits posted to an account by entries dated at or before asOf
+	SumAccountLines(ctx context.Context, accountID string, asOf time.Time) (totalDebit, totalCredit money.Money, err error)
+	// SumLinesByCurrency totals the lines posted to a set of accounts by entries
+	// dated at or before asOf, grouped by the accounts' currency
+	SumLinesByCurrency(ctx context.Context, accountIDs []string, asOf time.Time) ([]CurrencyTotals, error)
+	// SumLinesByAccount totals the lines of every account with postings matching
+	// the filter, ordered by currency and account code. The aggregation runs in
+	// the database.
+	SumLinesByAccount(ctx context.Context, filter LineFilter) ([]AccountTotals, error)
+	// GetAccountLines returns the lines posted to an account in statement
+	// order, by entry date and then line ID, each with the other lines of its entry
+	GetAccountLines(ctx context.Context, accountID string, query AccountLinesQuery) ([]AccountLine, error)
+	// SumAccountLinesThrough returns the total debits and credits posted to an
+	// account by every line up to and including pos in statement order
+	SumAccountLinesThrough(ctx context.Context, accountID string, pos LinePosition) (totalDebit, totalCredit money.Money, err error)
+}
+
+// LinePosition identifies a place in an account's lines in statement order.
+// A zero LineID places it before every line dated Date.
+type LinePosition struct {
+	Date   time.Time
+	LineID string
+}
+
+// AccountLinesQuery selects a page of an account's lines
+type AccountLinesQuery struct {
+	// From and To bound the entry dates included, inclusive
+	From time.Time
+	To   time.Time
+	// After, if set, only returns lines after this position
+	After *LinePosition
+	Limit int
+}
+
+// AccountLine is a line posted to an account together with its entry's details
+type AccountLine struct {
+	LineID          string
+	EntryID         string
+	Date            time.Time
+	Description     string
+	ReferenceID     string
+	TransactionType string
+	Debit           money.Money
+	Credit          money.Money
+	// Counterparts are the entry's lines on other accounts
+	Counterparts []CounterpartLine `gorm:"-"`
+}
+
+// CounterpartLine is a line on the other side of an account's posting
+type CounterpartLine struct {
+	EntryID   string
+	AccountID string
+	Code      string
+	Name      string
+	Currency  string
+	Debit     money.Money
+	Credit    money.Money
+}
+
+// LineFilter selects the entry lines included in an aggregate
+type LineFilter struct {
+	// From is the earliest entry date included; zero means from the beginning
+	From time.Time
+	// To is the latest entry date included
+	To time.Time
+	// AccountTypes restricts the aggregate to accounts of these types; empty means all types
+	AccountTypes []models.AccountType
+	// ExcludeTransactionTypes leaves out the lines of entries of these transaction types
+	ExcludeTransactionTypes []string
+}
+
+// AccountTotals holds the summed debits and credits of one account
+type AccountTotals struct {
+	AccountID   string
+	Code        string
+	Name        string
+	Type        models.AccountType
+	Currency    string
+	TotalDebit  money.Money
+	TotalCredit money.Money
+}
+
+// CurrencyTotals holds summed debits and credits in one currency
+type CurrencyTotals struct {
+	Currency    string
+	TotalDebit  money.Money
+	TotalCredit money.Money
 }
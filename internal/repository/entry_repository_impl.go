@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"gorm.io/gorm"
 )
 
@@ -17,37 +21,172 @@ func NewEntryRepository(db *gorm.DB) EntryRepository {
 	return &entryRepository{db: db}
 }
 
+// maxBalanceRetries bounds how often a posting is retried after losing a
+// race on an account's balance snapshot
+const maxBalanceRetries = 5
+
+// balanceRetryDelay is the wait before the first retry of a posting; it
+// doubles with each retry and is jittered so that contending postings spread out
+const balanceRetryDelay = 5 * time.Millisecond
+
 func (r *entryRepository) CreateEntry(ctx context.Context, entry *models.Entry) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Generate a new UUID for the entry if not set
+	return r.withBalanceRetry(ctx, func(tx *gorm.DB) error {
+		return insertEntry(tx, entry)
+	})
+}
+
+func (r *entryRepository) CreateReversal(ctx context.Context, originalID string, reversal *models.Entry) error {
+	return r.withBalanceRetry(ctx, func(tx *gorm.DB) error {
+		// Only a posted entry can be reversed; the status check makes a
+		// concurrent second reversal fail here
+		result := tx.Model(&models.Entry{}).
+			Where("id = ? AND status = ?", originalID, models.EntryStatusPosted).
+			Update("status", models.EntryStatusReversed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrEntryNotReversible, originalID)
+		}
+
+		reversal.ReversalOfID = originalID
+		return insertEntry(tx, reversal)
+	})
+}
+
+func (r *entryRepository) CreateTransactionEntry(ctx context.Context, walletTx *models.Transaction, entry *models.Entry, quoteID string, at time.Time) error {
+	return r.withBalanceRetry(ctx, func(tx *gorm.DB) error {
 		if entry.ID == "" {
 			entry.ID = uuid.New().String()
 		}
-
-		// Create the entry
-		if err := tx.Create(entry).Error; err != nil {
+		if quoteID != "" {
+			if err := consumeQuote(tx, quoteID, entry.ID, at); err != nil {
+				return err
+			}
+		}
+		if err := insertEntry(tx, entry); err != nil {
 			return err
 		}
-		
-		// Create all entry lines
-		for i := range entry.Lines {
-			entry.Lines[i].ID = uuid.New().String()
-			entry.Lines[i].EntryID = entry.ID
-			entry.Lines[i].CreatedAt = time.Now()
-
-			if err := tx.Create(&entry.Lines[i]).Error; err != nil {
+		walletTx.EntryID = entry.ID
+		return tx.Create(walletTx).Error
+	})
+}
+
+func (r *entryRepository) CreateEntries(ctx context.Context, entries []*models.Entry) error {
+	return r.withBalanceRetry(ctx, func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			if err := insertEntry(tx, entry); err != nil {
 				return err
 			}
 		}
-		
 		return nil
 	})
 }
 
+func (r *entryRepository) HasPostedEntries(ctx context.Context, transactionType string, date time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Entry{}).
+		Where("transaction_type = ? AND date = ? AND status = ?", transactionType, date, models.EntryStatusPosted).
+		Count(&count).
+		Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// withBalanceRetry runs fn in a database transaction, retrying it after a
+// jittered backoff when a concurrent posting updated one of the same balance
+// snapshots first
+func (r *entryRepository) withBalanceRetry(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	var err error
+	for attempt := 0; attempt < maxBalanceRetries; attempt++ {
+		if attempt > 0 {
+			delay := balanceRetryDelay << (attempt - 1)
+			delay += time.Duration(rand.Int64N(int64(delay)))
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		err = r.db.WithContext(ctx).Transaction(fn)
+		if !errors.Is(err, ErrBalanceVersionConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+// insertEntry writes an entry and its lines and updates the balance snapshots
+// of the accounts it touches, all within tx. The entry's accounting period
+// is locked until tx commits so that it cannot be closed under the entry.
+func insertEntry(tx *gorm.DB, entry *models.Entry) error {
+	if err := lockPeriodForPosting(tx, entry.Date); err != nil {
+		return err
+	}
+
+	// Generate a new UUID for the entry if not set
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+
+	// Create the entry; lines and rates are inserted below so GORM must not auto-save them
+	if err := tx.Omit("Lines", "ExchangeRates").Create(entry).Error; err != nil {
+		return err
+	}
+
+	// Update the materialized balances before the lines are written so that a
+	// snapshot seeded from entry_lines does not already include them
+	if countsTowardBalance(entry.Status) {
+		if err := applyBalanceDeltas(tx, entry.ID, entry.Lines); err != nil {
+			return err
+		}
+	}
+
+	// Create all entry lines
+	for i := range entry.Lines {
+		entry.Lines[i].ID = uuid.New().String()
+		entry.Lines[i].EntryID = entry.ID
+		entry.Lines[i].CreatedAt = time.Now()
+
+		if err := tx.Create(&entry.Lines[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	// Record the rates of a cross-currency entry
+	for i := range entry.ExchangeRates {
+		entry.ExchangeRates[i].ID = uuid.New().String()
+		entry.ExchangeRates[i].EntryID = entry.ID
+		entry.ExchangeRates[i].CreatedAt = time.Now()
+
+		if err := tx.Create(&entry.ExchangeRates[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachLineCurrencies sets the currency of each line's amounts, which are
+// read back from their columns without one
+func attachLineCurrencies(entry *models.Entry) {
+	for i := range entry.Lines {
+		line := &entry.Lines[i]
+		line.Debit = line.Debit.WithCurrency(line.Currency)
+		line.Credit = line.Credit.WithCurrency(line.Currency)
+	}
+}
+
 func (r *entryRepository) GetEntryByID(ctx context.Context, id string) (*models.Entry, error) {
 	var entry models.Entry
 	err := r.db.WithContext(ctx).
 		Preload("Lines").
+		Preload("ExchangeRates").
 		First(&entry, "id = ?", id).
 		Error
 
@@ -57,6 +196,7 @@ func (r *entryRepository) GetEntryByID(ctx context.Context, id string) (*models.
 		}
 		return nil, err
 	}
+	attachLineCurrencies(&entry)
 	return &entry, nil
 }
 
@@ -78,6 +218,7 @@ func (r *entryRepository) GetEntriesByDateRange(ctx context.Context, startDate,
 	offset := (page - 1) * pageSize
 	err = r.db.WithContext(ctx).
 		Preload("Lines").
+		Preload("ExchangeRates").
 		Where("date BETWEEN ? AND ?", startDate, endDate).
 		Order("date DESC").
 		Offset(offset).
@@ -88,6 +229,172 @@ func (r *entryRepository) GetEntriesByDateRange(ctx context.Context, startDate,
 	if err != nil {
 		return nil, 0, err
 	}
+	for _, entry := range entries {
+		attachLineCurrencies(entry)
+	}
 
 	return entries, total, nil
 }
+
+func (r *entryRepository) SumAccountLines(ctx context.Context, accountID string, asOf time.Time) (money.Money, money.Money, error) {
+	var totals lineTotals
+
+	// Pending and voided entries never affect the ledger balance
+	err := r.db.WithContext(ctx).
+		Table("entry_lines").
+		Select("COALESCE(SUM(entry_lines.debit), 0) AS total_debit, COALESCE(SUM(entry_lines.credit), 0) AS total_credit").
+		Joins("JOIN entries ON entries.id = entry_lines.entry_id").
+		Where("entry_lines.account_id = ?", accountID).
+		Where("entries.date <= ?", asOf).
+		Where("entries.status NOT IN ?", []string{models.EntryStatusPending, models.EntryStatusVoided}).
+		Scan(&totals).
+		Error
+
+	if err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+
+	return totals.TotalDebit, totals.TotalCredit, nil
+}
+
+func (r *entryRepository) SumLinesByCurrency(ctx context.Context, accountIDs []string, asOf time.Time) ([]CurrencyTotals, error) {
+	var totals []CurrencyTotals
+	if len(accountIDs) == 0 {
+		return totals, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Table("entry_lines").
+		Select("accounts.currency AS currency, COALESCE(SUM(entry_lines.debit), 0) AS total_debit, COALESCE(SUM(entry_lines.credit), 0) AS total_credit").
+		Joins("JOIN entries ON entries.id = entry_lines.entry_id").
+		Joins("JOIN accounts ON accounts.id = entry_lines.account_id").
+		Where("entry_lines.account_id IN ?", accountIDs).
+		Where("entries.date <= ?", asOf).
+		Where("entries.status NOT IN ?", []string{models.EntryStatusPending, models.EntryStatusVoided}).
+		Group("accounts.currency").
+		Order("accounts.currency").
+		Scan(&totals).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range totals {
+		totals[i].TotalDebit = totals[i].TotalDebit.WithCurrency(totals[i].Currency)
+		totals[i].TotalCredit = totals[i].TotalCredit.WithCurrency(totals[i].Currency)
+	}
+	return totals, nil
+}
+
+func (r *entryRepository) SumLinesByAccount(ctx context.Context, filter LineFilter) ([]AccountTotals, error) {
+	var totals []AccountTotals
+
+	query := r.db.WithContext(ctx).
+		Table("entry_lines").
+		Select("accounts.id AS account_id, COALESCE(accounts.code, '') AS code, accounts.name AS name, accounts.type AS type, accounts.currency AS currency, " +
+			"COALESCE(SUM(entry_lines.debit), 0) AS total_debit, COALESCE(SUM(entry_lines.credit), 0) AS total_credit").
+		Joins("JOIN entries ON entries.id = entry_lines.entry_id").
+		Joins("JOIN accounts ON accounts.id = entry_lines.account_id").
+		Where("entries.date <= ?", filter.To).
+		Where("entries.status NOT IN ?", []string{models.EntryStatusPending, models.EntryStatusVoided})
+	if !filter.From.IsZero() {
+		query = query.Where("entries.date >= ?", filter.From)
+	}
+	if len(filter.AccountTypes) > 0 {
+		query = query.Where("accounts.type IN ?", filter.AccountTypes)
+	}
+	if len(filter.ExcludeTransactionTypes) > 0 {
+		query = query.Where("entries.transaction_type NOT IN ?", filter.ExcludeTransactionTypes)
+	}
+
+	err := query.
+		Group("accounts.id, accounts.code, accounts.name, accounts.type, accounts.currency").
+		Order("accounts.currency, code, accounts.name, accounts.id").
+		Scan(&totals).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range totals {
+		totals[i].TotalDebit = totals[i].TotalDebit.WithCurrency(totals[i].Currency)
+		totals[i].TotalCredit = totals[i].TotalCredit.WithCurrency(totals[i].Currency)
+	}
+	return totals, nil
+}
+
+func (r *entryRepository) GetAccountLines(ctx context.Context, accountID string, query AccountLinesQuery) ([]AccountLine, error) {
+	var lines []AccountLine
+
+	db := r.db.WithContext(ctx).
+		Table("entry_lines").
+		Select("entry_lines.id AS line_id, entry_lines.entry_id AS entry_id, entries.date AS date, entries.description AS description, " +
+			"COALESCE(entries.reference_id, '') AS reference_id, entries.transaction_type AS transaction_type, entry_lines.debit AS debit, entry_lines.credit AS credit").
+		Joins("JOIN entries ON entries.id = entry_lines.entry_id").
+		Where("entry_lines.account_id = ?", accountID).
+		Where("entries.date BETWEEN ? AND ?", query.From, query.To).
+		Where("entries.status NOT IN ?", []string{models.EntryStatusPending, models.EntryStatusVoided})
+	if query.After != nil {
+		db = db.Where("(entries.date > ? OR (entries.date = ? AND entry_lines.id > ?))", query.After.Date, query.After.Date, query.After.LineID)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	if err := db.Order("entries.date, entry_lines.id").Scan(&lines).Error; err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return lines, nil
+	}
+
+	entryIDs := make([]string, 0, len(lines))
+	for _, line := range lines {
+		entryIDs = append(entryIDs, line.EntryID)
+	}
+
+	var counterparts []CounterpartLine
+	err := r.db.WithContext(ctx).
+		Table("entry_lines").
+		Select("entry_lines.entry_id AS entry_id, entry_lines.account_id AS account_id, COALESCE(accounts.code, '') AS code, " +
+			"accounts.name AS name, accounts.currency AS currency, entry_lines.debit AS debit, entry_lines.credit AS credit").
+		Joins("JOIN accounts ON accounts.id = entry_lines.account_id").
+		Where("entry_lines.entry_id IN ?", entryIDs).
+		Where("entry_lines.account_id <> ?", accountID).
+		Order("entry_lines.entry_id, accounts.name, entry_lines.id").
+		Scan(&counterparts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	byEntry := make(map[string][]CounterpartLine)
+	for _, c := range counterparts {
+		c.Debit = c.Debit.WithCurrency(c.Currency)
+		c.Credit = c.Credit.WithCurrency(c.Currency)
+		byEntry[c.EntryID] = append(byEntry[c.EntryID], c)
+	}
+	for i := range lines {
+		lines[i].Counterparts = byEntry[lines[i].EntryID]
+	}
+	return lines, nil
+}
+
+func (r *entryRepository) SumAccountLinesThrough(ctx context.Context, accountID string, pos LinePosition) (money.Money, money.Money, error) {
+	var totals lineTotals
+
+	err := r.db.WithContext(ctx).
+		Table("entry_lines").
+		Select("COALESCE(SUM(entry_lines.debit), 0) AS total_debit, COALESCE(SUM(entry_lines.credit), 0) AS total_credit").
+		Joins("JOIN entries ON entries.id = entry_lines.entry_id").
+		Where("entry_lines.account_id = ?", accountID).
+		Where("(entries.date < ? OR (entries.date = ? AND entry_lines.id <= ?))", pos.Date, pos.Date, pos.LineID).
+		Where("entries.status NOT IN ?", []string{models.EntryStatusPending, models.EntryStatusVoided}).
+		Scan(&totals).
+		Error
+	if err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+
+	return totals.TotalDebit, totals.TotalCredit, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ExchangeRateRepository defines the interface for stored exchange rates and
+// the audit of the conversions they served
+type ExchangeRateRepository interface {
+	// CreateRate stores a rate for a currency pair
+	CreateRate(ctx context.Context, rate *models.ExchangeRate) error
+
+	// GetEffectiveRate retrieves the rate from base to quote with the latest
+	// effective time at or before at, or nil if none is in effect
+	GetEffectiveRate(ctx context.Context, base, quote string, at time.Time) (*models.ExchangeRate, error)
+
+	// RecordConversion stores the audit record of a conversion
+	RecordConversion(ctx context.Context, audit *models.ExchangeRateAudit) error
+
+	// ListConversions retrieves the audit records of conversions made in [from, to], newest first
+	ListConversions(ctx context.Context, from, to time.Time) ([]*models.ExchangeRateAudit, error)
+}
+
+type exchangeRateRepository struct {
+	db *gorm.DB
+}
+
+// NewExchangeRateRepository creates a new ExchangeRateRepository
+func NewExchangeRateRepository(db *gorm.DB) ExchangeRateRepository {
+	return &exchangeRateRepository{db: db}
+}
+
+func (r *exchangeRateRepository) CreateRate(ctx context.Context, rate *models.ExchangeRate) error {
+	if rate.ID == "" {
+		rate.ID = uuid.New().String()
+	}
+	if rate.EffectiveFrom.IsZero() {
+		rate.EffectiveFrom = time.Now()
+	}
+	return r.db.WithContext(ctx).Create(rate).Error
+}
+
+func (r *exchangeRateRepository) GetEffectiveRate(ctx context.Context, base, quote string, at time.Time) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := r.db.WithContext(ctx).
+		Where("base_currency = ? AND quote_currency = ? AND effective_from <= ?", base, quote, at).
+		Order("effective_from DESC").
+		First(&rate).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *exchangeRateRepository) RecordConversion(ctx context.Context, audit *models.ExchangeRateAudit) error {
+	if audit.ID == "" {
+		audit.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Create(audit).Error
+}
+
+func (r *exchangeRateRepository) ListConversions(ctx context.Context, from, to time.Time) ([]*models.ExchangeRateAudit, error) {
+	var audits []*models.ExchangeRateAudit
+	err := r.db.WithContext(ctx).
+		Where("created_at >= ? AND created_at <= ?", from, to).
+		Order("created_at DESC").
+		Find(&audits).
+		Error
+	return audits, err
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FeeScheduleRepository defines the interface for fee schedule storage.
+// Schedules are only ever added, never updated.
+type FeeScheduleRepository interface {
+	// CreateSchedule stores a schedule as the next version for its
+	// transaction type, currency and account tier
+	CreateSchedule(ctx context.Context, schedule *models.FeeSchedule) error
+
+	// GetScheduleByID retrieves a schedule, or nil if it does not exist
+	GetScheduleByID(ctx context.Context, id string) (*models.FeeSchedule, error)
+
+	// GetEffectiveSchedule retrieves the schedule for a transaction type,
+	// currency and account tier with the latest effective time at or before
+	// at, or nil if none is in effect. Of schedules taking effect at the same
+	// time, the latest version wins.
+	GetEffectiveSchedule(ctx context.Context, txType, currency, tier string, at time.Time) (*models.FeeSchedule, error)
+
+	// ListSchedules retrieves the schedules matching a filter, by transaction
+	// type, currency, tier and then version
+	ListSchedules(ctx context.Context, filter FeeScheduleFilter) ([]*models.FeeSchedule, error)
+}
+
+// FeeScheduleFilter selects fee schedules; empty fields match every schedule
+type FeeScheduleFilter struct {
+	TransactionType string
+	Currency        string
+	AccountTier     string
+}
+
+type feeScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewFeeScheduleRepository creates a new FeeScheduleRepository
+func NewFeeScheduleRepository(db *gorm.DB) FeeScheduleRepository {
+	return &feeScheduleRepository{db: db}
+}
+
+// CreateSchedule implements FeeScheduleRepository. Two schedules created for
+// the same key at once cannot both take a version; the loser fails on the
+// unique index.
+func (r *feeScheduleRepository) CreateSchedule(ctx context.Context, schedule *models.FeeSchedule) error {
+	if schedule.ID == "" {
+		schedule.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		err := tx.Model(&models.FeeSchedule{}).
+			Where("transaction_type = ? AND currency = ? AND account_tier = ?", schedule.TransactionType, schedule.Currency, schedule.AccountTier).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).
+			Error
+		if err != nil {
+			return err
+		}
+		schedule.Version = latest + 1
+		return tx.Create(schedule).Error
+	})
+}
+
+func (r *feeScheduleRepository) GetScheduleByID(ctx context.Context, id string) (*models.FeeSchedule, error) {
+	var schedule models.FeeSchedule
+	if err := r.db.WithContext(ctx).First(&schedule, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *feeScheduleRepository) GetEffectiveSchedule(ctx context.Context, txType, currency, tier string, at time.Time) (*models.FeeSchedule, error) {
+	var schedule models.FeeSchedule
+	err := r.db.WithContext(ctx).
+		Where("transaction_type = ? AND currency = ? AND account_tier = ? AND effective_from <= ?", txType, currency, tier, at).
+		Order("effective_from DESC, version DESC").
+		First(&schedule).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *feeScheduleRepository) ListSchedules(ctx context.Context, filter FeeScheduleFilter) ([]*models.FeeSchedule, error) {
+	db := r.db.WithContext(ctx)
+	if filter.TransactionType != "" {
+		db = db.Where("transaction_type = ?", filter.TransactionType)
+	}
+	if filter.Currency != "" {
+		db = db.Where("currency = ?", filter.Currency)
+	}
+	if filter.AccountTier != "" {
+		db = db.Where("account_tier = ?", filter.AccountTier)
+	}
+
+	var schedules []*models.FeeSchedule
+	err := db.Order("transaction_type, currency, account_tier, version").Find(&schedules).Error
+	return schedules, err
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrQuoteNotFound is returned when an FX quote does not exist
+var ErrQuoteNotFound = errors.New("fx quote not found")
+
+// FXQuoteRepository defines the interface for FX quote storage. Quotes are
+// consumed by EntryRepository.CreateTransactionEntry together with the posting.
+type FXQuoteRepository interface {
+	// CreateQuote stores a new quote
+	CreateQuote(ctx context.Context, quote *models.FXQuote) error
+
+	// GetQuoteByID retrieves a quote, or nil if it does not exist
+	GetQuoteByID(ctx context.Context, id string) (*models.FXQuote, error)
+}
+
+type fxQuoteRepository struct {
+	db *gorm.DB
+}
+
+// NewFXQuoteRepository creates a new FXQuoteRepository
+func NewFXQuoteRepository(db *gorm.DB) FXQuoteRepository {
+	return &fxQuoteRepository{db: db}
+}
+
+func (r *fxQuoteRepository) CreateQuote(ctx context.Context, quote *models.FXQuote) error {
+	if quote.ID == "" {
+		quote.ID = uuid.New().String()
+	}
+	return r.db.WithContext(ctx).Create(quote).Error
+}
+
+func (r *fxQuoteRepository) GetQuoteByID(ctx context.Context, id string) (*models.FXQuote, error) {
+	var quote models.FXQuote
+	if err := r.db.WithContext(ctx).First(&quote, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	attachQuoteCurrencies(&quote)
+	return &quote, nil
+}
+
+// consumeQuote marks a quote as used by entryID within tx. The conditional
+// update makes a second consumer, or one arriving after expiry, fail here.
+func consumeQuote(tx *gorm.DB, quoteID, entryID string, at time.Time) error {
+	result := tx.Model(&models.FXQuote{}).
+		Where("id = ? AND consumed_at IS NULL AND expires_at > ?", quoteID, at).
+		Updates(map[string]interface{}{"consumed_at": at, "entry_id": entryID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil
+	}
+
+	// Explain why the quote could not be consumed
+	var quote models.FXQuote
+	if err := tx.First(&quote, "id = ?", quoteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s", ErrQuoteNotFound, quoteID)
+		}
+		return err
+	}
+	if err := quote.CheckUsable(at); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", models.ErrQuoteConsumed, quoteID)
+}
+
+// attachQuoteCurrencies sets the currency of a quote's amounts, which are read
+// back from their columns without one
+func attachQuoteCurrencies(quote *models.FXQuote) {
+	quote.SourceAmount = quote.SourceAmount.WithCurrency(quote.SourceCurrency)
+	quote.DestinationAmount = quote.DestinationAmount.WithCurrency(quote.DestinationCurrency)
+	quote.Fee = quote.Fee.WithCurrency(quote.SourceCurrency)
+}
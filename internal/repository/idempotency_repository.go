@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrIdempotencyKeyExists is returned when reserving a key that is already recorded
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// IdempotencyRepository defines the interface for idempotency key storage
+type IdempotencyRepository interface {
+	// Reserve records a new in-flight request. It returns ErrIdempotencyKeyExists
+	// if the key has been used before, unless the request that reserved it has
+	// held it for longer than lease without completing; such a key is taken
+	// over, since its request is presumed lost. The reservation set on key
+	// identifies the holder to Complete and Release.
+	Reserve(ctx context.Context, key *models.IdempotencyKey, lease time.Duration) error
+
+	// Get retrieves a recorded key, or nil if it has never been used
+	Get(ctx context.Context, key string) (*models.IdempotencyKey, error)
+
+	// Complete stores the response of the request holding the key under
+	// reservation. It does nothing if another request has taken the key over.
+	Complete(ctx context.Context, key, reservation string, statusCode int, contentType string, body []byte) error
+
+	// Release forgets a key still held under reservation so the request can be retried
+	Release(ctx context.Context, key, reservation string) error
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, key *models.IdempotencyKey, lease time.Duration) error {
+	now := time.Now()
+	key.StatusCode = 0
+	key.ReservedAt = now
+	key.Reservation = uuid.New().String()
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return fmt.Errorf("failed to reserve idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// Take over a reservation whose request never completed within the lease
+	result = r.db.WithContext(ctx).
+		Model(&models.IdempotencyKey{}).
+		Where("key = ? AND status_code = 0 AND reserved_at < ?", key.Key, now.Add(-lease)).
+		Updates(map[string]interface{}{
+			"method":       key.Method,
+			"path":         key.Path,
+			"request_hash": key.RequestHash,
+			"reserved_at":  now,
+			"reservation":  key.Reservation,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to reclaim idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrIdempotencyKeyExists, key.Key)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := r.db.WithContext(ctx).First(&record, "key = ?", key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, key, reservation string, statusCode int, contentType string, body []byte) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.IdempotencyKey{}).
+		Where("key = ? AND reservation = ? AND status_code = 0", key, reservation).
+		Updates(map[string]interface{}{
+			"status_code":   statusCode,
+			"content_type":  contentType,
+			"response_body": body,
+		}).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key, reservation string) error {
+	// Only in-flight keys are released; a stored response is never discarded
+	err := r.db.WithContext(ctx).
+		Where("key = ? AND reservation = ? AND status_code = 0", key, reservation).
+		Delete(&models.IdempotencyKey{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
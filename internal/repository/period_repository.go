@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPeriodOverlap is returned when a new accounting period overlaps an existing one
+	ErrPeriodOverlap = errors.New("accounting period overlaps an existing period")
+	// ErrPeriodNameTaken is returned when another accounting period already has the name
+	ErrPeriodNameTaken = errors.New("accounting period name is already in use")
+	// ErrPeriodStatusConflict is returned when a period's status changed concurrently
+	ErrPeriodStatusConflict = errors.New("accounting period status was changed concurrently")
+)
+
+type periodAdjustmentKey struct{}
+
+// WithPeriodAdjustment returns a context that carries the permission to post
+// adjustments into soft-closed accounting periods
+func WithPeriodAdjustment(ctx context.Context) context.Context {
+	return context.WithValue(ctx, periodAdjustmentKey{}, true)
+}
+
+// HasPeriodAdjustment reports whether ctx carries the period adjustment permission
+func HasPeriodAdjustment(ctx context.Context) bool {
+	allowed, _ := ctx.Value(periodAdjustmentKey{}).(bool)
+	return allowed
+}
+
+// PeriodRepository defines the interface for accounting period storage
+type PeriodRepository interface {
+	// CreatePeriod stores a new period, rejecting it with ErrPeriodOverlap if its
+	// dates overlap an existing period or ErrPeriodNameTaken if its name is in use
+	CreatePeriod(ctx context.Context, period *models.AccountingPeriod) error
+
+	// GetPeriodByID retrieves a period, or nil if it does not exist
+	GetPeriodByID(ctx context.Context, id string) (*models.AccountingPeriod, error)
+
+	// ListPeriods retrieves all periods, oldest first
+	ListPeriods(ctx context.Context) ([]*models.AccountingPeriod, error)
+
+	// FindPeriodForDate retrieves the period containing date, or nil if no period covers it
+	FindPeriodForDate(ctx context.Context, date time.Time) (*models.AccountingPeriod, error)
+
+	// UpdatePeriodStatus moves a period from one status to another, returning
+	// ErrPeriodStatusConflict if it is no longer in the from status
+	UpdatePeriodStatus(ctx context.Context, id string, from, to models.PeriodStatus, actor string) error
+
+	// SaveClosingBalances replaces the closing balance snapshot of a period
+	SaveClosingBalances(ctx context.Context, periodID string, balances []*models.PeriodClosingBalance) error
+
+	// GetClosingBalances retrieves the closing balance snapshot of a period
+	GetClosingBalances(ctx context.Context, periodID string) ([]*models.PeriodClosingBalance, error)
+}
+
+type periodRepository struct {
+	db *gorm.DB
+}
+
+// NewPeriodRepository creates a new PeriodRepository
+func NewPeriodRepository(db *gorm.DB) PeriodRepository {
+	return &periodRepository{db: db}
+}
+
+func (r *periodRepository) CreatePeriod(ctx context.Context, period *models.AccountingPeriod) error {
+	if period.ID == "" {
+		period.ID = uuid.New().String()
+	}
+	if period.Status == "" {
+		period.Status = models.PeriodStatusOpen
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var named int64
+		if err := tx.Model(&models.AccountingPeriod{}).Where("name = ?", period.Name).Count(&named).Error; err != nil {
+			return err
+		}
+		if named > 0 {
+			return fmt.Errorf("%w: %s", ErrPeriodNameTaken, period.Name)
+		}
+
+		var overlapping int64
+		err := tx.Model(&models.AccountingPeriod{}).
+			Where("start_date <= ? AND end_date >= ?", period.EndDate, period.StartDate).
+			Count(&overlapping).
+			Error
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return fmt.Errorf("%w: %s to %s", ErrPeriodOverlap, period.StartDate.Format(time.RFC3339), period.EndDate.Format(time.RFC3339))
+		}
+		return tx.Create(period).Error
+	})
+}
+
+func (r *periodRepository) GetPeriodByID(ctx context.Context, id string) (*models.AccountingPeriod, error) {
+	var period models.AccountingPeriod
+	err := r.db.WithContext(ctx).First(&period, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (r *periodRepository) ListPeriods(ctx context.Context) ([]*models.AccountingPeriod, error) {
+	var periods []*models.AccountingPeriod
+	err := r.db.WithContext(ctx).Order("start_date").Find(&periods).Error
+	return periods, err
+}
+
+func (r *periodRepository) FindPeriodForDate(ctx context.Context, date time.Time) (*models.AccountingPeriod, error) {
+	var period models.AccountingPeriod
+	err := r.db.WithContext(ctx).
+		Where("start_date <= ? AND end_date >= ?", date, date).
+		First(&period).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &period, nil
+}
+
+// lockPeriodForPosting share-locks the accounting period containing date, if
+// any, and rejects the posting if the period is closed to it. The lock holds
+// off a concurrent status change, and so a close and its balance snapshot,
+// until tx commits.
+func lockPeriodForPosting(tx *gorm.DB, date time.Time) error {
+	var period models.AccountingPeriod
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("start_date <= ? AND end_date >= ?", date, date).
+		Take(&period).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to lock accounting period for %s: %w", date.Format(time.RFC3339), err)
+	}
+	return period.CheckPosting(HasPeriodAdjustment(tx.Statement.Context))
+}
+
+func (r *periodRepository) UpdatePeriodStatus(ctx context.Context, id string, from, to models.PeriodStatus, actor string) error {
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": time.Now(),
+	}
+	if to == models.PeriodStatusOpen {
+		updates["closed_at"] = nil
+		updates["closed_by"] = ""
+	} else {
+		updates["closed_at"] = time.Now()
+		updates["closed_by"] = actor
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&models.AccountingPeriod{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrPeriodStatusConflict, id)
+	}
+	return nil
+}
+
+func (r *periodRepository) SaveClosingBalances(ctx context.Context, periodID string, balances []*models.PeriodClosingBalance) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("period_id = ?", periodID).Delete(&models.PeriodClosingBalance{}).Error; err != nil {
+			return err
+		}
+		for _, balance := range balances {
+			if balance.ID == "" {
+				balance.ID = uuid.New().String()
+			}
+			balance.PeriodID = periodID
+			if err := tx.Create(balance).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *periodRepository) GetClosingBalances(ctx context.Context, periodID string) ([]*models.PeriodClosingBalance, error) {
+	var balances []*models.PeriodClosingBalance
+	err := r.db.WithContext(ctx).
+		Where("period_id = ?", periodID).
+		Order("currency, account_id").
+		Find(&balances).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range balances {
+		b.TotalDebit = b.TotalDebit.WithCurrency(b.Currency)
+		b.TotalCredit = b.TotalCredit.WithCurrency(b.Currency)
+		b.Balance = b.Balance.WithCurrency(b.Currency)
+	}
+	return balances, nil
+}
@@ -14,6 +14,10 @@ type TransactionRepository interface {
 	
 	// GetTransactionByID retrieves a transaction by its ID
 	GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error)
+
+	// GetTransactionByEntryReference retrieves the transaction whose entry was
+	// posted with the given reference ID, or nil if there is none
+	GetTransactionByEntryReference(ctx context.Context, referenceID string) (*models.Transaction, error)
 	
 	// UpdateTransaction updates an existing transaction
 	UpdateTransaction(ctx context.Context, tx *models.Transaction) error
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type transactionRepository struct {
+	db *gorm.DB
+}
+
+// NewTransactionRepository creates a new TransactionRepository.
+func NewTransactionRepository(db *gorm.DB) TransactionRepository {
+	return &transactionRepository{db: db}
+}
+
+func (r *transactionRepository) CreateTransaction(ctx context.Context, tx *models.Transaction) error {
+	if tx.ID == "" {
+		tx.ID = uuid.New().String()
+	}
+
+	if err := r.db.WithContext(ctx).Create(tx).Error; err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *transactionRepository) GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error) {
+	var tx models.Transaction
+	err := r.db.WithContext(ctx).First(&tx, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get transaction by ID: %w", err)
+	}
+	return &tx, nil
+}
+
+func (r *transactionRepository) GetTransactionByEntryReference(ctx context.Context, referenceID string) (*models.Transaction, error) {
+	var tx models.Transaction
+	err := r.db.WithContext(ctx).
+		Select("transactions.*").
+		Joins("JOIN entries ON entries.id = transactions.entry_id").
+		Where("entries.reference_id = ?", referenceID).
+		First(&tx).
+		Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get transaction by entry reference: %w", err)
+	}
+	return &tx, nil
+}
+
+func (r *transactionRepository) UpdateTransaction(ctx context.Context, tx *models.Transaction) error {
+	tx.UpdatedAt = time.Now()
+
+	result := r.db.WithContext(ctx).Save(tx)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transaction: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("transaction with ID %s not found for update", tx.ID)
+	}
+	return nil
+}
+
+func (r *transactionRepository) GetTransactionsByAccountID(ctx context.Context, accountID string, page, pageSize int) ([]*models.Transaction, int64, error) {
+	var transactions []*models.Transaction
+	var total int64
+
+	err := r.db.WithContext(ctx).
+		Model(&models.Transaction{}).
+		Where("source_account_id = ? OR target_account_id = ?", accountID, accountID).
+		Count(&total).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err = r.db.WithContext(ctx).
+		Where("source_account_id = ? OR target_account_id = ?", accountID, accountID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&transactions).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+func (r *transactionRepository) GetTransactionsByDateRange(ctx context.Context, startDate, endDate time.Time, page, pageSize int) ([]*models.Transaction, int64, error) {
+	var transactions []*models.Transaction
+	var total int64
+
+	err := r.db.WithContext(ctx).
+		Model(&models.Transaction{}).
+		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+		Count(&total).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err = r.db.WithContext(ctx).
+		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&transactions).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
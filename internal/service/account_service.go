@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+var (
+	// ErrAccountHasBalance is returned when closing an account whose balance is not zero
+	ErrAccountHasBalance = errors.New("account balance must be zero to close it")
+	// ErrInvalidAccountCode is returned when an account code does not follow the chart of accounts format
+	ErrInvalidAccountCode = errors.New("invalid account code")
+	// ErrAccountCodeTaken is returned when another account already uses the code
+	ErrAccountCodeTaken = errors.New("account code is already in use")
+	// ErrInvalidAccountHierarchy is returned when an account cannot be placed under the requested parent
+	ErrInvalidAccountHierarchy = errors.New("invalid account hierarchy")
+)
+
+// accountCodePattern matches chart of accounts codes: a four-digit number
+// optionally followed by dash-separated segments, e.g. 2000-USER-WALLETS-NGN
+var accountCodePattern = regexp.MustCompile(`^[0-9]{4}(-[A-Z0-9]+)*$`)
+
+// CreateAccountRequest represents a request to open a new account
+type CreateAccountRequest struct {
+	Name            string
+	Code            string
+	Type            models.AccountType
+	ParentAccountID string
+	UserID          string
+	Currency        string
+	Tier            string // Defaults to models.DefaultAccountTier
+}
+
+// AccountService defines the interface for account management
+type AccountService interface {
+	// CreateAccount opens a new account
+	CreateAccount(ctx context.Context, req CreateAccountRequest) (*models.Account, error)
+
+	// GetAccount retrieves an account by ID, returning ErrAccountNotFound if it does not exist
+	GetAccount(ctx context.Context, id string) (*models.Account, error)
+
+	// ListAccountsByUser retrieves all accounts owned by a user
+	ListAccountsByUser(ctx context.Context, userID string) ([]*models.Account, error)
+
+	// RenameAccount changes the display name of an account
+	RenameAccount(ctx context.Context, id, name string) (*models.Account, error)
+
+	// ChangeAccountStatus moves an account to a new status, recording the reason
+	// and the actor in its status history. Closing requires a zero balance.
+	ChangeAccountStatus(ctx context.Context, id string, status models.AccountStatus, reason, actor string) (*models.Account, error)
+
+	// CloseAccount closes an account. Only accounts with a zero balance can be closed.
+	CloseAccount(ctx context.Context, id, reason, actor string) (*models.Account, error)
+
+	// GetStatusHistory retrieves the status changes of an account, oldest first
+	GetStatusHistory(ctx context.Context, id string) ([]*models.AccountStatusChange, error)
+
+	// GetAccountByCode retrieves an account by its chart of accounts code
+	GetAccountByCode(ctx context.Context, code string) (*models.Account, error)
+
+	// ListChildAccounts retrieves the direct children of an account
+	ListChildAccounts(ctx context.Context, id string) ([]*models.Account, error)
+
+	// MoveAccount places an account under a new parent, or at the top level if
+	// parentID is empty. Moves that would create a cycle are rejected.
+	MoveAccount(ctx context.Context, id, parentID string) (*models.Account, error)
+}
+
+// accountService implements AccountService
+type accountService struct {
+	accountRepo    repository.AccountRepository
+	balanceService BalanceService
+}
+
+// NewAccountService creates a new AccountService
+func NewAccountService(accountRepo repository.AccountRepository, balanceService BalanceService) AccountService {
+	return &accountService{
+		accountRepo:    accountRepo,
+		balanceService: balanceService,
+	}
+}
+
+// CreateAccount implements AccountService
+func (s *accountService) CreateAccount(ctx context.Context, req CreateAccountRequest) (*models.Account, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("account name is required")
+	}
+	if !req.Type.IsValid() {
+		return nil, fmt.Errorf("invalid account type %q", req.Type)
+	}
+	if len(req.Currency) != 3 {
+		return nil, fmt.Errorf("invalid currency %q", req.Currency)
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code != "" {
+		if !accountCodePattern.MatchString(code) {
+			return nil, fmt.Errorf("%w: %q, expected e.g. 2000-USER-WALLETS", ErrInvalidAccountCode, req.Code)
+		}
+		existing, err := s.accountRepo.GetAccountByCode(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("%w: %s", ErrAccountCodeTaken, code)
+		}
+	}
+
+	account := &models.Account{
+		Name:            name,
+		Code:            code,
+		Type:            req.Type,
+		ParentAccountID: req.ParentAccountID,
+		UserID:          req.UserID,
+		Currency:        strings.ToUpper(req.Currency),
+		Tier:            strings.TrimSpace(req.Tier),
+	}
+	if req.ParentAccountID != "" {
+		if err := s.checkParent(ctx, account, req.ParentAccountID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.accountRepo.CreateAccount(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// checkParent verifies that account may be placed under parentID: the parent
+// must exist, be open, share the account's type so that balances roll up in
+// the same direction, and have no postings of its own, since only leaf
+// accounts are posted to
+func (s *accountService) checkParent(ctx context.Context, account *models.Account, parentID string) error {
+	if parentID == account.ID {
+		return fmt.Errorf("%w: an account cannot be its own parent", ErrInvalidAccountHierarchy)
+	}
+
+	parent, err := s.accountRepo.GetAccountByID(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to get parent account %s: %w", parentID, err)
+	}
+	if parent == nil {
+		return fmt.Errorf("%w: parent account %s not found", ErrInvalidAccountHierarchy, parentID)
+	}
+	if parent.Type != account.Type {
+		return fmt.Errorf("%w: a %s account cannot be placed under %s account %s", ErrInvalidAccountHierarchy, account.Type, parent.Type, parentID)
+	}
+	if parent.Status == models.AccountStatusClosed {
+		return fmt.Errorf("%w: parent account %s is closed", ErrInvalidAccountHierarchy, parentID)
+	}
+
+	hasPostings, err := s.accountRepo.HasPostings(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if hasPostings {
+		return fmt.Errorf("%w: parent account %s already has postings and must stay a leaf", ErrInvalidAccountHierarchy, parentID)
+	}
+
+	return nil
+}
+
+// GetAccount implements AccountService
+func (s *accountService) GetAccount(ctx context.Context, id string) (*models.Account, error) {
+	account, err := s.accountRepo.GetAccountByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", id, err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+	}
+	return account, nil
+}
+
+// ListAccountsByUser implements AccountService
+func (s *accountService) ListAccountsByUser(ctx context.Context, userID string) ([]*models.Account, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	return s.accountRepo.GetAccountsByUserID(ctx, userID)
+}
+
+// RenameAccount implements AccountService
+func (s *accountService) RenameAccount(ctx context.Context, id, name string) (*models.Account, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("account name is required")
+	}
+
+	account, err := s.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	account.Name = name
+	if err := s.accountRepo.UpdateAccount(ctx, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// ChangeAccountStatus implements AccountService
+func (s *accountService) ChangeAccountStatus(ctx context.Context, id string, status models.AccountStatus, reason, actor string) (*models.Account, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to change an account's status")
+	}
+	if strings.TrimSpace(actor) == "" {
+		return nil, errors.New("an actor is required to change an account's status")
+	}
+	if !status.IsValid() {
+		return nil, fmt.Errorf("invalid account status %q", status)
+	}
+
+	account, err := s.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current := account.Status
+	if current == "" {
+		current = models.AccountStatusActive
+	}
+	if !current.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: account %s cannot go from %s to %s", models.ErrInvalidStatusTransition, id, current, status)
+	}
+
+	if status == models.AccountStatusClosed {
+		balance, err := s.balanceService.GetAvailableBalance(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !balance.IsZero() {
+			return nil, fmt.Errorf("%w: account %s has a balance of %s", ErrAccountHasBalance, id, balance)
+		}
+	}
+
+	change := &models.AccountStatusChange{
+		AccountID:  id,
+		FromStatus: current,
+		ToStatus:   status,
+		Reason:     reason,
+		Actor:      actor,
+	}
+	if err := s.accountRepo.ChangeAccountStatus(ctx, change); err != nil {
+		return nil, err
+	}
+
+	account.Status = status
+	account.UpdatedAt = change.CreatedAt
+	return account, nil
+}
+
+// CloseAccount implements AccountService
+func (s *accountService) CloseAccount(ctx context.Context, id, reason, actor string) (*models.Account, error) {
+	return s.ChangeAccountStatus(ctx, id, models.AccountStatusClosed, reason, actor)
+}
+
+// GetStatusHistory implements AccountService
+func (s *accountService) GetStatusHistory(ctx context.Context, id string) ([]*models.AccountStatusChange, error) {
+	if _, err := s.GetAccount(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.accountRepo.GetAccountStatusHistory(ctx, id)
+}
+
+// GetAccountByCode implements AccountService
+func (s *accountService) GetAccountByCode(ctx context.Context, code string) (*models.Account, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	account, err := s.accountRepo.GetAccountByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: code %s", ErrAccountNotFound, code)
+	}
+	return account, nil
+}
+
+// ListChildAccounts implements AccountService
+func (s *accountService) ListChildAccounts(ctx context.Context, id string) ([]*models.Account, error) {
+	if _, err := s.GetAccount(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.accountRepo.GetChildAccounts(ctx, id)
+}
+
+// MoveAccount implements AccountService
+func (s *accountService) MoveAccount(ctx context.Context, id, parentID string) (*models.Account, error) {
+	account, err := s.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != "" {
+		if err := s.checkParent(ctx, account, parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.accountRepo.SetParentAccount(ctx, id, parentID); err != nil {
+		if errors.Is(err, repository.ErrAccountCycle) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAccountHierarchy, err)
+		}
+		return nil, err
+	}
+
+	account.ParentAccountID = parentID
+	return account, nil
+}
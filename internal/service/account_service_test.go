@@ -0,0 +1,173 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountService_Lifecycle(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	accounts := service.NewAccountService(f.accountRepo, service.NewBalanceService(f.entryRepo, f.accountRepo, f.balanceRepo))
+
+	account, err := accounts.CreateAccount(ctx, service.CreateAccountRequest{
+		Name: "Alice wallet", Type: models.Liability, UserID: "alice", Currency: "usd",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", account.Currency)
+
+	_, err = accounts.CreateAccount(ctx, service.CreateAccountRequest{Name: "Bad", Type: "Cash", Currency: "USD"})
+	assert.Error(t, err)
+
+	listed, err := accounts.ListAccountsByUser(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, account.ID, listed[0].ID)
+
+	renamed, err := accounts.RenameAccount(ctx, account.ID, "  Alice main  ")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice main", renamed.Name)
+
+	// An account holding funds cannot be closed
+	_, err = f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: account.ID, Amount: money.MustParse("5", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+	_, err = accounts.CloseAccount(ctx, account.ID, "customer request", "ops")
+	assert.ErrorIs(t, err, service.ErrAccountHasBalance)
+
+	_, err = accounts.GetAccount(ctx, "missing")
+	assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	_, err = accounts.CloseAccount(ctx, "missing", "customer request", "ops")
+	assert.ErrorIs(t, err, service.ErrAccountNotFound)
+
+	empty, err := accounts.CreateAccount(ctx, service.CreateAccountRequest{Name: "Spare", Type: models.Liability, UserID: "alice", Currency: "USD"})
+	require.NoError(t, err)
+	closed, err := accounts.CloseAccount(ctx, empty.ID, "customer request", "ops")
+	require.NoError(t, err)
+	assert.Equal(t, models.AccountStatusClosed, closed.Status)
+
+	// Closed accounts are kept, and closing is final
+	stored, err := accounts.GetAccount(ctx, empty.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AccountStatusClosed, stored.Status)
+	_, err = accounts.ChangeAccountStatus(ctx, empty.ID, models.AccountStatusActive, "reopen", "ops")
+	assert.ErrorIs(t, err, models.ErrInvalidStatusTransition)
+}
+
+func TestAccountStatus_EnforcedAtPosting(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	accounts := service.NewAccountService(f.accountRepo, service.NewBalanceService(f.entryRepo, f.accountRepo, f.balanceRepo))
+	alice := f.createWallet(t, "alice", "USD")
+	bob := f.createWallet(t, "bob", "USD")
+	f.fund(t, alice, "100")
+
+	_, err := accounts.ChangeAccountStatus(ctx, alice.ID, models.AccountStatusFrozen, "fraud review", "compliance")
+	require.NoError(t, err)
+
+	// A frozen wallet can receive funds but not send them
+	_, err = f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("10", "USD"), Currency: "USD",
+	})
+	var statusErr *models.AccountStatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, models.AccountStatusFrozen, statusErr.Status)
+	assert.Equal(t, "debit", statusErr.Operation)
+	f.fund(t, alice, "5")
+
+	_, err = accounts.ChangeAccountStatus(ctx, alice.ID, models.AccountStatusActive, "review cleared", "compliance")
+	require.NoError(t, err)
+	_, err = f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("10", "USD"), Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	// Nothing can be posted to a closed account
+	carol := f.createWallet(t, "carol", "USD")
+	_, err = accounts.CloseAccount(ctx, carol.ID, "duplicate account", "support")
+	require.NoError(t, err)
+	_, err = f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: carol.ID, Amount: money.MustParse("1", "USD"), Currency: "USD"})
+	assert.ErrorIs(t, err, models.ErrPostingNotAllowed)
+
+	history, err := accounts.GetStatusHistory(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, models.AccountStatusFrozen, history[0].ToStatus)
+	assert.Equal(t, "fraud review", history[0].Reason)
+	assert.Equal(t, "compliance", history[0].Actor)
+}
+
+func TestAccountHierarchy(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	balances := service.NewBalanceService(f.entryRepo, f.accountRepo, f.balanceRepo)
+	accounts := service.NewAccountService(f.accountRepo, balances)
+
+	wallets, err := accounts.CreateAccount(ctx, service.CreateAccountRequest{
+		Name: "User wallets", Code: "2000-user-wallets", Type: models.Liability, Currency: "NGN",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2000-USER-WALLETS", wallets.Code)
+	ngn, err := accounts.CreateAccount(ctx, service.CreateAccountRequest{
+		Name: "NGN wallets", Code: "2000-USER-WALLETS-NGN", Type: models.Liability, ParentAccountID: wallets.ID, Currency: "NGN",
+	})
+	require.NoError(t, err)
+
+	_, err = accounts.CreateAccount(ctx, service.CreateAccountRequest{Name: "Dup", Code: "2000-USER-WALLETS", Type: models.Liability, Currency: "NGN"})
+	assert.ErrorIs(t, err, service.ErrAccountCodeTaken)
+	_, err = accounts.CreateAccount(ctx, service.CreateAccountRequest{Name: "Bad", Code: "WALLETS", Type: models.Liability, Currency: "NGN"})
+	assert.ErrorIs(t, err, service.ErrInvalidAccountCode)
+	_, err = accounts.CreateAccount(ctx, service.CreateAccountRequest{Name: "Cash", Type: models.Asset, ParentAccountID: ngn.ID, Currency: "NGN"})
+	assert.ErrorIs(t, err, service.ErrInvalidAccountHierarchy)
+
+	var leaves []*models.Account
+	for _, user := range []string{"alice", "bob"} {
+		wallet, err := accounts.CreateAccount(ctx, service.CreateAccountRequest{
+			Name: user + " NGN", Type: models.Liability, ParentAccountID: ngn.ID, UserID: user, Currency: "NGN",
+		})
+		require.NoError(t, err)
+		leaves = append(leaves, wallet)
+	}
+	f.fund(t, leaves[0], "100")
+	f.fund(t, leaves[1], "250.50")
+
+	// The parent node reports all of its wallets as one balance
+	rollup, err := balances.GetRollupBalance(ctx, wallets.ID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 4, rollup.AccountCount)
+	require.Len(t, rollup.Balances, 1)
+	assert.Equal(t, "NGN", rollup.Balances[0].Currency)
+	assert.Equal(t, "350.50", rollup.Balances[0].Balance.Decimal())
+
+	children, err := accounts.ListChildAccounts(ctx, ngn.ID)
+	require.NoError(t, err)
+	assert.Len(t, children, 2)
+
+	// Only leaf accounts can be posted to
+	_, err = f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: ngn.ID, Amount: money.MustParse("1", "NGN"), Currency: "NGN"})
+	assert.ErrorIs(t, err, service.ErrNonLeafAccount)
+
+	// Accounts with postings cannot become parents, and the tree cannot loop
+	_, err = accounts.CreateAccount(ctx, service.CreateAccountRequest{Name: "Sub", Type: models.Liability, ParentAccountID: leaves[0].ID, Currency: "NGN"})
+	assert.ErrorIs(t, err, service.ErrInvalidAccountHierarchy)
+	_, err = accounts.MoveAccount(ctx, wallets.ID, ngn.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidAccountHierarchy)
+	_, err = accounts.MoveAccount(ctx, wallets.ID, wallets.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidAccountHierarchy)
+
+	moved, err := accounts.MoveAccount(ctx, leaves[1].ID, wallets.ID)
+	require.NoError(t, err)
+	assert.Equal(t, wallets.ID, moved.ParentAccountID)
+	byCode, err := accounts.GetAccountByCode(ctx, "2000-user-wallets-ngn")
+	require.NoError(t, err)
+	assert.Equal(t, ngn.ID, byCode.ID)
+	rollup, err = balances.GetRollupBalance(ctx, ngn.ID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", rollup.Balances[0].Balance.Decimal())
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/ctel"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+var (
+	// ErrAccountNotFound is returned when the requested account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrInvalidStatementCursor is returned when a statement cursor cannot be decoded
+	ErrInvalidStatementCursor = errors.New("invalid statement cursor")
+)
+
+const (
+	// DefaultStatementPageSize is the number of statement lines returned when no limit is given
+	DefaultStatementPageSize = 50
+	// MaxStatementPageSize bounds the number of statement lines returned per page
+	MaxStatementPageSize = 500
+)
+
+// AccountBalance is the ledger balance of an account at a point in time
+type AccountBalance struct {
+	AccountID   string             `json:"account_id"`
+	AccountType models.AccountType `json:"account_type"`
+	Currency    string             `json:"currency"`
+	TotalDebit  money.Money        `json:"total_debit"`
+	TotalCredit money.Money        `json:"total_credit"`
+	Balance     money.Money        `json:"balance"`
+	AsOf        time.Time          `json:"as_of"`
+}
+
+// CurrencyBalance is the balance of a group of accounts in a single currency
+type CurrencyBalance struct {
+	Currency    string      `json:"currency"`
+	TotalDebit  money.Money `json:"total_debit"`
+	TotalCredit money.Money `json:"total_credit"`
+	Balance     money.Money `json:"balance"`
+}
+
+// RollupBalance is the combined balance of an account and all of its
+// descendants in the chart of accounts, per currency
+type RollupBalance struct {
+	AccountID    string             `json:"account_id"`
+	Code         string             `json:"code,omitempty"`
+	AccountType  models.AccountType `json:"account_type"`
+	AccountCount int                `json:"account_count"`
+	Balances     []CurrencyBalance  `json:"balances"`
+	AsOf         time.Time          `json:"as_of"`
+}
+
+// StatementRequest selects a page of an account statement
+type StatementRequest struct {
+	From time.Time
+	To   time.Time
+	// Cursor continues from the page that returned it; empty starts at From
+	Cursor string
+	Limit  int
+}
+
+// StatementCounterpart is an account on the other side of a statement line
+type StatementCounterpart struct {
+	AccountID string      `json:"account_id"`
+	Code      string      `json:"code,omitempty"`
+	Name      string      `json:"name"`
+	Debit     money.Money `json:"debit"`
+	Credit    money.Money `json:"credit"`
+}
+
+// StatementLine is one posting to the account, with the balance after it
+type StatementLine struct {
+	LineID          string                 `json:"line_id"`
+	EntryID         string                 `json:"entry_id"`
+	Date            time.Time              `json:"date"`
+	Description     string                 `json:"description"`
+	ReferenceID     string                 `json:"reference_id,omitempty"`
+	TransactionType string                 `json:"transaction_type"`
+	Debit           money.Money            `json:"debit"`
+	Credit          money.Money            `json:"credit"`
+	Balance         money.Money            `json:"balance"`
+	Counterparts    []StatementCounterpart `json:"counterparts"`
+}
+
+// Statement lists the postings to an account over a period. Opening and
+// closing balances cover the whole period; Lines holds one page of it, and
+// NextCursor is set when more lines follow.
+type Statement struct {
+	AccountID      string             `json:"account_id"`
+	Code           string             `json:"code,omitempty"`
+	Name           string             `json:"name"`
+	AccountType    models.AccountType `json:"account_type"`
+	Currency       string             `json:"currency"`
+	From           time.Time          `json:"from"`
+	To             time.Time          `json:"to"`
+	OpeningBalance money.Money        `json:"opening_balance"`
+	ClosingBalance money.Money        `json:"closing_balance"`
+	Lines          []StatementLine    `json:"lines"`
+	NextCursor     string             `json:"next_cursor,omitempty"`
+}
+
+// BalanceService defines the interface for account balance queries
+type BalanceService interface {
+	// GetBalance returns the ledger balance of an account as of the given time.
+	// The sign follows the account type: debit-normal accounts are debits minus
+	// credits, credit-normal accounts are credits minus debits.
+	GetBalance(ctx context.Context, accountID string, asOf time.Time) (*AccountBalance, error)
+
+	// GetAvailableBalance returns the current ledger balance of an account,
+	// read from its materialized balance snapshot
+	GetAvailableBalance(ctx context.Context, accountID string) (money.Money, error)
+
+	// GetRollupBalance returns the balance of an account together with all of
+	// its descendants as of the given time, one total per currency. The sign
+	// follows the type of the requested node.
+	GetRollupBalance(ctx context.Context, accountID string, asOf time.Time) (*RollupBalance, error)
+
+	// GetStatement returns a page of an account's statement, with a running
+	// balance after each line in the account's normal direction
+	GetStatement(ctx context.Context, accountID string, req StatementRequest) (*Statement, error)
+}
+
+// balanceService implements BalanceService over the posted entry lines
+type balanceService struct {
+	entryRepo   repository.EntryRepository
+	accountRepo repository.AccountRepository
+	balanceRepo repository.BalanceRepository
+}
+
+// Ensure balanceService can back the lien manager's funds checks
+var _ ctel.AccountService = (*balanceService)(nil)
+
+// NewBalanceService creates a new BalanceService
+func NewBalanceService(entryRepo repository.EntryRepository, accountRepo repository.AccountRepository, balanceRepo repository.BalanceRepository) BalanceService {
+	return &balanceService{
+		entryRepo:   entryRepo,
+		accountRepo: accountRepo,
+		balanceRepo: balanceRepo,
+	}
+}
+
+// GetBalance implements BalanceService
+func (s *balanceService) GetBalance(ctx context.Context, accountID string, asOf time.Time) (*AccountBalance, error) {
+	account, err := s.accountRepo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", accountID, err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+
+	totalDebit, totalCredit, err := s.entryRepo.SumAccountLines(ctx, accountID, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum entry lines for account %s: %w", accountID, err)
+	}
+
+	totalDebit = totalDebit.WithCurrency(account.Currency)
+	totalCredit = totalCredit.WithCurrency(account.Currency)
+	balance, err := normalBalance(account.Type, totalDebit, totalCredit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute balance for account %s: %w", accountID, err)
+	}
+
+	return &AccountBalance{
+		AccountID:   account.ID,
+		AccountType: account.Type,
+		Currency:    account.Currency,
+		TotalDebit:  totalDebit,
+		TotalCredit: totalCredit,
+		Balance:     balance,
+		AsOf:        asOf,
+	}, nil
+}
+
+// GetAvailableBalance implements BalanceService and ctel.AccountService
+func (s *balanceService) GetAvailableBalance(ctx context.Context, accountID string) (money.Money, error) {
+	account, err := s.accountRepo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to get account %s: %w", accountID, err)
+	}
+	if account == nil {
+		return money.Money{}, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+
+	snapshot, err := s.balanceRepo.GetSnapshot(ctx, accountID)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to get balance snapshot for account %s: %w", accountID, err)
+	}
+	if snapshot == nil {
+		// Nothing has been posted to the account yet
+		return money.Zero(account.Currency), nil
+	}
+
+	return normalBalance(account.Type, snapshot.TotalDebit.WithCurrency(account.Currency), snapshot.TotalCredit.WithCurrency(account.Currency))
+}
+
+// GetRollupBalance implements BalanceService
+func (s *balanceService) GetRollupBalance(ctx context.Context, accountID string, asOf time.Time) (*RollupBalance, error) {
+	account, err := s.accountRepo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", accountID, err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+
+	ids, err := s.accountRepo.GetSubtreeAccountIDs(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get descendants of account %s: %w", accountID, err)
+	}
+
+	totals, err := s.entryRepo.SumLinesByCurrency(ctx, ids, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum entry lines under account %s: %w", accountID, err)
+	}
+
+	rollup := &RollupBalance{
+		AccountID:    account.ID,
+		Code:         account.Code,
+		AccountType:  account.Type,
+		AccountCount: len(ids),
+		Balances:     make([]CurrencyBalance, 0, len(totals)),
+		AsOf:         asOf,
+	}
+	for _, t := range totals {
+		balance, err := normalBalance(account.Type, t.TotalDebit, t.TotalCredit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute %s balance under account %s: %w", t.Currency, accountID, err)
+		}
+		rollup.Balances = append(rollup.Balances, CurrencyBalance{
+			Currency:    t.Currency,
+			TotalDebit:  t.TotalDebit,
+			TotalCredit: t.TotalCredit,
+			Balance:     balance,
+		})
+	}
+
+	return rollup, nil
+}
+
+// GetStatement implements BalanceService
+func (s *balanceService) GetStatement(ctx context.Context, accountID string, req StatementRequest) (*Statement, error) {
+	if req.To.Before(req.From) {
+		return nil, ErrInvalidReportPeriod
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultStatementPageSize
+	}
+	if limit > MaxStatementPageSize {
+		limit = MaxStatementPageSize
+	}
+
+	var after *repository.LinePosition
+	if req.Cursor != "" {
+		pos, err := decodeStatementCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &pos
+	}
+
+	account, err := s.accountRepo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", accountID, err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+
+	statement := &Statement{
+		AccountID:   account.ID,
+		Code:        account.Code,
+		Name:        account.Name,
+		AccountType: account.Type,
+		Currency:    account.Currency,
+		From:        req.From,
+		To:          req.To,
+		Lines:       []StatementLine{},
+	}
+
+	// Everything dated before From makes up the opening balance
+	if statement.OpeningBalance, err = s.balanceThrough(ctx, account, repository.LinePosition{Date: req.From}); err != nil {
+		return nil, err
+	}
+	totalDebit, totalCredit, err := s.entryRepo.SumAccountLines(ctx, accountID, req.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum entry lines for account %s: %w", accountID, err)
+	}
+	if statement.ClosingBalance, err = normalBalance(account.Type, totalDebit.WithCurrency(account.Currency), totalCredit.WithCurrency(account.Currency)); err != nil {
+		return nil, fmt.Errorf("failed to compute closing balance for account %s: %w", accountID, err)
+	}
+
+	// Fetch one extra line to learn whether another page follows
+	lines, err := s.entryRepo.GetAccountLines(ctx, accountID, repository.AccountLinesQuery{
+		From:  req.From,
+		To:    req.To,
+		After: after,
+		Limit: limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry lines for account %s: %w", accountID, err)
+	}
+	hasMore := len(lines) > limit
+	if hasMore {
+		lines = lines[:limit]
+	}
+
+	// The running balance of a later page starts where the previous page ended
+	running := statement.OpeningBalance
+	if after != nil {
+		if running, err = s.balanceThrough(ctx, account, *after); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, line := range lines {
+		debit := line.Debit.WithCurrency(account.Currency)
+		credit := line.Credit.WithCurrency(account.Currency)
+		delta, err := normalBalance(account.Type, debit, credit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute balance for account %s: %w", accountID, err)
+		}
+		if running, err = running.Add(delta); err != nil {
+			return nil, fmt.Errorf("failed to compute balance for account %s: %w", accountID, err)
+		}
+
+		statementLine := StatementLine{
+			LineID:          line.LineID,
+			EntryID:         line.EntryID,
+			Date:            line.Date,
+			Description:     line.Description,
+			ReferenceID:     line.ReferenceID,
+			TransactionType: line.TransactionType,
+			Debit:           debit,
+			Credit:          credit,
+			Balance:         running,
+			Counterparts:    make([]StatementCounterpart, 0, len(line.Counterparts)),
+		}
+		for _, c := range line.Counterparts {
+			statementLine.Counterparts = append(statementLine.Counterparts, StatementCounterpart{
+				AccountID: c.AccountID,
+				Code:      c.Code,
+				Name:      c.Name,
+				Debit:     c.Debit,
+				Credit:    c.Credit,
+			})
+		}
+		statement.Lines = append(statement.Lines, statementLine)
+	}
+
+	if hasMore {
+		last := lines[len(lines)-1]
+		statement.NextCursor = encodeStatementCursor(repository.LinePosition{Date: last.Date, LineID: last.LineID})
+	}
+
+	return statement, nil
+}
+
+// balanceThrough returns an account's balance after every line up to and including pos
+func (s *balanceService) balanceThrough(ctx context.Context, account *models.Account, pos repository.LinePosition) (money.Money, error) {
+	totalDebit, totalCredit, err := s.entryRepo.SumAccountLinesThrough(ctx, account.ID, pos)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to sum entry lines for account %s: %w", account.ID, err)
+	}
+	balance, err := normalBalance(account.Type, totalDebit.WithCurrency(account.Currency), totalCredit.WithCurrency(account.Currency))
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to compute balance for account %s: %w", account.ID, err)
+	}
+	return balance, nil
+}
+
+// encodeStatementCursor turns the position of the last line on a page into an opaque cursor
+func encodeStatementCursor(pos repository.LinePosition) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pos.Date.Format(time.RFC3339Nano) + "|" + pos.LineID))
+}
+
+// decodeStatementCursor reverses encodeStatementCursor
+func decodeStatementCursor(cursor string) (repository.LinePosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return repository.LinePosition{}, ErrInvalidStatementCursor
+	}
+	date, lineID, ok := strings.Cut(string(raw), "|")
+	if !ok || lineID == "" {
+		return repository.LinePosition{}, ErrInvalidStatementCursor
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		return repository.LinePosition{}, ErrInvalidStatementCursor
+	}
+	return repository.LinePosition{Date: parsed, LineID: lineID}, nil
+}
+
+// normalBalance returns the balance of an account in its normal direction:
+// debits minus credits for debit-normal accounts, credits minus debits otherwise
+func normalBalance(accountType models.AccountType, totalDebit, totalCredit money.Money) (money.Money, error) {
+	if accountType.IsDebitNormal() {
+		return totalDebit.Sub(totalCredit)
+	}
+	return totalCredit.Sub(totalDebit)
+}
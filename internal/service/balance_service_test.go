@@ -0,0 +1,148 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceService_GetBalance(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	balances := service.NewBalanceService(f.entryRepo, f.accountRepo, f.balanceRepo)
+
+	wallet := f.createWallet(t, "alice", "USD")
+	_, err := f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: wallet.ID, Amount: money.MustParse("100", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+	afterDeposit := time.Now()
+
+	_, err = f.svc.ProcessWithdrawal(ctx, service.WithdrawalRequest{AccountID: wallet.ID, Amount: money.MustParse("30", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+
+	// The wallet is a credit-normal liability of the platform
+	balance, err := balances.GetBalance(ctx, wallet.ID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "70.00 USD", balance.Balance.String())
+	assert.Equal(t, "30.00 USD", balance.TotalDebit.String())
+	assert.Equal(t, "100.00 USD", balance.TotalCredit.String())
+
+	// Bank clearing is a debit-normal asset
+	clearing, err := balances.GetBalance(ctx, service.SystemAccountID(service.SystemAccountBankClearing, "USD"), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.Asset, clearing.AccountType)
+	assert.Equal(t, "70.00 USD", clearing.Balance.String())
+
+	// Point-in-time balances ignore later entries
+	balance, err = balances.GetBalance(ctx, wallet.ID, afterDeposit)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00 USD", balance.Balance.String())
+
+	available, err := balances.GetAvailableBalance(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "70.00 USD", available.String())
+
+	_, err = balances.GetBalance(ctx, "missing-account", time.Now())
+	assert.True(t, errors.Is(err, service.ErrAccountNotFound))
+}
+
+func TestBalanceSnapshots_OverdrawAndDrift(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	verifier := service.NewBalanceVerifier(f.balanceRepo, time.Hour)
+
+	wallet := f.createWallet(t, "alice", "USD")
+	_, err := f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: wallet.ID, Amount: money.MustParse("50", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+	_, err = f.svc.ProcessWithdrawal(ctx, service.WithdrawalRequest{AccountID: wallet.ID, Amount: money.MustParse("20", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+
+	snapshot, err := f.balanceRepo.GetSnapshot(ctx, wallet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, int64(2), snapshot.Version)
+	assert.Equal(t, "20.00", snapshot.TotalDebit.Decimal())
+	assert.Equal(t, "50.00", snapshot.TotalCredit.Decimal())
+
+	// A withdrawal beyond the balance is rejected and leaves the snapshot untouched
+	_, err = f.svc.ProcessWithdrawal(ctx, service.WithdrawalRequest{AccountID: wallet.ID, Amount: money.MustParse("31", "USD"), Currency: "USD"})
+	assert.True(t, errors.Is(err, repository.ErrInsufficientFunds))
+
+	snapshot, err = f.balanceRepo.GetSnapshot(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), snapshot.Version)
+
+	drifts, err := verifier.Verify(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// Tampering with a snapshot is flagged against the entry lines
+	require.NoError(t, f.db.Model(&models.BalanceSnapshot{}).
+		Where("account_id = ?", wallet.ID).
+		Update("total_credit", 80).Error)
+
+	drifts, err = verifier.Verify(ctx)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, wallet.ID, drifts[0].AccountID)
+	assert.Equal(t, "80.00", drifts[0].SnapshotCredit.Decimal())
+	assert.Equal(t, "50.00", drifts[0].LedgerCredit.Decimal())
+}
+
+func TestGetStatement(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	balances := service.NewBalanceService(f.entryRepo, f.accountRepo, f.balanceRepo)
+	alice := f.createWallet(t, "alice", "USD")
+	bob := f.createWallet(t, "bob", "USD")
+
+	f.fund(t, alice, "100")
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := f.svc.ProcessTransfer(ctx, service.TransferRequest{
+			SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("10", "USD"), Currency: "USD",
+			Reference: fmt.Sprintf("t-%d", i),
+		})
+		require.NoError(t, err)
+	}
+
+	req := service.StatementRequest{From: start, To: time.Now(), Limit: 2}
+	statement, err := balances.GetStatement(ctx, alice.ID, req)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", statement.OpeningBalance.Decimal())
+	assert.Equal(t, "50.00", statement.ClosingBalance.Decimal())
+
+	// Page through, checking that the running balance carries across pages
+	var lines []service.StatementLine
+	for {
+		lines = append(lines, statement.Lines...)
+		if statement.NextCursor == "" {
+			break
+		}
+		assert.Len(t, statement.Lines, 2)
+		req.Cursor = statement.NextCursor
+		statement, err = balances.GetStatement(ctx, alice.ID, req)
+		require.NoError(t, err)
+	}
+	require.Len(t, lines, 5)
+	for i, line := range lines {
+		assert.Equal(t, "10.00", line.Debit.Decimal())
+		assert.Equal(t, money.MustParse(fmt.Sprint(90-10*i), "USD").Decimal(), line.Balance.Decimal())
+		require.Len(t, line.Counterparts, 1)
+		assert.Equal(t, bob.ID, line.Counterparts[0].AccountID)
+		assert.Equal(t, "10.00", line.Counterparts[0].Credit.Decimal())
+	}
+
+	_, err = balances.GetStatement(ctx, alice.ID, service.StatementRequest{To: time.Now(), Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, service.ErrInvalidStatementCursor)
+	_, err = balances.GetStatement(ctx, "missing", service.StatementRequest{To: time.Now()})
+	assert.ErrorIs(t, err, service.ErrAccountNotFound)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+// BalanceVerifier periodically recomputes account balances from entry lines
+// and reports any snapshot that has drifted from the ledger.
+type BalanceVerifier struct {
+	balanceRepo repository.BalanceRepository
+	interval    time.Duration
+}
+
+// NewBalanceVerifier creates a new BalanceVerifier that runs every interval
+func NewBalanceVerifier(balanceRepo repository.BalanceRepository, interval time.Duration) *BalanceVerifier {
+	return &BalanceVerifier{
+		balanceRepo: balanceRepo,
+		interval:    interval,
+	}
+}
+
+// Verify performs a single verification pass and returns the drifted accounts
+func (v *BalanceVerifier) Verify(ctx context.Context) ([]repository.BalanceDrift, error) {
+	drifts, err := v.balanceRepo.FindDrift(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range drifts {
+		log.Printf("Balance drift on account %s: snapshot debit=%s credit=%s (version %d), ledger debit=%s credit=%s",
+			d.AccountID, d.SnapshotDebit, d.SnapshotCredit, d.Version, d.LedgerDebit, d.LedgerCredit)
+	}
+
+	return drifts, nil
+}
+
+// Start runs Verify on every tick until the context is cancelled
+func (v *BalanceVerifier) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(v.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := v.Verify(ctx); err != nil {
+					log.Printf("Balance verification failed: %v", err)
+				}
+			}
+		}
+	}()
+}
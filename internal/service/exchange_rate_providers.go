@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+// Names of the built-in rate providers, as recorded in the source of a rate
+const (
+	RateSourceStatic = "static"
+	RateSourceDB     = "db"
+	RateSourceHTTP   = "http"
+)
+
+// RateProvider is one source of exchange rates in an ExchangeRateService's chain
+type RateProvider interface {
+	// Name identifies the provider in the source of the rates it serves
+	Name() string
+
+	// Rate returns the rate from base to quote in effect at at, or an error
+	// wrapping ErrRateNotFound if the provider has no rate for the pair
+	Rate(ctx context.Context, base, quote string, at time.Time) (*ResolvedRate, error)
+}
+
+// StaticRate is one entry of a static rate table
+type StaticRate struct {
+	Base  string     `json:"base"`
+	Quote string     `json:"quote"`
+	Rate  money.Rate `json:"rate"`
+}
+
+// staticRateFile is the layout of a static rate table config file
+type staticRateFile struct {
+	AsOf  time.Time    `json:"as_of"`
+	Rates []StaticRate `json:"rates"`
+}
+
+// StaticRateProvider serves rates from a fixed table
+type StaticRateProvider struct {
+	asOf  time.Time
+	rates map[string]money.Rate
+}
+
+// NewStaticRateProvider creates a StaticRateProvider whose rates were current
+// at asOf. A zero asOf marks the rates as current at whatever time they are asked for.
+func NewStaticRateProvider(asOf time.Time, rates []StaticRate) (*StaticRateProvider, error) {
+	p := &StaticRateProvider{asOf: asOf, rates: make(map[string]money.Rate, len(rates))}
+	for _, r := range rates {
+		if r.Rate.Sign() <= 0 {
+			return nil, fmt.Errorf("static rate %s/%s must be positive", r.Base, r.Quote)
+		}
+		p.rates[pairKey(r.Base, r.Quote)] = r.Rate
+	}
+	return p, nil
+}
+
+// LoadStaticRateProvider creates a StaticRateProvider from a JSON config file
+// of the form {"as_of": "2024-01-01T00:00:00Z", "rates": [{"base": "USD", "quote": "EUR", "rate": 0.92}]}
+func LoadStaticRateProvider(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate file: %w", err)
+	}
+
+	var file staticRateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rate file %s: %w", path, err)
+	}
+	return NewStaticRateProvider(file.AsOf, file.Rates)
+}
+
+// Name implements RateProvider
+func (p *StaticRateProvider) Name() string {
+	return RateSourceStatic
+}
+
+// Rate implements RateProvider
+func (p *StaticRateProvider) Rate(ctx context.Context, base, quote string, at time.Time) (*ResolvedRate, error) {
+	rate, ok := p.rates[pairKey(base, quote)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrRateNotFound, base, quote)
+	}
+
+	asOf := p.asOf
+	if asOf.IsZero() {
+		asOf = at
+	}
+	return &ResolvedRate{Base: base, Quote: quote, Rate: rate, Source: RateSourceStatic, AsOf: asOf}, nil
+}
+
+// DBRateProvider serves the stored rate in effect at the requested time
+type DBRateProvider struct {
+	rateRepo repository.ExchangeRateRepository
+}
+
+// NewDBRateProvider creates a new DBRateProvider
+func NewDBRateProvider(rateRepo repository.ExchangeRateRepository) *DBRateProvider {
+	return &DBRateProvider{rateRepo: rateRepo}
+}
+
+// Name implements RateProvider
+func (p *DBRateProvider) Name() string {
+	return RateSourceDB
+}
+
+// Rate implements RateProvider. A stored rate is as old as its effective time.
+func (p *DBRateProvider) Rate(ctx context.Context, base, quote string, at time.Time) (*ResolvedRate, error) {
+	stored, err := p.rateRepo.GetEffectiveRate(ctx, base, quote, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stored rate: %w", err)
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("%w: %s/%s", ErrRateNotFound, base, quote)
+	}
+	return &ResolvedRate{Base: base, Quote: quote, Rate: stored.Rate, Source: RateSourceDB, AsOf: stored.EffectiveFrom}, nil
+}
+
+// httpRateResponse is the body an HTTP rate provider answers with
+type httpRateResponse struct {
+	Rate float64   `json:"rate"`
+	AsOf time.Time `json:"as_of"`
+}
+
+// HTTPRateProvider serves rates from an HTTP endpoint. It requests
+// GET <url>?base=USD&quote=EUR&at=<RFC3339 time> and expects
+// {"rate": 0.92, "as_of": "..."} back, or 404 when the endpoint has no rate
+// for the pair.
+type HTTPRateProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPRateProvider creates a new HTTPRateProvider. A nil client uses one
+// with a 5 second timeout.
+func NewHTTPRateProvider(url string, client *http.Client) *HTTPRateProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &HTTPRateProvider{url: url, client: client}
+}
+
+// Name implements RateProvider
+func (p *HTTPRateProvider) Name() string {
+	return RateSourceHTTP
+}
+
+// Rate implements RateProvider. Responses without as_of are taken as current at at.
+func (p *HTTPRateProvider) Rate(ctx context.Context, base, quote string, at time.Time) (*ResolvedRate, error) {
+	query := url.Values{"base": {base}, "quote": {quote}, "at": {at.UTC().Format(time.RFC3339)}}
+	sep := "?"
+	if strings.Contains(p.url, "?") {
+		sep = "&"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+sep+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build rate request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s/%s", ErrRateNotFound, base, quote)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("rate endpoint returned %s", resp.Status)
+	}
+
+	var body httpRateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode rate response: %w", err)
+	}
+	// The endpoint may quote more places than a rate holds
+	rate, err := money.RateFromFloat(body.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("rate endpoint returned invalid rate for %s/%s: %w", base, quote, err)
+	}
+	if rate.Sign() <= 0 {
+		return nil, fmt.Errorf("rate endpoint returned non-positive rate %v for %s/%s", body.Rate, base, quote)
+	}
+	if body.AsOf.IsZero() {
+		body.AsOf = at
+	}
+	return &ResolvedRate{Base: base, Quote: quote, Rate: rate, Source: RateSourceHTTP, AsOf: body.AsOf}, nil
+}
+
+// pairKey identifies a currency pair in rate tables and the rate cache
+func pairKey(base, quote string) string {
+	return base + "/" + quote
+}
@@ -2,44 +2,323 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+var (
+	// ErrRateNotFound is returned when no provider has, or can derive, a rate for a currency pair
+	ErrRateNotFound = errors.New("exchange rate not found")
+	// ErrRateStale is returned when the only rates available for a pair are older than the maximum staleness
+	ErrRateStale = errors.New("exchange rate is stale")
+	// ErrInvalidRate is returned when a rate to store is malformed
+	ErrInvalidRate = errors.New("invalid exchange rate")
 )
 
 // ExchangeRateService defines the interface for exchange rate related operations
 type ExchangeRateService interface {
 	// GetExchangeRate gets the exchange rate between two currencies
-	GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error)
-	
-	// ConvertAmount converts an amount from one currency to another using the current exchange rate
-	ConvertAmount(ctx context.Context, amount float64, fromCurrency, toCurrency string) (float64, error)
+	GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (money.Rate, error)
+
+	// GetRate gets the exchange rate between two currencies along with where it came from
+	GetRate(ctx context.Context, fromCurrency, toCurrency string) (*ResolvedRate, error)
+
+	// GetRateAt gets the exchange rate that was in effect at a point in time.
+	// Historical rates bypass the cache and must be fresh relative to at.
+	GetRateAt(ctx context.Context, fromCurrency, toCurrency string, at time.Time) (*ResolvedRate, error)
+
+	// ConvertAmount converts an amount into another currency using the current exchange rate,
+	// rounded to the target currency's minor units, and audits the rate that served it
+	ConvertAmount(ctx context.Context, amount money.Money, toCurrency string) (money.Money, error)
+
+	// CreateRate stores a rate served by the DB rate provider from its effective time
+	CreateRate(ctx context.Context, rate *models.ExchangeRate) error
+}
+
+// ResolvedRate is a rate between two currencies and the provenance it was served with
+type ResolvedRate struct {
+	Base   string
+	Quote  string
+	Rate   money.Rate // Units of Quote per unit of Base
+	Source string     // The provider, or how the rate was derived, e.g. "inverse(db)"
+	AsOf   time.Time  // When the rate was current; derived rates are as old as their oldest input
+}
+
+// identityRate converts a currency to itself
+var identityRate = money.MustParseRate("1")
+
+// ExchangeRateConfig configures how an ExchangeRateService resolves rates
+type ExchangeRateConfig struct {
+	// BaseCurrency is the currency cross rates are derived through when no
+	// provider quotes a pair directly or inversely
+	BaseCurrency string
+
+	// CacheTTL is how long a resolved rate is reused. Zero disables caching.
+	CacheTTL time.Duration
+
+	// MaxStaleness is the oldest a rate may be before it is refused with
+	// ErrRateStale. Zero accepts rates of any age.
+	MaxStaleness time.Duration
+}
+
+// cachedRate is a resolved rate and when it stops being reused
+type cachedRate struct {
+	rate      *ResolvedRate
+	expiresAt time.Time
 }
 
 // exchangeRateService implements ExchangeRateService
 type exchangeRateService struct {
-	// Add any dependencies here, like a cache or external API client
+	providers []RateProvider
+	rateRepo  repository.ExchangeRateRepository
+	config    ExchangeRateConfig
+
+	mu    sync.Mutex
+	cache map[string]cachedRate
 }
 
-// NewExchangeRateService creates a new ExchangeRateService
-func NewExchangeRateService() ExchangeRateService {
-	return &exchangeRateService{}
+// NewExchangeRateService creates a new ExchangeRateService that asks providers
+// for rates in order and audits conversions to rateRepo
+func NewExchangeRateService(providers []RateProvider, rateRepo repository.ExchangeRateRepository, config ExchangeRateConfig) ExchangeRateService {
+	return &exchangeRateService{
+		providers: providers,
+		rateRepo:  rateRepo,
+		config:    config,
+		cache:     make(map[string]cachedRate),
+	}
 }
 
 // GetExchangeRate implements ExchangeRateService
-func (s *exchangeRateService) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error) {
-	// TODO: Implement actual exchange rate lookup
-	// This is a placeholder implementation that returns a fixed rate
-	return 1.0, nil
+func (s *exchangeRateService) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (money.Rate, error) {
+	rate, err := s.GetRate(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		return money.Rate{}, err
+	}
+	return rate.Rate, nil
+}
+
+// GetRate implements ExchangeRateService. A pair is resolved from the first
+// provider quoting it directly, then from the first quoting its inverse, then
+// as a cross rate through the base currency. Rates older than the maximum
+// staleness are never served.
+func (s *exchangeRateService) GetRate(ctx context.Context, fromCurrency, toCurrency string) (*ResolvedRate, error) {
+	if fromCurrency == toCurrency {
+		return &ResolvedRate{Base: fromCurrency, Quote: toCurrency, Rate: identityRate, Source: "identity", AsOf: time.Now()}, nil
+	}
+
+	key := pairKey(fromCurrency, toCurrency)
+	if rate, ok := s.cached(key); ok {
+		return rate, nil
+	}
+
+	rate, err := s.resolve(ctx, fromCurrency, toCurrency, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.CacheTTL > 0 {
+		s.mu.Lock()
+		s.cache[key] = cachedRate{rate: rate, expiresAt: time.Now().Add(s.config.CacheTTL)}
+		s.mu.Unlock()
+	}
+	return rate, nil
+}
+
+// GetRateAt implements ExchangeRateService
+func (s *exchangeRateService) GetRateAt(ctx context.Context, fromCurrency, toCurrency string, at time.Time) (*ResolvedRate, error) {
+	if fromCurrency == toCurrency {
+		return &ResolvedRate{Base: fromCurrency, Quote: toCurrency, Rate: identityRate, Source: "identity", AsOf: at}, nil
+	}
+	return s.resolve(ctx, fromCurrency, toCurrency, at)
 }
 
 // ConvertAmount implements ExchangeRateService
-func (s *exchangeRateService) ConvertAmount(ctx context.Context, amount float64, fromCurrency, toCurrency string) (float64, error) {
+func (s *exchangeRateService) ConvertAmount(ctx context.Context, amount money.Money, toCurrency string) (money.Money, error) {
+	fromCurrency := amount.Currency()
+	if fromCurrency == "" {
+		return money.Money{}, errors.New("amount has no currency")
+	}
 	if fromCurrency == toCurrency {
 		return amount, nil
 	}
 
-	rate, err := s.GetExchangeRate(ctx, fromCurrency, toCurrency)
+	rate, err := s.GetRate(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	converted, err := amount.Convert(rate.Rate, toCurrency)
 	if err != nil {
-		return 0, err
+		return money.Money{}, err
 	}
 
-	return amount * rate, nil
+	// A conversion that cannot be audited is not made
+	err = s.rateRepo.RecordConversion(ctx, &models.ExchangeRateAudit{
+		FromCurrency:    fromCurrency,
+		ToCurrency:      toCurrency,
+		Amount:          amount,
+		ConvertedAmount: converted,
+		Rate:            rate.Rate,
+		Source:          rate.Source,
+		RateAsOf:        rate.AsOf,
+	})
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to audit conversion: %w", err)
+	}
+
+	return converted, nil
+}
+
+// CreateRate implements ExchangeRateService
+func (s *exchangeRateService) CreateRate(ctx context.Context, rate *models.ExchangeRate) error {
+	if rate.Rate.Sign() <= 0 {
+		return fmt.Errorf("%w: rate must be positive", ErrInvalidRate)
+	}
+	if rate.BaseCurrency == rate.QuoteCurrency {
+		return fmt.Errorf("%w: base and quote currency are both %s", ErrInvalidRate, rate.BaseCurrency)
+	}
+	if err := s.rateRepo.CreateRate(ctx, rate); err != nil {
+		return err
+	}
+
+	// Drop every cached rate so pairs derived from this one pick it up
+	s.mu.Lock()
+	s.cache = make(map[string]cachedRate)
+	s.mu.Unlock()
+	return nil
+}
+
+// cached returns the cached rate for a pair if it has neither expired nor gone stale
+func (s *exchangeRateService) cached(key string) (*ResolvedRate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) || s.isStale(entry.rate, time.Now()) {
+		delete(s.cache, key)
+		return nil, false
+	}
+	return entry.rate, true
+}
+
+// resolve derives the rate in effect at at for a pair directly, inversely or
+// through the base currency
+func (s *exchangeRateService) resolve(ctx context.Context, fromCurrency, toCurrency string, at time.Time) (*ResolvedRate, error) {
+	rate, directErr := s.resolvePair(ctx, fromCurrency, toCurrency, at)
+	if directErr == nil {
+		return rate, nil
+	}
+
+	base := s.config.BaseCurrency
+	if base == "" || base == fromCurrency || base == toCurrency {
+		return nil, directErr
+	}
+
+	first, err := s.resolvePair(ctx, fromCurrency, base, at)
+	if err != nil {
+		return nil, mostRelevant(directErr, fmt.Errorf("no cross rate %s/%s via %s: %w", fromCurrency, toCurrency, base, err))
+	}
+	second, err := s.resolvePair(ctx, base, toCurrency, at)
+	if err != nil {
+		return nil, mostRelevant(directErr, fmt.Errorf("no cross rate %s/%s via %s: %w", fromCurrency, toCurrency, base, err))
+	}
+
+	cross, err := first.Rate.Mul(second.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive cross rate %s/%s via %s: %w", fromCurrency, toCurrency, base, err)
+	}
+	asOf := first.AsOf
+	if second.AsOf.Before(asOf) {
+		asOf = second.AsOf
+	}
+	return &ResolvedRate{
+		Base:   fromCurrency,
+		Quote:  toCurrency,
+		Rate:   cross,
+		Source: fmt.Sprintf("cross(%s,%s)", first.Source, second.Source),
+		AsOf:   asOf,
+	}, nil
+}
+
+// resolvePair finds a rate for a pair that a provider quotes directly or inversely
+func (s *exchangeRateService) resolvePair(ctx context.Context, base, quote string, at time.Time) (*ResolvedRate, error) {
+	rate, directErr := s.lookup(ctx, base, quote, at)
+	if directErr == nil {
+		return rate, nil
+	}
+
+	inverse, err := s.lookup(ctx, quote, base, at)
+	if err != nil {
+		return nil, mostRelevant(directErr, err)
+	}
+	inverted, err := inverse.Rate.Inverse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to invert %s/%s rate: %w", quote, base, err)
+	}
+	return &ResolvedRate{
+		Base:   base,
+		Quote:  quote,
+		Rate:   inverted,
+		Source: fmt.Sprintf("inverse(%s)", inverse.Source),
+		AsOf:   inverse.AsOf,
+	}, nil
+}
+
+// lookup asks each provider in turn for a pair, returning the first fresh
+// rate. A provider that fails or only has a stale rate is passed over; if no
+// provider has a fresh rate the lookup fails with ErrRateStale when one had a
+// stale rate, with the first provider error when one failed, and otherwise
+// with ErrRateNotFound.
+func (s *exchangeRateService) lookup(ctx context.Context, base, quote string, at time.Time) (*ResolvedRate, error) {
+	var stale, failed error
+	for _, p := range s.providers {
+		rate, err := p.Rate(ctx, base, quote, at)
+		switch {
+		case err == nil && s.isStale(rate, at):
+			if stale == nil {
+				stale = fmt.Errorf("%w: %s/%s from %s is as of %s", ErrRateStale, base, quote, p.Name(), rate.AsOf.Format(time.RFC3339))
+			}
+		case err == nil:
+			return rate, nil
+		case errors.Is(err, ErrRateNotFound):
+		default:
+			if failed == nil {
+				failed = fmt.Errorf("rate provider %s: %w", p.Name(), err)
+			}
+		}
+	}
+
+	switch {
+	case stale != nil:
+		return nil, stale
+	case failed != nil:
+		return nil, failed
+	default:
+		return nil, fmt.Errorf("%w: %s/%s", ErrRateNotFound, base, quote)
+	}
+}
+
+// mostRelevant picks the error to report when every way of resolving a rate
+// failed: the first that is not just a missing rate, or else the first
+func mostRelevant(errs ...error) error {
+	for _, err := range errs {
+		if !errors.Is(err, ErrRateNotFound) {
+			return err
+		}
+	}
+	return errs[0]
+}
+
+// isStale reports whether a rate was older than the maximum staleness at at
+func (s *exchangeRateService) isStale(rate *ResolvedRate, at time.Time) bool {
+	return s.config.MaxStaleness > 0 && at.Sub(rate.AsOf) > s.config.MaxStaleness
 }
@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProvider wraps a provider and counts the lookups made through it
+type countingProvider struct {
+	service.RateProvider
+	calls int
+}
+
+func (p *countingProvider) Rate(ctx context.Context, base, quote string, at time.Time) (*service.ResolvedRate, error) {
+	p.calls++
+	return p.RateProvider.Rate(ctx, base, quote, at)
+}
+
+func TestExchangeRateService(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rates": [
+		{"base": "USD", "quote": "EUR", "rate": 0.9},
+		{"base": "GBP", "quote": "USD", "rate": 1.25}
+	]}`), 0o600))
+	static, err := service.LoadStaticRateProvider(path)
+	require.NoError(t, err)
+	counted := &countingProvider{RateProvider: static}
+
+	rates := service.NewExchangeRateService(
+		[]service.RateProvider{service.NewDBRateProvider(f.rateRepo), counted},
+		f.rateRepo,
+		service.ExchangeRateConfig{BaseCurrency: "USD", CacheTTL: time.Hour, MaxStaleness: 24 * time.Hour},
+	)
+
+	t.Run("direct, inverse and cross rates", func(t *testing.T) {
+		rate, err := rates.GetRate(ctx, "USD", "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "0.9", rate.Rate.String())
+		assert.Equal(t, "static", rate.Source)
+
+		rate, err = rates.GetRate(ctx, "USD", "GBP")
+		require.NoError(t, err)
+		assert.Equal(t, "0.8", rate.Rate.String())
+		assert.Equal(t, "inverse(static)", rate.Source)
+
+		rate, err = rates.GetRate(ctx, "GBP", "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "1.125", rate.Rate.String())
+		assert.Equal(t, "cross(static,static)", rate.Source)
+
+		_, err = rates.GetRate(ctx, "USD", "JPY")
+		assert.ErrorIs(t, err, service.ErrRateNotFound)
+	})
+
+	t.Run("resolved rates are cached", func(t *testing.T) {
+		before := counted.calls
+		_, err := rates.GetRate(ctx, "GBP", "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, before, counted.calls)
+	})
+
+	t.Run("stored rates take precedence from their effective time", func(t *testing.T) {
+		require.NoError(t, rates.CreateRate(ctx, &models.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: money.MustParseRate("0.95"), EffectiveFrom: time.Now().Add(-time.Minute)}))
+		require.NoError(t, rates.CreateRate(ctx, &models.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: money.MustParseRate("0.99"), EffectiveFrom: time.Now().Add(time.Hour)}))
+		assert.ErrorIs(t, rates.CreateRate(ctx, &models.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "EUR"}), service.ErrInvalidRate)
+
+		rate, err := rates.GetRate(ctx, "USD", "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "0.95", rate.Rate.String())
+		assert.Equal(t, "db", rate.Source)
+	})
+
+	t.Run("stale rates fail closed", func(t *testing.T) {
+		require.NoError(t, rates.CreateRate(ctx, &models.ExchangeRate{BaseCurrency: "CHF", QuoteCurrency: "USD", Rate: money.MustParseRate("1.1"), EffectiveFrom: time.Now().Add(-48 * time.Hour)}))
+
+		_, err := rates.GetRate(ctx, "CHF", "USD")
+		assert.ErrorIs(t, err, service.ErrRateStale)
+		_, err = rates.GetRate(ctx, "CHF", "EUR")
+		assert.ErrorIs(t, err, service.ErrRateStale, "a cross rate is as stale as its oldest leg")
+	})
+
+	t.Run("conversions are audited", func(t *testing.T) {
+		converted, err := rates.ConvertAmount(ctx, money.MustParse("100", "GBP"), "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "118.75", converted.Decimal(), "1.25 GBP/USD crossed with 0.95 USD/EUR")
+
+		audits, err := f.rateRepo.ListConversions(ctx, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, audits, 1)
+		assert.Equal(t, "GBP", audits[0].FromCurrency)
+		assert.Equal(t, "EUR", audits[0].ToCurrency)
+		assert.Equal(t, "cross(static,db)", audits[0].Source)
+		assert.Equal(t, "1.1875", audits[0].Rate.String())
+		assert.Equal(t, "118.75", audits[0].ConvertedAmount.Decimal())
+	})
+}
+
+func TestHTTPRateProvider(t *testing.T) {
+	asOf := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("base") != "USD" || r.URL.Query().Get("quote") != "NGN" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"rate": 1500.5, "as_of": asOf})
+	}))
+	defer stub.Close()
+
+	provider := service.NewHTTPRateProvider(stub.URL, nil)
+	rate, err := provider.Rate(context.Background(), "USD", "NGN", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "1500.5", rate.Rate.String())
+	assert.Equal(t, "http", rate.Source)
+	assert.True(t, asOf.Equal(rate.AsOf))
+
+	_, err = provider.Rate(context.Background(), "USD", "EUR", time.Now())
+	assert.ErrorIs(t, err, service.ErrRateNotFound)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+// ErrFeeScheduleNotFound is returned when the requested fee schedule does not exist
+var ErrFeeScheduleNotFound = errors.New("fee schedule not found")
+
+// FeeService defines the interface for fee schedules and the fees they charge
+type FeeService interface {
+	// CreateSchedule stores a schedule as the next version for its transaction
+	// type, currency and account tier. It takes effect at its effective time,
+	// which defaults to now and may not be in the past.
+	CreateSchedule(ctx context.Context, schedule *models.FeeSchedule) error
+
+	// GetSchedule retrieves a schedule by ID
+	GetSchedule(ctx context.Context, id string) (*models.FeeSchedule, error)
+
+	// ListSchedules retrieves every version of the schedules matching a filter
+	ListSchedules(ctx context.Context, filter repository.FeeScheduleFilter) ([]*models.FeeSchedule, error)
+
+	// AssessFee works out the fee on a transaction from the schedule in effect
+	// at the time of the request. It returns nil if no schedule applies.
+	AssessFee(ctx context.Context, req FeeAssessmentRequest) (*AssessedFee, error)
+}
+
+// FeeAssessmentRequest describes the transaction a fee is assessed on
+type FeeAssessmentRequest struct {
+	TransactionType string      // One of the models.FeeTransaction* types
+	AccountTier     string      // Tier of the account charged; empty means the default tier
+	Amount          money.Money // Amount of the transaction; its currency selects the schedule
+	At              time.Time   // When the transaction happens; zero means now
+}
+
+// AssessedFee is the fee charged on one transaction and the schedule that set it
+type AssessedFee struct {
+	Amount          money.Money `json:"amount"`
+	ScheduleID      string      `json:"schedule_id"`
+	ScheduleVersion int         `json:"schedule_version"`
+}
+
+type feeService struct {
+	scheduleRepo repository.FeeScheduleRepository
+}
+
+// NewFeeService creates a new FeeService
+func NewFeeService(scheduleRepo repository.FeeScheduleRepository) FeeService {
+	return &feeService{scheduleRepo: scheduleRepo}
+}
+
+// CreateSchedule implements FeeService. Schedules cannot take effect in the
+// past so that the fee charged on a past transaction never changes.
+func (s *feeService) CreateSchedule(ctx context.Context, schedule *models.FeeSchedule) error {
+	schedule.Currency = strings.ToUpper(schedule.Currency)
+	schedule.AccountTier = strings.TrimSpace(schedule.AccountTier)
+	if schedule.AccountTier == "" {
+		schedule.AccountTier = models.DefaultAccountTier
+	}
+	schedule.AttachCurrency()
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if schedule.EffectiveFrom.IsZero() {
+		schedule.EffectiveFrom = now
+	}
+	if schedule.EffectiveFrom.Before(now) {
+		return fmt.Errorf("%w: schedule cannot take effect in the past", models.ErrInvalidFeeSchedule)
+	}
+
+	if err := s.scheduleRepo.CreateSchedule(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to store fee schedule: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule implements FeeService
+func (s *feeService) GetSchedule(ctx context.Context, id string) (*models.FeeSchedule, error) {
+	schedule, err := s.scheduleRepo.GetScheduleByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee schedule %s: %w", id, err)
+	}
+	if schedule == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFeeScheduleNotFound, id)
+	}
+	return schedule, nil
+}
+
+// ListSchedules implements FeeService
+func (s *feeService) ListSchedules(ctx context.Context, filter repository.FeeScheduleFilter) ([]*models.FeeSchedule, error) {
+	filter.Currency = strings.ToUpper(filter.Currency)
+	return s.scheduleRepo.ListSchedules(ctx, filter)
+}
+
+// AssessFee implements FeeService. Accounts whose tier has no schedule of its
+// own are charged by the default tier's.
+func (s *feeService) AssessFee(ctx context.Context, req FeeAssessmentRequest) (*AssessedFee, error) {
+	at := req.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	tier := req.AccountTier
+	if tier == "" {
+		tier = models.DefaultAccountTier
+	}
+	currency := req.Amount.Currency()
+
+	schedule, err := s.scheduleRepo.GetEffectiveSchedule(ctx, req.TransactionType, currency, tier, at)
+	if err == nil && schedule == nil && tier != models.DefaultAccountTier {
+		schedule, err = s.scheduleRepo.GetEffectiveSchedule(ctx, req.TransactionType, currency, models.DefaultAccountTier, at)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find fee schedule: %w", err)
+	}
+	if schedule == nil {
+		return nil, nil
+	}
+
+	amount, err := schedule.Calculate(req.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate fee with schedule %s: %w", schedule.ID, err)
+	}
+	return &AssessedFee{Amount: amount, ScheduleID: schedule.ID, ScheduleVersion: schedule.Version}, nil
+}
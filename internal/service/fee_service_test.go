@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeScheduleCalculate(t *testing.T) {
+	usd := func(amount string) money.Money { return money.MustParse(amount, "USD") }
+	schedules := map[string]*models.FeeSchedule{
+		"flat":       {FeeType: models.FeeTypeFlat, FlatAmount: usd("1.25")},
+		"percentage": {FeeType: models.FeeTypePercentage, Rate: money.MustParseRate("0.015")},
+		"capped":     {FeeType: models.FeeTypeCapped, Rate: money.MustParseRate("0.01"), MinAmount: usd("0.50"), MaxAmount: usd("5")},
+		"tiered": {FeeType: models.FeeTypeTiered, Tiers: models.FeeTiers{
+			{UpTo: usd("100"), Flat: usd("0.30")},
+			{UpTo: usd("1000"), Flat: usd("0.30"), Rate: money.MustParseRate("0.01")},
+			{Rate: money.MustParseRate("0.005")},
+		}},
+	}
+
+	tests := []struct {
+		schedule string
+		amount   string
+		want     string
+	}{
+		{"flat", "10", "1.25"},
+		{"flat", "10000", "1.25"},
+		{"percentage", "200", "3.00"},
+		{"percentage", "0.33", "0.00"},
+		{"capped", "20", "0.50"},
+		{"capped", "200", "2.00"},
+		{"capped", "2000", "5.00"},
+		{"tiered", "100", "0.30"},
+		{"tiered", "500", "5.30"},
+		{"tiered", "5000", "25.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule+"/"+tt.amount, func(t *testing.T) {
+			schedule := *schedules[tt.schedule]
+			schedule.TransactionType = models.FeeTransactionTransfer
+			schedule.Currency = "USD"
+			schedule.AccountTier = models.DefaultAccountTier
+			require.NoError(t, schedule.Validate())
+
+			fee, err := schedule.Calculate(usd(tt.amount))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, fee.Decimal())
+		})
+	}
+
+	invalid := []*models.FeeSchedule{
+		{FeeType: models.FeeTypeFlat},
+		{FeeType: models.FeeTypePercentage, Rate: money.MustParseRate("1.5")},
+		{FeeType: models.FeeTypePercentage, Rate: money.MustParseRate("0.0000001")},
+		{FeeType: models.FeeTypeCapped, Rate: money.MustParseRate("0.01"), MinAmount: usd("10"), MaxAmount: usd("5")},
+		{FeeType: models.FeeTypeTiered, Tiers: models.FeeTiers{{UpTo: usd("100"), Flat: usd("1")}}},
+		{FeeType: models.FeeTypeTiered, Tiers: models.FeeTiers{{UpTo: usd("100")}, {UpTo: usd("50")}, {}}},
+		{FeeType: models.FeeTypeFlat, FlatAmount: usd("0.001")},
+	}
+	for _, schedule := range invalid {
+		schedule.TransactionType = models.FeeTransactionTransfer
+		schedule.Currency = "USD"
+		schedule.AccountTier = models.DefaultAccountTier
+		assert.ErrorIs(t, schedule.Validate(), models.ErrInvalidFeeSchedule, "%s schedule %+v", schedule.FeeType, schedule)
+	}
+}
+
+func TestFeeScheduleVersions(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	fees := service.NewFeeService(repository.NewFeeScheduleRepository(f.db))
+
+	assess := func(tier, amount string, at time.Time) *service.AssessedFee {
+		t.Helper()
+		fee, err := fees.AssessFee(ctx, service.FeeAssessmentRequest{
+			TransactionType: models.FeeTransactionWithdrawal, AccountTier: tier, Amount: money.MustParse(amount, "USD"), At: at,
+		})
+		require.NoError(t, err)
+		return fee
+	}
+
+	assert.Nil(t, assess("", "100", time.Now()), "nothing is charged without a schedule")
+
+	v1 := &models.FeeSchedule{
+		TransactionType: models.FeeTransactionWithdrawal, Currency: "usd", FeeType: models.FeeTypeFlat,
+		FlatAmount: money.MustParse("2", "USD"), CreatedBy: "pricing",
+	}
+	require.NoError(t, fees.CreateSchedule(ctx, v1))
+	assert.Equal(t, 1, v1.Version)
+	assert.Equal(t, "USD", v1.Currency)
+	assert.Equal(t, models.DefaultAccountTier, v1.AccountTier)
+
+	repricing := time.Now().Add(time.Hour)
+	v2 := &models.FeeSchedule{
+		TransactionType: models.FeeTransactionWithdrawal, Currency: "USD", FeeType: models.FeeTypePercentage,
+		Rate: money.MustParseRate("0.01"), EffectiveFrom: repricing, CreatedBy: "pricing",
+	}
+	require.NoError(t, fees.CreateSchedule(ctx, v2))
+	assert.Equal(t, 2, v2.Version)
+
+	premium := &models.FeeSchedule{
+		TransactionType: models.FeeTransactionWithdrawal, Currency: "USD", AccountTier: "premium", FeeType: models.FeeTypeFlat,
+		FlatAmount: money.MustParse("0.10", "USD"), CreatedBy: "pricing",
+	}
+	require.NoError(t, fees.CreateSchedule(ctx, premium))
+	assert.Equal(t, 1, premium.Version, "versions are counted per tier")
+
+	t.Run("the schedule in effect at the time sets the fee", func(t *testing.T) {
+		fee := assess("", "500", time.Now())
+		require.NotNil(t, fee)
+		assert.Equal(t, "2.00", fee.Amount.Decimal())
+		assert.Equal(t, v1.ID, fee.ScheduleID)
+
+		fee = assess("", "500", repricing.Add(time.Minute))
+		require.NotNil(t, fee)
+		assert.Equal(t, "5.00", fee.Amount.Decimal())
+		assert.Equal(t, 2, fee.ScheduleVersion)
+	})
+
+	t.Run("tiers without schedules fall back to the default tier", func(t *testing.T) {
+		fee := assess("premium", "500", time.Now())
+		require.NotNil(t, fee)
+		assert.Equal(t, "0.10", fee.Amount.Decimal())
+
+		fee = assess("business", "500", time.Now())
+		require.NotNil(t, fee)
+		assert.Equal(t, v1.ID, fee.ScheduleID)
+	})
+
+	t.Run("schedules cannot be backdated", func(t *testing.T) {
+		err := fees.CreateSchedule(ctx, &models.FeeSchedule{
+			TransactionType: models.FeeTransactionWithdrawal, Currency: "USD", FeeType: models.FeeTypeFlat,
+			FlatAmount: money.MustParse("1", "USD"), EffectiveFrom: time.Now().Add(-time.Hour), CreatedBy: "pricing",
+		})
+		assert.ErrorIs(t, err, models.ErrInvalidFeeSchedule)
+	})
+
+	listed, err := fees.ListSchedules(ctx, repository.FeeScheduleFilter{Currency: "usd", AccountTier: models.DefaultAccountTier})
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, v1.ID, listed[0].ID)
+	assert.Equal(t, "USD", listed[0].FlatAmount.Currency())
+
+	_, err = fees.GetSchedule(ctx, "missing")
+	assert.ErrorIs(t, err, service.ErrFeeScheduleNotFound)
+}
+
+func TestProcessTransferChargesFee(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	alice := f.createWallet(t, "alice", "USD")
+	bob := f.createWallet(t, "bob", "USD")
+	f.fund(t, alice, "100")
+
+	tx, err := f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("40", "USD"), Currency: "USD",
+		Fee: &service.AssessedFee{Amount: money.MustParse("0.75", "USD"), ScheduleID: "schedule-1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0.75", tx.Fee.Decimal())
+	assert.Equal(t, "schedule-1", tx.FeeScheduleID)
+
+	// The fee is posted to revenue in the transfer's own entry
+	entry, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assertEntryLines(t, entry, map[string][2]string{
+		alice.ID: {"40.75", "0.00"},
+		bob.ID:   {"0.00", "40.00"},
+		service.SystemAccountID(service.SystemAccountFeeRevenue, "USD"): {"0.00", "0.75"},
+	})
+
+	_, err = f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("10", "USD"), Currency: "USD",
+		Fee: &service.AssessedFee{Amount: money.MustParse("1", "EUR")},
+	})
+	assert.Error(t, err, "fees are charged in the transfer's currency")
+}
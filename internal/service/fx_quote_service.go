@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+var (
+	// ErrInvalidQuoteRequest is returned when a quote cannot be issued for the requested exchange
+	ErrInvalidQuoteRequest = errors.New("invalid fx quote request")
+	// ErrQuoteMismatch is returned when an exchange differs from the quote it uses
+	ErrQuoteMismatch = errors.New("exchange does not match its fx quote")
+)
+
+// FXQuoteService defines the interface for issuing the quotes currency exchanges convert at
+type FXQuoteService interface {
+	// CreateQuote prices an exchange at the current rate and locks the price until the quote expires
+	CreateQuote(ctx context.Context, req QuoteRequest) (*models.FXQuote, error)
+
+	// GetQuote retrieves a quote, or an error wrapping repository.ErrQuoteNotFound
+	GetQuote(ctx context.Context, id string) (*models.FXQuote, error)
+
+	// GetUsableQuote retrieves a quote and checks that it is unused, unexpired
+	// and was issued for the given exchange
+	GetUsableQuote(ctx context.Context, id string, req ExchangeRequest) (*models.FXQuote, error)
+}
+
+// QuoteRequest defines the request for an FX quote
+type QuoteRequest struct {
+	AccountID           string      `json:"account_id,omitempty"` // If set, only this account may use the quote
+	SourceCurrency      string      `json:"source_currency"`
+	SourceAmount        money.Money `json:"source_amount"`
+	DestinationCurrency string      `json:"destination_currency"`
+}
+
+// QuoteConfig configures how FX quotes are priced
+type QuoteConfig struct {
+	// TTL is how long a quote can be used after it is issued
+	TTL time.Duration
+
+	// Spread is the fraction of the mid rate the platform keeps, e.g. 0.005 for 50 basis points
+	Spread money.Rate
+}
+
+type fxQuoteService struct {
+	quoteRepo   repository.FXQuoteRepository
+	accountRepo repository.AccountRepository
+	rateService ExchangeRateService
+	feeService  FeeService
+	config      QuoteConfig
+}
+
+// NewFXQuoteService creates a new FXQuoteService pricing quotes at the rates
+// served by rateService, with the wallet.exchange fee of feeService's schedules
+func NewFXQuoteService(
+	quoteRepo repository.FXQuoteRepository,
+	accountRepo repository.AccountRepository,
+	rateService ExchangeRateService,
+	feeService FeeService,
+	config QuoteConfig,
+) FXQuoteService {
+	return &fxQuoteService{
+		quoteRepo:   quoteRepo,
+		accountRepo: accountRepo,
+		rateService: rateService,
+		feeService:  feeService,
+		config:      config,
+	}
+}
+
+// CreateQuote implements FXQuoteService
+func (s *fxQuoteService) CreateQuote(ctx context.Context, req QuoteRequest) (*models.FXQuote, error) {
+	if req.SourceCurrency == req.DestinationCurrency {
+		return nil, fmt.Errorf("%w: source and destination currencies must differ", ErrInvalidQuoteRequest)
+	}
+	amount, err := validateAmount(req.SourceAmount, req.SourceCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuoteRequest, err)
+	}
+
+	mid, err := s.rateService.GetRate(ctx, req.SourceCurrency, req.DestinationCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	kept, err := identityRate.Sub(s.config.Spread)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply spread: %w", err)
+	}
+	rate, err := mid.Rate.Mul(kept)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply spread: %w", err)
+	}
+	destAmount, err := amount.Convert(rate, req.DestinationCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert amount: %w", err)
+	}
+	if !destAmount.IsPositive() {
+		return nil, fmt.Errorf("%w: %s converts to nothing", ErrInvalidQuoteRequest, amount)
+	}
+
+	now := time.Now()
+	fee, err := s.assessFee(ctx, req.AccountID, amount, now)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &models.FXQuote{
+		AccountID:           req.AccountID,
+		SourceCurrency:      req.SourceCurrency,
+		DestinationCurrency: req.DestinationCurrency,
+		SourceAmount:        amount,
+		DestinationAmount:   destAmount,
+		MidRate:             mid.Rate,
+		Spread:              s.config.Spread,
+		Rate:                rate,
+		Fee:                 fee.Amount,
+		FeeScheduleID:       fee.ScheduleID,
+		RateSource:          mid.Source,
+		RateAsOf:            mid.AsOf,
+		ExpiresAt:           now.Add(s.config.TTL),
+		CreatedAt:           now,
+	}
+	if err := s.quoteRepo.CreateQuote(ctx, quote); err != nil {
+		return nil, fmt.Errorf("failed to store quote: %w", err)
+	}
+	return quote, nil
+}
+
+// assessFee prices the wallet.exchange fee on amount for the tier of the
+// account the quote is issued to. Quotes open to any account are priced at
+// the default tier. Without a fee service no fee is charged.
+func (s *fxQuoteService) assessFee(ctx context.Context, accountID string, amount money.Money, at time.Time) (*AssessedFee, error) {
+	if s.feeService == nil {
+		return &AssessedFee{Amount: money.Zero(amount.Currency())}, nil
+	}
+	tier := models.DefaultAccountTier
+	if accountID != "" {
+		account, err := s.accountRepo.GetAccountByID(ctx, accountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account %s: %w", accountID, err)
+		}
+		if account == nil {
+			return nil, fmt.Errorf("%w: account %s not found", ErrInvalidQuoteRequest, accountID)
+		}
+		tier = account.Tier
+	}
+
+	fee, err := s.feeService.AssessFee(ctx, FeeAssessmentRequest{
+		TransactionType: models.FeeTransactionExchange,
+		AccountTier:     tier,
+		Amount:          amount,
+		At:              at,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fee == nil {
+		return &AssessedFee{Amount: money.Zero(amount.Currency())}, nil
+	}
+	return fee, nil
+}
+
+// GetQuote implements FXQuoteService
+func (s *fxQuoteService) GetQuote(ctx context.Context, id string) (*models.FXQuote, error) {
+	quote, err := s.quoteRepo.GetQuoteByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		return nil, fmt.Errorf("%w: %s", repository.ErrQuoteNotFound, id)
+	}
+	return quote, nil
+}
+
+// GetUsableQuote implements FXQuoteService. The quote is only checked here;
+// it is used up when the exchange posts.
+func (s *fxQuoteService) GetUsableQuote(ctx context.Context, id string, req ExchangeRequest) (*models.FXQuote, error) {
+	quote, err := s.GetQuote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := quote.CheckUsable(time.Now()); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case quote.AccountID != "" && quote.AccountID != req.SourceAccountID:
+		return nil, fmt.Errorf("%w: quote %s was issued to account %s", ErrQuoteMismatch, id, quote.AccountID)
+	case quote.SourceCurrency != req.SourceCurrency || quote.DestinationCurrency != req.DestinationCurrency:
+		return nil, fmt.Errorf("%w: quote %s is for %s to %s", ErrQuoteMismatch, id, quote.SourceCurrency, quote.DestinationCurrency)
+	case !quote.SourceAmount.Equal(req.SourceAmount.WithCurrency(req.SourceCurrency)):
+		return nil, fmt.Errorf("%w: quote %s is for %s", ErrQuoteMismatch, id, quote.SourceAmount)
+	}
+	return quote, nil
+}
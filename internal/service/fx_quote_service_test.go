@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFXQuotes(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	usd := f.createWallet(t, "alice-usd", "USD")
+	eur := f.createWallet(t, "alice-eur", "EUR")
+	f.fund(t, usd, "500")
+
+	static, err := service.NewStaticRateProvider(time.Time{}, []service.StaticRate{{Base: "USD", Quote: "EUR", Rate: money.MustParseRate("0.9")}})
+	require.NoError(t, err)
+	rates := service.NewExchangeRateService([]service.RateProvider{static}, f.rateRepo, service.ExchangeRateConfig{})
+	quoteRepo := repository.NewFXQuoteRepository(f.db)
+	fees := service.NewFeeService(repository.NewFeeScheduleRepository(f.db))
+	require.NoError(t, fees.CreateSchedule(ctx, &models.FeeSchedule{
+		TransactionType: models.FeeTransactionExchange, Currency: "USD", FeeType: models.FeeTypePercentage, Rate: money.MustParseRate("0.005"), CreatedBy: "test",
+	}))
+	quotes := service.NewFXQuoteService(quoteRepo, f.accountRepo, rates, fees, service.QuoteConfig{TTL: time.Minute, Spread: money.MustParseRate("0.01")})
+
+	quote, err := quotes.CreateQuote(ctx, service.QuoteRequest{
+		AccountID: usd.ID, SourceCurrency: "USD", SourceAmount: money.MustParse("100", "USD"), DestinationCurrency: "EUR",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0.891", quote.Rate.String(), "mid rate less a 1% spread")
+	assert.Equal(t, "89.10", quote.DestinationAmount.Decimal())
+	assert.Equal(t, "0.50", quote.Fee.Decimal())
+	assert.NotEmpty(t, quote.FeeScheduleID)
+	assert.Equal(t, "static", quote.RateSource)
+
+	exchange := service.ExchangeRequest{
+		SourceAccountID: usd.ID, SourceAmount: money.MustParse("100", "USD"), SourceCurrency: "USD",
+		DestinationAccountID: eur.ID, DestinationCurrency: "EUR",
+	}
+
+	t.Run("quotes only serve the exchange they were issued for", func(t *testing.T) {
+		mismatched := exchange
+		mismatched.SourceAmount = money.MustParse("150", "USD")
+		_, err := quotes.GetUsableQuote(ctx, quote.ID, mismatched)
+		assert.ErrorIs(t, err, service.ErrQuoteMismatch)
+
+		mismatched = exchange
+		mismatched.SourceAccountID = eur.ID
+		_, err = quotes.GetUsableQuote(ctx, quote.ID, mismatched)
+		assert.ErrorIs(t, err, service.ErrQuoteMismatch)
+
+		_, err = quotes.GetUsableQuote(ctx, "missing", exchange)
+		assert.ErrorIs(t, err, repository.ErrQuoteNotFound)
+	})
+
+	t.Run("a quote is used up with the posting", func(t *testing.T) {
+		usable, err := quotes.GetUsableQuote(ctx, quote.ID, exchange)
+		require.NoError(t, err)
+		req := exchange
+		req.QuoteID = usable.ID
+		req.ExchangeRate = usable.Rate
+		req.DestinationAmount = usable.DestinationAmount
+		req.MidRate = &usable.MidRate
+		req.Fee = &service.AssessedFee{Amount: usable.Fee, ScheduleID: usable.FeeScheduleID}
+
+		tx, err := f.svc.ProcessExchange(ctx, req)
+		require.NoError(t, err)
+
+		// The spread is realized against the EUR position at the mid rate, and
+		// the quoted fee is charged in the same entry
+		entry, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+		require.NoError(t, err)
+		assertEntryLines(t, entry, map[string][2]string{
+			usd.ID: {"100.50", "0.00"},
+			service.SystemAccountID(service.SystemAccountFeeRevenue, "USD"):         {"0.00", "0.50"},
+			service.SystemAccountID(service.SystemAccountFXPosition, "USD"):         {"0.00", "100.00"},
+			service.SystemAccountID(service.SystemAccountFXPosition, "EUR"):         {"90.00", "0.00"},
+			service.SystemAccountID(service.SystemAccountFXRealizedGainLoss, "EUR"): {"0.00", "0.90"},
+			eur.ID: {"0.00", "89.10"},
+		})
+		assert.Equal(t, "0.90", tx.Metadata["realized_fx_gain_loss"])
+		assert.Equal(t, quote.FeeScheduleID, tx.FeeScheduleID)
+
+		consumed, err := quotes.GetQuote(ctx, quote.ID)
+		require.NoError(t, err)
+		require.NotNil(t, consumed.ConsumedAt)
+		assert.Equal(t, tx.EntryID, consumed.EntryID)
+
+		_, err = quotes.GetUsableQuote(ctx, quote.ID, exchange)
+		assert.ErrorIs(t, err, models.ErrQuoteConsumed)
+		_, err = f.svc.ProcessExchange(ctx, req)
+		assert.ErrorIs(t, err, models.ErrQuoteConsumed, "the posting itself refuses a used quote")
+	})
+
+	t.Run("expired quotes are refused", func(t *testing.T) {
+		expiring := service.NewFXQuoteService(quoteRepo, f.accountRepo, rates, nil, service.QuoteConfig{TTL: 0})
+		stale, err := expiring.CreateQuote(ctx, service.QuoteRequest{SourceCurrency: "USD", SourceAmount: money.MustParse("100", "USD"), DestinationCurrency: "EUR"})
+		require.NoError(t, err)
+
+		_, err = expiring.GetUsableQuote(ctx, stale.ID, exchange)
+		assert.ErrorIs(t, err, models.ErrQuoteExpired)
+
+		req := exchange
+		req.QuoteID = stale.ID
+		req.ExchangeRate = stale.Rate
+		req.DestinationAmount = stale.DestinationAmount
+		_, err = f.svc.ProcessExchange(ctx, req)
+		assert.ErrorIs(t, err, models.ErrQuoteExpired)
+	})
+
+	// Only the one successful exchange was posted
+	debit, credit, err := f.entryRepo.SumAccountLines(ctx, eur.ID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "0.00", debit.Decimal())
+	assert.Equal(t, "89.10", credit.Decimal())
+
+	_, err = quotes.CreateQuote(ctx, service.QuoteRequest{SourceCurrency: "USD", SourceAmount: money.MustParse("100", "USD"), DestinationCurrency: "USD"})
+	assert.ErrorIs(t, err, service.ErrInvalidQuoteRequest)
+}
@@ -5,24 +5,33 @@ import (
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 )
 
 // TransactionService defines the interface for transaction operations
 type TransactionService interface {
 	// Entry operations
 	CreateEntry(ctx context.Context, entry *models.Entry) error
+	// CreateEntries validates entries and posts them in one database
+	// transaction, so either all of them are posted or none is
+	CreateEntries(ctx context.Context, entries ...*models.Entry) error
 	GetEntryByID(ctx context.Context, id string) (*models.Entry, error)
 	GetEntriesByDateRange(ctx context.Context, startDate, endDate time.Time, page, pageSize int) ([]*models.Entry, int64, error)
 	ValidateEntry(ctx context.Context, entry *models.Entry) error
 
-	// Wallet operations
+	// Wallet operations. A request with a ReferenceID posts at most once: if a
+	// transaction was already posted under it, that transaction is returned.
 	ProcessTransfer(ctx context.Context, req TransferRequest) (*models.Transaction, error)
 	ProcessDeposit(ctx context.Context, req DepositRequest) (*models.Transaction, error)
 	ProcessWithdrawal(ctx context.Context, req WithdrawalRequest) (*models.Transaction, error)
 	ProcessExchange(ctx context.Context, req ExchangeRequest) (*models.Transaction, error)
 	ProcessFee(ctx context.Context, req FeeRequest) (*models.Transaction, error)
-	
+	// GetTransactionByReference retrieves the transaction posted under a
+	// request's ReferenceID, or nil if none was
+	GetTransactionByReference(ctx context.Context, referenceID string) (*models.Transaction, error)
+
 	// Reversal operations
+	ReverseEntry(ctx context.Context, entryID, reason string) (*models.Entry, error)
 	ReverseTransfer(ctx context.Context, transactionID string) error
 	ReverseDeposit(ctx context.Context, transactionID string) error
 	ReverseWithdrawal(ctx context.Context, transactionID string) error
@@ -32,46 +41,56 @@ type TransactionService interface {
 
 // TransferRequest defines the request for a transfer operation
 type TransferRequest struct {
-	SourceAccountID      string  `json:"source_account_id"`
-	DestinationAccountID string  `json:"destination_account_id"`
-	Amount               float64 `json:"amount"`
-	Currency             string  `json:"currency"`
-	Reference            string  `json:"reference,omitempty"`
+	SourceAccountID      string       `json:"source_account_id"`
+	DestinationAccountID string       `json:"destination_account_id"`
+	Amount               money.Money  `json:"amount"`
+	Currency             string       `json:"currency"`
+	Reference            string       `json:"reference,omitempty"`
+	ReferenceID          string       `json:"reference_id,omitempty"` // Posting key, such as a CTE transaction ID
+	Fee                  *AssessedFee `json:"fee,omitempty"`          // Charged to the source account in the same entry
 }
 
 // DepositRequest defines the request for a deposit operation
 type DepositRequest struct {
-	AccountID string  `json:"account_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Reference string  `json:"reference,omitempty"`
+	AccountID   string      `json:"account_id"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	Reference   string      `json:"reference,omitempty"`
+	ReferenceID string      `json:"reference_id,omitempty"` // Posting key, such as a CTE transaction ID
 }
 
 // WithdrawalRequest defines the request for a withdrawal operation
 type WithdrawalRequest struct {
-	AccountID string  `json:"account_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Reference string  `json:"reference,omitempty"`
+	AccountID   string       `json:"account_id"`
+	Amount      money.Money  `json:"amount"`
+	Currency    string       `json:"currency"`
+	Reference   string       `json:"reference,omitempty"`
+	ReferenceID string       `json:"reference_id,omitempty"` // Posting key, such as a CTE transaction ID
+	Fee         *AssessedFee `json:"fee,omitempty"`          // Charged to the account in the same entry
 }
 
 // ExchangeRequest defines the request for a currency exchange operation
 type ExchangeRequest struct {
-	SourceAccountID      string  `json:"source_account_id"`
-	SourceAmount         float64 `json:"source_amount"`
-	SourceCurrency       string  `json:"source_currency"`
-	DestinationAccountID string  `json:"destination_account_id"`
-	DestinationAmount    float64 `json:"destination_amount"`
-	DestinationCurrency  string  `json:"destination_currency"`
-	ExchangeRate         float64 `json:"exchange_rate"`
-	Reference            string  `json:"reference,omitempty"`
+	SourceAccountID      string       `json:"source_account_id"`
+	SourceAmount         money.Money  `json:"source_amount"`
+	SourceCurrency       string       `json:"source_currency"`
+	DestinationAccountID string       `json:"destination_account_id"`
+	DestinationAmount    money.Money  `json:"destination_amount"`
+	DestinationCurrency  string       `json:"destination_currency"`
+	ExchangeRate         money.Rate   `json:"exchange_rate"`
+	MidRate              *money.Rate  `json:"mid_rate,omitempty"` // Market rate; converting at another rate realizes a gain or loss
+	Reference            string       `json:"reference,omitempty"`
+	ReferenceID          string       `json:"reference_id,omitempty"` // Posting key, such as a CTE transaction ID
+	QuoteID              string       `json:"quote_id,omitempty"`     // FX quote the posting uses up; it must be unused and unexpired
+	Fee                  *AssessedFee `json:"fee,omitempty"`          // Charged to the source account in the source currency, in the same entry
 }
 
 // FeeRequest defines the request for a fee operation
 type FeeRequest struct {
-	AccountID string  `json:"account_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Reference string  `json:"reference,omitempty"`
-	FeeType   string  `json:"fee_type,omitempty"`
+	AccountID   string      `json:"account_id"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	Reference   string      `json:"reference,omitempty"`
+	ReferenceID string      `json:"reference_id,omitempty"` // Posting key, such as a CTE transaction ID
+	FeeType     string      `json:"fee_type,omitempty"`
 }
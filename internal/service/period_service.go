@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+var (
+	// ErrPeriodNotFound is returned when the requested accounting period does not exist
+	ErrPeriodNotFound = errors.New("accounting period not found")
+	// ErrInvalidPeriod is returned when a new accounting period is malformed
+	ErrInvalidPeriod = errors.New("invalid accounting period")
+)
+
+// PeriodCloseTransactionType is the transaction type of the entries that move
+// revenue and expenses into retained earnings when a period is closed
+const PeriodCloseTransactionType = "period_close"
+
+// WithPeriodAdjustment returns a context that carries the permission to post
+// adjustments into soft-closed accounting periods
+func WithPeriodAdjustment(ctx context.Context) context.Context {
+	return repository.WithPeriodAdjustment(ctx)
+}
+
+// HasPeriodAdjustment reports whether ctx carries the period adjustment permission
+func HasPeriodAdjustment(ctx context.Context) bool {
+	return repository.HasPeriodAdjustment(ctx)
+}
+
+// PeriodService defines the interface for accounting period operations
+type PeriodService interface {
+	// CreatePeriod opens a new accounting period covering start to end inclusive
+	CreatePeriod(ctx context.Context, name string, start, end time.Time) (*models.AccountingPeriod, error)
+
+	// GetPeriod retrieves an accounting period by ID
+	GetPeriod(ctx context.Context, id string) (*models.AccountingPeriod, error)
+
+	// ListPeriods retrieves all accounting periods, oldest first
+	ListPeriods(ctx context.Context) ([]*models.AccountingPeriod, error)
+
+	// ClosePeriod soft- or hard-closes a period and snapshots its closing balances
+	ClosePeriod(ctx context.Context, id string, req ClosePeriodRequest) (*models.AccountingPeriod, error)
+
+	// ReopenPeriod reopens a soft-closed period. Hard-closed periods are final.
+	ReopenPeriod(ctx context.Context, id, actor string) (*models.AccountingPeriod, error)
+
+	// GetClosingBalances retrieves the balances snapshotted when a period was last closed
+	GetClosingBalances(ctx context.Context, id string) ([]*models.PeriodClosingBalance, error)
+}
+
+// ClosePeriodRequest defines how an accounting period is closed
+type ClosePeriodRequest struct {
+	// Hard closes the period for good; otherwise adjustments can still be
+	// posted with the period adjustment permission
+	Hard bool
+	// PostClosingEntries moves the balances of Revenue and Expense accounts
+	// into retained earnings, one entry per currency
+	PostClosingEntries bool
+	// Actor is who closed the period
+	Actor string
+}
+
+// periodService implements PeriodService
+type periodService struct {
+	periodRepo  repository.PeriodRepository
+	entryRepo   repository.EntryRepository
+	accountRepo repository.AccountRepository
+	txService   TransactionService
+}
+
+// NewPeriodService creates a new PeriodService
+func NewPeriodService(periodRepo repository.PeriodRepository, entryRepo repository.EntryRepository, accountRepo repository.AccountRepository, txService TransactionService) PeriodService {
+	return &periodService{
+		periodRepo:  periodRepo,
+		entryRepo:   entryRepo,
+		accountRepo: accountRepo,
+		txService:   txService,
+	}
+}
+
+// CreatePeriod implements PeriodService
+func (s *periodService) CreatePeriod(ctx context.Context, name string, start, end time.Time) (*models.AccountingPeriod, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidPeriod)
+	}
+	if start.IsZero() || end.IsZero() {
+		return nil, fmt.Errorf("%w: start and end dates are required", ErrInvalidPeriod)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: end date is before start date", ErrInvalidPeriod)
+	}
+
+	period := &models.AccountingPeriod{
+		Name:      name,
+		StartDate: start,
+		EndDate:   end,
+		Status:    models.PeriodStatusOpen,
+	}
+	if err := s.periodRepo.CreatePeriod(ctx, period); err != nil {
+		return nil, fmt.Errorf("failed to create accounting period: %w", err)
+	}
+	return period, nil
+}
+
+// GetPeriod implements PeriodService
+func (s *periodService) GetPeriod(ctx context.Context, id string) (*models.AccountingPeriod, error) {
+	period, err := s.periodRepo.GetPeriodByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounting period %s: %w", id, err)
+	}
+	if period == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPeriodNotFound, id)
+	}
+	return period, nil
+}
+
+// ListPeriods implements PeriodService
+func (s *periodService) ListPeriods(ctx context.Context) ([]*models.AccountingPeriod, error) {
+	return s.periodRepo.ListPeriods(ctx)
+}
+
+// ClosePeriod implements PeriodService. An open period is soft-closed first so
+// that no new entries land in it, then the closing entries are posted as
+// adjustments and the balances are snapshotted. A hard close of a soft-closed
+// period snapshots again to capture any adjustments made since. If any step
+// fails, a period this call soft-closed is reopened; closing entries already
+// posted stay, and closing again only moves what they did not.
+func (s *periodService) ClosePeriod(ctx context.Context, id string, req ClosePeriodRequest) (*models.AccountingPeriod, error) {
+	period, err := s.GetPeriod(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	target := models.PeriodStatusSoftClosed
+	if req.Hard {
+		target = models.PeriodStatusHardClosed
+	}
+	if !period.Status.CanTransitionTo(target) {
+		return nil, fmt.Errorf("%w: cannot move period %s from %s to %s", models.ErrInvalidPeriodTransition, period.Name, period.Status, target)
+	}
+
+	softClosed := false
+	if period.Status == models.PeriodStatusOpen {
+		if err := s.periodRepo.UpdatePeriodStatus(ctx, id, models.PeriodStatusOpen, models.PeriodStatusSoftClosed, req.Actor); err != nil {
+			return nil, fmt.Errorf("failed to close accounting period %s: %w", period.Name, err)
+		}
+		softClosed = true
+	}
+
+	if err := s.finishClose(ctx, period, req); err != nil {
+		if softClosed {
+			if reopenErr := s.periodRepo.UpdatePeriodStatus(ctx, id, models.PeriodStatusSoftClosed, models.PeriodStatusOpen, req.Actor); reopenErr != nil {
+				return nil, fmt.Errorf("%w (also failed to reopen accounting period %s: %v)", err, period.Name, reopenErr)
+			}
+		}
+		return nil, err
+	}
+
+	return s.GetPeriod(ctx, id)
+}
+
+// finishClose posts the closing entries of a soft-closed period if asked to,
+// moves it to hard-closed for a hard close and snapshots its balances. The
+// status change waits for the postings that hold the period's lock, and no
+// posting lands after it, so the snapshot sees every entry of the period; a
+// hard close whose snapshot fails is taken back to soft-closed.
+func (s *periodService) finishClose(ctx context.Context, period *models.AccountingPeriod, req ClosePeriodRequest) error {
+	if req.PostClosingEntries {
+		if err := s.postClosingEntries(WithPeriodAdjustment(ctx), period); err != nil {
+			return err
+		}
+	}
+
+	if req.Hard {
+		if err := s.periodRepo.UpdatePeriodStatus(ctx, period.ID, models.PeriodStatusSoftClosed, models.PeriodStatusHardClosed, req.Actor); err != nil {
+			return fmt.Errorf("failed to hard-close accounting period %s: %w", period.Name, err)
+		}
+	}
+	if err := s.snapshotClosingBalances(ctx, period); err != nil {
+		if req.Hard {
+			if reopenErr := s.periodRepo.UpdatePeriodStatus(ctx, period.ID, models.PeriodStatusHardClosed, models.PeriodStatusSoftClosed, req.Actor); reopenErr != nil {
+				return fmt.Errorf("%w (also failed to return accounting period %s to soft-closed: %v)", err, period.Name, reopenErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// postClosingEntries zeroes every Revenue and Expense account as of the end of
+// the period and books the net income of each currency to retained earnings.
+// Balances are cumulative, so closing again after adjustments only moves the
+// adjustments.
+func (s *periodService) postClosingEntries(ctx context.Context, period *models.AccountingPeriod) error {
+	totals, err := s.entryRepo.SumLinesByAccount(ctx, repository.LineFilter{
+		To:           period.EndDate,
+		AccountTypes: []models.AccountType{models.Revenue, models.Expense},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to aggregate entry lines: %w", err)
+	}
+
+	for _, group := range groupByCurrency(totals) {
+		var lines []models.EntryLine
+		totalDebit := money.Zero(group.currency)
+		totalCredit := money.Zero(group.currency)
+		for _, t := range group.totals {
+			net, err := t.TotalDebit.Sub(t.TotalCredit)
+			if err != nil {
+				return fmt.Errorf("failed to net account %s: %w", t.AccountID, err)
+			}
+			// Post the opposite of the account's balance to bring it to zero
+			switch {
+			case net.IsPositive():
+				lines = append(lines, models.EntryLine{AccountID: t.AccountID, Credit: net})
+				totalCredit, err = totalCredit.Add(net)
+			case net.IsNegative():
+				lines = append(lines, models.EntryLine{AccountID: t.AccountID, Debit: net.Abs()})
+				totalDebit, err = totalDebit.Add(net.Abs())
+			}
+			if err != nil {
+				return fmt.Errorf("failed to total closing entry: %w", err)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		retained, err := ensureSystemAccount(ctx, s.accountRepo, SystemAccountRetainedEarnings, group.currency)
+		if err != nil {
+			return err
+		}
+		// Net income is credited to retained earnings, a net loss debited
+		net, err := totalDebit.Sub(totalCredit)
+		if err != nil {
+			return fmt.Errorf("failed to net closing entry: %w", err)
+		}
+		switch {
+		case net.IsPositive():
+			lines = append(lines, models.EntryLine{AccountID: retained.ID, Credit: net})
+		case net.IsNegative():
+			lines = append(lines, models.EntryLine{AccountID: retained.ID, Debit: net.Abs()})
+		}
+
+		entry := &models.Entry{
+			Description:     fmt.Sprintf("Closing entry for period %s (%s)", period.Name, group.currency),
+			Date:            period.EndDate,
+			TransactionType: PeriodCloseTransactionType,
+			ReferenceID:     period.ID,
+			Status:          models.EntryStatusPosted,
+			Lines:           lines,
+		}
+		if err := s.txService.CreateEntry(ctx, entry); err != nil {
+			return fmt.Errorf("failed to post closing entry for %s: %w", group.currency, err)
+		}
+	}
+
+	return nil
+}
+
+// snapshotClosingBalances records the balance of every account with postings
+// as of the end of the period
+func (s *periodService) snapshotClosingBalances(ctx context.Context, period *models.AccountingPeriod) error {
+	totals, err := s.entryRepo.SumLinesByAccount(ctx, repository.LineFilter{To: period.EndDate})
+	if err != nil {
+		return fmt.Errorf("failed to aggregate entry lines: %w", err)
+	}
+
+	balances := make([]*models.PeriodClosingBalance, 0, len(totals))
+	for _, t := range totals {
+		balance, err := normalBalance(t.Type, t.TotalDebit, t.TotalCredit)
+		if err != nil {
+			return fmt.Errorf("failed to compute balance of account %s: %w", t.AccountID, err)
+		}
+		balances = append(balances, &models.PeriodClosingBalance{
+			AccountID:   t.AccountID,
+			AccountType: t.Type,
+			Currency:    t.Currency,
+			TotalDebit:  t.TotalDebit,
+			TotalCredit: t.TotalCredit,
+			Balance:     balance,
+		})
+	}
+
+	if err := s.periodRepo.SaveClosingBalances(ctx, period.ID, balances); err != nil {
+		return fmt.Errorf("failed to save closing balances of period %s: %w", period.Name, err)
+	}
+	return nil
+}
+
+// ReopenPeriod implements PeriodService. Closing entries and the closing
+// balance snapshot are kept; closing the period again replaces the snapshot.
+func (s *periodService) ReopenPeriod(ctx context.Context, id, actor string) (*models.AccountingPeriod, error) {
+	period, err := s.GetPeriod(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !period.Status.CanTransitionTo(models.PeriodStatusOpen) {
+		return nil, fmt.Errorf("%w: cannot reopen %s period %s", models.ErrInvalidPeriodTransition, period.Status, period.Name)
+	}
+
+	if err := s.periodRepo.UpdatePeriodStatus(ctx, id, period.Status, models.PeriodStatusOpen, actor); err != nil {
+		return nil, fmt.Errorf("failed to reopen accounting period %s: %w", period.Name, err)
+	}
+	return s.GetPeriod(ctx, id)
+}
+
+// GetClosingBalances implements PeriodService
+func (s *periodService) GetClosingBalances(ctx context.Context, id string) ([]*models.PeriodClosingBalance, error) {
+	if _, err := s.GetPeriod(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.periodRepo.GetClosingBalances(ctx, id)
+}
@@ -0,0 +1,155 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountingPeriods(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	periods := service.NewPeriodService(f.periodRepo, f.entryRepo, f.accountRepo, f.svc)
+
+	now := time.Now()
+	period, err := periods.CreatePeriod(ctx, "current", now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, models.PeriodStatusOpen, period.Status)
+	_, err = periods.CreatePeriod(ctx, "overlapping", now, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, repository.ErrPeriodOverlap)
+	_, err = periods.CreatePeriod(ctx, "backwards", now.Add(3*time.Hour), now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, service.ErrInvalidPeriod)
+
+	alice := f.createWallet(t, "alice", "USD")
+	f.fund(t, alice, "100")
+	_, err = f.svc.ProcessFee(ctx, service.FeeRequest{AccountID: alice.ID, Amount: money.MustParse("10", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+	hosting := &models.Account{Name: "Hosting", Type: models.Expense, Currency: "USD"}
+	require.NoError(t, f.accountRepo.CreateAccount(ctx, hosting))
+	require.NoError(t, f.svc.CreateEntry(ctx, &models.Entry{
+		Description: "Hosting bill", Date: now, TransactionType: "expense", Status: models.EntryStatusPosted,
+		Lines: []models.EntryLine{
+			{AccountID: hosting.ID, Debit: money.MustParse("4", "USD")},
+			{AccountID: service.SystemAccountID(service.SystemAccountBankClearing, "USD"), Credit: money.MustParse("4", "USD")},
+		},
+	}))
+
+	closed, err := periods.ClosePeriod(ctx, period.ID, service.ClosePeriodRequest{PostClosingEntries: true, Actor: "controller"})
+	require.NoError(t, err)
+	assert.Equal(t, models.PeriodStatusSoftClosed, closed.Status)
+	assert.Equal(t, "controller", closed.ClosedBy)
+	require.NotNil(t, closed.ClosedAt)
+
+	// Revenue and expenses are zeroed and the net income lands in retained earnings
+	snapshot, err := periods.GetClosingBalances(ctx, period.ID)
+	require.NoError(t, err)
+	balances := make(map[string]string)
+	for _, b := range snapshot {
+		balances[b.AccountID] = b.Balance.Decimal()
+	}
+	assert.Equal(t, "0.00", balances[service.SystemAccountID(service.SystemAccountFeeRevenue, "USD")])
+	assert.Equal(t, "0.00", balances[hosting.ID])
+	assert.Equal(t, "6.00", balances[service.SystemAccountID(service.SystemAccountRetainedEarnings, "USD")])
+	assert.Equal(t, "90.00", balances[alice.ID])
+
+	// A soft-closed period only accepts adjustments
+	_, err = f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: alice.ID, Amount: money.MustParse("5", "USD"), Currency: "USD"})
+	var closedErr *models.PeriodClosedError
+	require.ErrorAs(t, err, &closedErr)
+	assert.Equal(t, models.PeriodStatusSoftClosed, closedErr.Status)
+	_, err = f.svc.ProcessDeposit(service.WithPeriodAdjustment(ctx), service.DepositRequest{AccountID: alice.ID, Amount: money.MustParse("5", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+
+	// Dates outside every period are unaffected
+	require.NoError(t, f.svc.CreateEntry(ctx, &models.Entry{
+		Description: "Next period", Date: now.Add(2 * time.Hour), TransactionType: "expense", Status: models.EntryStatusPosted,
+		Lines: []models.EntryLine{
+			{AccountID: hosting.ID, Debit: money.MustParse("1", "USD")},
+			{AccountID: service.SystemAccountID(service.SystemAccountBankClearing, "USD"), Credit: money.MustParse("1", "USD")},
+		},
+	}))
+
+	reopened, err := periods.ReopenPeriod(ctx, period.ID, "controller")
+	require.NoError(t, err)
+	assert.Equal(t, models.PeriodStatusOpen, reopened.Status)
+	assert.Nil(t, reopened.ClosedAt)
+	f.fund(t, alice, "1")
+
+	// A hard-closed period is final
+	closed, err = periods.ClosePeriod(ctx, period.ID, service.ClosePeriodRequest{Hard: true, Actor: "controller"})
+	require.NoError(t, err)
+	assert.Equal(t, models.PeriodStatusHardClosed, closed.Status)
+	_, err = f.svc.ProcessDeposit(service.WithPeriodAdjustment(ctx), service.DepositRequest{AccountID: alice.ID, Amount: money.MustParse("5", "USD"), Currency: "USD"})
+	assert.ErrorIs(t, err, models.ErrPeriodClosed)
+	_, err = periods.ReopenPeriod(ctx, period.ID, "controller")
+	assert.ErrorIs(t, err, models.ErrInvalidPeriodTransition)
+	_, err = periods.ClosePeriod(ctx, period.ID, service.ClosePeriodRequest{Actor: "controller"})
+	assert.ErrorIs(t, err, models.ErrInvalidPeriodTransition)
+
+	// The hard close snapshotted the adjustments made since the soft close
+	snapshot, err = periods.GetClosingBalances(ctx, period.ID)
+	require.NoError(t, err)
+	for _, b := range snapshot {
+		if b.AccountID == alice.ID {
+			assert.Equal(t, "96.00", b.Balance.Decimal())
+		}
+	}
+}
+
+func TestClosePeriod_ReopensOnFailure(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	periods := service.NewPeriodService(f.periodRepo, f.entryRepo, f.accountRepo, f.svc)
+
+	now := time.Now()
+	period, err := periods.CreatePeriod(ctx, "current", now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	f.fund(t, f.createWallet(t, "alice", "USD"), "100")
+
+	// Without a table to snapshot into, the close fails after the period was soft-closed
+	require.NoError(t, f.db.Migrator().DropTable(&models.PeriodClosingBalance{}))
+	_, err = periods.ClosePeriod(ctx, period.ID, service.ClosePeriodRequest{Hard: true, Actor: "controller"})
+	require.Error(t, err)
+
+	period, err = periods.GetPeriod(ctx, period.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PeriodStatusOpen, period.Status)
+	assert.Nil(t, period.ClosedAt)
+}
+
+// stalePeriodRepository misses every period, as a posting that read its
+// period just before the period was closed would
+type stalePeriodRepository struct {
+	repository.PeriodRepository
+}
+
+func (stalePeriodRepository) FindPeriodForDate(ctx context.Context, date time.Time) (*models.AccountingPeriod, error) {
+	return nil, nil
+}
+
+func TestPosting_RechecksPeriodWhenItCommits(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	periods := service.NewPeriodService(f.periodRepo, f.entryRepo, f.accountRepo, f.svc)
+
+	now := time.Now()
+	period, err := periods.CreatePeriod(ctx, "current", now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	alice := f.createWallet(t, "alice", "USD")
+	f.fund(t, alice, "100")
+	_, err = periods.ClosePeriod(ctx, period.ID, service.ClosePeriodRequest{Actor: "controller"})
+	require.NoError(t, err)
+
+	svc := service.NewTransactionService(f.entryRepo, f.accountRepo, f.txRepo, stalePeriodRepository{f.periodRepo})
+	_, err = svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: alice.ID, Amount: money.MustParse("5", "USD"), Currency: "USD"})
+	assert.ErrorIs(t, err, models.ErrPeriodClosed)
+	_, err = svc.ProcessDeposit(service.WithPeriodAdjustment(ctx), service.DepositRequest{AccountID: alice.ID, Amount: money.MustParse("5", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+}
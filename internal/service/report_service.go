@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+)
+
+// ErrInvalidReportPeriod is returned when a report period ends before it starts
+var ErrInvalidReportPeriod = errors.New("report period must not end before it starts")
+
+// TrialBalanceLine is one account's row in a trial balance. Exactly one of
+// Debit and Credit is non-zero unless the account nets to zero.
+type TrialBalanceLine struct {
+	AccountID   string             `json:"account_id"`
+	Code        string             `json:"code,omitempty"`
+	Name        string             `json:"name"`
+	AccountType models.AccountType `json:"account_type"`
+	Debit       money.Money        `json:"debit"`
+	Credit      money.Money        `json:"credit"`
+}
+
+// TrialBalanceSection is the trial balance of the accounts held in one currency
+type TrialBalanceSection struct {
+	Currency    string             `json:"currency"`
+	Lines       []TrialBalanceLine `json:"lines"`
+	TotalDebit  money.Money        `json:"total_debit"`
+	TotalCredit money.Money        `json:"total_credit"`
+	Balanced    bool               `json:"balanced"`
+}
+
+// TrialBalance lists the debit or credit balance of every account with
+// postings at a point in time. Currencies are never summed together, so there
+// is one section per currency.
+type TrialBalance struct {
+	AsOf     time.Time             `json:"as_of"`
+	Sections []TrialBalanceSection `json:"sections"`
+	Balanced bool                  `json:"balanced"`
+}
+
+// ReportLine is one account's amount in an income statement or balance sheet,
+// positive in the normal direction of the account's type
+type ReportLine struct {
+	AccountID   string             `json:"account_id"`
+	Code        string             `json:"code,omitempty"`
+	Name        string             `json:"name"`
+	AccountType models.AccountType `json:"account_type"`
+	Amount      money.Money        `json:"amount"`
+}
+
+// IncomeStatementSection is the income statement of the accounts held in one currency
+type IncomeStatementSection struct {
+	Currency      string       `json:"currency"`
+	Revenue       []ReportLine `json:"revenue"`
+	Expenses      []ReportLine `json:"expenses"`
+	TotalRevenue  money.Money  `json:"total_revenue"`
+	TotalExpenses money.Money  `json:"total_expenses"`
+	// NetIncome is revenue minus expenses; negative for a net loss
+	NetIncome money.Money `json:"net_income"`
+}
+
+// IncomeStatement summarizes revenue and expenses over a period, one section per currency
+type IncomeStatement struct {
+	From     time.Time                `json:"from"`
+	To       time.Time                `json:"to"`
+	Sections []IncomeStatementSection `json:"sections"`
+}
+
+// BalanceSheetSection is the balance sheet of the accounts held in one currency
+type BalanceSheetSection struct {
+	Currency string `json:"currency"`
+	// Assets also lists System accounts such as FX positions, which are debit-normal
+	Assets      []ReportLine `json:"assets"`
+	Liabilities []ReportLine `json:"liabilities"`
+	Equity      []ReportLine `json:"equity"`
+	// CurrentEarnings is revenue minus expenses not yet closed to an equity account
+	CurrentEarnings           money.Money `json:"current_earnings"`
+	TotalAssets               money.Money `json:"total_assets"`
+	TotalLiabilities          money.Money `json:"total_liabilities"`
+	TotalEquity               money.Money `json:"total_equity"`
+	TotalLiabilitiesAndEquity money.Money `json:"total_liabilities_and_equity"`
+	// Balanced reports whether assets equal liabilities plus equity
+	Balanced bool `json:"balanced"`
+}
+
+// BalanceSheet shows the financial position at a point in time, one section per currency
+type BalanceSheet struct {
+	AsOf     time.Time             `json:"as_of"`
+	Sections []BalanceSheetSection `json:"sections"`
+	Balanced bool                  `json:"balanced"`
+}
+
+// ReportService defines the interface for financial reports over the ledger
+type ReportService interface {
+	// TrialBalance returns the trial balance as of the given time
+	TrialBalance(ctx context.Context, asOf time.Time) (*TrialBalance, error)
+
+	// IncomeStatement returns revenue, expenses and net income for entries
+	// dated between from and to, inclusive
+	IncomeStatement(ctx context.Context, from, to time.Time) (*IncomeStatement, error)
+
+	// BalanceSheet returns assets, liabilities and equity as of the given
+	// time. Revenue and expenses not yet closed are included in equity as
+	// current earnings.
+	BalanceSheet(ctx context.Context, asOf time.Time) (*BalanceSheet, error)
+}
+
+// reportService implements ReportService over aggregated entry lines
+type reportService struct {
+	entryRepo repository.EntryRepository
+}
+
+// NewReportService creates a new ReportService
+func NewReportService(entryRepo repository.EntryRepository) ReportService {
+	return &reportService{entryRepo: entryRepo}
+}
+
+// TrialBalance implements ReportService
+func (s *reportService) TrialBalance(ctx context.Context, asOf time.Time) (*TrialBalance, error) {
+	totals, err := s.entryRepo.SumLinesByAccount(ctx, repository.LineFilter{To: asOf})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate entry lines: %w", err)
+	}
+
+	report := &TrialBalance{
+		AsOf:     asOf,
+		Sections: []TrialBalanceSection{},
+		Balanced: true,
+	}
+
+	// Totals arrive ordered by currency, so each currency is one contiguous run
+	var section *TrialBalanceSection
+	for _, t := range totals {
+		if section == nil || section.Currency != t.Currency {
+			report.Sections = append(report.Sections, TrialBalanceSection{
+				Currency:    t.Currency,
+				Lines:       []TrialBalanceLine{},
+				TotalDebit:  money.Zero(t.Currency),
+				TotalCredit: money.Zero(t.Currency),
+			})
+			section = &report.Sections[len(report.Sections)-1]
+		}
+
+		net, err := t.TotalDebit.Sub(t.TotalCredit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute balance of account %s: %w", t.AccountID, err)
+		}
+
+		line := TrialBalanceLine{
+			AccountID:   t.AccountID,
+			Code:        t.Code,
+			Name:        t.Name,
+			AccountType: t.Type,
+			Debit:       money.Zero(t.Currency),
+			Credit:      money.Zero(t.Currency),
+		}
+		if net.IsPositive() {
+			line.Debit = net
+		} else if net.IsNegative() {
+			line.Credit = net.Abs()
+		}
+
+		if section.TotalDebit, err = section.TotalDebit.Add(line.Debit); err != nil {
+			return nil, fmt.Errorf("failed to total %s debits: %w", t.Currency, err)
+		}
+		if section.TotalCredit, err = section.TotalCredit.Add(line.Credit); err != nil {
+			return nil, fmt.Errorf("failed to total %s credits: %w", t.Currency, err)
+		}
+		section.Lines = append(section.Lines, line)
+	}
+
+	for i := range report.Sections {
+		report.Sections[i].Balanced = report.Sections[i].TotalDebit.Equal(report.Sections[i].TotalCredit)
+		report.Balanced = report.Balanced && report.Sections[i].Balanced
+	}
+
+	return report, nil
+}
+
+// IncomeStatement implements ReportService
+func (s *reportService) IncomeStatement(ctx context.Context, from, to time.Time) (*IncomeStatement, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidReportPeriod
+	}
+
+	// Closing entries move the period's result into retained earnings; counting
+	// them would zero every account the statement reports on
+	totals, err := s.entryRepo.SumLinesByAccount(ctx, repository.LineFilter{
+		From:                    from,
+		To:                      to,
+		AccountTypes:            []models.AccountType{models.Revenue, models.Expense},
+		ExcludeTransactionTypes: []string{PeriodCloseTransactionType},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate entry lines: %w", err)
+	}
+
+	report := &IncomeStatement{From: from, To: to, Sections: []IncomeStatementSection{}}
+	for _, group := range groupByCurrency(totals) {
+		section := IncomeStatementSection{Currency: group.currency, Revenue: []ReportLine{}, Expenses: []ReportLine{}}
+		for _, t := range group.totals {
+			line, err := toReportLine(t)
+			if err != nil {
+				return nil, err
+			}
+			if t.Type == models.Revenue {
+				section.Revenue = append(section.Revenue, line)
+			} else {
+				section.Expenses = append(section.Expenses, line)
+			}
+		}
+
+		if section.TotalRevenue, err = sumReportLines(group.currency, section.Revenue); err != nil {
+			return nil, err
+		}
+		if section.TotalExpenses, err = sumReportLines(group.currency, section.Expenses); err != nil {
+			return nil, err
+		}
+		if section.NetIncome, err = section.TotalRevenue.Sub(section.TotalExpenses); err != nil {
+			return nil, fmt.Errorf("failed to compute %s net income: %w", group.currency, err)
+		}
+		report.Sections = append(report.Sections, section)
+	}
+
+	return report, nil
+}
+
+// BalanceSheet implements ReportService
+func (s *reportService) BalanceSheet(ctx context.Context, asOf time.Time) (*BalanceSheet, error) {
+	totals, err := s.entryRepo.SumLinesByAccount(ctx, repository.LineFilter{To: asOf})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate entry lines: %w", err)
+	}
+
+	report := &BalanceSheet{AsOf: asOf, Sections: []BalanceSheetSection{}, Balanced: true}
+	for _, group := range groupByCurrency(totals) {
+		section := BalanceSheetSection{
+			Currency:    group.currency,
+			Assets:      []ReportLine{},
+			Liabilities: []ReportLine{},
+			Equity:      []ReportLine{},
+		}
+		var earnings []ReportLine
+		for _, t := range group.totals {
+			line, err := toReportLine(t)
+			if err != nil {
+				return nil, err
+			}
+			switch t.Type {
+			case models.Liability:
+				section.Liabilities = append(section.Liabilities, line)
+			case models.Equity:
+				section.Equity = append(section.Equity, line)
+			case models.Revenue:
+				earnings = append(earnings, line)
+			case models.Expense:
+				line.Amount = line.Amount.Neg()
+				earnings = append(earnings, line)
+			default:
+				section.Assets = append(section.Assets, line)
+			}
+		}
+
+		if section.CurrentEarnings, err = sumReportLines(group.currency, earnings); err != nil {
+			return nil, err
+		}
+		if section.TotalAssets, err = sumReportLines(group.currency, section.Assets); err != nil {
+			return nil, err
+		}
+		if section.TotalLiabilities, err = sumReportLines(group.currency, section.Liabilities); err != nil {
+			return nil, err
+		}
+		equity, err := sumReportLines(group.currency, section.Equity)
+		if err != nil {
+			return nil, err
+		}
+		if section.TotalEquity, err = equity.Add(section.CurrentEarnings); err != nil {
+			return nil, fmt.Errorf("failed to total %s equity: %w", group.currency, err)
+		}
+		if section.TotalLiabilitiesAndEquity, err = section.TotalLiabilities.Add(section.TotalEquity); err != nil {
+			return nil, fmt.Errorf("failed to total %s liabilities and equity: %w", group.currency, err)
+		}
+
+		section.Balanced = section.TotalAssets.Equal(section.TotalLiabilitiesAndEquity)
+		report.Balanced = report.Balanced && section.Balanced
+		report.Sections = append(report.Sections, section)
+	}
+
+	return report, nil
+}
+
+// currencyGroup holds the account totals of one currency
+type currencyGroup struct {
+	currency string
+	totals   []repository.AccountTotals
+}
+
+// groupByCurrency splits account totals, which arrive ordered by currency, into one group per currency
+func groupByCurrency(totals []repository.AccountTotals) []currencyGroup {
+	var groups []currencyGroup
+	for _, t := range totals {
+		if len(groups) == 0 || groups[len(groups)-1].currency != t.Currency {
+			groups = append(groups, currencyGroup{currency: t.Currency})
+		}
+		groups[len(groups)-1].totals = append(groups[len(groups)-1].totals, t)
+	}
+	return groups
+}
+
+// toReportLine converts account totals to a report line with the amount in the account's normal direction
+func toReportLine(t repository.AccountTotals) (ReportLine, error) {
+	amount, err := normalBalance(t.Type, t.TotalDebit, t.TotalCredit)
+	if err != nil {
+		return ReportLine{}, fmt.Errorf("failed to compute balance of account %s: %w", t.AccountID, err)
+	}
+	return ReportLine{
+		AccountID:   t.AccountID,
+		Code:        t.Code,
+		Name:        t.Name,
+		AccountType: t.Type,
+		Amount:      amount,
+	}, nil
+}
+
+// sumReportLines totals the amounts of report lines in one currency
+func sumReportLines(currency string, lines []ReportLine) (money.Money, error) {
+	total := money.Zero(currency)
+	for _, line := range lines {
+		var err error
+		if total, err = total.Add(line.Amount); err != nil {
+			return money.Money{}, fmt.Errorf("failed to total %s amounts: %w", currency, err)
+		}
+	}
+	return total, nil
+}
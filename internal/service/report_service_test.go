@@ -0,0 +1,145 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrialBalance(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	reports := service.NewReportService(f.entryRepo)
+
+	alice := f.createWallet(t, "alice", "USD")
+	bob := f.createWallet(t, "bob", "USD")
+	chidi := f.createWallet(t, "chidi", "NGN")
+	f.fund(t, alice, "100")
+	f.fund(t, chidi, "5000")
+	_, err := f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("40", "USD"), Currency: "USD",
+	})
+	require.NoError(t, err)
+	_, err = f.svc.ProcessFee(ctx, service.FeeRequest{AccountID: alice.ID, Amount: money.MustParse("1.25", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+
+	report, err := reports.TrialBalance(ctx, time.Now())
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+
+	// Currencies are reported separately, never summed together
+	require.Len(t, report.Sections, 2)
+	ngn, usd := report.Sections[0], report.Sections[1]
+	assert.Equal(t, "NGN", ngn.Currency)
+	assert.Equal(t, "5000.00", ngn.TotalDebit.Decimal())
+	assert.Equal(t, "5000.00", ngn.TotalCredit.Decimal())
+
+	assert.Equal(t, "USD", usd.Currency)
+	assert.True(t, usd.Balanced)
+	assert.Equal(t, "100.00", usd.TotalDebit.Decimal())
+	assert.Equal(t, "100.00", usd.TotalCredit.Decimal())
+
+	credits := make(map[string]string)
+	for _, line := range usd.Lines {
+		assert.True(t, line.Debit.IsZero() || line.Credit.IsZero(), "account %s has both columns set", line.AccountID)
+		credits[line.AccountID] = line.Credit.Decimal()
+	}
+	assert.Equal(t, "58.75", credits[alice.ID])
+	assert.Equal(t, "40.00", credits[bob.ID])
+
+	// Nothing was posted before the first deposit
+	report, err = reports.TrialBalance(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, report.Sections)
+	assert.True(t, report.Balanced)
+}
+
+func TestIncomeStatementAndBalanceSheet(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	reports := service.NewReportService(f.entryRepo)
+
+	usd := f.createWallet(t, "alice-usd", "USD")
+	eur := f.createWallet(t, "alice-eur", "EUR")
+	f.fund(t, usd, "200")
+	_, err := f.svc.ProcessExchange(ctx, service.ExchangeRequest{
+		SourceAccountID: usd.ID, SourceAmount: money.MustParse("100", "USD"), SourceCurrency: "USD",
+		DestinationAccountID: eur.ID, DestinationAmount: money.MustParse("90", "EUR"), DestinationCurrency: "EUR",
+		ExchangeRate: money.MustParseRate("0.9"),
+	})
+	require.NoError(t, err)
+	_, err = f.svc.ProcessFee(ctx, service.FeeRequest{AccountID: usd.ID, Amount: money.MustParse("2.5", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+
+	hosting := &models.Account{Name: "Hosting", Type: models.Expense, Currency: "USD"}
+	require.NoError(t, f.accountRepo.CreateAccount(ctx, hosting))
+	require.NoError(t, f.svc.CreateEntry(ctx, &models.Entry{
+		Description: "Hosting bill", TransactionType: "expense", Date: time.Now(), Status: models.EntryStatusPosted,
+		Lines: []models.EntryLine{
+			{AccountID: hosting.ID, Debit: money.MustParse("10", "USD")},
+			{AccountID: service.SystemAccountID(service.SystemAccountBankClearing, "USD"), Credit: money.MustParse("10", "USD")},
+		},
+	}))
+
+	statement, err := reports.IncomeStatement(ctx, time.Now().Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+	require.Len(t, statement.Sections, 1)
+	pnl := statement.Sections[0]
+	assert.Equal(t, "USD", pnl.Currency)
+	assert.Equal(t, "2.50", pnl.TotalRevenue.Decimal())
+	assert.Equal(t, "10.00", pnl.TotalExpenses.Decimal())
+	assert.Equal(t, "-7.50", pnl.NetIncome.Decimal())
+
+	statement, err = reports.IncomeStatement(ctx, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, statement.Sections)
+	_, err = reports.IncomeStatement(ctx, time.Now(), time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, service.ErrInvalidReportPeriod)
+
+	// Assets = Liabilities + Equity holds in every currency once earnings are rolled into equity
+	sheet, err := reports.BalanceSheet(ctx, time.Now())
+	require.NoError(t, err)
+	assert.True(t, sheet.Balanced)
+	require.Len(t, sheet.Sections, 2)
+	eurSheet, usdSheet := sheet.Sections[0], sheet.Sections[1]
+
+	assert.Equal(t, "EUR", eurSheet.Currency)
+	assert.Equal(t, "90.00", eurSheet.TotalAssets.Decimal())
+	assert.Equal(t, "90.00", eurSheet.TotalLiabilities.Decimal())
+
+	assert.Equal(t, "USD", usdSheet.Currency)
+	assert.Equal(t, "90.00", usdSheet.TotalAssets.Decimal())
+	assert.Equal(t, "97.50", usdSheet.TotalLiabilities.Decimal())
+	assert.Equal(t, "-7.50", usdSheet.CurrentEarnings.Decimal())
+	assert.Equal(t, "-7.50", usdSheet.TotalEquity.Decimal())
+	assert.Equal(t, "90.00", usdSheet.TotalLiabilitiesAndEquity.Decimal())
+}
+
+func TestIncomeStatement_IgnoresClosingEntries(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	reports := service.NewReportService(f.entryRepo)
+	periods := service.NewPeriodService(f.periodRepo, f.entryRepo, f.accountRepo, f.svc)
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	period, err := periods.CreatePeriod(ctx, "current", from, to)
+	require.NoError(t, err)
+	alice := f.createWallet(t, "alice", "USD")
+	f.fund(t, alice, "100")
+	_, err = f.svc.ProcessFee(ctx, service.FeeRequest{AccountID: alice.ID, Amount: money.MustParse("4", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+	_, err = periods.ClosePeriod(ctx, period.ID, service.ClosePeriodRequest{PostClosingEntries: true, Actor: "controller"})
+	require.NoError(t, err)
+
+	statement, err := reports.IncomeStatement(ctx, from, to)
+	require.NoError(t, err)
+	require.Len(t, statement.Sections, 1)
+	assert.Equal(t, "4.00", statement.Sections[0].TotalRevenue.Decimal())
+	assert.Equal(t, "4.00", statement.Sections[0].NetIncome.Decimal())
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidRevaluation is returned when a revaluation cannot be run for the requested dates
+	ErrInvalidRevaluation = errors.New("invalid fx revaluation")
+	// ErrRevaluationExists is returned when a revaluation was already posted at the requested date
+	ErrRevaluationExists = errors.New("fx revaluation already posted")
+)
+
+// Transaction types of the entries posted by a revaluation
+const (
+	RevaluationTransactionType         = "fx_revaluation"
+	RevaluationReversalTransactionType = "fx_revaluation_reversal"
+)
+
+// revaluationPageSize is how many lines of an account are read at a time when
+// valuing its postings at historical rates
+const revaluationPageSize = 500
+
+// RevaluationService defines the interface for measuring foreign-currency
+// balances in the reporting currency
+type RevaluationService interface {
+	// Revalue values every foreign-currency Asset, Liability and System
+	// account, such as the FX position accounts, at the rate in effect at
+	// req.AsOf, posts the gain or loss of each account and reverses it on
+	// req.ReverseOn. A date can only be revalued again once its earlier
+	// revaluation entries have been reversed.
+	Revalue(ctx context.Context, req RevaluationRequest) (*Revaluation, error)
+}
+
+// RevaluationRequest defines the request for a revaluation
+type RevaluationRequest struct {
+	// AsOf is the date balances are measured and the adjustments are posted at
+	AsOf time.Time
+	// ReverseOn is the date the adjustments are reversed at; zero means the
+	// start of the first accounting period after AsOf
+	ReverseOn time.Time
+}
+
+// Revaluation is the outcome of a revaluation run
+type Revaluation struct {
+	AsOf              time.Time
+	ReverseOn         time.Time
+	ReportingCurrency string
+	Accounts          []AccountRevaluation
+	// Entries are the adjustments posted, each followed by its reversal
+	Entries []*models.Entry
+}
+
+// AccountRevaluation is the revaluation of one foreign-currency account.
+// Amounts are signed debit minus credit.
+type AccountRevaluation struct {
+	AccountID string
+	Currency  string
+	// Balance is the account balance in its own currency
+	Balance money.Money
+	// Rate converts the account currency to the reporting currency at AsOf
+	Rate money.Rate
+	// RevaluedAmount is the balance converted at Rate
+	RevaluedAmount money.Money
+	// HistoricalAmount is the sum of the account's postings each converted at
+	// the rate in effect on the day it was posted
+	HistoricalAmount money.Money
+	// GainLoss is RevaluedAmount less HistoricalAmount
+	GainLoss money.Money
+	// RevaluationAccountID is the reporting-currency account GainLoss is
+	// posted to; it is empty when there is no gain or loss
+	RevaluationAccountID string
+}
+
+// revaluationService implements RevaluationService
+type revaluationService struct {
+	entryRepo         repository.EntryRepository
+	accountRepo       repository.AccountRepository
+	periodRepo        repository.PeriodRepository
+	txService         TransactionService
+	rateService       ExchangeRateService
+	reportingCurrency string
+}
+
+// NewRevaluationService creates a new RevaluationService measuring balances in reportingCurrency
+func NewRevaluationService(
+	entryRepo repository.EntryRepository,
+	accountRepo repository.AccountRepository,
+	periodRepo repository.PeriodRepository,
+	txService TransactionService,
+	rateService ExchangeRateService,
+	reportingCurrency string,
+) RevaluationService {
+	return &revaluationService{
+		entryRepo:         entryRepo,
+		accountRepo:       accountRepo,
+		periodRepo:        periodRepo,
+		txService:         txService,
+		rateService:       rateService,
+		reportingCurrency: reportingCurrency,
+	}
+}
+
+// Revalue implements RevaluationService. The adjustment of each run is
+// measured against historical rates, not the previous run, so the previous
+// run must have been reversed by the time the next one posts. The entries of
+// a run, adjustments and reversals alike, are posted in one database
+// transaction.
+func (s *revaluationService) Revalue(ctx context.Context, req RevaluationRequest) (*Revaluation, error) {
+	if req.AsOf.IsZero() {
+		return nil, fmt.Errorf("%w: as-of date is required", ErrInvalidRevaluation)
+	}
+	reverseOn, err := s.reversalDate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	posted, err := s.entryRepo.HasPostedEntries(ctx, RevaluationTransactionType, req.AsOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up revaluations at %s: %w", req.AsOf.Format(time.RFC3339), err)
+	}
+	if posted {
+		return nil, fmt.Errorf("%w at %s", ErrRevaluationExists, req.AsOf.Format(time.RFC3339))
+	}
+
+	totals, err := s.entryRepo.SumLinesByAccount(ctx, repository.LineFilter{
+		To:           req.AsOf,
+		AccountTypes: []models.AccountType{models.Asset, models.Liability, models.System},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate entry lines: %w", err)
+	}
+
+	result := &Revaluation{AsOf: req.AsOf, ReverseOn: reverseOn, ReportingCurrency: s.reportingCurrency}
+	rates := newHistoricalRates(s.rateService, s.reportingCurrency, req.AsOf)
+	for _, group := range groupByCurrency(totals) {
+		if group.currency == s.reportingCurrency {
+			continue
+		}
+
+		var revalued []AccountRevaluation
+		for _, t := range group.totals {
+			account, err := s.revalueAccount(ctx, t, rates)
+			if err != nil {
+				return nil, err
+			}
+			if account.Balance.IsZero() && account.GainLoss.IsZero() {
+				continue
+			}
+			if !account.GainLoss.IsZero() {
+				revaluation, err := ensureRevaluationAccount(ctx, s.accountRepo, t, s.reportingCurrency)
+				if err != nil {
+					return nil, err
+				}
+				account.RevaluationAccountID = revaluation.ID
+			}
+			revalued = append(revalued, *account)
+		}
+		result.Accounts = append(result.Accounts, revalued...)
+
+		entries, err := s.adjustmentEntries(ctx, group.currency, revalued, req.AsOf, reverseOn)
+		if err != nil {
+			return nil, err
+		}
+		result.Entries = append(result.Entries, entries...)
+	}
+
+	if len(result.Entries) > 0 {
+		if err := s.txService.CreateEntries(ctx, result.Entries...); err != nil {
+			return nil, fmt.Errorf("failed to post revaluation: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// reversalDate returns when the adjustments of a revaluation are reversed
+func (s *revaluationService) reversalDate(ctx context.Context, req RevaluationRequest) (time.Time, error) {
+	if !req.ReverseOn.IsZero() {
+		if !req.ReverseOn.After(req.AsOf) {
+			return time.Time{}, fmt.Errorf("%w: reversal date must be after the as-of date", ErrInvalidRevaluation)
+		}
+		return req.ReverseOn, nil
+	}
+
+	periods, err := s.periodRepo.ListPeriods(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to list accounting periods: %w", err)
+	}
+	for _, period := range periods {
+		if period.StartDate.After(req.AsOf) {
+			return period.StartDate, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: no accounting period starts after %s to reverse on", ErrInvalidRevaluation, req.AsOf.Format(time.RFC3339))
+}
+
+// revalueAccount measures one account in the reporting currency at the
+// current rate and at the rates its postings were made at
+func (s *revaluationService) revalueAccount(ctx context.Context, t repository.AccountTotals, rates *historicalRates) (*AccountRevaluation, error) {
+	balance, err := t.TotalDebit.Sub(t.TotalCredit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute balance of account %s: %w", t.AccountID, err)
+	}
+	rate, err := rates.at(ctx, t.Currency, rates.asOf)
+	if err != nil {
+		return nil, err
+	}
+	revalued, err := balance.Convert(rate, s.reportingCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revalue account %s: %w", t.AccountID, err)
+	}
+	historical, err := s.historicalAmount(ctx, t.AccountID, t.Currency, rates)
+	if err != nil {
+		return nil, err
+	}
+	gainLoss, err := revalued.Sub(historical)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute gain or loss of account %s: %w", t.AccountID, err)
+	}
+
+	return &AccountRevaluation{
+		AccountID:        t.AccountID,
+		Currency:         t.Currency,
+		Balance:          balance,
+		Rate:             rate,
+		RevaluedAmount:   revalued,
+		HistoricalAmount: historical,
+		GainLoss:         gainLoss,
+	}, nil
+}
+
+// historicalAmount converts the postings of an account up to the revaluation
+// date, netted per day, at the rate in effect at the end of each day
+func (s *revaluationService) historicalAmount(ctx context.Context, accountID, currency string, rates *historicalRates) (money.Money, error) {
+	total := money.Zero(s.reportingCurrency)
+	dayNet := money.Zero(currency)
+	var day time.Time
+
+	// flush converts the running day's net postings and adds them to the total
+	flush := func() error {
+		if dayNet.IsZero() {
+			return nil
+		}
+		at := day.Add(24 * time.Hour).Add(-time.Nanosecond)
+		if at.After(rates.asOf) {
+			at = rates.asOf
+		}
+		rate, err := rates.at(ctx, currency, at)
+		if err != nil {
+			return err
+		}
+		converted, err := dayNet.Convert(rate, s.reportingCurrency)
+		if err != nil {
+			return fmt.Errorf("failed to convert postings of account %s: %w", accountID, err)
+		}
+		total, err = total.Add(converted)
+		return err
+	}
+
+	query := repository.AccountLinesQuery{To: rates.asOf, Limit: revaluationPageSize}
+	for {
+		lines, err := s.entryRepo.GetAccountLines(ctx, accountID, query)
+		if err != nil {
+			return money.Money{}, fmt.Errorf("failed to get lines of account %s: %w", accountID, err)
+		}
+		for _, line := range lines {
+			lineDay := line.Date.UTC().Truncate(24 * time.Hour)
+			if !lineDay.Equal(day) {
+				if err := flush(); err != nil {
+					return money.Money{}, err
+				}
+				day, dayNet = lineDay, money.Zero(currency)
+			}
+			if dayNet, err = dayNet.Add(line.Debit.WithCurrency(currency)); err == nil {
+				dayNet, err = dayNet.Sub(line.Credit.WithCurrency(currency))
+			}
+			if err != nil {
+				return money.Money{}, fmt.Errorf("failed to net postings of account %s: %w", accountID, err)
+			}
+		}
+		if len(lines) < revaluationPageSize {
+			break
+		}
+		last := lines[len(lines)-1]
+		query.After = &repository.LinePosition{Date: last.Date, LineID: last.LineID}
+	}
+
+	if err := flush(); err != nil {
+		return money.Money{}, err
+	}
+	return total, nil
+}
+
+// adjustmentEntries builds the entry booking the gain or loss of each account
+// revalued in one currency to the account's revaluation account, and its
+// reversal. The gains and losses are netted into a single unrealized gain or
+// loss line, which is left out when they cancel, as they do for balances the
+// FX position accounts offset. No entries are built when there is no gain or
+// loss at all.
+func (s *revaluationService) adjustmentEntries(ctx context.Context, currency string, accounts []AccountRevaluation, asOf, reverseOn time.Time) ([]*models.Entry, error) {
+	var lines []models.EntryLine
+	net := money.Zero(s.reportingCurrency)
+	for _, account := range accounts {
+		if account.GainLoss.IsZero() {
+			continue
+		}
+		// A gain raises the carrying value of a balance, a loss lowers it
+		lines = append(lines, signedLine(account.RevaluationAccountID, account.GainLoss))
+		var err error
+		if net, err = net.Add(account.GainLoss); err != nil {
+			return nil, fmt.Errorf("failed to total revaluation of %s: %w", currency, err)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	if !net.IsZero() {
+		unrealized, err := ensureSystemAccount(ctx, s.accountRepo, SystemAccountFXUnrealizedGainLoss, s.reportingCurrency)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, signedLine(unrealized.ID, net.Neg()))
+	}
+
+	reversed := make([]models.EntryLine, 0, len(lines))
+	for _, line := range lines {
+		reversed = append(reversed, models.EntryLine{AccountID: line.AccountID, Debit: line.Credit, Credit: line.Debit})
+	}
+
+	entry := &models.Entry{
+		ID:              uuid.New().String(),
+		Description:     fmt.Sprintf("FX revaluation of %s balances at %s", currency, asOf.Format("2006-01-02")),
+		Date:            asOf,
+		TransactionType: RevaluationTransactionType,
+		Status:          models.EntryStatusPosted,
+		Lines:           lines,
+	}
+	reversal := &models.Entry{
+		Description:     fmt.Sprintf("Reversal of FX revaluation of %s balances at %s", currency, asOf.Format("2006-01-02")),
+		Date:            reverseOn,
+		TransactionType: RevaluationReversalTransactionType,
+		ReferenceID:     entry.ID,
+		Status:          models.EntryStatusPosted,
+		Lines:           reversed,
+	}
+	return []*models.Entry{entry, reversal}, nil
+}
+
+// signedLine debits a positive amount to an account or credits a negative one
+func signedLine(accountID string, amount money.Money) models.EntryLine {
+	if amount.IsNegative() {
+		return models.EntryLine{AccountID: accountID, Credit: amount.Abs()}
+	}
+	return models.EntryLine{AccountID: accountID, Debit: amount}
+}
+
+// historicalRates looks up and remembers the rates from foreign currencies to
+// the reporting currency over one revaluation run
+type historicalRates struct {
+	rateService       ExchangeRateService
+	reportingCurrency string
+	asOf              time.Time
+	rates             map[string]money.Rate
+}
+
+func newHistoricalRates(rateService ExchangeRateService, reportingCurrency string, asOf time.Time) *historicalRates {
+	return &historicalRates{
+		rateService:       rateService,
+		reportingCurrency: reportingCurrency,
+		asOf:              asOf,
+		rates:             make(map[string]money.Rate),
+	}
+}
+
+// at returns the rate from currency to the reporting currency in effect at at
+func (h *historicalRates) at(ctx context.Context, currency string, at time.Time) (money.Rate, error) {
+	key := currency + "@" + at.UTC().Format(time.RFC3339Nano)
+	if rate, ok := h.rates[key]; ok {
+		return rate, nil
+	}
+	resolved, err := h.rateService.GetRateAt(ctx, currency, h.reportingCurrency, at)
+	if err != nil {
+		return money.Rate{}, fmt.Errorf("failed to get %s/%s rate at %s: %w", currency, h.reportingCurrency, at.Format(time.RFC3339), err)
+	}
+	h.rates[key] = resolved.Rate
+	return resolved.Rate, nil
+}
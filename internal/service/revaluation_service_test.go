@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevaluation(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	rates := service.NewExchangeRateService([]service.RateProvider{service.NewDBRateProvider(f.rateRepo)}, f.rateRepo, service.ExchangeRateConfig{BaseCurrency: "USD"})
+	require.NoError(t, rates.CreateRate(ctx, &models.ExchangeRate{BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: money.MustParseRate("1.10"), EffectiveFrom: now.AddDate(0, 0, -10)}))
+	require.NoError(t, rates.CreateRate(ctx, &models.ExchangeRate{BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: money.MustParseRate("1.20"), EffectiveFrom: now.AddDate(0, 0, -2)}))
+	periods := service.NewPeriodService(f.periodRepo, f.entryRepo, f.accountRepo, f.svc)
+	revaluations := service.NewRevaluationService(f.entryRepo, f.accountRepo, f.periodRepo, f.svc, rates, "USD")
+
+	// EUR bought today at 1.20, and earlier while EUR was at 1.10 and then at
+	// 1.20, all of it through the EUR position account. A fee was then earned
+	// in EUR while it was at 1.10.
+	eur := f.createWallet(t, "alice-eur", "EUR")
+	usd := f.createWallet(t, "alice-usd", "USD")
+	f.fund(t, usd, "500")
+	_, err := f.svc.ProcessExchange(ctx, service.ExchangeRequest{
+		SourceAccountID: usd.ID, SourceAmount: money.MustParse("12", "USD"), SourceCurrency: "USD",
+		DestinationAccountID: eur.ID, DestinationAmount: money.MustParse("10", "EUR"), DestinationCurrency: "EUR",
+		ExchangeRate: money.MustParseRate("0.83333333"),
+	})
+	require.NoError(t, err)
+	position := service.SystemAccountID(service.SystemAccountFXPosition, "EUR")
+	for _, d := range []struct {
+		amount string
+		date   time.Time
+	}{{"100", now.AddDate(0, 0, -5)}, {"50", now.AddDate(0, 0, -1)}} {
+		require.NoError(t, f.svc.CreateEntry(ctx, &models.Entry{
+			Description: "EUR received", Date: d.date, TransactionType: "exchange", Status: models.EntryStatusPosted,
+			Lines: []models.EntryLine{
+				{AccountID: position, Debit: money.MustParse(d.amount, "EUR")},
+				{AccountID: eur.ID, Credit: money.MustParse(d.amount, "EUR")},
+			},
+		}))
+	}
+	revenue := &models.Account{Name: "EUR fees", Type: models.Revenue, Currency: "EUR"}
+	require.NoError(t, f.accountRepo.CreateAccount(ctx, revenue))
+	require.NoError(t, f.svc.CreateEntry(ctx, &models.Entry{
+		Description: "EUR fee", Date: now.AddDate(0, 0, -5), TransactionType: "fee", Status: models.EntryStatusPosted,
+		Lines: []models.EntryLine{
+			{AccountID: eur.ID, Debit: money.MustParse("20", "EUR")},
+			{AccountID: revenue.ID, Credit: money.MustParse("20", "EUR")},
+		},
+	}))
+
+	asOf := now.Add(time.Minute)
+	_, err = revaluations.Revalue(ctx, service.RevaluationRequest{AsOf: asOf})
+	assert.ErrorIs(t, err, service.ErrInvalidRevaluation, "no period to reverse in")
+	_, err = revaluations.Revalue(ctx, service.RevaluationRequest{AsOf: asOf, ReverseOn: asOf})
+	assert.ErrorIs(t, err, service.ErrInvalidRevaluation)
+
+	next, err := periods.CreatePeriod(ctx, "next", now.Add(time.Hour), now.AddDate(0, 1, 0))
+	require.NoError(t, err)
+
+	// The adjustment is not posted without its reversal
+	_, err = periods.ClosePeriod(ctx, next.ID, service.ClosePeriodRequest{Actor: "controller"})
+	require.NoError(t, err)
+	_, err = revaluations.Revalue(ctx, service.RevaluationRequest{AsOf: asOf})
+	assert.ErrorIs(t, err, models.ErrPeriodClosed)
+	posted, err := f.entryRepo.HasPostedEntries(ctx, service.RevaluationTransactionType, asOf)
+	require.NoError(t, err)
+	assert.False(t, posted)
+	_, err = periods.ReopenPeriod(ctx, next.ID, "controller")
+	require.NoError(t, err)
+
+	result, err := revaluations.Revalue(ctx, service.RevaluationRequest{AsOf: asOf})
+	require.NoError(t, err)
+	assert.Equal(t, next.StartDate.Unix(), result.ReverseOn.Unix())
+
+	// The EUR wallet owes 140 EUR booked at 160 USD that is now worth 168,
+	// and the position offsetting it holds 160 EUR booked at 182 USD that is
+	// now worth 192. The fee revenue is not revalued.
+	require.Len(t, result.Accounts, 2)
+	accounts := make(map[string]service.AccountRevaluation)
+	for _, account := range result.Accounts {
+		accounts[account.AccountID] = account
+	}
+	wallet := accounts[eur.ID]
+	assert.Equal(t, "-140.00", wallet.Balance.Decimal())
+	assert.Equal(t, "1.2", wallet.Rate.String())
+	assert.Equal(t, "-168.00", wallet.RevaluedAmount.Decimal())
+	assert.Equal(t, "-160.00", wallet.HistoricalAmount.Decimal())
+	assert.Equal(t, "-8.00", wallet.GainLoss.Decimal())
+	assert.Equal(t, service.RevaluationAccountID(eur.ID, "USD"), wallet.RevaluationAccountID)
+	held := accounts[position]
+	assert.Equal(t, "160.00", held.Balance.Decimal())
+	assert.Equal(t, "192.00", held.RevaluedAmount.Decimal())
+	assert.Equal(t, "182.00", held.HistoricalAmount.Decimal())
+	assert.Equal(t, "10.00", held.GainLoss.Decimal())
+	assert.Equal(t, service.RevaluationAccountID(position, "USD"), held.RevaluationAccountID)
+
+	// Each account's adjustment is posted to its own revaluation account at
+	// the as-of date, the net gain to unrealized gain or loss, and all of it
+	// is reversed in the next period
+	unrealized := service.SystemAccountID(service.SystemAccountFXUnrealizedGainLoss, "USD")
+	require.Len(t, result.Entries, 2)
+	adjustment, reversal := result.Entries[0], result.Entries[1]
+	assert.Equal(t, service.RevaluationTransactionType, adjustment.TransactionType)
+	assertEntryLines(t, adjustment, map[string][2]string{
+		wallet.RevaluationAccountID: {"0.00", "8.00"},
+		held.RevaluationAccountID:   {"10.00", "0.00"},
+		unrealized:                  {"0.00", "2.00"},
+	})
+	assert.Equal(t, service.RevaluationReversalTransactionType, reversal.TransactionType)
+	assert.Equal(t, adjustment.ID, reversal.ReferenceID)
+	assert.Equal(t, next.StartDate.Unix(), reversal.Date.Unix())
+	assertEntryLines(t, reversal, map[string][2]string{
+		wallet.RevaluationAccountID: {"8.00", "0.00"},
+		held.RevaluationAccountID:   {"0.00", "10.00"},
+		unrealized:                  {"2.00", "0.00"},
+	})
+	walletRevaluation, err := f.accountRepo.GetAccountByID(ctx, wallet.RevaluationAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, models.Liability, walletRevaluation.Type, "adjustments are reported with the balance they adjust")
+	assert.Equal(t, "USD", walletRevaluation.Currency)
+
+	debit, credit, err := f.entryRepo.SumAccountLines(ctx, unrealized, asOf)
+	require.NoError(t, err)
+	assert.Equal(t, "0.00", debit.Decimal())
+	assert.Equal(t, "2.00", credit.Decimal())
+	debit, credit, err = f.entryRepo.SumAccountLines(ctx, unrealized, next.StartDate)
+	require.NoError(t, err)
+	assert.True(t, debit.Equal(credit), "the reversal clears the adjustment")
+
+	// A date is only revalued once
+	_, err = revaluations.Revalue(ctx, service.RevaluationRequest{AsOf: asOf})
+	assert.ErrorIs(t, err, service.ErrRevaluationExists)
+}
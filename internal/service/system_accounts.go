@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/google/uuid"
+)
+
+// SystemAccountPurpose identifies a platform-owned account that sits on the
+// other side of wallet postings.
+type SystemAccountPurpose string
+
+const (
+	// SystemAccountBankClearing holds funds in transit to and from external banks
+	SystemAccountBankClearing SystemAccountPurpose = "bank-clearing"
+	// SystemAccountFeeRevenue collects fees charged to wallets
+	SystemAccountFeeRevenue SystemAccountPurpose = "fee-revenue"
+	// SystemAccountFXPosition carries the platform's open position in each currency
+	SystemAccountFXPosition SystemAccountPurpose = "fx-position"
+	// SystemAccountRetainedEarnings accumulates net income moved out of closed periods
+	SystemAccountRetainedEarnings SystemAccountPurpose = "retained-earnings"
+	// SystemAccountFXRealizedGainLoss books the gain or loss locked in when an
+	// exchange converts at a rate other than the market rate
+	SystemAccountFXRealizedGainLoss SystemAccountPurpose = "fx-realized-gain-loss"
+	// SystemAccountFXUnrealizedGainLoss books the gain or loss of revaluing
+	// foreign-currency balances in the reporting currency
+	SystemAccountFXUnrealizedGainLoss SystemAccountPurpose = "fx-unrealized-gain-loss"
+	// SystemAccountFXRevaluation carries, in the reporting currency, the
+	// revaluation adjustment of one foreign-currency account. Its accounts are
+	// kept per revalued account; see RevaluationAccountID.
+	SystemAccountFXRevaluation SystemAccountPurpose = "fx-revaluation"
+)
+
+// systemAccountNamespace seeds the deterministic IDs of system accounts so that
+// every instance of the service resolves the same account for a purpose and currency.
+var systemAccountNamespace = uuid.MustParse("6f1c2a4e-3b8d-4c5e-9a7f-1d2e3f4a5b6c")
+
+// systemAccountTypes maps each purpose to the account type it is booked under
+var systemAccountTypes = map[SystemAccountPurpose]models.AccountType{
+	SystemAccountBankClearing:         models.Asset,
+	SystemAccountFeeRevenue:           models.Revenue,
+	SystemAccountFXPosition:           models.System,
+	SystemAccountRetainedEarnings:     models.Equity,
+	SystemAccountFXRealizedGainLoss:   models.Revenue,
+	SystemAccountFXUnrealizedGainLoss: models.Revenue,
+}
+
+// SystemAccountID returns the deterministic account ID for a system account
+func SystemAccountID(purpose SystemAccountPurpose, currency string) string {
+	return uuid.NewSHA1(systemAccountNamespace, []byte(string(purpose)+":"+currency)).String()
+}
+
+// RevaluationAccountID returns the deterministic ID of the account carrying,
+// in reportingCurrency, the revaluation adjustment of a foreign-currency account
+func RevaluationAccountID(accountID, reportingCurrency string) string {
+	return uuid.NewSHA1(systemAccountNamespace, []byte(string(SystemAccountFXRevaluation)+":"+accountID+":"+reportingCurrency)).String()
+}
+
+// systemAccount retrieves the system account for a purpose and currency,
+// creating it on first use.
+func (s *transactionServiceImpl) systemAccount(ctx context.Context, purpose SystemAccountPurpose, currency string) (*models.Account, error) {
+	return ensureSystemAccount(ctx, s.accountRepo, purpose, currency)
+}
+
+// ensureSystemAccount retrieves the system account for a purpose and currency
+// from accountRepo, creating it if it does not exist yet.
+func ensureSystemAccount(ctx context.Context, accountRepo repository.AccountRepository, purpose SystemAccountPurpose, currency string) (*models.Account, error) {
+	return ensureAccount(ctx, accountRepo, &models.Account{
+		ID:       SystemAccountID(purpose, currency),
+		Name:     fmt.Sprintf("System %s (%s)", purpose, currency),
+		Type:     systemAccountTypes[purpose],
+		Currency: currency,
+	})
+}
+
+// ensureRevaluationAccount retrieves the account carrying the revaluation
+// adjustment of a foreign-currency account, creating it if it does not exist
+// yet. It is of the revalued account's type, so that the adjustment is
+// reported alongside the balance it adjusts.
+func ensureRevaluationAccount(ctx context.Context, accountRepo repository.AccountRepository, revalued repository.AccountTotals, reportingCurrency string) (*models.Account, error) {
+	return ensureAccount(ctx, accountRepo, &models.Account{
+		ID:       RevaluationAccountID(revalued.AccountID, reportingCurrency),
+		Name:     fmt.Sprintf("System %s of %s (%s)", SystemAccountFXRevaluation, revalued.Name, reportingCurrency),
+		Type:     revalued.Type,
+		Currency: reportingCurrency,
+	})
+}
+
+// ensureAccount retrieves the platform-owned account with account's ID from
+// accountRepo, creating it as account if it does not exist yet.
+func ensureAccount(ctx context.Context, accountRepo repository.AccountRepository, account *models.Account) (*models.Account, error) {
+	existing, err := accountRepo.GetAccountByID(ctx, account.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", account.Name, err)
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	if err := accountRepo.CreateAccount(ctx, account); err != nil {
+		// Another request may have created it concurrently
+		existing, getErr := accountRepo.GetAccountByID(ctx, account.ID)
+		if getErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to create account %s: %w", account.Name, err)
+	}
+
+	return account, nil
+}
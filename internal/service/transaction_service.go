@@ -4,96 +4,333 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrEntryNotFound is returned when the requested ledger entry does not exist
+	ErrEntryNotFound = errors.New("entry not found")
+	// ErrEntryAlreadyReversed is returned when reversing an entry that has already been reversed
+	ErrEntryAlreadyReversed = errors.New("entry has already been reversed")
+	// ErrEntryNotReversible is returned when an entry cannot be reversed, e.g. because it is itself a reversal
+	ErrEntryNotReversible = errors.New("entry cannot be reversed")
+	// ErrTransactionNotFound is returned when the requested wallet transaction does not exist
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrNonLeafAccount is returned when posting to an account that has child accounts
+	ErrNonLeafAccount = errors.New("postings are only allowed on leaf accounts")
+	// ErrInvalidEntry is returned when an entry is malformed, e.g. has a line
+	// with a negative amount
+	ErrInvalidEntry = errors.New("invalid entry")
+	// ErrUnbalancedEntry is wrapped by every UnbalancedEntryError
+	ErrUnbalancedEntry = errors.New("entry does not balance")
+	// ErrLineCurrencyMismatch is returned when a line's currency differs from its account's
+	ErrLineCurrencyMismatch = errors.New("line currency does not match account currency")
+	// ErrCrossCurrencyEntry is returned when an entry spans currencies without
+	// routing through the FX position accounts at a recorded rate
+	ErrCrossCurrencyEntry = errors.New("invalid cross-currency entry")
+)
+
+// CurrencyImbalance is the amount by which one currency of an entry fails to balance
+type CurrencyImbalance struct {
+	Currency    string      `json:"currency"`
+	TotalDebit  money.Money `json:"total_debit"`
+	TotalCredit money.Money `json:"total_credit"`
+	// Difference is debits minus credits
+	Difference money.Money `json:"difference"`
+}
+
+// UnbalancedEntryError reports every currency in which an entry's debits do
+// not equal its credits
+type UnbalancedEntryError struct {
+	Imbalances []CurrencyImbalance
+}
+
+func (e *UnbalancedEntryError) Error() string {
+	parts := make([]string, 0, len(e.Imbalances))
+	for _, imb := range e.Imbalances {
+		parts = append(parts, fmt.Sprintf("%s debits %s, credits %s", imb.Currency, imb.TotalDebit.Decimal(), imb.TotalCredit.Decimal()))
+	}
+	return fmt.Sprintf("%s: %s", ErrUnbalancedEntry, strings.Join(parts, "; "))
+}
+
+// Unwrap lets callers match the error with errors.Is(err, ErrUnbalancedEntry)
+func (e *UnbalancedEntryError) Unwrap() error {
+	return ErrUnbalancedEntry
+}
+
 // transactionServiceImpl is the implementation of TransactionService
 type transactionServiceImpl struct {
-	repo         repository.EntryRepository
-	accountRepo  repository.AccountRepository
+	repo        repository.EntryRepository
+	accountRepo repository.AccountRepository
+	txRepo      repository.TransactionRepository
+	periodRepo  repository.PeriodRepository
 }
 
 // TransactionResponse represents the response for transaction operations
 type TransactionResponse struct {
-	ID              string    `json:"id"`
-	Description     string    `json:"description"`
-	TransactionType string    `json:"transaction_type"`
-	ReferenceID     string    `json:"reference_id,omitempty"`
-	Status          string    `json:"status"`
-	Date            time.Time `json:"date"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              string         `json:"id"`
+	Description     string         `json:"description"`
+	TransactionType string         `json:"transaction_type"`
+	ReferenceID     string         `json:"reference_id,omitempty"`
+	Status          string         `json:"status"`
+	Date            time.Time      `json:"date"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
 	Lines           []LineResponse `json:"lines,omitempty"`
 }
 
 type LineResponse struct {
-	ID        string    `json:"id"`
-	AccountID string    `json:"account_id"`
-	Debit     float64   `json:"debit"`
-	Credit    float64   `json:"credit"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string      `json:"id"`
+	AccountID string      `json:"account_id"`
+	Debit     money.Money `json:"debit"`
+	Credit    money.Money `json:"credit"`
+	CreatedAt time.Time   `json:"created_at"`
 }
 
 // NewTransactionService creates a new TransactionService
-func NewTransactionService(entryRepo repository.EntryRepository, accountRepo repository.AccountRepository) TransactionService {
+func NewTransactionService(entryRepo repository.EntryRepository, accountRepo repository.AccountRepository, txRepo repository.TransactionRepository, periodRepo repository.PeriodRepository) TransactionService {
 	return &transactionServiceImpl{
-		repo:         entryRepo,
-		accountRepo:  accountRepo,
+		repo:        entryRepo,
+		accountRepo: accountRepo,
+		txRepo:      txRepo,
+		periodRepo:  periodRepo,
 	}
 }
 
-// ValidateEntry ensures the entry follows double-entry accounting rules
+// ValidateEntry ensures the entry follows double-entry accounting rules. Each
+// line is in its account's currency and debits must equal credits separately
+// in every currency. An entry spanning currencies must route each of them
+// through its FX position account and record the rates that connect them.
 func (s *transactionServiceImpl) ValidateEntry(ctx context.Context, entry *models.Entry) error {
 	if len(entry.Lines) < 2 {
-		return errors.New("entry must have at least two lines")
+		return fmt.Errorf("%w: entry must have at least two lines", ErrInvalidEntry)
 	}
 
-	var totalDebit, totalCredit float64
-	accountIDs := make(map[string]bool)
+	totalDebit := make(map[string]money.Money)
+	totalCredit := make(map[string]money.Money)
+	var currencies []string
+	accounts := make(map[string]*models.Account)
+	positions := make(map[string]bool)
+
+	for i := range entry.Lines {
+		line := &entry.Lines[i]
 
-	for _, line := range entry.Lines {
 		// Ensure account exists
-		if _, exists := accountIDs[line.AccountID]; !exists {
-			account, err := s.accountRepo.GetAccountByID(ctx, line.AccountID)
+		account, exists := accounts[line.AccountID]
+		if !exists {
+			var err error
+			account, err = s.accountRepo.GetAccountByID(ctx, line.AccountID)
 			if err != nil {
 				return fmt.Errorf("error validating account %s: %w", line.AccountID, err)
 			}
 			if account == nil {
-				return fmt.Errorf("account %s not found", line.AccountID)
+				return fmt.Errorf("%w: %s", ErrAccountNotFound, line.AccountID)
 			}
-			accountIDs[line.AccountID] = true
+
+			// Parent accounts only aggregate their children's balances
+			hasChildren, err := s.accountRepo.HasChildAccounts(ctx, line.AccountID)
+			if err != nil {
+				return fmt.Errorf("error validating account %s: %w", line.AccountID, err)
+			}
+			if hasChildren {
+				return fmt.Errorf("%w: account %s has child accounts", ErrNonLeafAccount, line.AccountID)
+			}
+			accounts[line.AccountID] = account
+		}
+
+		// The account's status must allow the direction of the posting
+		if err := account.CheckPosting(line.Debit.IsPositive(), line.Credit.IsPositive()); err != nil {
+			return err
 		}
 
 		// Validate line amounts
-		if line.Debit < 0 || line.Credit < 0 {
-			return errors.New("debit and credit amounts must be non-negative")
+		if line.Debit.IsNegative() || line.Credit.IsNegative() {
+			return fmt.Errorf("%w: debit and credit amounts must be non-negative", ErrInvalidEntry)
 		}
-		if line.Debit > 0 && line.Credit > 0 {
-			return errors.New("a line cannot have both debit and credit amounts")
+		if line.Debit.IsPositive() && line.Credit.IsPositive() {
+			return fmt.Errorf("%w: a line cannot have both debit and credit amounts", ErrInvalidEntry)
 		}
 
-		totalDebit += line.Debit
-		totalCredit += line.Credit
+		currency, err := lineCurrency(line, account)
+		if err != nil {
+			return err
+		}
+		line.Currency = currency
+		line.Debit = line.Debit.WithCurrency(currency)
+		line.Credit = line.Credit.WithCurrency(currency)
+
+		if _, seen := totalDebit[currency]; !seen {
+			currencies = append(currencies, currency)
+			totalDebit[currency] = money.Zero(currency)
+			totalCredit[currency] = money.Zero(currency)
+		}
+		if line.AccountID == SystemAccountID(SystemAccountFXPosition, currency) {
+			positions[currency] = true
+		}
+
+		debit, err := totalDebit[currency].Add(line.Debit)
+		if err != nil {
+			return fmt.Errorf("invalid debit on account %s: %w", line.AccountID, err)
+		}
+		credit, err := totalCredit[currency].Add(line.Credit)
+		if err != nil {
+			return fmt.Errorf("invalid credit on account %s: %w", line.AccountID, err)
+		}
+		totalDebit[currency] = debit
+		totalCredit[currency] = credit
 	}
 
-	if totalDebit != totalCredit {
-		return fmt.Errorf("total debits (%f) do not equal total credits (%f)", totalDebit, totalCredit)
+	var imbalances []CurrencyImbalance
+	for _, currency := range currencies {
+		if totalDebit[currency].Cmp(totalCredit[currency]) != 0 {
+			difference, err := totalDebit[currency].Sub(totalCredit[currency])
+			if err != nil {
+				return err
+			}
+			imbalances = append(imbalances, CurrencyImbalance{
+				Currency:    currency,
+				TotalDebit:  totalDebit[currency],
+				TotalCredit: totalCredit[currency],
+				Difference:  difference,
+			})
+		}
+	}
+	if len(imbalances) > 0 {
+		return &UnbalancedEntryError{Imbalances: imbalances}
 	}
 
+	if len(currencies) > 1 {
+		return validateCrossCurrency(entry, currencies, positions)
+	}
+	return nil
+}
+
+// lineCurrency resolves the currency of a line from its Currency field or its
+// amounts, defaulting to the account's, and ensures they all agree
+func lineCurrency(line *models.EntryLine, account *models.Account) (string, error) {
+	currency := strings.ToUpper(line.Currency)
+	for _, amount := range []money.Money{line.Debit, line.Credit} {
+		if amount.Currency() == "" {
+			continue
+		}
+		if currency == "" {
+			currency = amount.Currency()
+		} else if amount.Currency() != currency {
+			return "", fmt.Errorf("%w: line on account %s is in %s but its amount is in %s", ErrLineCurrencyMismatch, line.AccountID, currency, amount.Currency())
+		}
+	}
+	if currency == "" {
+		currency = account.Currency
+	}
+	if currency != account.Currency {
+		return "", fmt.Errorf("%w: line in %s posted to %s account %s", ErrLineCurrencyMismatch, currency, account.Currency, line.AccountID)
+	}
+	return currency, nil
+}
+
+// validateCrossCurrency ensures every currency of an entry passes through its
+// FX position account and that the entry's rates link all of its currencies
+func validateCrossCurrency(entry *models.Entry, currencies []string, positions map[string]bool) error {
+	for _, currency := range currencies {
+		if !positions[currency] {
+			return fmt.Errorf("%w: no line on the %s FX position account", ErrCrossCurrencyEntry, currency)
+		}
+	}
+	if len(entry.ExchangeRates) == 0 {
+		return fmt.Errorf("%w: no exchange rate recorded", ErrCrossCurrencyEntry)
+	}
+
+	// Each rate joins two currencies; together they must connect all of them
+	group := make(map[string]string, len(currencies))
+	for _, currency := range currencies {
+		group[currency] = currency
+	}
+	var find func(string) string
+	find = func(c string) string {
+		if group[c] != c {
+			group[c] = find(group[c])
+		}
+		return group[c]
+	}
+	for i := range entry.ExchangeRates {
+		rate := &entry.ExchangeRates[i]
+		rate.BaseCurrency = strings.ToUpper(rate.BaseCurrency)
+		rate.QuoteCurrency = strings.ToUpper(rate.QuoteCurrency)
+		if _, ok := group[rate.BaseCurrency]; !ok {
+			return fmt.Errorf("%w: rate base currency %s is not used by the entry", ErrCrossCurrencyEntry, rate.BaseCurrency)
+		}
+		if _, ok := group[rate.QuoteCurrency]; !ok {
+			return fmt.Errorf("%w: rate quote currency %s is not used by the entry", ErrCrossCurrencyEntry, rate.QuoteCurrency)
+		}
+		if rate.BaseCurrency == rate.QuoteCurrency || rate.Rate.Sign() <= 0 {
+			return fmt.Errorf("%w: invalid rate %v for %s/%s", ErrCrossCurrencyEntry, rate.Rate, rate.BaseCurrency, rate.QuoteCurrency)
+		}
+		group[find(rate.BaseCurrency)] = find(rate.QuoteCurrency)
+	}
+	for _, currency := range currencies[1:] {
+		if find(currency) != find(currencies[0]) {
+			return fmt.Errorf("%w: no exchange rate links %s to %s", ErrCrossCurrencyEntry, currency, currencies[0])
+		}
+	}
 	return nil
 }
 
 // CreateEntry creates a new transaction entry with validation
 func (s *transactionServiceImpl) CreateEntry(ctx context.Context, entry *models.Entry) error {
+	if err := s.checkEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	prepareEntry(entry)
+	return s.repo.CreateEntry(ctx, entry)
+}
+
+// CreateEntries implements TransactionService
+func (s *transactionServiceImpl) CreateEntries(ctx context.Context, entries ...*models.Entry) error {
+	for _, entry := range entries {
+		if err := s.checkEntry(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		prepareEntry(entry)
+	}
+	return s.repo.CreateEntries(ctx, entries)
+}
+
+// checkEntry validates an entry and checks that its period accepts postings
+func (s *transactionServiceImpl) checkEntry(ctx context.Context, entry *models.Entry) error {
 	// Validate the entry
 	if err := s.ValidateEntry(ctx, entry); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
+	return s.checkPeriod(ctx, entry.Date)
+}
 
+// checkPeriod rejects entries dated in a closed accounting period unless the
+// context carries the period adjustment permission. Dates outside every
+// defined period are always allowed. This only turns entries away early; the
+// repository checks again under a lock on the period as it posts them.
+func (s *transactionServiceImpl) checkPeriod(ctx context.Context, date time.Time) error {
+	period, err := s.periodRepo.FindPeriodForDate(ctx, date)
+	if err != nil {
+		return fmt.Errorf("failed to find accounting period for %s: %w", date.Format(time.RFC3339), err)
+	}
+	if period == nil {
+		return nil
+	}
+	return period.CheckPosting(HasPeriodAdjustment(ctx))
+}
+
+// prepareEntry sets the IDs and timestamps of a new entry and its lines
+func prepareEntry(entry *models.Entry) {
 	// Set timestamps
 	now := time.Now()
 	entry.CreatedAt = now
@@ -114,8 +351,80 @@ func (s *transactionServiceImpl) CreateEntry(ctx context.Context, entry *models.
 		entry.Lines[i].ID = uuid.New().String()
 		entry.Lines[i].EntryID = entry.ID
 	}
+}
 
-	return s.repo.CreateEntry(ctx, entry)
+// ReverseEntry posts a mirror of a posted entry with debits and credits
+// swapped, links it to the original and marks the original as reversed
+func (s *transactionServiceImpl) ReverseEntry(ctx context.Context, entryID, reason string) (*models.Entry, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, errors.New("a reason is required to reverse an entry")
+	}
+
+	original, err := s.repo.GetEntryByID(ctx, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry %s: %w", entryID, err)
+	}
+	if original == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, entryID)
+	}
+	if original.ReversalOfID != "" {
+		return nil, fmt.Errorf("%w: entry %s is itself the reversal of %s", ErrEntryNotReversible, entryID, original.ReversalOfID)
+	}
+	switch original.Status {
+	case models.EntryStatusPosted:
+	case models.EntryStatusReversed:
+		return nil, fmt.Errorf("%w: %s", ErrEntryAlreadyReversed, entryID)
+	default:
+		return nil, fmt.Errorf("%w: entry %s is %s", ErrEntryNotReversible, entryID, original.Status)
+	}
+
+	lines := make([]models.EntryLine, 0, len(original.Lines))
+	for _, line := range original.Lines {
+		lines = append(lines, models.EntryLine{
+			AccountID: line.AccountID,
+			Currency:  line.Currency,
+			Debit:     line.Credit,
+			Credit:    line.Debit,
+		})
+	}
+
+	// A reversal converts back at the rates of the original
+	rates := make([]models.EntryExchangeRate, 0, len(original.ExchangeRates))
+	for _, rate := range original.ExchangeRates {
+		rates = append(rates, models.EntryExchangeRate{
+			BaseCurrency:  rate.BaseCurrency,
+			QuoteCurrency: rate.QuoteCurrency,
+			Rate:          rate.Rate,
+		})
+	}
+
+	reversal := &models.Entry{
+		Description:     fmt.Sprintf("Reversal of %s: %s", original.ID, reason),
+		Date:            time.Now(),
+		TransactionType: original.TransactionType,
+		ReferenceID:     original.ReferenceID,
+		Status:          models.EntryStatusPosted,
+		Lines:           lines,
+		ExchangeRates:   rates,
+	}
+
+	if err := s.ValidateEntry(ctx, reversal); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	if err := s.checkPeriod(ctx, reversal.Date); err != nil {
+		return nil, err
+	}
+	prepareEntry(reversal)
+
+	if err := s.repo.CreateReversal(ctx, original.ID, reversal); err != nil {
+		if errors.Is(err, repository.ErrEntryNotReversible) {
+			// Another request reversed the entry after it was read
+			return nil, fmt.Errorf("%w: %s", ErrEntryAlreadyReversed, entryID)
+		}
+		return nil, fmt.Errorf("failed to reverse entry %s: %w", entryID, err)
+	}
+
+	return reversal, nil
 }
 
 // GetEntryByID retrieves a transaction entry by its ID
@@ -130,106 +439,447 @@ func (s *transactionServiceImpl) GetEntriesByDateRange(ctx context.Context, star
 
 // ProcessTransfer processes a transfer between two accounts
 func (s *transactionServiceImpl) ProcessTransfer(ctx context.Context, req TransferRequest) (*models.Transaction, error) {
-	// TODO: Implement transfer logic with proper validation and transaction handling
+	if posted, err := s.postedTransaction(ctx, req.ReferenceID); err != nil || posted != nil {
+		return posted, err
+	}
+	if req.SourceAccountID == req.DestinationAccountID {
+		return nil, errors.New("source and destination accounts cannot be the same")
+	}
+	amount, err := validateAmount(req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.walletAccount(ctx, req.SourceAccountID, req.Currency); err != nil {
+		return nil, err
+	}
+	if _, err := s.walletAccount(ctx, req.DestinationAccountID, req.Currency); err != nil {
+		return nil, err
+	}
+
 	tx := &models.Transaction{
-		ID:          uuid.New().String(),
-		Type:        "transfer",
-		Status:      "completed",
-		Description: fmt.Sprintf("Transfer of %f %s to account %s", req.Amount, req.Currency, req.DestinationAccountID),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Type:            models.TransactionTypeTransfer,
+		SourceAccountID: req.SourceAccountID,
+		TargetAccountID: req.DestinationAccountID,
+		Amount:          amount,
+		Currency:        req.Currency,
+		Reference:       req.Reference,
+		Description:     fmt.Sprintf("Transfer of %s to account %s", amount, req.DestinationAccountID),
 	}
 
-	return tx, nil
+	// Money leaves the source wallet and lands in the destination wallet
+	lines := []models.EntryLine{
+		{AccountID: req.SourceAccountID, Debit: amount},
+		{AccountID: req.DestinationAccountID, Credit: amount},
+	}
+	lines, err = s.chargeFee(ctx, tx, lines, 0, req.Currency, req.Fee)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.postTransaction(ctx, tx, "transfer", req.ReferenceID, lines, "")
 }
 
 // ProcessDeposit processes a deposit to an account
 func (s *transactionServiceImpl) ProcessDeposit(ctx context.Context, req DepositRequest) (*models.Transaction, error) {
-	// TODO: Implement deposit logic with proper validation and transaction handling
+	if posted, err := s.postedTransaction(ctx, req.ReferenceID); err != nil || posted != nil {
+		return posted, err
+	}
+	amount, err := validateAmount(req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.walletAccount(ctx, req.AccountID, req.Currency); err != nil {
+		return nil, err
+	}
+	clearing, err := s.systemAccount(ctx, SystemAccountBankClearing, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	tx := &models.Transaction{
-		ID:          uuid.New().String(),
-		Type:        "deposit",
-		Status:      "completed",
-		Description: fmt.Sprintf("Deposit of %f %s", req.Amount, req.Currency),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Type:            models.TransactionTypeDeposit,
+		SourceAccountID: clearing.ID,
+		TargetAccountID: req.AccountID,
+		Amount:          amount,
+		Currency:        req.Currency,
+		Reference:       req.Reference,
+		Description:     fmt.Sprintf("Deposit of %s", amount),
 	}
 
-	return tx, nil
+	// Funds received at the bank are owed to the wallet holder
+	lines := []models.EntryLine{
+		{AccountID: clearing.ID, Debit: amount},
+		{AccountID: req.AccountID, Credit: amount},
+	}
+
+	return s.postTransaction(ctx, tx, "deposit", req.ReferenceID, lines, "")
 }
 
 // ProcessWithdrawal processes a withdrawal from an account
 func (s *transactionServiceImpl) ProcessWithdrawal(ctx context.Context, req WithdrawalRequest) (*models.Transaction, error) {
-	// TODO: Implement withdrawal logic with proper validation and transaction handling
+	if posted, err := s.postedTransaction(ctx, req.ReferenceID); err != nil || posted != nil {
+		return posted, err
+	}
+	amount, err := validateAmount(req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.walletAccount(ctx, req.AccountID, req.Currency); err != nil {
+		return nil, err
+	}
+	clearing, err := s.systemAccount(ctx, SystemAccountBankClearing, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	tx := &models.Transaction{
-		ID:          uuid.New().String(),
-		Type:        "withdrawal",
-		Status:      "completed",
-		Description: fmt.Sprintf("Withdrawal of %f %s", req.Amount, req.Currency),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Type:            models.TransactionTypeWithdrawal,
+		SourceAccountID: req.AccountID,
+		TargetAccountID: clearing.ID,
+		Amount:          amount,
+		Currency:        req.Currency,
+		Reference:       req.Reference,
+		Description:     fmt.Sprintf("Withdrawal of %s", amount),
 	}
 
-	return tx, nil
+	// The wallet is reduced and the funds leave through bank clearing
+	lines := []models.EntryLine{
+		{AccountID: req.AccountID, Debit: amount},
+		{AccountID: clearing.ID, Credit: amount},
+	}
+	lines, err = s.chargeFee(ctx, tx, lines, 0, req.Currency, req.Fee)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.postTransaction(ctx, tx, "withdrawal", req.ReferenceID, lines, "")
 }
 
 // ProcessExchange processes a currency exchange between two accounts
 func (s *transactionServiceImpl) ProcessExchange(ctx context.Context, req ExchangeRequest) (*models.Transaction, error) {
-	// TODO: Implement currency exchange logic with proper validation and transaction handling
+	if posted, err := s.postedTransaction(ctx, req.ReferenceID); err != nil || posted != nil {
+		return posted, err
+	}
+	if req.SourceCurrency == req.DestinationCurrency {
+		return nil, errors.New("source and destination currencies must differ")
+	}
+	if req.ExchangeRate.Sign() <= 0 {
+		return nil, errors.New("exchange rate must be positive")
+	}
+	sourceAmount, err := validateAmount(req.SourceAmount, req.SourceCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source amount: %w", err)
+	}
+	destAmount, err := validateAmount(req.DestinationAmount, req.DestinationCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination amount: %w", err)
+	}
+	if _, err := s.walletAccount(ctx, req.SourceAccountID, req.SourceCurrency); err != nil {
+		return nil, err
+	}
+	if _, err := s.walletAccount(ctx, req.DestinationAccountID, req.DestinationCurrency); err != nil {
+		return nil, err
+	}
+	sourcePosition, err := s.systemAccount(ctx, SystemAccountFXPosition, req.SourceCurrency)
+	if err != nil {
+		return nil, err
+	}
+	destPosition, err := s.systemAccount(ctx, SystemAccountFXPosition, req.DestinationCurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	tx := &models.Transaction{
-		ID:          uuid.New().String(),
-		Type:        "exchange",
-		Status:      "completed",
-		Description: fmt.Sprintf("Exchange %f %s to %f %s", req.SourceAmount, req.SourceCurrency, req.DestinationAmount, req.DestinationCurrency),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Type:            models.TransactionTypeExchange,
+		SourceAccountID: req.SourceAccountID,
+		TargetAccountID: req.DestinationAccountID,
+		Amount:          sourceAmount,
+		Currency:        req.SourceCurrency,
+		Reference:       req.Reference,
+		Description:     fmt.Sprintf("Exchange %s to %s", sourceAmount, destAmount),
+		Metadata: models.JSONMap{
+			"destination_amount":   destAmount.Decimal(),
+			"destination_currency": req.DestinationCurrency,
+			"exchange_rate":        req.ExchangeRate.Decimal(),
+		},
 	}
 
-	return tx, nil
+	// Each currency leg balances on its own through the FX position accounts
+	lines := []models.EntryLine{
+		{AccountID: req.SourceAccountID, Debit: sourceAmount},
+		{AccountID: sourcePosition.ID, Credit: sourceAmount},
+		{AccountID: destPosition.ID, Debit: destAmount},
+		{AccountID: req.DestinationAccountID, Credit: destAmount},
+	}
+	rate := models.EntryExchangeRate{BaseCurrency: req.SourceCurrency, QuoteCurrency: req.DestinationCurrency, Rate: req.ExchangeRate}
+
+	// Converting at a rate other than the market rate realizes a gain or loss:
+	// the position takes on the source amount at its market value and the
+	// difference from what was paid out is booked against the position
+	if req.MidRate != nil && req.MidRate.Sign() > 0 {
+		marketValue, err := sourceAmount.Convert(*req.MidRate, req.DestinationCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to value exchange at the market rate: %w", err)
+		}
+		gain, err := marketValue.Sub(destAmount)
+		if err != nil {
+			return nil, err
+		}
+		if !gain.IsZero() {
+			realized, err := s.systemAccount(ctx, SystemAccountFXRealizedGainLoss, req.DestinationCurrency)
+			if err != nil {
+				return nil, err
+			}
+			lines[2].Debit = marketValue
+			if gain.IsPositive() {
+				lines = append(lines, models.EntryLine{AccountID: realized.ID, Credit: gain})
+			} else {
+				lines = append(lines, models.EntryLine{AccountID: realized.ID, Debit: gain.Abs()})
+			}
+			tx.Metadata["realized_fx_gain_loss"] = gain.Decimal()
+		}
+	}
+
+	lines, err = s.chargeFee(ctx, tx, lines, 0, req.SourceCurrency, req.Fee)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.postTransaction(ctx, tx, "exchange", req.ReferenceID, lines, req.QuoteID, rate)
+}
+
+// chargeFee adds an assessed fee to the debit of the payer's line and credits
+// it to fee revenue in the same entry, recording the schedule that set it on
+// the transaction. A nil fee charges nothing.
+func (s *transactionServiceImpl) chargeFee(ctx context.Context, tx *models.Transaction, lines []models.EntryLine, payer int, currency string, fee *AssessedFee) ([]models.EntryLine, error) {
+	if fee == nil {
+		return lines, nil
+	}
+	if !fee.Amount.SameCurrency(money.Zero(currency)) {
+		return nil, fmt.Errorf("fee is in %s, not %s", fee.Amount.Currency(), currency)
+	}
+	amount := fee.Amount.WithCurrency(currency)
+	if amount.IsNegative() || !amount.HasValidPrecision() {
+		return nil, fmt.Errorf("invalid fee %s", amount)
+	}
+
+	tx.FeeScheduleID = fee.ScheduleID
+	if amount.IsZero() {
+		return lines, nil
+	}
+	revenue, err := s.systemAccount(ctx, SystemAccountFeeRevenue, currency)
+	if err != nil {
+		return nil, err
+	}
+	total, err := lines[payer].Debit.Add(amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add fee: %w", err)
+	}
+	lines[payer].Debit = total
+	tx.Fee = amount
+	tx.FeeCurrency = currency
+	return append(lines, models.EntryLine{AccountID: revenue.ID, Credit: amount}), nil
 }
 
 // ProcessFee processes a fee transaction
 func (s *transactionServiceImpl) ProcessFee(ctx context.Context, req FeeRequest) (*models.Transaction, error) {
-	// TODO: Implement fee processing logic
-	// This is a placeholder implementation
+	if posted, err := s.postedTransaction(ctx, req.ReferenceID); err != nil || posted != nil {
+		return posted, err
+	}
+	amount, err := validateAmount(req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.walletAccount(ctx, req.AccountID, req.Currency); err != nil {
+		return nil, err
+	}
+	revenue, err := s.systemAccount(ctx, SystemAccountFeeRevenue, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	tx := &models.Transaction{
-		ID:          uuid.New().String(),
-		Type:        "fee",
-		Status:      "completed",
-		Description: fmt.Sprintf("Fee of %f %s", req.Amount, req.Currency),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Type:            models.TransactionTypeFee,
+		SourceAccountID: req.AccountID,
+		TargetAccountID: revenue.ID,
+		Amount:          amount,
+		Currency:        req.Currency,
+		Fee:             amount,
+		FeeCurrency:     req.Currency,
+		Reference:       req.Reference,
+		Description:     fmt.Sprintf("Fee of %s", amount),
+	}
+	if req.FeeType != "" {
+		tx.Metadata = models.JSONMap{"fee_type": req.FeeType}
+	}
+
+	lines := []models.EntryLine{
+		{AccountID: req.AccountID, Debit: amount},
+		{AccountID: revenue.ID, Credit: amount},
+	}
+
+	return s.postTransaction(ctx, tx, "fee", req.ReferenceID, lines, "")
+}
+
+// postTransaction posts the ledger entry of tx and records tx as completed in
+// one database transaction, so neither is kept without the other. If the
+// entry cannot be posted the transaction is kept as failed instead.
+// The entry is posted under referenceID, or the transaction's own ID if it
+// is empty. Entries spanning currencies pass the rates they convert at and,
+// if the rate was quoted, the quote the posting uses up.
+func (s *transactionServiceImpl) postTransaction(ctx context.Context, tx *models.Transaction, entryType, referenceID string, lines []models.EntryLine, quoteID string, rates ...models.EntryExchangeRate) (*models.Transaction, error) {
+	tx.ID = uuid.New().String()
+	if referenceID == "" {
+		referenceID = tx.ID
+	}
+
+	entry := &models.Entry{
+		Description:     tx.Description,
+		Date:            time.Now(),
+		TransactionType: entryType,
+		ReferenceID:     referenceID,
+		Status:          "posted",
+		Lines:           lines,
+		ExchangeRates:   rates,
+	}
+
+	err := s.checkEntry(ctx, entry)
+	if err == nil {
+		prepareEntry(entry)
+		completedAt := time.Now()
+		tx.Status = models.TransactionStatusCompleted
+		tx.CompletedAt = &completedAt
+		err = s.repo.CreateTransactionEntry(ctx, tx, entry, quoteID, completedAt)
+	}
+	if err != nil {
+		tx.Status = models.TransactionStatusFailed
+		tx.EntryID = ""
+		tx.CompletedAt = nil
+		if createErr := s.txRepo.CreateTransaction(ctx, tx); createErr != nil {
+			return nil, fmt.Errorf("failed to post entry: %v (also failed to record transaction as failed: %v)", err, createErr)
+		}
+		return nil, fmt.Errorf("failed to post entry: %w", err)
 	}
 
 	return tx, nil
 }
 
+// GetTransactionByReference implements TransactionService
+func (s *transactionServiceImpl) GetTransactionByReference(ctx context.Context, referenceID string) (*models.Transaction, error) {
+	tx, err := s.txRepo.GetTransactionByEntryReference(ctx, referenceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction posted under %s: %w", referenceID, err)
+	}
+	return tx, nil
+}
+
+// postedTransaction returns the transaction already posted under a request's
+// referenceID, or nil if there is none or the request has no reference.
+// Callers hold the reference exclusively, as a CTE worker holds its event's
+// lease, so the lookup and the posting that follows do not race.
+func (s *transactionServiceImpl) postedTransaction(ctx context.Context, referenceID string) (*models.Transaction, error) {
+	if referenceID == "" {
+		return nil, nil
+	}
+	return s.GetTransactionByReference(ctx, referenceID)
+}
+
+// walletAccount retrieves an account and ensures it is held in the given currency
+func (s *transactionServiceImpl) walletAccount(ctx context.Context, accountID, currency string) (*models.Account, error) {
+	if accountID == "" {
+		return nil, errors.New("account ID is required")
+	}
+
+	account, err := s.accountRepo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", accountID, err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+	if account.Currency != currency {
+		return nil, fmt.Errorf("%w: account %s is held in %s, not %s", ErrLineCurrencyMismatch, accountID, account.Currency, currency)
+	}
+
+	return account, nil
+}
+
+// validateAmount checks that an operation amount is positive and representable
+// in its currency, and returns it tagged with that currency
+func validateAmount(amount money.Money, currency string) (money.Money, error) {
+	if currency == "" {
+		return money.Money{}, errors.New("currency is required")
+	}
+	if !amount.SameCurrency(money.Zero(currency)) {
+		return money.Money{}, fmt.Errorf("amount is in %s, not %s", amount.Currency(), currency)
+	}
+	amount = amount.WithCurrency(currency)
+	if !amount.IsPositive() {
+		return money.Money{}, errors.New("amount must be greater than zero")
+	}
+	if !amount.HasValidPrecision() {
+		return money.Money{}, fmt.Errorf("amount %s has more decimal places than %s allows", amount.Decimal(), currency)
+	}
+	return amount, nil
+}
+
 // ReverseTransfer reverses a transfer transaction
 func (s *transactionServiceImpl) ReverseTransfer(ctx context.Context, transactionID string) error {
-	// TODO: Implement transfer reversal logic with proper validation and transaction handling
-	return nil
+	return s.reverseTransaction(ctx, transactionID, models.TransactionTypeTransfer)
 }
 
 // ReverseDeposit reverses a deposit transaction
 func (s *transactionServiceImpl) ReverseDeposit(ctx context.Context, transactionID string) error {
-	// TODO: Implement deposit reversal logic with proper validation and transaction handling
-	return nil
+	return s.reverseTransaction(ctx, transactionID, models.TransactionTypeDeposit)
 }
 
 // ReverseWithdrawal reverses a withdrawal transaction
 func (s *transactionServiceImpl) ReverseWithdrawal(ctx context.Context, transactionID string) error {
-	// TODO: Implement withdrawal reversal logic with proper validation and transaction handling
-	return nil
+	return s.reverseTransaction(ctx, transactionID, models.TransactionTypeWithdrawal)
 }
 
 // ReverseExchange reverses a currency exchange transaction
 func (s *transactionServiceImpl) ReverseExchange(ctx context.Context, transactionID string) error {
-	// TODO: Implement exchange reversal logic with proper validation and transaction handling
-	return nil
+	return s.reverseTransaction(ctx, transactionID, models.TransactionTypeExchange)
 }
 
 // ReverseFee reverses a fee transaction
 func (s *transactionServiceImpl) ReverseFee(ctx context.Context, transactionID string) error {
-	// TODO: Implement fee reversal logic with proper validation and transaction handling
+	return s.reverseTransaction(ctx, transactionID, models.TransactionTypeFee)
+}
+
+// reverseTransaction reverses the ledger entry of a completed wallet
+// transaction of the given type and marks the transaction as reversed
+func (s *transactionServiceImpl) reverseTransaction(ctx context.Context, transactionID string, txType models.TransactionType) error {
+	tx, err := s.txRepo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction %s: %w", transactionID, err)
+	}
+	if tx == nil {
+		return fmt.Errorf("%w: %s", ErrTransactionNotFound, transactionID)
+	}
+	if tx.Type != txType {
+		return fmt.Errorf("transaction %s is a %s, not a %s", transactionID, tx.Type, txType)
+	}
+	if tx.Status == models.TransactionStatusReversed {
+		return fmt.Errorf("%w: transaction %s", ErrEntryAlreadyReversed, transactionID)
+	}
+	if tx.Status != models.TransactionStatusCompleted || tx.EntryID == "" {
+		return fmt.Errorf("%w: transaction %s is %s", ErrEntryNotReversible, transactionID, tx.Status)
+	}
+
+	reason := fmt.Sprintf("%s %s reversed", strings.ToLower(string(txType)), transactionID)
+	if _, err := s.ReverseEntry(ctx, tx.EntryID, reason); err != nil {
+		// A previous attempt may have reversed the entry but not updated the transaction
+		if !errors.Is(err, ErrEntryAlreadyReversed) {
+			return err
+		}
+	}
+
+	tx.Status = models.TransactionStatusReversed
+	if err := s.txRepo.UpdateTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("entry %s reversed but failed to update transaction %s: %w", tx.EntryID, transactionID, err)
+	}
+
 	return nil
 }
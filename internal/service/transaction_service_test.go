@@ -0,0 +1,446 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/models"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type serviceFixture struct {
+	db          *gorm.DB
+	accountRepo repository.AccountRepository
+	entryRepo   repository.EntryRepository
+	txRepo      repository.TransactionRepository
+	balanceRepo repository.BalanceRepository
+	periodRepo  repository.PeriodRepository
+	rateRepo    repository.ExchangeRateRepository
+	svc         service.TransactionService
+}
+
+// setupService creates a transaction service backed by a fresh in-memory SQLite database.
+func setupService(t *testing.T) *serviceFixture {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	testDB, err := db.InitTestDB(dsn)
+	require.NoError(t, err, "Failed to initialize test database")
+
+	err = testDB.AutoMigrate(
+		&models.Account{},
+		&models.Entry{},
+		&models.EntryLine{},
+		&models.EntryExchangeRate{},
+		&models.Transaction{},
+		&models.BalanceSnapshot{},
+		&models.AccountStatusChange{},
+		&models.AccountingPeriod{},
+		&models.PeriodClosingBalance{},
+		&models.ExchangeRate{},
+		&models.ExchangeRateAudit{},
+		&models.FXQuote{},
+		&models.FeeSchedule{},
+	)
+	require.NoError(t, err, "Failed to run migrations")
+
+	t.Cleanup(func() {
+		if sqlDB, err := testDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	f := &serviceFixture{
+		db:          testDB,
+		accountRepo: repository.NewAccountRepository(testDB),
+		entryRepo:   repository.NewEntryRepository(testDB),
+		txRepo:      repository.NewTransactionRepository(testDB),
+		balanceRepo: repository.NewBalanceRepository(testDB),
+		periodRepo:  repository.NewPeriodRepository(testDB),
+		rateRepo:    repository.NewExchangeRateRepository(testDB),
+	}
+	f.svc = service.NewTransactionService(f.entryRepo, f.accountRepo, f.txRepo, f.periodRepo)
+	return f
+}
+
+func (f *serviceFixture) createWallet(t *testing.T, name, currency string) *models.Account {
+	account := &models.Account{Name: name, Type: models.Liability, UserID: "user-" + name, Currency: currency}
+	require.NoError(t, f.accountRepo.CreateAccount(context.Background(), account))
+	return account
+}
+
+// fund deposits an amount into a wallet so that it can be debited
+func (f *serviceFixture) fund(t *testing.T, account *models.Account, amount string) {
+	_, err := f.svc.ProcessDeposit(context.Background(), service.DepositRequest{
+		AccountID: account.ID, Amount: money.MustParse(amount, account.Currency), Currency: account.Currency,
+	})
+	require.NoError(t, err)
+}
+
+// assertEntryLines checks the debit and credit posted to each account by an entry, as decimal strings.
+func assertEntryLines(t *testing.T, entry *models.Entry, expected map[string][2]string) {
+	require.NotNil(t, entry)
+	require.Len(t, entry.Lines, len(expected))
+	for _, line := range entry.Lines {
+		want, ok := expected[line.AccountID]
+		require.True(t, ok, "unexpected line for account %s", line.AccountID)
+		assert.Equal(t, want[0], line.Debit.Decimal(), "debit for account %s", line.AccountID)
+		assert.Equal(t, want[1], line.Credit.Decimal(), "credit for account %s", line.AccountID)
+	}
+}
+
+func TestProcessDeposit(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	wallet := f.createWallet(t, "alice", "USD")
+
+	tx, err := f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: wallet.ID, Amount: money.MustParse("150", "USD"), Currency: "USD", Reference: "dep-1"})
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusCompleted, tx.Status)
+	require.NotEmpty(t, tx.EntryID)
+
+	stored, err := f.txRepo.GetTransactionByID(ctx, tx.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, tx.EntryID, stored.EntryID)
+
+	entry, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assert.Equal(t, tx.ID, entry.ReferenceID)
+	assertEntryLines(t, entry, map[string][2]string{
+		service.SystemAccountID(service.SystemAccountBankClearing, "USD"): {"150.00", "0.00"},
+		wallet.ID: {"0.00", "150.00"},
+	})
+}
+
+func TestProcessDeposit_ReferenceIDPostsOnce(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	wallet := f.createWallet(t, "alice", "USD")
+	req := service.DepositRequest{AccountID: wallet.ID, Amount: money.MustParse("150", "USD"), Currency: "USD", ReferenceID: "cte-tx-1"}
+
+	first, err := f.svc.ProcessDeposit(ctx, req)
+	require.NoError(t, err)
+	entry, err := f.svc.GetEntryByID(ctx, first.EntryID)
+	require.NoError(t, err)
+	assert.Equal(t, "cte-tx-1", entry.ReferenceID)
+
+	// A repeated request returns the posted transaction instead of posting again
+	second, err := f.svc.ProcessDeposit(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	var entries int64
+	require.NoError(t, f.db.Model(&models.Entry{}).Where("reference_id = ?", "cte-tx-1").Count(&entries).Error)
+	assert.EqualValues(t, 1, entries)
+
+	found, err := f.svc.GetTransactionByReference(ctx, "cte-tx-1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, first.ID, found.ID)
+	found, err = f.svc.GetTransactionByReference(ctx, "cte-tx-2")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestProcessTransferAndWithdrawal(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	alice := f.createWallet(t, "alice", "USD")
+	bob := f.createWallet(t, "bob", "USD")
+	f.fund(t, alice, "100")
+
+	tx, err := f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("40", "USD"), Currency: "USD",
+	})
+	require.NoError(t, err)
+	entry, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assertEntryLines(t, entry, map[string][2]string{
+		alice.ID: {"40.00", "0.00"},
+		bob.ID:   {"0.00", "40.00"},
+	})
+
+	tx, err = f.svc.ProcessWithdrawal(ctx, service.WithdrawalRequest{AccountID: bob.ID, Amount: money.MustParse("25", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+	entry, err = f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assertEntryLines(t, entry, map[string][2]string{
+		bob.ID: {"25.00", "0.00"},
+		service.SystemAccountID(service.SystemAccountBankClearing, "USD"): {"0.00", "25.00"},
+	})
+
+	// Currency mismatches are rejected before anything is recorded
+	_, err = f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("10", "EUR"), Currency: "EUR",
+	})
+	assert.Error(t, err)
+}
+
+func TestProcessExchangeAndFee(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	usd := f.createWallet(t, "alice-usd", "USD")
+	eur := f.createWallet(t, "alice-eur", "EUR")
+	f.fund(t, usd, "200")
+
+	tx, err := f.svc.ProcessExchange(ctx, service.ExchangeRequest{
+		SourceAccountID:      usd.ID,
+		SourceAmount:         money.MustParse("100", "USD"),
+		SourceCurrency:       "USD",
+		DestinationAccountID: eur.ID,
+		DestinationAmount:    money.MustParse("90", "EUR"),
+		DestinationCurrency:  "EUR",
+		ExchangeRate:         money.MustParseRate("0.9"),
+	})
+	require.NoError(t, err)
+	entry, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assertEntryLines(t, entry, map[string][2]string{
+		usd.ID: {"100.00", "0.00"},
+		service.SystemAccountID(service.SystemAccountFXPosition, "USD"): {"0.00", "100.00"},
+		service.SystemAccountID(service.SystemAccountFXPosition, "EUR"): {"90.00", "0.00"},
+		eur.ID: {"0.00", "90.00"},
+	})
+
+	tx, err = f.svc.ProcessFee(ctx, service.FeeRequest{AccountID: usd.ID, Amount: money.MustParse("2.5", "USD"), Currency: "USD", FeeType: "exchange"})
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionTypeFee, tx.Type)
+	entry, err = f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assertEntryLines(t, entry, map[string][2]string{
+		usd.ID: {"2.50", "0.00"},
+		service.SystemAccountID(service.SystemAccountFeeRevenue, "USD"): {"0.00", "2.50"},
+	})
+}
+
+func TestProcessFee_ReferenceIDPostsOnce(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	wallet := f.createWallet(t, "alice", "USD")
+	f.fund(t, wallet, "10")
+	req := service.FeeRequest{AccountID: wallet.ID, Amount: money.MustParse("1.5", "USD"), Currency: "USD", ReferenceID: "fee-1"}
+
+	first, err := f.svc.ProcessFee(ctx, req)
+	require.NoError(t, err)
+	second, err := f.svc.ProcessFee(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	var entries int64
+	require.NoError(t, f.db.Model(&models.Entry{}).Where("reference_id = ?", "fee-1").Count(&entries).Error)
+	assert.EqualValues(t, 1, entries)
+}
+
+func TestProcessExchange_FailedPostingLeavesNoEntry(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	usd := f.createWallet(t, "alice-usd", "USD")
+	eur := f.createWallet(t, "alice-eur", "EUR")
+	f.fund(t, usd, "200")
+
+	// The quote is checked in the same database transaction as the posting, so
+	// the entry is rolled back with it
+	_, err := f.svc.ProcessExchange(ctx, service.ExchangeRequest{
+		SourceAccountID:      usd.ID,
+		SourceAmount:         money.MustParse("100", "USD"),
+		SourceCurrency:       "USD",
+		DestinationAccountID: eur.ID,
+		DestinationAmount:    money.MustParse("90", "EUR"),
+		DestinationCurrency:  "EUR",
+		ExchangeRate:         money.MustParseRate("0.9"),
+		QuoteID:              "missing-quote",
+	})
+	require.ErrorIs(t, err, repository.ErrQuoteNotFound)
+
+	var entries int64
+	require.NoError(t, f.db.Model(&models.Entry{}).Where("transaction_type = ?", "exchange").Count(&entries).Error)
+	assert.Zero(t, entries)
+
+	var exchanges []models.Transaction
+	require.NoError(t, f.db.Where("type = ?", models.TransactionTypeExchange).Find(&exchanges).Error)
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, models.TransactionStatusFailed, exchanges[0].Status)
+	assert.Empty(t, exchanges[0].EntryID)
+	assert.Nil(t, exchanges[0].CompletedAt)
+}
+
+func TestValidateEntry_ExactCents(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	alice := f.createWallet(t, "alice", "USD")
+	bob := f.createWallet(t, "bob", "USD")
+	carol := f.createWallet(t, "carol", "USD")
+
+	// 0.10 + 0.20 credits against a 0.30 debit do not balance in float64
+	entry := &models.Entry{
+		Description:     "split",
+		TransactionType: "transfer",
+		Lines: []models.EntryLine{
+			{AccountID: alice.ID, Debit: money.MustParse("0.30", "USD")},
+			{AccountID: bob.ID, Credit: money.MustParse("0.10", "USD")},
+			{AccountID: carol.ID, Credit: money.MustParse("0.20", "USD")},
+		},
+	}
+	assert.NoError(t, f.svc.ValidateEntry(ctx, entry))
+
+	entry.Lines[0].Debit = money.MustParse("0.31", "USD")
+	assert.Error(t, f.svc.ValidateEntry(ctx, entry))
+
+	// Amounts finer than the currency's minor unit are rejected
+	_, err := f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: alice.ID, Amount: money.MustParse("1.005", "USD"), Currency: "USD"})
+	assert.Error(t, err)
+}
+
+func TestValidateEntry_MultiCurrency(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	usd := f.createWallet(t, "alice-usd", "USD")
+	eur := f.createWallet(t, "alice-eur", "EUR")
+	usdPosition := service.SystemAccountID(service.SystemAccountFXPosition, "USD")
+	eurPosition := service.SystemAccountID(service.SystemAccountFXPosition, "EUR")
+	f.fund(t, usd, "200")
+
+	// Line currencies and the rate are stored with the entry
+	tx, err := f.svc.ProcessExchange(ctx, service.ExchangeRequest{
+		SourceAccountID: usd.ID, SourceAmount: money.MustParse("100", "USD"), SourceCurrency: "USD",
+		DestinationAccountID: eur.ID, DestinationAmount: money.MustParse("90", "EUR"), DestinationCurrency: "EUR",
+		ExchangeRate: money.MustParseRate("0.9"),
+	})
+	require.NoError(t, err)
+	entry, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	for _, line := range entry.Lines {
+		assert.Contains(t, []string{"USD", "EUR"}, line.Currency)
+		assert.Equal(t, line.Currency, line.Debit.Currency())
+	}
+	require.Len(t, entry.ExchangeRates, 1)
+	assert.Equal(t, "USD", entry.ExchangeRates[0].BaseCurrency)
+	assert.Equal(t, "EUR", entry.ExchangeRates[0].QuoteCurrency)
+	assert.Equal(t, "0.9", entry.ExchangeRates[0].Rate.String())
+
+	// Debiting USD and crediting EUR one-to-one leaves both currencies unbalanced
+	err = f.svc.ValidateEntry(ctx, &models.Entry{Lines: []models.EntryLine{
+		{AccountID: usd.ID, Debit: money.MustParse("100", "USD")},
+		{AccountID: eur.ID, Credit: money.MustParse("100", "EUR")},
+	}})
+	var unbalanced *service.UnbalancedEntryError
+	require.ErrorAs(t, err, &unbalanced)
+	assert.ErrorIs(t, err, service.ErrUnbalancedEntry)
+	require.Len(t, unbalanced.Imbalances, 2)
+	assert.Equal(t, "USD", unbalanced.Imbalances[0].Currency)
+	assert.Equal(t, "100.00", unbalanced.Imbalances[0].Difference.Decimal())
+	assert.Equal(t, "EUR", unbalanced.Imbalances[1].Currency)
+	assert.Equal(t, "-100.00", unbalanced.Imbalances[1].Difference.Decimal())
+
+	// A line must be in its account's currency
+	err = f.svc.ValidateEntry(ctx, &models.Entry{Lines: []models.EntryLine{
+		{AccountID: usd.ID, Currency: "EUR", Debit: money.MustParse("100", "EUR")},
+		{AccountID: eur.ID, Currency: "EUR", Credit: money.MustParse("100", "EUR")},
+	}})
+	assert.ErrorIs(t, err, service.ErrLineCurrencyMismatch)
+
+	// Balanced currencies still need the FX position accounts and a rate
+	exchange := func(rates ...models.EntryExchangeRate) *models.Entry {
+		return &models.Entry{
+			Lines: []models.EntryLine{
+				{AccountID: usd.ID, Debit: money.MustParse("100", "USD")},
+				{AccountID: usdPosition, Credit: money.MustParse("100", "USD")},
+				{AccountID: eurPosition, Debit: money.MustParse("90", "EUR")},
+				{AccountID: eur.ID, Credit: money.MustParse("90", "EUR")},
+			},
+			ExchangeRates: rates,
+		}
+	}
+	err = f.svc.ValidateEntry(ctx, exchange())
+	assert.ErrorIs(t, err, service.ErrCrossCurrencyEntry)
+	err = f.svc.ValidateEntry(ctx, exchange(models.EntryExchangeRate{BaseCurrency: "USD", QuoteCurrency: "GBP", Rate: money.MustParseRate("0.8")}))
+	assert.ErrorIs(t, err, service.ErrCrossCurrencyEntry)
+	assert.NoError(t, f.svc.ValidateEntry(ctx, exchange(models.EntryExchangeRate{BaseCurrency: "usd", QuoteCurrency: "eur", Rate: money.MustParseRate("0.9")})))
+
+	bob := f.createWallet(t, "bob-usd", "USD")
+	bobEUR := f.createWallet(t, "bob-eur", "EUR")
+	err = f.svc.ValidateEntry(ctx, &models.Entry{
+		Lines: []models.EntryLine{
+			{AccountID: usd.ID, Debit: money.MustParse("100", "USD")},
+			{AccountID: bob.ID, Credit: money.MustParse("100", "USD")},
+			{AccountID: eur.ID, Debit: money.MustParse("90", "EUR")},
+			{AccountID: bobEUR.ID, Credit: money.MustParse("90", "EUR")},
+		},
+		ExchangeRates: []models.EntryExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: money.MustParseRate("0.9")}},
+	})
+	assert.ErrorIs(t, err, service.ErrCrossCurrencyEntry, "cross-currency entries must route through FX positions")
+
+	// A reversal converts back at the same rate
+	_, err = f.svc.ReverseEntry(ctx, entry.ID, "customer cancelled")
+	require.NoError(t, err)
+}
+
+func TestReverseEntry(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	alice := f.createWallet(t, "alice", "USD")
+	bob := f.createWallet(t, "bob", "USD")
+	f.fund(t, alice, "100")
+
+	tx, err := f.svc.ProcessTransfer(ctx, service.TransferRequest{
+		SourceAccountID: alice.ID, DestinationAccountID: bob.ID, Amount: money.MustParse("40", "USD"), Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	reversal, err := f.svc.ReverseEntry(ctx, tx.EntryID, "sent to the wrong wallet")
+	require.NoError(t, err)
+	assert.Equal(t, tx.EntryID, reversal.ReversalOfID)
+
+	stored, err := f.svc.GetEntryByID(ctx, reversal.ID)
+	require.NoError(t, err)
+	assertEntryLines(t, stored, map[string][2]string{
+		alice.ID: {"0.00", "40.00"},
+		bob.ID:   {"40.00", "0.00"},
+	})
+
+	original, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EntryStatusReversed, original.Status)
+
+	balance, err := service.NewBalanceService(f.entryRepo, f.accountRepo, f.balanceRepo).GetAvailableBalance(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.True(t, balance.IsZero(), "bob's balance should be restored, got %s", balance)
+
+	_, err = f.svc.ReverseEntry(ctx, tx.EntryID, "again")
+	assert.ErrorIs(t, err, service.ErrEntryAlreadyReversed)
+
+	_, err = f.svc.ReverseEntry(ctx, reversal.ID, "undo the undo")
+	assert.ErrorIs(t, err, service.ErrEntryNotReversible)
+
+	_, err = f.svc.ReverseEntry(ctx, "missing", "no such entry")
+	assert.ErrorIs(t, err, service.ErrEntryNotFound)
+}
+
+func TestReverseDeposit(t *testing.T) {
+	f := setupService(t)
+	ctx := context.Background()
+	wallet := f.createWallet(t, "alice", "USD")
+
+	tx, err := f.svc.ProcessDeposit(ctx, service.DepositRequest{AccountID: wallet.ID, Amount: money.MustParse("75", "USD"), Currency: "USD"})
+	require.NoError(t, err)
+
+	// The type must match the transaction being reversed
+	require.Error(t, f.svc.ReverseWithdrawal(ctx, tx.ID))
+
+	require.NoError(t, f.svc.ReverseDeposit(ctx, tx.ID))
+
+	stored, err := f.txRepo.GetTransactionByID(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusReversed, stored.Status)
+
+	entry, err := f.svc.GetEntryByID(ctx, tx.EntryID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EntryStatusReversed, entry.Status)
+
+	assert.ErrorIs(t, f.svc.ReverseDeposit(ctx, tx.ID), service.ErrEntryAlreadyReversed)
+}
@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/handlers"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/api/middleware"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/db"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/cte"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/ctel"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/executors"
+	cteStore "github.com/ISRAEL-DUFF/fintech-ledger/internal/engine/store/postgres"
+	"github.com/ISRAEL-DUFF/fintech-ledger/internal/money"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/repository"
 	"github.com/ISRAEL-DUFF/fintech-ledger/internal/service"
 	"github.com/joho/godotenv"
@@ -19,6 +28,15 @@ const (
 	appName    = "fintech-ledger"
 	appVersion = "1.0.0"
 	defaultPort = "8080"
+
+	defaultBalanceVerifyInterval = time.Hour
+
+	defaultFXBaseCurrency = "USD"
+	defaultFXCacheTTL     = time.Minute
+	defaultFXMaxStaleness = 24 * time.Hour
+	defaultFXQuoteTTL     = 30 * time.Second
+
+	defaultCTEWorkers = 4
 )
 
 func main() {
@@ -37,15 +55,74 @@ func main() {
 	// Initialize repositories
 	entryRepo := repository.NewEntryRepository(dbConn)
 	accountRepo := repository.NewAccountRepository(dbConn)
+	transactionRepo := repository.NewTransactionRepository(dbConn)
+	balanceRepo := repository.NewBalanceRepository(dbConn)
+	idempotencyRepo := repository.NewIdempotencyRepository(dbConn)
+	periodRepo := repository.NewPeriodRepository(dbConn)
+	exchangeRateRepo := repository.NewExchangeRateRepository(dbConn)
+	fxQuoteRepo := repository.NewFXQuoteRepository(dbConn)
+	feeScheduleRepo := repository.NewFeeScheduleRepository(dbConn)
 
 	// Initialize services
-	transactionService := service.NewTransactionService(entryRepo, accountRepo)
+	transactionService := service.NewTransactionService(entryRepo, accountRepo, transactionRepo, periodRepo)
+	balanceService := service.NewBalanceService(entryRepo, accountRepo, balanceRepo)
+	accountService := service.NewAccountService(accountRepo, balanceService)
+	reportService := service.NewReportService(entryRepo)
+	periodService := service.NewPeriodService(periodRepo, entryRepo, accountRepo, transactionService)
+	exchangeRateService := service.NewExchangeRateService(rateProviders(exchangeRateRepo), exchangeRateRepo, service.ExchangeRateConfig{
+		BaseCurrency: envOrDefault("FX_BASE_CURRENCY", defaultFXBaseCurrency),
+		CacheTTL:     envDuration("FX_CACHE_TTL", defaultFXCacheTTL),
+		MaxStaleness: envDuration("FX_MAX_STALENESS", defaultFXMaxStaleness),
+	})
+	feeService := service.NewFeeService(feeScheduleRepo)
+	fxQuoteService := service.NewFXQuoteService(fxQuoteRepo, accountRepo, exchangeRateService, feeService, service.QuoteConfig{
+		TTL:    envDuration("FX_QUOTE_TTL", defaultFXQuoteTTL),
+		Spread: envRate("FX_SPREAD", money.Rate{}),
+	})
+	revaluationService := service.NewRevaluationService(entryRepo, accountRepo, periodRepo, transactionService, exchangeRateService,
+		envOrDefault("FX_REPORTING_CURRENCY", envOrDefault("FX_BASE_CURRENCY", defaultFXBaseCurrency)))
+
+	// Initialize the CTE engine. Liens check funds against the ledger
+	// balance, and the executors post through the transaction service.
+	lienManager := ctel.NewLienManager(cteStore.NewLienStore(dbConn), balanceService)
+	executorFactory := executors.NewExecutorFactory(accountRepo, transactionRepo, transactionService, fxQuoteService, feeService, *lienManager)
+	if err := executorFactory.InitializeDefaultExecutors(context.Background()); err != nil {
+		log.Fatalf("Error initializing CTE executors: %v", err)
+	}
+	cteEngine := cte.NewEngine(cteStore.NewEventStore(dbConn), cte.WithTimeoutHandler(lienManager.ExpireEventLiens))
+	for txType, executor := range executorFactory.GetAllExecutors() {
+		cteEngine.RegisterExecutor(txType, executor)
+	}
+
+	// Background work runs until the server shuts down
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Start the background balance verifier
+	service.NewBalanceVerifier(balanceRepo, balanceVerifyInterval()).Start(backgroundCtx)
+
+	// Start the CTE workers, and the recovery scanner that requeues the
+	// events of workers that died, right away and then on its interval
+	cteLease := envDuration("CTE_LEASE", cte.DefaultWorkerLease)
+	workerPool := cte.NewWorkerPool(cteEngine, cte.WorkerConfig{
+		Workers: envInt("CTE_WORKERS", defaultCTEWorkers),
+		Lease:   cteLease,
+	})
+	workerPool.Start(backgroundCtx)
+	cte.NewRecoveryScanner(cteEngine, cte.RecoveryConfig{
+		Policy:   recoveryPolicy(),
+		Lease:    cteLease,
+		Interval: envDuration("CTE_RECOVERY_INTERVAL", cte.DefaultRecoveryInterval),
+	}).Start(backgroundCtx)
 
 	// Initialize API server
 	server := api.NewServer()
 
+	// Make retried mutating requests with an Idempotency-Key safe
+	server.Use(middleware.Idempotency(idempotencyRepo, envDuration("IDEMPOTENCY_LEASE", middleware.DefaultIdempotencyLease)))
+
 	// Set up routes
-	setupRoutes(server, transactionService)
+	setupRoutes(server, transactionService, accountService, balanceService, reportService, periodService, exchangeRateService, fxQuoteService, revaluationService, feeService)
 
 	// Get port from environment variable or use default
 	port := os.Getenv("PORT")
@@ -68,6 +145,11 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	// Stop the background work; events the workers leave unfinished are
+	// picked up again after a restart
+	stopBackground()
+	workerPool.Wait()
+
 	// Create a deadline to wait for (commented out as not currently used)
 	// ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	// defer cancel()
@@ -75,11 +157,106 @@ func main() {
 	log.Println("Server exiting")
 }
 
+// balanceVerifyInterval reads BALANCE_VERIFY_INTERVAL (e.g. "30m") or falls back to the default
+func balanceVerifyInterval() time.Duration {
+	if v := os.Getenv("BALANCE_VERIFY_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid BALANCE_VERIFY_INTERVAL %q, using %s", v, defaultBalanceVerifyInterval)
+	}
+	return defaultBalanceVerifyInterval
+}
+
+// recoveryPolicy reads how orphaned CTE events are recovered from
+// CTE_RECOVERY_POLICY (e.g. "*=COMPENSATE,wallet.payout=RESUME"); unset, every
+// event is compensated
+func recoveryPolicy() cte.RecoveryPolicy {
+	policy, err := cte.ParseRecoveryPolicy(os.Getenv("CTE_RECOVERY_POLICY"))
+	if err != nil {
+		log.Fatalf("Error parsing CTE_RECOVERY_POLICY: %v", err)
+	}
+	return policy
+}
+
+// rateProviders builds the exchange rate provider chain: stored rates first,
+// then the HTTP endpoint at FX_RATES_URL, then the static table in FX_RATES_FILE
+func rateProviders(exchangeRateRepo repository.ExchangeRateRepository) []service.RateProvider {
+	providers := []service.RateProvider{service.NewDBRateProvider(exchangeRateRepo)}
+	if url := os.Getenv("FX_RATES_URL"); url != "" {
+		providers = append(providers, service.NewHTTPRateProvider(url, nil))
+	}
+	if path := os.Getenv("FX_RATES_FILE"); path != "" {
+		static, err := service.LoadStaticRateProvider(path)
+		if err != nil {
+			log.Fatalf("Error loading exchange rates: %v", err)
+		}
+		providers = append(providers, static)
+	}
+	return providers
+}
+
+// envOrDefault reads an environment variable or falls back to the default
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// envDuration reads a duration (e.g. "30s") from an environment variable or falls back to the default
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+		log.Printf("Invalid %s %q, using %s", key, v, fallback)
+	}
+	return fallback
+}
+
+// envInt reads a positive integer (e.g. "4") from an environment variable or falls back to the default
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("Invalid %s %q, using %d", key, v, fallback)
+	}
+	return fallback
+}
+
+// envRate reads a non-negative rate (e.g. "0.005") from an environment variable or falls back to the default
+func envRate(key string, fallback money.Rate) money.Rate {
+	if v := os.Getenv(key); v != "" {
+		if r, err := money.ParseRate(v); err == nil && r.Sign() >= 0 {
+			return r
+		}
+		log.Printf("Invalid %s %q, using %v", key, v, fallback)
+	}
+	return fallback
+}
+
 // setupRoutes configures all the routes for the application
-func setupRoutes(server *api.Server, transactionService service.TransactionService) {
+func setupRoutes(server *api.Server, transactionService service.TransactionService, accountService service.AccountService, balanceService service.BalanceService, reportService service.ReportService, periodService service.PeriodService, exchangeRateService service.ExchangeRateService, fxQuoteService service.FXQuoteService, revaluationService service.RevaluationService, feeService service.FeeService) {
 	// Initialize transaction handler
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
 
+	// Initialize account handler
+	accountHandler := handlers.NewAccountHandler(accountService, balanceService)
+
+	// Initialize report handler
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	// Initialize accounting period handler
+	periodHandler := handlers.NewPeriodHandler(periodService)
+
+	// Initialize foreign exchange handler
+	fxHandler := handlers.NewFXHandler(exchangeRateService, fxQuoteService, revaluationService)
+
+	// Initialize fee schedule handler
+	feeHandler := handlers.NewFeeHandler(feeService)
+
 	// Mount API routes
 	server.MountHandlers(
 		// Health check routes
@@ -89,6 +266,16 @@ func setupRoutes(server *api.Server, transactionService service.TransactionServi
 		},
 		// Transaction routes
 		transactionHandler.RegisterRoutes,
+		// Account routes
+		accountHandler.RegisterRoutes,
+		// Report routes
+		reportHandler.RegisterRoutes,
+		// Accounting period routes
+		periodHandler.RegisterRoutes,
+		// Foreign exchange routes
+		fxHandler.RegisterRoutes,
+		// Fee schedule routes
+		feeHandler.RegisterRoutes,
 	)
 }
 
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Wallet-level transactions, each linked to the ledger entry that posted it
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id TEXT PRIMARY KEY,
+    type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    source_account_id TEXT,
+    target_account_id TEXT,
+    amount DECIMAL(19,4) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    fee DECIMAL(19,4) DEFAULT 0,
+    fee_currency VARCHAR(3),
+    reference VARCHAR(255),
+    description TEXT,
+    entry_id TEXT REFERENCES entries(id),
+    metadata JSONB,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions(type);
+CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
+CREATE INDEX IF NOT EXISTS idx_transactions_source_account_id ON transactions(source_account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_target_account_id ON transactions(target_account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_entry_id ON transactions(entry_id);
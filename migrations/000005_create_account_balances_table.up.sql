@@ -0,0 +1,25 @@
+-- +goose Up
+-- Materialized running balance per account, kept in step with entry_lines by
+-- the posting transaction and guarded by an optimistic-lock version
+
+CREATE TABLE IF NOT EXISTS account_balances (
+    account_id TEXT PRIMARY KEY REFERENCES accounts(id),
+    total_debit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    total_credit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 0,
+    last_entry_id TEXT,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Backfill snapshots for accounts that already have postings
+INSERT INTO account_balances (account_id, total_debit, total_credit, version, updated_at)
+SELECT entry_lines.account_id,
+       COALESCE(SUM(entry_lines.debit), 0),
+       COALESCE(SUM(entry_lines.credit), 0),
+       1,
+       NOW()
+FROM entry_lines
+JOIN entries ON entries.id = entry_lines.entry_id
+WHERE entries.status NOT IN ('pending', 'voided')
+GROUP BY entry_lines.account_id
+ON CONFLICT (account_id) DO NOTHING;
@@ -0,0 +1,4 @@
+-- +goose Up
+-- Lien amounts use the same DECIMAL(19,4) precision as every other monetary column
+
+ALTER TABLE cte_liens ALTER COLUMN amount TYPE DECIMAL(19,4);
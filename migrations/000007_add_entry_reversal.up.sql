@@ -0,0 +1,7 @@
+-- +goose Up
+-- A reversal entry points back at the entry it offsets; an entry can be
+-- reversed at most once
+
+ALTER TABLE entries ADD COLUMN IF NOT EXISTS reversal_of_id TEXT REFERENCES entries(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_entries_reversal_of_id ON entries(reversal_of_id) WHERE reversal_of_id IS NOT NULL;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Responses to mutating API requests made with an Idempotency-Key header, so
+-- that retries replay the original response instead of repeating the request
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key TEXT PRIMARY KEY,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- CTE events created with an idempotency key
+ALTER TABLE cte_events ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+ALTER TABLE cte_events ADD COLUMN IF NOT EXISTS request_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cte_events_idempotency_key ON cte_events (idempotency_key);
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Account lifecycle status. Accounts are closed rather than deleted because
+-- entry_lines keep referencing them.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE accounts ADD CONSTRAINT chk_accounts_status CHECK (status IN ('active', 'frozen', 'dormant', 'closed'));
+
+CREATE INDEX IF NOT EXISTS idx_accounts_status ON accounts (status);
+
+-- Every status transition with who made it and why
+CREATE TABLE IF NOT EXISTS account_status_history (
+    id TEXT PRIMARY KEY,
+    account_id TEXT NOT NULL REFERENCES accounts(id),
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_status_history_account_id ON account_status_history (account_id, created_at);
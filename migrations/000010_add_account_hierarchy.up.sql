@@ -0,0 +1,11 @@
+-- +goose Up
+-- Chart of accounts: accounts carry an optional code (e.g. 2000-USER-WALLETS)
+-- and may sit under a parent account. Only leaf accounts are posted to; parent
+-- balances are rolled up from their descendants.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS code VARCHAR(64);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS parent_account_id TEXT REFERENCES accounts(id);
+ALTER TABLE accounts ADD CONSTRAINT chk_accounts_parent_not_self CHECK (parent_account_id <> id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_code ON accounts (code);
+CREATE INDEX IF NOT EXISTS idx_accounts_parent_account_id ON accounts (parent_account_id);
@@ -0,0 +1,4 @@
+-- +goose Up
+-- Reports aggregate entry lines by entry date, e.g. the trial balance as of a
+-- point in time
+CREATE INDEX IF NOT EXISTS idx_entries_date ON entries (date);
@@ -0,0 +1,33 @@
+-- +goose Up
+-- Accounting periods. Entries dated in a soft-closed period need the
+-- adjustment permission; nothing may be posted into a hard-closed one.
+
+CREATE TABLE IF NOT EXISTS accounting_periods (
+    id TEXT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    start_date TIMESTAMPTZ NOT NULL,
+    end_date TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    closed_at TIMESTAMPTZ,
+    closed_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_accounting_periods_status CHECK (status IN ('open', 'soft_closed', 'hard_closed')),
+    CONSTRAINT chk_accounting_periods_dates CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_accounting_periods_dates ON accounting_periods (start_date, end_date);
+
+-- Balance of every account with postings as of the end of a closed period
+CREATE TABLE IF NOT EXISTS period_closing_balances (
+    id TEXT PRIMARY KEY,
+    period_id TEXT NOT NULL REFERENCES accounting_periods(id),
+    account_id TEXT NOT NULL REFERENCES accounts(id),
+    account_type VARCHAR(20) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    total_debit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    total_credit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT idx_period_closing_balances_period_account UNIQUE (period_id, account_id)
+);
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Every entry line records its currency, which must be its account's.
+-- Existing lines take the currency of the account they were posted to.
+
+ALTER TABLE entry_lines ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT '';
+
+UPDATE entry_lines
+SET currency = accounts.currency
+FROM accounts
+WHERE accounts.id = entry_lines.account_id AND entry_lines.currency = '';
+
+-- Rates at which cross-currency entries converted through the FX position accounts
+CREATE TABLE IF NOT EXISTS entry_exchange_rates (
+    id TEXT PRIMARY KEY,
+    entry_id TEXT NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+    base_currency VARCHAR(3) NOT NULL,
+    quote_currency VARCHAR(3) NOT NULL,
+    rate DECIMAL(19,8) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_entry_exchange_rates_rate CHECK (rate > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entry_exchange_rates_entry_id ON entry_exchange_rates (entry_id);
@@ -0,0 +1,29 @@
+-- +goose Up
+-- Stored exchange rates; the latest rate effective at a given time applies
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id TEXT PRIMARY KEY,
+    base_currency VARCHAR(3) NOT NULL,
+    quote_currency VARCHAR(3) NOT NULL,
+    rate DECIMAL(19,8) NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    source VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_exchange_rates_rate CHECK (rate > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_pair ON exchange_rates (base_currency, quote_currency, effective_from);
+
+-- The rate and source that served each currency conversion
+CREATE TABLE IF NOT EXISTS exchange_rate_audits (
+    id TEXT PRIMARY KEY,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount DECIMAL(19,4) NOT NULL,
+    converted_amount DECIMAL(19,4) NOT NULL,
+    rate DECIMAL(19,8) NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    rate_as_of TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_audits_created_at ON exchange_rate_audits (created_at);
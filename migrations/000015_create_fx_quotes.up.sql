@@ -0,0 +1,26 @@
+-- +goose Up
+-- FX quotes lock the rate, spread and fee of one exchange until they expire.
+-- The exchange posting sets consumed_at and entry_id in its own transaction.
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id TEXT PRIMARY KEY,
+    account_id TEXT,
+    source_currency VARCHAR(3) NOT NULL,
+    destination_currency VARCHAR(3) NOT NULL,
+    source_amount DECIMAL(19,4) NOT NULL,
+    destination_amount DECIMAL(19,4) NOT NULL,
+    mid_rate DECIMAL(19,8) NOT NULL,
+    spread DECIMAL(9,6) NOT NULL,
+    rate DECIMAL(19,8) NOT NULL,
+    fee DECIMAL(19,4) NOT NULL,
+    rate_source VARCHAR(255) NOT NULL,
+    rate_as_of TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    entry_id TEXT REFERENCES entries(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_fx_quotes_rate CHECK (rate > 0 AND mid_rate > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fx_quotes_account_id ON fx_quotes (account_id);
+CREATE INDEX IF NOT EXISTS idx_fx_quotes_expires_at ON fx_quotes (expires_at);
+CREATE INDEX IF NOT EXISTS idx_fx_quotes_entry_id ON fx_quotes (entry_id);
@@ -0,0 +1,33 @@
+-- +goose Up
+-- Fee schedules price the fee charged on a transaction type in a currency for
+-- an account tier. Rows are never updated: each change is a new version that
+-- takes effect at effective_from, so past fees can be worked out again.
+CREATE TABLE IF NOT EXISTS fee_schedules (
+    id TEXT PRIMARY KEY,
+    transaction_type VARCHAR(64) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    account_tier VARCHAR(32) NOT NULL,
+    version INTEGER NOT NULL,
+    fee_type VARCHAR(20) NOT NULL,
+    flat_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    rate DECIMAL(9,6) NOT NULL DEFAULT 0,
+    min_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    max_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    tiers JSONB,
+    effective_from TIMESTAMPTZ NOT NULL,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_fee_schedules_fee_type CHECK (fee_type IN ('flat', 'percentage', 'tiered', 'capped')),
+    CONSTRAINT chk_fee_schedules_rate CHECK (rate >= 0 AND rate <= 1)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fee_schedules_version ON fee_schedules (transaction_type, currency, account_tier, version);
+CREATE INDEX IF NOT EXISTS idx_fee_schedules_effective_from ON fee_schedules (transaction_type, currency, account_tier, effective_from);
+
+-- Accounts are charged the fee schedules of their tier
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS tier VARCHAR(32) NOT NULL DEFAULT 'standard';
+
+-- The schedule version that priced the fee of a transaction or quote
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee_schedule_id TEXT REFERENCES fee_schedules(id);
+CREATE INDEX IF NOT EXISTS idx_transactions_fee_schedule_id ON transactions (fee_schedule_id);
+ALTER TABLE fx_quotes ADD COLUMN IF NOT EXISTS fee_schedule_id TEXT REFERENCES fee_schedules(id);
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Workers lease the events they run so that several ledger instances can
+-- share them; an event whose lease has lapsed is picked up by another worker
+ALTER TABLE cte_events ADD COLUMN IF NOT EXISTS lease_owner VARCHAR(255);
+ALTER TABLE cte_events ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Workers look for queued events by state and lease
+CREATE INDEX IF NOT EXISTS idx_cte_events_lease ON cte_events (state, lease_expires_at);
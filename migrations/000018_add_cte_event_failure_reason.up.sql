@@ -0,0 +1,3 @@
+-- +goose Up
+-- Events the engine fails itself, such as on a timeout, record why
+ALTER TABLE cte_events ADD COLUMN IF NOT EXISTS failure_reason TEXT;
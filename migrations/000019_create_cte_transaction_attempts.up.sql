@@ -0,0 +1,14 @@
+-- +goose Up
+-- Every attempt at executing a CTE transaction, retries included
+CREATE TABLE IF NOT EXISTS cte_transaction_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES cte_transactions(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL REFERENCES cte_events(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (transaction_id, attempt)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cte_transaction_attempts_event_id ON cte_transaction_attempts (event_id);
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Transactions may carry conditions on the outcome of their dependencies
+ALTER TABLE cte_transactions ADD COLUMN IF NOT EXISTS conditions JSONB;
+
+-- Transactions whose conditions do not hold are recorded as SKIPPED
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION validate_transaction_state()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.state NOT IN ('PENDING', 'EXECUTING', 'COMPLETED', 'FAILED', 'COMPENSATED', 'SKIPPED') THEN
+        RAISE EXCEPTION 'Invalid transaction state: %', NEW.state;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,4 @@
+-- +goose Up
+-- A key reserved by a request that never completed, for example because the
+-- server crashed, is taken over by a retry once its reservation outlives the lease
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS reserved_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,4 @@
+-- +goose Up
+-- Identifies the request holding a key, so that a request whose key was taken
+-- over cannot store its response or release the key under the new holder
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS reservation VARCHAR(36);